@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/sobhit-avrl/depman-v1/pkg/depman"
+	"github.com/spf13/cobra"
+)
+
+// completionShells are the shells a completion script can be generated for
+var completionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+// runCompletion writes the completion script for shell to w
+func runCompletion(w io.Writer, shell string) error {
+	switch shell {
+	case "bash":
+		return rootCmd.GenBashCompletionV2(w, true)
+	case "zsh":
+		return rootCmd.GenZshCompletion(w)
+	case "fish":
+		return rootCmd.GenFishCompletion(w, true)
+	case "powershell":
+		return rootCmd.GenPowerShellCompletionWithDesc(w)
+	default:
+		return fmt.Errorf("unsupported shell '%s' (supported: %s)", shell, strings.Join(completionShells, ", "))
+	}
+}
+
+// completeDependencyNames completes dependency names from the configuration,
+// leaving out names already given on the command line
+func completeDependencyNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	// Reading stdin would block the shell waiting for completions
+	if configPath == depman.StdinConfigPath {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var config *depman.DependencyConfig
+	var err error
+	if configGlob != "" {
+		config, err = depman.LoadDependencyConfigs(configGlob)
+	} else {
+		config, err = depman.LoadDependencyConfigFormat(configPath, configFormat)
+	}
+	if err != nil {
+		// No configuration means nothing to complete, not an error worth showing mid-completion
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var names []string
+	for _, dep := range config.Dependencies {
+		if strings.HasPrefix(dep.Name, toComplete) && !slices.Contains(args, dep.Name) {
+			if dep.Description != "" {
+				names = append(names, dep.Name+"\t"+dep.Description)
+			} else {
+				names = append(names, dep.Name)
+			}
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
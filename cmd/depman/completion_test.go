@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// TestRunCompletion tests generating a completion script for each shell
+func TestRunCompletion(t *testing.T) {
+	for _, shell := range completionShells {
+		t.Run(shell, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := runCompletion(&buf, shell); err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+			if !strings.Contains(buf.String(), "depman") {
+				t.Errorf("Expected a completion script for depman but got: %q", buf.String())
+			}
+		})
+	}
+
+	t.Run("Unsupported shell", func(t *testing.T) {
+		if err := runCompletion(&bytes.Buffer{}, "tcsh"); err == nil {
+			t.Errorf("Expected an error but got none")
+		}
+	})
+}
+
+// TestCompleteDependencyNames tests completing dependency names from the configuration
+func TestCompleteDependencyNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app-dependencies.yml")
+	config := `version: "1.1"
+name: "Completion App"
+dependencies:
+  - name: "node"
+    description: "JavaScript runtime"
+  - name: "nginx"
+  - name: "python"
+`
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	testCases := []struct {
+		name       string
+		configPath string
+		args       []string
+		toComplete string
+		expected   []string
+	}{
+		{name: "All names", configPath: path, expected: []string{"node\tJavaScript runtime", "nginx", "python"}},
+		{name: "Prefix", configPath: path, toComplete: "n", expected: []string{"node\tJavaScript runtime", "nginx"}},
+		{name: "Already given names are left out", configPath: path, args: []string{"node"}, expected: []string{"nginx", "python"}},
+		{name: "Missing configuration", configPath: filepath.Join(t.TempDir(), "missing.yml"), expected: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			configPath = tc.configPath
+			defer func() { configPath = "" }()
+
+			names, directive := completeDependencyNames(verifyCmd, tc.args, tc.toComplete)
+			if directive != cobra.ShellCompDirectiveNoFileComp {
+				t.Errorf("Expected file completion to be disabled but got directive %d", directive)
+			}
+			if strings.Join(names, ",") != strings.Join(tc.expected, ",") {
+				t.Errorf("Expected %q but got %q", tc.expected, names)
+			}
+		})
+	}
+}
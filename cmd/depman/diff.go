@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sobhit-avrl/depman-v1/pkg/depman"
+)
+
+// Kinds of drift between the configuration and the installed state
+const (
+	diffMissing = "missing" // Not installed
+	diffChanged = "changed" // Installed at a version needing an update or violating the constraint
+)
+
+// diffEntry is a dependency whose installed state differs from the configuration
+type diffEntry struct {
+	Name       string `json:"name"`                 // Name of the dependency
+	Kind       string `json:"kind"`                 // diffMissing or diffChanged
+	Current    string `json:"current,omitempty"`    // Installed version, if installed
+	Required   string `json:"required,omitempty"`   // Required version
+	Constraint string `json:"constraint,omitempty"` // Version constraint, if any
+	Compatible bool   `json:"compatible"`           // Whether the installed version satisfies the constraint
+}
+
+// buildDiff returns the drifted dependencies in configuration order, leaving out those that
+// are up to date, skipped or were not checked
+func buildDiff(deps []depman.Dependency, statuses map[string]*depman.DependencyStatus) []diffEntry {
+	entries := []diffEntry{}
+	for _, dep := range deps {
+		status, ok := statuses[dep.Name]
+		if !ok || status.Skipped {
+			continue
+		}
+
+		entry := diffEntry{
+			Name:       dep.Name,
+			Current:    status.CurrentVersion,
+			Required:   dep.Version.Required,
+			Constraint: dep.Version.Constraint,
+			Compatible: status.Compatible,
+		}
+		switch {
+		case !status.Installed:
+			entry.Kind = diffMissing
+			entry.Current = ""
+		case !status.Compatible || status.RequiredUpdate != depman.NoUpdate:
+			entry.Kind = diffChanged
+		default:
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// formatDiff renders drifted dependencies diff-style: "+" for missing ones and "~" for ones
+// installed at the wrong version
+func formatDiff(entries []diffEntry) string {
+	var b strings.Builder
+	for _, entry := range entries {
+		if entry.Kind == diffMissing {
+			fmt.Fprintf(&b, "+ %s %s\n", entry.Name, entry.Required)
+			continue
+		}
+
+		fmt.Fprintf(&b, "~ %s %s -> %s", entry.Name, entry.Current, entry.Required)
+		if !entry.Compatible && entry.Constraint != "" {
+			fmt.Fprintf(&b, " (does not satisfy %s)", entry.Constraint)
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/sobhit-avrl/depman-v1/pkg/depman"
+)
+
+func TestFormatDiff(t *testing.T) {
+	deps := []depman.Dependency{
+		{Name: "current", Version: depman.Version{Required: "1.0.0"}},
+		{Name: "missing", Version: depman.Version{Required: "2.0.0"}},
+		{Name: "outdated", Version: depman.Version{Required: "3.1.0"}},
+		{Name: "incompatible", Version: depman.Version{Required: "4.0.0", Constraint: "^4.0.0"}},
+		{Name: "skipped", Version: depman.Version{Required: "5.0.0"}},
+		{Name: "disabled", Version: depman.Version{Required: "6.0.0"}},
+	}
+	statuses := map[string]*depman.DependencyStatus{
+		"current":      {Name: "current", Installed: true, CurrentVersion: "1.0.0", Compatible: true},
+		"missing":      {Name: "missing"},
+		"outdated":     {Name: "outdated", Installed: true, CurrentVersion: "3.0.0", Compatible: true, RequiredUpdate: depman.MinorUpdate},
+		"incompatible": {Name: "incompatible", Installed: true, CurrentVersion: "3.9.0", RequiredUpdate: depman.MajorUpdate},
+		"skipped":      {Name: "skipped", Skipped: true},
+	}
+
+	entries := buildDiff(deps, statuses)
+
+	expected := "+ missing 2.0.0\n" +
+		"~ outdated 3.0.0 -> 3.1.0\n" +
+		"~ incompatible 3.9.0 -> 4.0.0 (does not satisfy ^4.0.0)\n"
+	if got := formatDiff(entries); got != expected {
+		t.Errorf("Expected diff:\n%s\nbut got:\n%s", expected, got)
+	}
+
+	// The JSON form carries the same entries
+	data, err := json.Marshal(entries)
+	if err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+	var decoded []diffEntry
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+	if len(decoded) != 3 || decoded[0].Kind != diffMissing || decoded[2].Kind != diffChanged || decoded[2].Current != "3.9.0" {
+		t.Errorf("Expected missing, outdated and incompatible entries but got %+v", decoded)
+	}
+
+	// Nothing drifted
+	if entries := buildDiff(deps[:1], statuses); len(entries) != 0 || formatDiff(entries) != "" {
+		t.Errorf("Expected no drift but got %+v", entries)
+	}
+}
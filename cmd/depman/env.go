@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sobhit-avrl/depman-v1/pkg/depman"
+)
+
+// formatEnvDiff renders environment changes one per line: PATH entries added (+=) or removed
+// (-=), then variables added (+), changed (~) and removed (-)
+// Secret values are masked, as the output often ends up in CI logs
+func formatEnvDiff(diff depman.EnvironmentDiff) string {
+	if diff.Empty() {
+		return "No environment changes\n"
+	}
+	diff = depman.MaskEnvironmentDiff(diff)
+
+	var b strings.Builder
+	for _, path := range diff.AddedPaths {
+		fmt.Fprintf(&b, "PATH += %s\n", path)
+	}
+	for _, path := range diff.RemovedPaths {
+		fmt.Fprintf(&b, "PATH -= %s\n", path)
+	}
+	for _, change := range diff.Added {
+		fmt.Fprintf(&b, "+ %s=%s\n", change.Key, change.New)
+	}
+	for _, change := range diff.Changed {
+		fmt.Fprintf(&b, "~ %s=%s (was %s)\n", change.Key, change.New, change.Old)
+	}
+	for _, change := range diff.Removed {
+		fmt.Fprintf(&b, "- %s (was %s)\n", change.Key, change.Old)
+	}
+	return b.String()
+}
+
+// runEnv prints how the environment of all dependencies would change the current environment,
+// without installing anything
+func runEnv() error {
+	manager, err := createManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize: %w", err)
+	}
+
+	diff, err := manager.PlanEnvironment()
+	if err != nil {
+		return fmt.Errorf("failed to compute environment: %w", err)
+	}
+
+	fmt.Print(formatEnvDiff(diff))
+	return nil
+}
@@ -1,13 +1,31 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"maps"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/sobhit-avrl/depman-v1/internal/downloader"
 	"github.com/sobhit-avrl/depman-v1/internal/logger"
 	"github.com/sobhit-avrl/depman-v1/pkg/depman"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 var (
@@ -16,11 +34,76 @@ var (
 
 	// Flags
 	configPath   string
+	configGlob   string
+	configFormat string
+	configSum    string
 	platformFlag string
+	archFlag     string
+	pkgManager   string
+	prefix       string
 	logLevel     string
 	verbose      bool
+	trace        bool
+	logFile      string
+	logTime      string
+	logUTC       bool
+	skipDeps     []string
+	tags         []string
+	excludeTags  []string
+	keepEnv      bool
+	noColor      bool
+	quiet        bool
+	insecure     bool
+	requireSum   bool
+	caCertFile   string
+	offline      bool
 	outputFile   string
 	force        bool
+	graphFormat  string
+	whyRecursive bool
+
+	installTimeout time.Duration
+	dlTimeout      time.Duration
+	dlRetries      int
+	tempDir        string
+	keepDownloads  string
+	auditLogPath   string
+	cleanEnv       bool
+	parallel       int
+	checkWorkers   int
+	downloadSlots  int
+	verifyRetries  int
+	verifyDelay    time.Duration
+	ensureRetries  int
+	retryDelay     time.Duration
+	stateFile      string
+	stateTTL       time.Duration
+	frozen         bool
+	lockfilePath   string
+	runLockPath    string
+	runLockWait    time.Duration
+	showEnv        bool
+
+	initName   string
+	initTools  []string
+	initOutput string
+
+	checksumAlgorithm string
+	crossHostRedirect bool
+	maxRedirects      int
+	constraintFirst   bool
+	checkURLs         bool
+	deepCheck         bool
+	healthTimeout     time.Duration
+	exportFormat      string
+	exportOutput      string
+	watchInterval     time.Duration
+	lintAllPlatforms  bool
+	listCoverage      bool
+	listJSON          bool
+	diffJSON          bool
+	migrateFrom       string
+	migrateTo         string
 
 	// Root command
 	rootCmd = &cobra.Command{
@@ -35,6 +118,9 @@ It can check for, install, and verify dependencies on various platforms.`,
 			if verbose {
 				logLevel = "debug"
 			}
+			if trace {
+				logLevel = "trace"
+			}
 		},
 	}
 
@@ -47,12 +133,30 @@ It can check for, install, and verify dependencies on various platforms.`,
 		},
 	}
 
+	// Diff command
+	diffCmd = &cobra.Command{
+		Use:   "diff",
+		Short: "Show how installed dependencies differ from the configuration",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDiff()
+		},
+	}
+
+	// Env command
+	envCmd = &cobra.Command{
+		Use:   "env",
+		Short: "Show how the dependency environment changes the current environment",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runEnv()
+		},
+	}
+
 	// Ensure command
 	ensureCmd = &cobra.Command{
-		Use:   "ensure",
-		Short: "Ensure all dependencies are installed and up to date",
+		Use:   "ensure [names...]",
+		Short: "Ensure all (or the named) dependencies are installed and up to date",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runEnsure()
+			return runEnsure(cmd.Context(), args)
 		},
 	}
 
@@ -82,37 +186,360 @@ It can check for, install, and verify dependencies on various platforms.`,
 			return runGenerate()
 		},
 	}
+
+	// Init command
+	initCmd = &cobra.Command{
+		Use:   "init",
+		Short: "Create a starter configuration by probing installed tools",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runInit()
+		},
+	}
+
+	// Verify command
+	verifyCmd = &cobra.Command{
+		Use:   "verify [names...]",
+		Short: "Run verify commands and show their raw output",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runVerify(args)
+		},
+	}
+
+	// Clean command
+	cleanCmd = &cobra.Command{
+		Use:   "clean [names...]",
+		Short: "Uninstall dependencies using their uninstall commands",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runClean(cmd.Context(), args)
+		},
+	}
+
+	// Checksum command
+	checksumCmd = &cobra.Command{
+		Use:   "checksum <url>",
+		Short: "Download a file and print its checksum for pinning",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runChecksum(args[0])
+		},
+	}
+
+	// Export command
+	exportCmd = &cobra.Command{
+		Use:   "export",
+		Short: "Export the dependency environment for containers and CI",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runExport()
+		},
+	}
+
+	// Migrate command
+	migrateCmd = &cobra.Command{
+		Use:   "migrate",
+		Short: "Upgrade the configuration file to a newer schema version",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMigrate()
+		},
+	}
+
+	// Lint command
+	lintCmd = &cobra.Command{
+		Use:   "lint",
+		Short: "Validate the configuration without touching the system",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLint()
+		},
+	}
+
+	// Watch command
+	watchCmd = &cobra.Command{
+		Use:   "watch",
+		Short: "Periodically check dependencies and log changes",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWatch()
+		},
+	}
+
+	// Completion command
+	completionCmd = &cobra.Command{
+		Use:   "completion [bash|zsh|fish|powershell]",
+		Short: "Generate a shell completion script",
+		Long: `Generate a shell completion script for depman and write it to stdout.
+
+Dependency names are completed from the configuration file, for example:
+
+  source <(depman completion bash)`,
+		ValidArgs: completionShells,
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCompletion(os.Stdout, args[0])
+		},
+	}
+
+	// Graph command
+	graphCmd = &cobra.Command{
+		Use:   "graph",
+		Short: "Show the dependency graph",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGraph()
+		},
+	}
+
+	// Why command
+	whyCmd = &cobra.Command{
+		Use:               "why <name>",
+		Short:             "Show which dependencies require a dependency",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeDependencyNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWhy(args[0])
+		},
+	}
 )
 
+// exitInterrupted is the exit code after Ctrl+C or SIGTERM aborted the command (128 + SIGINT)
+const exitInterrupted = 130
+
 func main() {
+	os.Exit(run())
+}
+
+// run executes the root command and returns the exit code
+// It is separate from main so deferred cleanup runs before the process exits
+func run() int {
+	// Flush and close log files once the command has finished
+	defer closeLoggers()
+
+	// Cancel running work on Ctrl+C or SIGTERM, so installs are killed and cleaned up
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
 	// Execute the root command
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	err := rootCmd.ExecuteContext(ctx)
+	interrupted := ctx.Err() != nil
+	stop()
+	if err != nil {
+		printError(os.Stderr, err)
+		if interrupted && errors.Is(err, context.Canceled) {
+			return exitInterrupted
+		}
+		return 1
+	}
+	return 0
+}
+
+// printError prints a command's error, listing configuration problems one per line
+func printError(w io.Writer, err error) {
+	var validationErr *depman.ValidationError
+	if !errors.As(err, &validationErr) {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return
+	}
+
+	fmt.Fprintln(w, "Error: invalid dependency configuration:")
+	for _, problem := range validationErr.Errors() {
+		fmt.Fprintf(w, "  - %v\n", problem)
 	}
 }
 
 func init() {
 	// Add flags to root command
-	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", "Path to dependency configuration file")
+	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", "Path to dependency configuration file (- reads it from stdin)")
+	rootCmd.PersistentFlags().StringVar(&configFormat, "config-format", "", "Parse the configuration as yaml, toml or json instead of detecting it from the extension (use with --config - to read stdin)")
+	rootCmd.PersistentFlags().StringVar(&configSum, "config-checksum", "", "Refuse the configuration file unless it matches this checksum (algorithm:hash, e.g. sha256:...)")
+	rootCmd.PersistentFlags().StringVar(&configGlob, "config-glob", "", "Glob matching multiple configuration files to merge (e.g. 'services/*/app-dependencies.yml')")
 	rootCmd.PersistentFlags().StringVarP(&platformFlag, "platform", "p", "", "Override platform detection (windows, linux, darwin)")
-	rootCmd.PersistentFlags().StringVarP(&logLevel, "log-level", "l", "info", "Log level (debug, info, warn, error)")
+	rootCmd.PersistentFlags().StringVar(&archFlag, "arch", "", "Override the architecture substituted for {arch} in download URLs (e.g. amd64, arm64)")
+	rootCmd.PersistentFlags().StringVar(&prefix, "prefix", "", "Install each dependency into <prefix>/<name>/<version> and add its bin directory to PATH")
+	rootCmd.PersistentFlags().StringVar(&pkgManager, "package-manager", "", "Package manager for package installers that don't name one (default: detected, e.g. apt, brew, winget)")
+	rootCmd.PersistentFlags().StringVarP(&logLevel, "log-level", "l", "info", "Log level (trace, debug, info, warn, error)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
+	rootCmd.PersistentFlags().BoolVar(&trace, "trace", false, "Log every executed command with its directory and environment (implies --verbose)")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Only log errors (results are still printed)")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored log output")
+	rootCmd.PersistentFlags().StringSliceVar(&skipDeps, "skip", nil, "Dependencies to skip (comma-separated)")
+	rootCmd.PersistentFlags().StringSliceVar(&tags, "tag", nil, "Only operate on dependencies with any of these tags (comma-separated)")
+	rootCmd.PersistentFlags().StringSliceVar(&excludeTags, "exclude-tag", nil, "Skip dependencies with any of these tags (comma-separated)")
+	rootCmd.PersistentFlags().BoolVar(&keepEnv, "keep-existing-env", false, "Keep environment variables already set in the shell instead of overriding them from config")
+	rootCmd.PersistentFlags().BoolVar(&insecure, "insecure", false, "Skip TLS certificate verification for downloads (dangerous)")
+	rootCmd.PersistentFlags().BoolVar(&constraintFirst, "constraint-first", false, "Treat dependencies satisfying their constraint as up to date, even if older than the required version")
+	rootCmd.PersistentFlags().BoolVar(&requireSum, "require-checksum", false, "Refuse to download dependencies that have no pinned sha256 or sha512 checksum (checksum_url alone is not enough)")
+	rootCmd.PersistentFlags().StringVar(&caCertFile, "ca-cert", "", "PEM file with extra CA certificates to trust for downloads")
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "Never access the network; downloads and URL checks fail while local verification still works")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "Also write logs to this file (rotated at 10MB)")
+	rootCmd.PersistentFlags().StringVar(&logTime, "log-time-format", "", "Timestamp layout of log entries, as a Go time layout or 'rfc3339' (default \"2006-01-02 15:04:05\")")
+	rootCmd.PersistentFlags().BoolVar(&logUTC, "log-utc", false, "Log timestamps in UTC instead of local time")
+	rootCmd.PersistentFlags().StringVar(&auditLogPath, "audit-log", "", "Append a JSON line per install and uninstall to this file")
 
 	// Add commands
 	rootCmd.AddCommand(checkCmd)
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().BoolVar(&diffJSON, "json", false, "Print the drifted dependencies as JSON")
+	rootCmd.AddCommand(envCmd)
 	rootCmd.AddCommand(ensureCmd)
 	rootCmd.AddCommand(listCmd)
+	listCmd.Flags().BoolVar(&listCoverage, "coverage", false, "Print, as JSON, the dependencies missing configuration for each platform")
+	listCmd.Flags().BoolVar(&listJSON, "json", false, "Print the full configuration as JSON")
 	rootCmd.AddCommand(versionCmd)
 
+	// Check flags
+	checkCmd.Flags().BoolVar(&checkURLs, "urls", false, "Also check that download URLs are reachable")
+	addDeepCheckFlags(checkCmd)
+
+	// Ensure flags
+	ensureCmd.ValidArgsFunction = completeDependencyNames
+	ensureCmd.Flags().BoolVarP(&force, "force", "f", false, "Reinstall the selected dependencies even if already up to date")
+	ensureCmd.Flags().BoolVar(&frozen, "frozen", false, "Verify installed versions match the lockfile without installing or updating")
+	ensureCmd.Flags().StringVar(&lockfilePath, "lockfile", "", "Lockfile pinning download checksums, rewritten after ensuring (or to check with --frozen, default "+depman.DefaultLockfileName+")")
+	ensureCmd.Flags().IntVar(&parallel, "parallel", 0, "Install up to N independent dependencies concurrently")
+	ensureCmd.Flags().IntVar(&checkWorkers, "check-concurrency", 0, "Run up to N verify commands at once with --parallel (default: the --parallel value)")
+	ensureCmd.Flags().IntVar(&downloadSlots, "download-concurrency", 0, "Download at most N dependencies at once with --parallel (default: no limit)")
+	ensureCmd.Flags().IntVar(&verifyRetries, "verify-retries", 0, "Retry post-install verification up to N times")
+	ensureCmd.Flags().IntVar(&ensureRetries, "retries", 0, "Retry dependencies that failed to install up to N times at the end of the run")
+	ensureCmd.Flags().DurationVar(&retryDelay, "retry-delay", 5*time.Second, "Delay before retrying failed dependencies")
+	ensureCmd.Flags().StringVar(&stateFile, "state-file", "", "Skip verifying recently confirmed dependencies using this state file (e.g. "+depman.DefaultStateFileName+")")
+	ensureCmd.Flags().DurationVar(&stateTTL, "state-ttl", depman.DefaultStateTTL, "How long a confirmed dependency skips verification")
+	ensureCmd.Flags().DurationVar(&verifyDelay, "verify-delay", 2*time.Second, "Delay between post-install verification retries")
+	ensureCmd.Flags().BoolVar(&cleanEnv, "clean-env", false, "Run install and verify commands with a minimal environment plus the configured variables")
+	ensureCmd.Flags().StringVar(&keepDownloads, "keep-downloads", "", "Keep downloaded artifacts in this directory for inspection")
+	ensureCmd.Flags().StringVar(&tempDir, "temp-dir", "", "Directory for downloads and extraction (default system temp)")
+	ensureCmd.Flags().DurationVar(&installTimeout, "install-timeout", 0, "Maximum duration for each install command (0 for no limit)")
+	ensureCmd.Flags().DurationVar(&dlTimeout, "download-timeout", 0, "Timeout of each download unless its installer sets one (default 5m)")
+	ensureCmd.Flags().BoolVar(&showEnv, "show-env", false, "Print the PATH entries and variables added or changed by the ensured dependencies")
+	ensureCmd.Flags().StringVar(&runLockPath, "run-lock", "", "Lock file preventing concurrent ensure runs (default "+depman.DefaultRunLockName+" next to --state-file, else one per configuration and user; share one path across configurations or users installing into the same directories)")
+	ensureCmd.Flags().DurationVar(&runLockWait, "lock-wait", 0, "How long to wait for another depman process to finish (0 fails immediately)")
+	ensureCmd.Flags().IntVar(&dlRetries, "download-retries", 0, "Retry transient download failures up to N times unless the installer sets max_retries")
+
 	// Add Generate Command
 	rootCmd.AddCommand(generateCmd)
 	generateCmd.Flags().StringVarP(&outputFile, "output", "o", "app-dependencies.yml", "Output file path")
 	generateCmd.Flags().BoolVarP(&force, "force", "f", false, "Force overwrite existing file")
+
+	// Add Init Command
+	rootCmd.AddCommand(initCmd)
+	initCmd.Flags().StringVar(&initName, "name", "", "Application name")
+	initCmd.Flags().StringArrayVar(&initTools, "tool", nil, "Tool to probe and add (repeatable)")
+	initCmd.Flags().StringVarP(&initOutput, "output", "o", "app-dependencies.yml", "Output file path")
+	initCmd.Flags().BoolVarP(&force, "force", "f", false, "Force overwrite existing file")
+
+	// Add Verify Command
+	rootCmd.AddCommand(verifyCmd)
+	verifyCmd.ValidArgsFunction = completeDependencyNames
+	addDeepCheckFlags(verifyCmd)
+
+	// Add Clean Command
+	rootCmd.AddCommand(cleanCmd)
+	cleanCmd.ValidArgsFunction = completeDependencyNames
+
+	// Add Checksum Command
+	rootCmd.AddCommand(checksumCmd)
+	checksumCmd.Flags().StringVar(&checksumAlgorithm, "algorithm", downloader.DefaultAlgorithm, "Checksum algorithm (sha1, sha256, sha512)")
+	checksumCmd.Flags().BoolVar(&crossHostRedirect, "allow-cross-host-redirect", false, "Follow redirects to other hosts (e.g. a release page to its CDN)")
+	checksumCmd.Flags().IntVar(&maxRedirects, "max-redirects", 0, "Redirects to follow (0 uses the default of 10, negative disables redirects)")
+
+	// Add Export Command
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringVar(&exportFormat, "format", "dotenv", "Output format (dotenv)")
+	exportCmd.Flags().StringVar(&exportOutput, "out", ".env", "Output file path")
+
+	// Add Graph Command
+	rootCmd.AddCommand(graphCmd)
+	graphCmd.Flags().StringVar(&graphFormat, "format", "text", "Output format (text, dot)")
+
+	// Add Why Command
+	rootCmd.AddCommand(whyCmd)
+	whyCmd.Flags().BoolVarP(&whyRecursive, "recursive", "r", false, "Also show dependencies that require it indirectly")
+
+	// Add Migrate Command
+	rootCmd.AddCommand(migrateCmd)
+	migrateCmd.Flags().StringVar(&migrateFrom, "from", "", "Schema version to migrate from (default the version in the file)")
+	migrateCmd.Flags().StringVar(&migrateTo, "to", depman.CurrentConfigVersion, "Schema version to migrate to")
+
+	// Add Lint Command
+	rootCmd.AddCommand(lintCmd)
+	lintCmd.Flags().BoolVar(&lintAllPlatforms, "all-platforms", false, "Also check coverage of every supported platform ("+strings.Join(depman.SupportedPlatforms, ", ")+")")
+
+	// Add Watch Command
+	rootCmd.AddCommand(watchCmd)
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", 5*time.Minute, "Time between checks")
+
+	// Add Completion Command
+	rootCmd.AddCommand(completionCmd)
+	rootCmd.RegisterFlagCompletionFunc("skip", completeDependencyNames)
+	rootCmd.RegisterFlagCompletionFunc("config-format", cobra.FixedCompletions(
+		[]string{depman.ConfigFormatYAML, depman.ConfigFormatTOML, depman.ConfigFormatJSON}, cobra.ShellCompDirectiveNoFileComp))
+}
+
+// loggerOptions maps the logging flags to logger options
+func loggerOptions() logger.Options {
+	opts := logger.Options{
+		Level:         logger.LevelInfo,
+		Output:        os.Stdout,
+		ShowTimestamp: true,
+		ShowColors:    !noColor,
+	}
+
+	switch strings.ToLower(logLevel) {
+	case "trace":
+		opts.Level = logger.LevelTrace
+	case "debug":
+		opts.Level = logger.LevelDebug
+	case "info":
+		opts.Level = logger.LevelInfo
+	case "warn":
+		opts.Level = logger.LevelWarn
+	case "error":
+		opts.Level = logger.LevelError
+	}
+
+	// Quiet only shows errors, results are still printed
+	if quiet {
+		opts.Level = logger.LevelError
+	}
+
+	// Timestamps for log aggregation
+	opts.TimeFormat = logTime
+	if strings.EqualFold(logTime, "rfc3339") {
+		opts.TimeFormat = time.RFC3339
+	}
+	opts.UTC = logUTC
+
+	// Write logs to a file as well if requested
+	if logFile != "" {
+		opts.FilePath = logFile
+		opts.MaxSizeMB = 10
+		opts.MaxBackups = 3
+	}
+
+	return opts
+}
+
+// loggers holds the loggers created for the running command, closed when it finishes
+var loggers []*logger.Logger
+
+// newLogger creates a logger configured by the logging flags
+func newLogger() *logger.Logger {
+	log := logger.New(loggerOptions())
+	loggers = append(loggers, log)
+	return log
+}
+
+// closeLoggers closes the log files of every logger created for the running command
+func closeLoggers() {
+	for _, log := range loggers {
+		if err := log.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to close log file: %v\n", err)
+		}
+	}
+	loggers = nil
 }
 
 // createManager creates a new dependency manager with the specified options
 func createManager() (*depman.Manager, error) {
+	return createManagerWithLogger(newLogger())
+}
+
+// createManagerWithLogger creates a new dependency manager that logs to log
+func createManagerWithLogger(log *logger.Logger) (*depman.Manager, error) {
 	// Set up options
 	var options []depman.Option
 
@@ -120,22 +547,121 @@ func createManager() (*depman.Manager, error) {
 	if platformFlag != "" {
 		options = append(options, depman.WithPlatform(platformFlag))
 	}
+	if archFlag != "" {
+		options = append(options, depman.WithArch(archFlag))
+	}
+	if prefix != "" {
+		options = append(options, depman.WithPrefix(prefix))
+	}
+	if pkgManager != "" {
+		options = append(options, depman.WithPackageManager(pkgManager))
+	}
 
-	// Set log level
-	loggerLevel := logger.LevelInfo
-	switch strings.ToLower(logLevel) {
-	case "debug":
-		loggerLevel = logger.LevelDebug
-	case "info":
-		loggerLevel = logger.LevelInfo
-	case "warn":
-		loggerLevel = logger.LevelWarn
-	case "error":
-		loggerLevel = logger.LevelError
+	// Set up logging
+	options = append(options, depman.WithLogger(log))
+
+	// Force the configuration format if specified
+	if configFormat != "" {
+		options = append(options, depman.WithConfigFormat(configFormat))
+	}
+
+	// Verify the configuration file wasn't tampered with if requested
+	if configSum != "" {
+		options = append(options, depman.WithConfigChecksum(configSum))
+	}
+
+	// Skip dependencies if requested
+	if len(skipDeps) > 0 {
+		options = append(options, depman.WithSkip(skipDeps...))
+	}
+
+	// Filter dependencies by tag if requested
+	if len(tags) > 0 {
+		options = append(options, depman.WithTags(tags...))
+	}
+	if len(excludeTags) > 0 {
+		options = append(options, depman.WithExcludeTags(excludeTags...))
+	}
+
+	// Identify downloads with the CLI version
+	options = append(options, depman.WithUserAgent("depman/"+version))
+
+	// Apply TLS settings for downloads
+	if insecure {
+		options = append(options, depman.WithInsecureSkipVerify(true))
+	}
+	if caCertFile != "" {
+		options = append(options, depman.WithCACertFile(caCertFile))
+	}
+	if offline {
+		options = append(options, depman.WithOffline(true))
+	}
+	if requireSum {
+		options = append(options, depman.WithRequireChecksum(true))
+	}
+
+	// Let satisfied constraints take precedence over required versions if requested
+	if constraintFirst {
+		options = append(options, depman.WithConstraintFirst(true))
+	}
+
+	// Keep existing environment variables if requested
+	if keepEnv {
+		options = append(options, depman.WithOverrideExistingEnv(false))
+	}
+
+	// Retry post-install verification if requested
+	if verifyRetries > 0 {
+		options = append(options, depman.WithVerifyRetries(verifyRetries, verifyDelay))
+	}
+
+	// Use a custom temp directory if specified
+	if tempDir != "" {
+		options = append(options, depman.WithTempDir(tempDir))
+	}
+
+	// Run commands in a minimal environment if requested
+	if cleanEnv {
+		options = append(options, depman.WithCleanInstallEnv(true))
+	}
+
+	// Keep downloaded artifacts if requested
+	if keepDownloads != "" {
+		options = append(options, depman.WithKeepDownloads(keepDownloads))
+	}
+
+	// Record installs and uninstalls if requested
+	if auditLogPath != "" {
+		options = append(options, depman.WithAuditLog(auditLogPath))
+	}
+
+	// Trust recently confirmed dependencies if requested
+	if stateFile != "" {
+		options = append(options, depman.WithStateFile(stateFile), depman.WithStateTTL(stateTTL))
+	}
+
+	// Retry failed installs if requested
+	if ensureRetries > 0 {
+		options = append(options, depman.WithEnsureRetries(ensureRetries, retryDelay))
+	}
+
+	// Set install timeout if specified
+	if installTimeout > 0 {
+		options = append(options, depman.WithInstallTimeout(installTimeout))
+	}
+
+	// Set download limits, which dependencies may override
+	if dlTimeout > 0 {
+		options = append(options, depman.WithDownloadTimeout(dlTimeout))
+	}
+	if dlRetries > 0 {
+		options = append(options, depman.WithDownloadRetries(dlRetries))
 	}
-	options = append(options, depman.WithLogLevel(loggerLevel))
 
 	// Create manager
+	if configGlob != "" {
+		return depman.NewManagerFromGlob(configGlob, options...)
+	}
 	return depman.NewManager(configPath, options...)
 }
 
@@ -145,6 +671,7 @@ func runCheck() error {
 	if err != nil {
 		return fmt.Errorf("failed to initialize: %w", err)
 	}
+	applyDeepCheck(manager)
 
 	// Check dependencies
 	statuses, err := manager.CheckAllDependencies()
@@ -160,27 +687,79 @@ func runCheck() error {
 	for name, status := range statuses {
 		fmt.Printf("- %s: ", name)
 
+		if status.Skipped {
+			fmt.Println("Skipped")
+			continue
+		}
+
+		ok := true
 		if status.Installed {
 			fmt.Printf("Installed (v%s)", status.CurrentVersion)
 			if status.RequiredUpdate != depman.NoUpdate {
 				fmt.Printf(" [%s needed]", status.RequiredUpdate)
-				allOk = false
+				ok = false
 			}
 			if !status.Compatible {
 				fmt.Printf(" [Incompatible]")
-				allOk = false
+				ok = false
 			}
+		} else if status.Broken {
+			fmt.Printf("Installed but verify failed")
+			ok = false
 		} else {
 			fmt.Printf("Not installed")
-			allOk = false
+			ok = false
 		}
 
 		if status.Error != nil {
 			fmt.Printf(" [Error: %v]", status.Error)
+			ok = false
+		}
+
+		if status.HealthChecked && !status.Healthy {
+			fmt.Printf(" [Unhealthy]")
+			ok = false
+		}
+
+		// Optional dependencies never fail the check
+		if status.Optional {
+			fmt.Printf(" [Optional]")
+		} else if !ok {
 			allOk = false
 		}
 
 		fmt.Println()
+		printDeprecation(status)
+		printHealth(status)
+
+		if verbose {
+			printCommandOutput("Verify output", status.VerifyOutput)
+		}
+	}
+
+	// Check download URLs if requested
+	if checkURLs {
+		fmt.Println()
+		fmt.Println("Download URLs:")
+		fmt.Println("==============")
+
+		checks := manager.CheckDownloadURLs()
+		for _, name := range slices.Sorted(maps.Keys(checks)) {
+			check := checks[name]
+			fmt.Printf("- %s: ", name)
+			if check.Error != nil {
+				fmt.Printf("Unreachable [Error: %v]", check.Error)
+				if status := statuses[name]; status == nil || !status.Optional {
+					allOk = false
+				}
+			} else {
+				fmt.Printf("Reachable")
+				if check.Size >= 0 {
+					fmt.Printf(" (%d bytes)", check.Size)
+				}
+			}
+			fmt.Println()
+		}
 	}
 
 	if !allOk {
@@ -190,19 +769,150 @@ func runCheck() error {
 	return nil
 }
 
-// runEnsure ensures all dependencies are installed and up to date
-func runEnsure() error {
+// runDiff prints the dependencies whose installed state has drifted from the configuration
+func runDiff() error {
+	manager, err := createManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize: %w", err)
+	}
+
+	statuses, err := manager.CheckAllDependencies()
+	if err != nil {
+		return fmt.Errorf("failed to check dependencies: %w", err)
+	}
+
+	entries := buildDiff(manager.Config.Dependencies, statuses)
+	if diffJSON {
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode diff: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("All dependencies match the configuration")
+		return nil
+	}
+	fmt.Print(formatDiff(entries))
+	return nil
+}
+
+// runLockFile returns the --run-lock path, defaulting to a lock file next to the state file if
+// one is used, otherwise to one per configuration in the user cache directory, so no lock file
+// is left in the project
+// That default only serializes runs of the same configuration by the same user; runs of other
+// configurations or users (e.g. root and a run_as user) sharing install directories need a
+// common --run-lock or --state-file
+func runLockFile(configPath string) string {
+	if runLockPath != "" {
+		return runLockPath
+	}
+	if stateFile != "" {
+		return filepath.Join(filepath.Dir(stateFile), depman.DefaultRunLockName)
+	}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+
+	// Runs for the same configuration share a lock, found or read from stdin in the same directory
+	key := configPath
+	if key == "" || key == depman.StdinConfigPath {
+		key = "."
+	}
+	if abs, err := filepath.Abs(key); err == nil {
+		key = abs
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(dir, "depman", "run-"+hex.EncodeToString(sum[:8])+".lock")
+}
+
+// runEnsure ensures the named (or all) dependencies are installed and up to date
+func runEnsure(ctx context.Context, names []string) error {
 	manager, err := createManager()
 	if err != nil {
 		return fmt.Errorf("failed to initialize: %w", err)
 	}
 
+	// Only ensure the named dependencies, if any
+	if len(names) > 0 {
+		depman.WithOnly(names...)(manager)
+	}
+
+	// Reinstall even if already up to date if requested
+	if force {
+		depman.WithForceReinstall(true)(manager)
+	}
+
+	// Throttle checks and downloads separately from the install workers
+	if checkWorkers > 0 {
+		depman.WithCheckConcurrency(checkWorkers)(manager)
+	}
+	if downloadSlots > 0 {
+		depman.WithDownloadConcurrency(downloadSlots)(manager)
+	}
+
+	// In frozen mode only compare against the lockfile
+	if frozen {
+		return runFrozen(manager)
+	}
+
+	// Keep concurrent ensure runs from installing over each other
+	depman.WithRunLock(runLockFile(manager.ConfigPath), runLockWait)(manager)
+
+	// Verify downloads against the checksums of an existing lockfile
+	if lockfilePath != "" {
+		lock, err := depman.LoadLockfile(lockfilePath)
+		switch {
+		case err == nil:
+			depman.WithLockfile(lock)(manager)
+		case !errors.Is(err, fs.ErrNotExist):
+			return err
+		}
+	}
+
+	// Show download progress unless asked to be quiet, combined into one overall
+	// bar when downloads run in parallel
+	var aggregator *progressAggregator
+	if !quiet && parallel > 0 {
+		aggregator = newProgressAggregator(os.Stdout, isTerminal(os.Stdout))
+		depman.WithDownloadProgress(aggregator.update)(manager)
+		depman.WithStatusCallback(aggregator.status)(manager)
+	} else if !quiet {
+		progress := newProgressRenderer(os.Stdout, isTerminal(os.Stdout))
+		depman.WithDownloadProgress(progress.update)(manager)
+	}
+
 	// Ensure dependencies
-	statuses, err := manager.EnsureDependencies()
+	var statuses map[string]*depman.DependencyStatus
+	var report *depman.EnsureReport
+	if parallel > 0 {
+		statuses, report, err = manager.EnsureDependenciesParallelContext(ctx, parallel)
+	} else {
+		statuses, report, err = manager.EnsureDependenciesContext(ctx)
+	}
+	if aggregator != nil {
+		aggregator.finish()
+	}
 	if err != nil {
+		var mismatch *depman.ChecksumMismatchError
+		if errors.As(err, &mismatch) {
+			fmt.Fprintf(os.Stderr, "Hint: the download changed (got %s); pin it again if this is expected, see 'depman checksum'\n", mismatch.Actual)
+		}
 		return fmt.Errorf("failed to ensure dependencies: %w", err)
 	}
 
+	// Record the versions ensure just verified if requested
+	if lockfilePath != "" {
+		if err := manager.BuildLockfileFrom(statuses).Save(lockfilePath); err != nil {
+			return fmt.Errorf("failed to write lockfile: %w", err)
+		}
+		fmt.Printf("Lockfile written to %s\n", lockfilePath)
+	}
+
 	// Print results
 	fmt.Println("Dependency Status:")
 	fmt.Println("==================")
@@ -210,6 +920,11 @@ func runEnsure() error {
 	for name, status := range statuses {
 		fmt.Printf("- %s: ", name)
 
+		if status.Skipped {
+			fmt.Println("Skipped")
+			continue
+		}
+
 		if status.Installed {
 			fmt.Printf("Installed (v%s)", status.CurrentVersion)
 			if status.Compatible {
@@ -217,6 +932,8 @@ func runEnsure() error {
 			} else {
 				fmt.Printf(" [Incompatible]")
 			}
+		} else if status.ManualAction {
+			fmt.Printf("Not installed")
 		} else {
 			fmt.Printf("Failed to install")
 		}
@@ -225,9 +942,103 @@ func runEnsure() error {
 			fmt.Printf(" [Error: %v]", status.Error)
 		}
 
+		if status.Optional {
+			fmt.Printf(" [Optional]")
+		}
+
 		fmt.Println()
+		printDeprecation(status)
+
+		if verbose {
+			printCommandOutput("Install output", status.InstallOutput)
+			printCommandOutput("Verify output", status.VerifyOutput)
+		}
+	}
+
+	// Print summary footer
+	fmt.Println()
+	fmt.Printf("Summary: %d installed, %d updated, %d up to date, %d skipped, %d failed (%s)\n",
+		report.Installed, report.Updated, report.UpToDate, report.Skipped, report.Failed,
+		report.Duration.Round(time.Millisecond))
+
+	// Show what ensuring changed in the environment
+	if showEnv {
+		fmt.Println()
+		fmt.Println("Environment Changes:")
+		fmt.Println("====================")
+		fmt.Print(formatEnvDiff(manager.EnvironmentChanges()))
+	}
+
+	return nil
+}
+
+// printDeprecation prints the deprecation message of a dependency, if any
+func printDeprecation(status *depman.DependencyStatus) {
+	if status.Deprecated {
+		fmt.Printf("  DEPRECATED: %s\n", status.DeprecationMessage)
+	}
+}
+
+// printHealth prints why a dependency's health check failed, if it did
+func printHealth(status *depman.DependencyStatus) {
+	if status.HealthChecked && !status.Healthy {
+		fmt.Printf("  UNHEALTHY: %v\n", status.HealthError)
+	}
+}
+
+// addDeepCheckFlags adds the flags running health checks to a command that verifies dependencies
+func addDeepCheckFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&deepCheck, "deep", false, "Also run the health check of each installed dependency")
+	cmd.Flags().DurationVar(&healthTimeout, "health-check-timeout", depman.DefaultHealthCheckTimeout, "Maximum duration of each health check")
+}
+
+// applyDeepCheck enables health checks on the manager if --deep was given
+func applyDeepCheck(manager *depman.Manager) {
+	if deepCheck {
+		depman.WithDeepCheck(true)(manager)
+		depman.WithHealthCheckTimeout(healthTimeout)(manager)
+	}
+}
+
+// printCommandOutput prints raw command output indented under a dependency
+func printCommandOutput(label, output string) {
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return
+	}
+
+	fmt.Printf("  %s:\n", label)
+	for _, line := range strings.Split(output, "\n") {
+		fmt.Printf("    %s\n", line)
+	}
+}
+
+// runFrozen verifies installed dependencies against the lockfile, refusing to change anything
+func runFrozen(manager *depman.Manager) error {
+	path := lockfilePath
+	if path == "" {
+		path = depman.DefaultLockfileName
+	}
+
+	lock, err := depman.LoadLockfile(path)
+	if err != nil {
+		return err
+	}
+
+	drift, err := manager.VerifyLockfile(lock)
+	if err != nil {
+		return fmt.Errorf("failed to verify lockfile: %w", err)
+	}
+
+	if len(drift) > 0 {
+		fmt.Println("Lockfile drift:")
+		for _, d := range drift {
+			fmt.Printf("- %s\n", d)
+		}
+		return fmt.Errorf("installed dependencies do not match %s", path)
 	}
 
+	fmt.Printf("All dependencies match %s\n", path)
 	return nil
 }
 
@@ -238,9 +1049,28 @@ func runList() error {
 		return fmt.Errorf("failed to initialize: %w", err)
 	}
 
+	// Print the platform coverage report instead of the listing if requested
+	if listCoverage {
+		data, err := json.MarshalIndent(manager.PlatformCoverage(), "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode coverage: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
 	// Get configuration
 	config := manager.Config
 
+	if listJSON {
+		data, err := config.JSON()
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
 	fmt.Printf("Application: %s\n", config.Name)
 	if config.Description != "" {
 		fmt.Printf("Description: %s\n", config.Description)
@@ -252,7 +1082,14 @@ func runList() error {
 	fmt.Println("=============")
 
 	for _, dep := range config.Dependencies {
-		fmt.Printf("- %s: %s\n", dep.Name, dep.Description)
+		fmt.Printf("- %s: %s", dep.Name, dep.Description)
+		if !dep.IsEnabled() {
+			fmt.Printf(" (disabled)")
+		}
+		fmt.Println()
+		if dep.Deprecated != "" {
+			fmt.Printf("  DEPRECATED: %s\n", dep.Deprecated)
+		}
 		fmt.Printf("  Version: %s", dep.Version.Required)
 		if dep.Version.Constraint != "" {
 			fmt.Printf(" (Constraint: %s)", dep.Version.Constraint)
@@ -279,27 +1116,400 @@ func runList() error {
 	return nil
 }
 
+// runVerify runs each dependency's verify command and prints the raw results
+func runVerify(names []string) error {
+	manager, err := createManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize: %w", err)
+	}
+	applyDeepCheck(manager)
+
+	statuses, err := manager.VerifyDependencies(names...)
+	if err != nil {
+		return fmt.Errorf("failed to verify dependencies: %w", err)
+	}
+
+	// Print results
+	fmt.Println("Verify Results:")
+	fmt.Println("===============")
+
+	failed, unhealthy := 0, 0
+	for _, status := range statuses {
+		fmt.Printf("- %s: ", status.Name)
+
+		if status.Installed {
+			fmt.Printf("OK (version: %s)", status.CurrentVersion)
+			if status.HealthChecked && !status.Healthy {
+				fmt.Printf(" [Unhealthy]")
+				unhealthy++
+			}
+		} else {
+			fmt.Printf("FAILED")
+			if status.Error != nil {
+				fmt.Printf(" [Error: %v]", status.Error)
+			}
+			failed++
+		}
+		fmt.Println()
+
+		printHealth(status)
+		printCommandOutput("Output", status.VerifyOutput)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d verify command(s) failed", failed)
+	}
+	if unhealthy > 0 {
+		return fmt.Errorf("%d health check(s) failed", unhealthy)
+	}
+
+	return nil
+}
+
+// runClean uninstalls dependencies and reports what was removed
+func runClean(ctx context.Context, names []string) error {
+	manager, err := createManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize: %w", err)
+	}
+
+	results, cleanErr := manager.CleanContext(ctx, names...)
+
+	// Print results
+	fmt.Println("Clean Summary:")
+	fmt.Println("==============")
+
+	for _, result := range results {
+		fmt.Printf("- %s: ", result.Name)
+
+		switch {
+		case result.Error != nil:
+			fmt.Printf("Uninstall failed [Error: %v]", result.Error)
+		case result.Uninstalled:
+			fmt.Printf("Uninstalled")
+		default:
+			fmt.Printf("No uninstall command")
+		}
+		fmt.Println()
+
+		for _, path := range result.RemovedPaths {
+			fmt.Printf("  Removed from PATH: %s\n", path)
+		}
+		for _, key := range result.RemovedVariables {
+			fmt.Printf("  Unset variable: %s\n", key)
+		}
+
+		if verbose {
+			printCommandOutput("Uninstall output", result.Output)
+		}
+	}
+
+	if cleanErr != nil {
+		return fmt.Errorf("failed to clean dependencies: %w", cleanErr)
+	}
+
+	return nil
+}
+
+// runGraph prints the dependency graph as a text tree or Graphviz DOT
+func runGraph() error {
+	manager, err := createManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize: %w", err)
+	}
+
+	graph, err := manager.BuildGraph()
+	if err != nil {
+		return fmt.Errorf("failed to build dependency graph: %w", err)
+	}
+
+	switch strings.ToLower(graphFormat) {
+	case "text":
+		fmt.Print(graph.Text())
+	case "dot":
+		fmt.Print(graph.DOT())
+	default:
+		return fmt.Errorf("unsupported graph format: %s", graphFormat)
+	}
+
+	return nil
+}
+
+// runWhy prints the dependencies that require the named dependency
+func runWhy(name string) error {
+	manager, err := createManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize: %w", err)
+	}
+
+	var dependents []string
+	if whyRecursive {
+		dependents, err = manager.TransitiveDependents(name)
+	} else {
+		dependents, err = manager.Dependents(name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(dependents) == 0 {
+		fmt.Printf("No dependencies require %s\n", name)
+		return nil
+	}
+
+	fmt.Printf("%s is required by:\n", name)
+	for _, dependent := range dependents {
+		fmt.Printf("- %s\n", dependent)
+	}
+	return nil
+}
+
+// runMigrate upgrades the configuration file to a newer schema version
+func runMigrate() error {
+	if configGlob != "" {
+		return fmt.Errorf("migrate works on a single configuration file, use --config")
+	}
+
+	manager, err := createManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize: %w", err)
+	}
+
+	if err := manager.MigrateConfig(migrateFrom, migrateTo); err != nil {
+		return fmt.Errorf("failed to migrate configuration: %w", err)
+	}
+
+	fmt.Printf("Configuration migrated to version %s\n", migrateTo)
+	return nil
+}
+
+// runLint validates the configuration and prints every problem found
+func runLint() error {
+	manager, err := createManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize: %w", err)
+	}
+
+	var platforms []string
+	if lintAllPlatforms {
+		platforms = depman.SupportedPlatforms
+	}
+
+	problems := manager.Lint(platforms...)
+
+	// Print results
+	fmt.Println("Lint Results:")
+	fmt.Println("=============")
+
+	if len(problems) == 0 {
+		fmt.Println("No problems found")
+		return nil
+	}
+
+	for _, problem := range problems {
+		fmt.Printf("- %v\n", problem)
+	}
+
+	return fmt.Errorf("%d problem(s) found", len(problems))
+}
+
+// runWatch checks dependencies on every interval until interrupted, logging changes
+func runWatch() error {
+	if watchInterval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+
+	// Share the logger with the manager so the log file is only opened once
+	log := newLogger()
+	manager, err := createManagerWithLogger(log)
+	if err != nil {
+		return fmt.Errorf("failed to initialize: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	log.Infof("Watching dependencies every %s (Ctrl+C to stop)", watchInterval)
+
+	var previous map[string]*depman.DependencyStatus
+	for {
+		// Query version sources again so that new upstream releases are noticed
+		manager.ForgetLatestVersions()
+
+		statuses, err := manager.CheckAllDependencies()
+		if err != nil {
+			return fmt.Errorf("failed to check dependencies: %w", err)
+		}
+
+		for _, change := range depman.DiffStatuses(previous, statuses) {
+			switch {
+			case previous == nil:
+				log.Infof("%s: %s", change.Name, change.To)
+			case change.Degraded():
+				log.Warnf("%s", change)
+			default:
+				log.Infof("%s", change)
+			}
+		}
+		previous = statuses
+
+		select {
+		case <-ctx.Done():
+			log.Infof("Stopping watch")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// runExport writes the environment of all dependencies in the requested format
+func runExport() error {
+	manager, err := createManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize: %w", err)
+	}
+
+	switch strings.ToLower(exportFormat) {
+	case "dotenv":
+		if err := manager.ExportDotEnv(exportOutput); err != nil {
+			return fmt.Errorf("failed to export environment: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported export format: %s", exportFormat)
+	}
+
+	fmt.Printf("Environment written to %s\n", exportOutput)
+	return nil
+}
+
+// runChecksum downloads a file to a temporary directory and prints its checksum
+func runChecksum(url string) error {
+	tempDir, err := os.MkdirTemp("", "depman-checksum-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	result, err := downloader.Download(downloader.DownloadOptions{
+		URL:                    url,
+		DestDir:                tempDir,
+		Algorithm:              strings.ToLower(checksumAlgorithm),
+		UserAgent:              "depman/" + version,
+		InsecureSkipVerify:     insecure,
+		CACertFile:             caCertFile,
+		AllowCrossHostRedirect: crossHostRedirect,
+		MaxRedirects:           maxRedirects,
+		Offline:                offline,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", url, err)
+	}
+
+	fmt.Printf("%s:%s  %s\n", result.Algorithm, result.Checksum, filepath.Base(result.FilePath))
+	return nil
+}
+
+// confirmOverwrite asks the user before overwriting an existing file, unless --force is set
+func confirmOverwrite(path string) bool {
+	if _, err := os.Stat(path); err != nil || force {
+		return true
+	}
+
+	// Prompt user for confirmation
+	fmt.Printf("File %s already exists. Overwrite? [y/N] ", path)
+	var response string
+	fmt.Scanln(&response)
+
+	return strings.ToLower(response) == "y" || strings.ToLower(response) == "yes"
+}
+
+// runInit probes the system for the requested tools and writes a starter configuration
+func runInit() error {
+	reader := bufio.NewReader(os.Stdin)
+	prompt := func(question string) string {
+		fmt.Print(question)
+		answer, _ := reader.ReadString('\n')
+		return strings.TrimSpace(answer)
+	}
+
+	// Ask for anything not given via flags
+	if initName == "" {
+		if len(initTools) > 0 {
+			initName = "My Application"
+		} else {
+			initName = prompt("Application name: ")
+		}
+	}
+	if len(initTools) == 0 {
+		for _, tool := range strings.Split(prompt("Tools (comma-separated): "), ",") {
+			if tool = strings.TrimSpace(tool); tool != "" {
+				initTools = append(initTools, tool)
+			}
+		}
+	}
+	if len(initTools) == 0 {
+		return fmt.Errorf("no tools specified")
+	}
+
+	// Probe each tool for its installed version
+	platform := runtime.GOOS
+	if platformFlag != "" {
+		platform = platformFlag
+	}
+
+	probes := make([]*depman.ProbeResult, 0, len(initTools))
+	for _, tool := range initTools {
+		probe, err := depman.ProbeTool(tool)
+		switch {
+		case err != nil:
+			fmt.Printf("- %s: %v\n", tool, err)
+		case probe.Path == "":
+			fmt.Printf("- %s: not found\n", tool)
+		case probe.Version == "":
+			fmt.Printf("- %s: found at %s (version unknown)\n", tool, probe.Path)
+		default:
+			fmt.Printf("- %s: found v%s at %s\n", tool, probe.Version, probe.Path)
+		}
+		probes = append(probes, probe)
+	}
+
+	// Render the configuration
+	var data bytes.Buffer
+	encoder := yaml.NewEncoder(&data)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(depman.ScaffoldConfig(initName, platform, probes)); err != nil {
+		return fmt.Errorf("failed to render configuration: %w", err)
+	}
+
+	if !confirmOverwrite(initOutput) {
+		fmt.Println("Operation cancelled.")
+		return nil
+	}
+
+	if err := os.WriteFile(initOutput, data.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write configuration file: %w", err)
+	}
+
+	fmt.Printf("Dependency configuration created at %s\n", initOutput)
+	fmt.Println("Dependencies are check-only: add install commands and remove install_mode for ensure to install them.")
+
+	return nil
+}
+
 // Add this function to handle the generate command
 func runGenerate() error {
 	// Check if file already exists
-	if _, err := os.Stat(outputFile); err == nil {
-		// File exists
-		if !force {
-			// Prompt user for confirmation
-			fmt.Printf("File %s already exists. Overwrite? [y/N] ", outputFile)
-			var response string
-			fmt.Scanln(&response)
-
-			if strings.ToLower(response) != "y" && strings.ToLower(response) != "yes" {
-				fmt.Println("Operation cancelled.")
-				return nil
-			}
-		}
+	if !confirmOverwrite(outputFile) {
+		fmt.Println("Operation cancelled.")
+		return nil
 	}
 
 	// Template content
 	template := `# Dependency configuration for depman
-version: "1.0"
+version: "1.1"
 name: "My Application"
 description: "Application dependencies configuration"
 
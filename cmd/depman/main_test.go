@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sobhit-avrl/depman-v1/internal/logger"
+)
+
+// TestLoggerOptions tests mapping the logging flags to logger options
+func TestLoggerOptions(t *testing.T) {
+	testCases := []struct {
+		name           string
+		logLevel       string
+		quiet          bool
+		noColor        bool
+		expectedLevel  logger.Level
+		expectedColors bool
+	}{
+		{name: "Defaults", logLevel: "info", expectedLevel: logger.LevelInfo, expectedColors: true},
+		{name: "Debug level", logLevel: "debug", expectedLevel: logger.LevelDebug, expectedColors: true},
+		{name: "Trace level", logLevel: "trace", expectedLevel: logger.LevelTrace, expectedColors: true},
+		{name: "No color", logLevel: "info", noColor: true, expectedLevel: logger.LevelInfo, expectedColors: false},
+		{name: "Quiet", logLevel: "info", quiet: true, expectedLevel: logger.LevelError, expectedColors: true},
+		{name: "Quiet overrides debug", logLevel: "debug", quiet: true, expectedLevel: logger.LevelError, expectedColors: true},
+		{name: "Quiet without color", logLevel: "warn", quiet: true, noColor: true, expectedLevel: logger.LevelError, expectedColors: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logLevel, quiet, noColor = tc.logLevel, tc.quiet, tc.noColor
+			defer func() { logLevel, quiet, noColor = "info", false, false }()
+
+			opts := loggerOptions()
+			if opts.Level != tc.expectedLevel {
+				t.Errorf("Expected level %s but got %s", tc.expectedLevel, opts.Level)
+			}
+			if opts.ShowColors != tc.expectedColors {
+				t.Errorf("Expected ShowColors %v but got %v", tc.expectedColors, opts.ShowColors)
+			}
+		})
+	}
+}
+
+// TestRunLockFile tests where the run lock is kept by default
+func TestRunLockFile(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("LocalAppData", t.TempDir())
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+	defer func() { runLockPath, stateFile = "", "" }()
+
+	// Without a state file the lock is kept per configuration in the cache directory
+	project := t.TempDir()
+	configPath := filepath.Join(project, "app-dependencies.yml")
+	lock := runLockFile(configPath)
+	if !strings.HasPrefix(lock, filepath.Join(cacheDir, "depman")+string(filepath.Separator)) {
+		t.Errorf("Expected the lock in the cache directory but got %s", lock)
+	}
+	if other := runLockFile(filepath.Join(t.TempDir(), "app-dependencies.yml")); other == lock {
+		t.Errorf("Expected different configurations to use different locks but both use %s", lock)
+	}
+
+	// A state file gets the lock next to it
+	stateFile = filepath.Join(project, ".cache", "depman-state.json")
+	if lock := runLockFile(configPath); lock != filepath.Join(project, ".cache", ".depman-run.lock") {
+		t.Errorf("Expected the lock next to the state file but got %s", lock)
+	}
+
+	// An explicit path wins
+	runLockPath = filepath.Join(project, "custom.lock")
+	if lock := runLockFile(configPath); lock != runLockPath {
+		t.Errorf("Expected %s but got %s", runLockPath, lock)
+	}
+}
+
+// TestCloseLoggers tests that the log files of loggers created for a command are closed
+func TestCloseLoggers(t *testing.T) {
+	logFile = filepath.Join(t.TempDir(), "depman.log")
+	defer func() { logFile = "" }()
+
+	log := newLogger()
+	log.Infof("Logged before closing")
+	closeLoggers()
+
+	if len(loggers) != 0 {
+		t.Errorf("Expected no loggers left open but got %d", len(loggers))
+	}
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if !strings.Contains(string(data), "Logged before closing") {
+		t.Errorf("Expected the log file to contain the message but got %q", data)
+	}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sobhit-avrl/depman-v1/pkg/depman"
+)
+
+const (
+	// progressRedrawInterval limits how often the terminal progress bar is redrawn
+	progressRedrawInterval = 100 * time.Millisecond
+
+	// progressLogInterval limits how often progress lines are printed when not on a terminal
+	progressLogInterval = 5 * time.Second
+
+	// progressBarWidth is the number of cells in the terminal progress bar
+	progressBarWidth = 30
+)
+
+// progressRenderer shows download progress as a progress bar on a terminal,
+// or as periodic lines otherwise (e.g. in CI logs)
+type progressRenderer struct {
+	out       io.Writer
+	tty       bool
+	mu        sync.Mutex
+	downloads map[string]*downloadState
+}
+
+// downloadState tracks the rendering of a single download
+type downloadState struct {
+	start    time.Time // When the first progress was reported
+	last     time.Time // When progress was last rendered
+	finished bool      // Whether completion has been rendered
+}
+
+// newProgressRenderer creates a renderer writing to out
+func newProgressRenderer(out io.Writer, tty bool) *progressRenderer {
+	return &progressRenderer{
+		out:       out,
+		tty:       tty,
+		downloads: make(map[string]*downloadState),
+	}
+}
+
+// update renders the progress of a download, throttled to avoid flooding the output
+// It is safe for concurrent use
+func (r *progressRenderer) update(name string, downloaded, total int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	done := downloaded == total
+	state := r.downloads[name]
+	if state == nil || (state.finished && !done) {
+		// First report, or a new download (e.g. a retry) after a finished one
+		state = &downloadState{start: now}
+		r.downloads[name] = state
+	}
+	if state.finished {
+		return
+	}
+	interval := progressLogInterval
+	if r.tty {
+		interval = progressRedrawInterval
+	}
+	if !done && !state.last.IsZero() && now.Sub(state.last) < interval {
+		return
+	}
+
+	elapsed := now.Sub(state.start)
+	if r.tty {
+		fmt.Fprintf(r.out, "\r%s", formatProgressBar(name, downloaded, total, elapsed))
+		if done {
+			fmt.Fprintln(r.out)
+		}
+	} else {
+		fmt.Fprintln(r.out, formatProgressLine(name, downloaded, total, elapsed))
+	}
+
+	state.last = now
+	state.finished = done
+}
+
+// progressAggregator combines the downloads of a parallel ensure into a single overall
+// progress bar and a compact status line per dependency, instead of one colliding bar each
+type progressAggregator struct {
+	out    io.Writer
+	tty    bool
+	mu     sync.Mutex
+	start  time.Time                   // When the first progress was reported
+	last   time.Time                   // When progress was last rendered
+	drawn  bool                        // Whether the terminal lines have been drawn
+	deps   map[string]*aggregatedState // Latest state of each dependency by name
+	closed bool                        // Whether finish has been called
+}
+
+// aggregatedState is the latest known state of a single dependency
+type aggregatedState struct {
+	phase      depman.Phase
+	downloaded int64
+	total      int64 // -1 if unknown
+	downloads  bool  // Whether any download progress was reported
+}
+
+// newProgressAggregator creates an aggregator writing to out
+func newProgressAggregator(out io.Writer, tty bool) *progressAggregator {
+	return &progressAggregator{
+		out:  out,
+		tty:  tty,
+		deps: make(map[string]*aggregatedState),
+	}
+}
+
+// update records the progress of one download and renders the overall progress
+// It is a depman.ProgressFunc and is safe for concurrent use
+func (a *progressAggregator) update(name string, downloaded, total int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	if a.start.IsZero() {
+		a.start = now
+	}
+
+	// A retried download starts over, so the latest report replaces the previous one
+	state := a.state(name)
+	state.phase = depman.PhaseDownloading
+	state.downloaded, state.total, state.downloads = downloaded, total, true
+
+	a.render(now, false)
+}
+
+// status records a dependency entering a phase and renders the overall progress
+// It is a depman.StatusFunc and is safe for concurrent use
+func (a *progressAggregator) status(name string, phase depman.Phase, _ *depman.DependencyStatus) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.state(name).phase = phase
+
+	// Phase changes are shown promptly on a terminal, where they are redrawn in place, but
+	// throttled like download progress otherwise to keep logs to a few lines
+	a.render(time.Now(), a.tty)
+}
+
+// finish renders the final progress and ends the terminal lines
+func (a *progressAggregator) finish() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.closed || len(a.deps) == 0 {
+		a.closed = true
+		return
+	}
+	a.render(time.Now(), true)
+	if a.tty {
+		fmt.Fprintln(a.out)
+	}
+	a.closed = true
+}
+
+// state returns the state of a dependency, creating it on first use
+// The caller must hold a.mu
+func (a *progressAggregator) state(name string) *aggregatedState {
+	state := a.deps[name]
+	if state == nil {
+		state = &aggregatedState{}
+		a.deps[name] = state
+	}
+	return state
+}
+
+// totals sums the progress of all downloads
+// The total is -1 if the size of any unfinished download is unknown
+// The caller must hold a.mu
+func (a *progressAggregator) totals() (downloaded, total int64) {
+	for _, state := range a.deps {
+		if !state.downloads {
+			continue
+		}
+		downloaded += state.downloaded
+		if state.total < 0 || total < 0 {
+			total = -1
+		} else {
+			total += state.total
+		}
+	}
+	return downloaded, total
+}
+
+// statusLine describes each dependency compactly, e.g. "go 45%, node installing, jq verified"
+// The caller must hold a.mu
+func (a *progressAggregator) statusLine() string {
+	names := make([]string, 0, len(a.deps))
+	for name := range a.deps {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		state := a.deps[name]
+		if state.phase == depman.PhaseDownloading && state.total > 0 {
+			parts[i] = fmt.Sprintf("%s %d%%", name, percent(state.downloaded, state.total))
+		} else {
+			parts[i] = name + " " + state.phase.String()
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// render draws the overall progress, throttled unless forced
+// The caller must hold a.mu
+func (a *progressAggregator) render(now time.Time, force bool) {
+	if a.closed {
+		return
+	}
+	interval := progressLogInterval
+	if a.tty {
+		interval = progressRedrawInterval
+	}
+	if !force && !a.last.IsZero() && now.Sub(a.last) < interval {
+		return
+	}
+
+	downloaded, total := a.totals()
+	elapsed := time.Duration(0)
+	if !a.start.IsZero() {
+		elapsed = now.Sub(a.start)
+	}
+
+	if a.tty {
+		// Redraw both lines in place: back to the bar line, then clear each line before writing
+		if a.drawn {
+			fmt.Fprint(a.out, "\033[1A")
+		}
+		fmt.Fprintf(a.out, "\r\033[K%s\n\033[K%s", formatProgressBar("Overall", downloaded, total, elapsed), a.statusLine())
+		a.drawn = true
+	} else {
+		fmt.Fprintf(a.out, "%s [%s]\n", formatProgressLine("all dependencies", downloaded, total, elapsed), a.statusLine())
+	}
+
+	a.last = now
+}
+
+// formatProgressLine describes download progress in a single log-friendly line
+func formatProgressLine(name string, downloaded, total int64, elapsed time.Duration) string {
+	if total < 0 {
+		return fmt.Sprintf("Downloading %s: %s (%s)", name, formatBytes(downloaded), formatSpeed(downloaded, elapsed))
+	}
+	return fmt.Sprintf("Downloading %s: %d%% (%s of %s, %s)", name, percent(downloaded, total),
+		formatBytes(downloaded), formatBytes(total), formatSpeed(downloaded, elapsed))
+}
+
+// formatProgressBar renders download progress as a terminal progress bar
+func formatProgressBar(name string, downloaded, total int64, elapsed time.Duration) string {
+	if total < 0 {
+		return fmt.Sprintf("%s %s %s", name, formatBytes(downloaded), formatSpeed(downloaded, elapsed))
+	}
+
+	filled := int(int64(progressBarWidth) * downloaded / max(total, 1))
+	filled = min(filled, progressBarWidth)
+	bar := strings.Repeat("#", filled) + strings.Repeat("-", progressBarWidth-filled)
+
+	return fmt.Sprintf("%s [%s] %3d%% %s/%s %s", name, bar, percent(downloaded, total),
+		formatBytes(downloaded), formatBytes(total), formatSpeed(downloaded, elapsed))
+}
+
+// percent returns downloaded as a percentage of total
+func percent(downloaded, total int64) int64 {
+	if total <= 0 {
+		return 100
+	}
+	return downloaded * 100 / total
+}
+
+// formatSpeed returns the average download speed
+func formatSpeed(downloaded int64, elapsed time.Duration) string {
+	if elapsed <= 0 {
+		return formatBytes(0) + "/s"
+	}
+	return formatBytes(int64(float64(downloaded)/elapsed.Seconds())) + "/s"
+}
+
+// formatBytes returns a human-readable size
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	value, suffix := float64(n)/unit, "KB"
+	for _, next := range []string{"MB", "GB", "TB"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, next
+	}
+	return fmt.Sprintf("%.1f %s", value, suffix)
+}
+
+// isTerminal reports whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
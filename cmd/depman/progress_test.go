@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sobhit-avrl/depman-v1/pkg/depman"
+)
+
+// TestFormatProgressLine tests the progress lines printed when not on a terminal
+func TestFormatProgressLine(t *testing.T) {
+	testCases := []struct {
+		name       string
+		downloaded int64
+		total      int64
+		elapsed    time.Duration
+		expected   string
+	}{
+		{name: "Known size", downloaded: 5 << 20, total: 20 << 20, elapsed: 2 * time.Second, expected: "Downloading node: 25% (5.0 MB of 20.0 MB, 2.5 MB/s)"},
+		{name: "Unknown size", downloaded: 1536, total: -1, elapsed: time.Second, expected: "Downloading node: 1.5 KB (1.5 KB/s)"},
+		{name: "Complete", downloaded: 512, total: 512, elapsed: time.Second, expected: "Downloading node: 100% (512 B of 512 B, 512 B/s)"},
+		{name: "No elapsed time", downloaded: 0, total: 100, elapsed: 0, expected: "Downloading node: 0% (0 B of 100 B, 0 B/s)"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if line := formatProgressLine("node", tc.downloaded, tc.total, tc.elapsed); line != tc.expected {
+				t.Errorf("Expected %q but got %q", tc.expected, line)
+			}
+		})
+	}
+}
+
+// TestProgressRendererThrottling tests that non-terminal output is throttled but always shows completion
+func TestProgressRendererThrottling(t *testing.T) {
+	var out bytes.Buffer
+	renderer := newProgressRenderer(&out, false)
+
+	for downloaded := int64(1); downloaded <= 100; downloaded++ {
+		renderer.update("node", downloaded, 100)
+	}
+	renderer.update("node", 100, 100) // Final completion call
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a first and a completion line but got:\n%s", out.String())
+	}
+	if !strings.HasPrefix(lines[1], "Downloading node: 100%") {
+		t.Errorf("Expected a completion line but got %q", lines[1])
+	}
+}
+
+// TestProgressAggregatorConcurrent tests summing progress reported by concurrent downloads
+func TestProgressAggregatorConcurrent(t *testing.T) {
+	var out bytes.Buffer
+	aggregator := newProgressAggregator(&out, false)
+
+	const downloads = 8
+	const chunks = 100
+	const chunkSize = 1024
+
+	var wg sync.WaitGroup
+	for i := range downloads {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			aggregator.status(name, depman.PhaseChecking, nil)
+			for chunk := int64(1); chunk <= chunks; chunk++ {
+				aggregator.update(name, chunk*chunkSize, chunks*chunkSize)
+			}
+			aggregator.status(name, depman.PhaseInstalling, nil)
+		}(fmt.Sprintf("dep%d", i))
+	}
+	wg.Wait()
+
+	downloaded, total := aggregator.totals()
+	if downloaded != downloads*chunks*chunkSize || total != downloads*chunks*chunkSize {
+		t.Errorf("Expected %d of %d bytes but got %d of %d", downloads*chunks*chunkSize, downloads*chunks*chunkSize, downloaded, total)
+	}
+	if line := aggregator.statusLine(); strings.Count(line, "installing") != downloads {
+		t.Errorf("Expected every dependency to be installing but got %q", line)
+	}
+}
+
+// TestProgressAggregatorPhaseThrottling tests that phase changes are only drawn promptly on a terminal
+func TestProgressAggregatorPhaseThrottling(t *testing.T) {
+	testCases := []struct {
+		name            string
+		tty             bool
+		marker          string // Text written once per render
+		expectedRenders int
+	}{
+		{name: "Terminal", tty: true, marker: "Overall", expectedRenders: 7},
+		{name: "Not a terminal", tty: false, marker: "all dependencies", expectedRenders: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			aggregator := newProgressAggregator(&out, tc.tty)
+
+			for _, name := range []string{"go", "jq"} {
+				aggregator.status(name, depman.PhaseChecking, nil)
+				aggregator.status(name, depman.PhaseInstalling, nil)
+				aggregator.status(name, depman.PhaseVerified, nil)
+			}
+			aggregator.finish()
+
+			if renders := strings.Count(out.String(), tc.marker); renders != tc.expectedRenders {
+				t.Errorf("Expected %d renders but got %d:\n%s", tc.expectedRenders, renders, out.String())
+			}
+		})
+	}
+}
+
+// TestProgressAggregatorTotals tests the overall progress as downloads start, retry and finish
+func TestProgressAggregatorTotals(t *testing.T) {
+	testCases := []struct {
+		name               string
+		updates            func(a *progressAggregator)
+		expectedDownloaded int64
+		expectedTotal      int64
+		expectedStatus     string
+	}{
+		{
+			name: "Known sizes",
+			updates: func(a *progressAggregator) {
+				a.update("go", 50, 200)
+				a.update("node", 100, 100)
+			},
+			expectedDownloaded: 150,
+			expectedTotal:      300,
+			expectedStatus:     "go 25%, node 100%",
+		},
+		{
+			name: "Unknown size",
+			updates: func(a *progressAggregator) {
+				a.update("go", 50, 200)
+				a.update("node", 30, -1)
+			},
+			expectedDownloaded: 80,
+			expectedTotal:      -1,
+			expectedStatus:     "go 25%, node downloading",
+		},
+		{
+			name: "Retried download replaces earlier progress",
+			updates: func(a *progressAggregator) {
+				a.update("go", 150, 200)
+				a.update("go", 20, 200)
+			},
+			expectedDownloaded: 20,
+			expectedTotal:      200,
+			expectedStatus:     "go 10%",
+		},
+		{
+			name: "Dependencies without downloads",
+			updates: func(a *progressAggregator) {
+				a.status("jq", depman.PhaseVerified, nil)
+				a.update("go", 200, 200)
+				a.status("go", depman.PhaseInstalling, nil)
+			},
+			expectedDownloaded: 200,
+			expectedTotal:      200,
+			expectedStatus:     "go installing, jq verified",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			aggregator := newProgressAggregator(&bytes.Buffer{}, false)
+			tc.updates(aggregator)
+
+			downloaded, total := aggregator.totals()
+			if downloaded != tc.expectedDownloaded || total != tc.expectedTotal {
+				t.Errorf("Expected %d of %d bytes but got %d of %d", tc.expectedDownloaded, tc.expectedTotal, downloaded, total)
+			}
+			if line := aggregator.statusLine(); line != tc.expectedStatus {
+				t.Errorf("Expected status line %q but got %q", tc.expectedStatus, line)
+			}
+		})
+	}
+}
+
+// TestProgressAggregatorFinish tests that the terminal lines are ended once and nothing is drawn afterwards
+func TestProgressAggregatorFinish(t *testing.T) {
+	var out bytes.Buffer
+	aggregator := newProgressAggregator(&out, true)
+
+	aggregator.update("go", 100, 100)
+	aggregator.finish()
+	aggregator.finish()
+	rendered := out.String()
+	aggregator.update("go", 100, 100)
+
+	if !strings.HasSuffix(rendered, "go 100%\n") {
+		t.Errorf("Expected the output to end with the status line and a newline but got %q", rendered)
+	}
+	if out.String() != rendered {
+		t.Errorf("Expected nothing to be drawn after finish but got %q", out.String()[len(rendered):])
+	}
+}
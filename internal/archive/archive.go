@@ -0,0 +1,191 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrEntryNotFound is returned when an archive has no entry matching the requested name
+var ErrEntryNotFound = errors.New("entry not found in archive")
+
+// Magic bytes identifying supported archive formats
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte("PK\x03\x04")
+)
+
+// ExtractFile extracts a single regular file from a tar, tar.gz or zip archive into destDir
+// and makes it executable, returning the path of the extracted file
+// The name matches the full entry path, or the base name of any entry when it has no slash,
+// so "tool" finds "tool-1.2.3/bin/tool"; more than one match is an error
+func ExtractFile(archivePath, name, destDir string) (string, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	header, _ := reader.Peek(len(zipMagic))
+
+	destPath := filepath.Join(destDir, path.Base(name))
+	switch {
+	case bytes.HasPrefix(header, zipMagic):
+		info, err := file.Stat()
+		if err != nil {
+			return "", fmt.Errorf("failed to stat archive: %w", err)
+		}
+		err = extractZip(file, info.Size(), name, destPath)
+		return destPath, err
+	case bytes.HasPrefix(header, gzipMagic):
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return "", fmt.Errorf("failed to decompress archive: %w", err)
+		}
+		defer gz.Close()
+		return destPath, extractTar(gz, name, destPath)
+	default:
+		return destPath, extractTar(reader, name, destPath)
+	}
+}
+
+// matches reports whether an archive entry matches the requested name
+func matches(entry, name string) bool {
+	entry = strings.TrimPrefix(entry, "./")
+	name = strings.TrimPrefix(name, "./")
+	if entry == name {
+		return true
+	}
+	return !strings.Contains(name, "/") && path.Base(entry) == name
+}
+
+// extractTar extracts the single matching regular file from a tar stream
+// The file at destPath is only replaced once the whole archive has been read
+func extractTar(r io.Reader, name, destPath string) error {
+	tr := tar.NewReader(r)
+	found := ""
+	tmpPath := ""
+	defer func() {
+		if tmpPath != "" {
+			os.Remove(tmpPath)
+		}
+	}()
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg || !matches(header.Name, name) {
+			continue
+		}
+		if found != "" {
+			return fmt.Errorf("'%s' matches more than one entry (%s, %s), give the full path", name, found, header.Name)
+		}
+		found = header.Name
+
+		// Keep reading to detect ambiguous names, so write the entry out now
+		if tmpPath, err = writeExecutable(tr, destPath); err != nil {
+			return err
+		}
+	}
+
+	if found == "" {
+		return fmt.Errorf("%w: %s", ErrEntryNotFound, name)
+	}
+	if err := replaceFile(tmpPath, destPath); err != nil {
+		return err
+	}
+	tmpPath = ""
+	return nil
+}
+
+// extractZip extracts the single matching regular file from a zip archive
+func extractZip(r io.ReaderAt, size int64, name, destPath string) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("failed to read zip archive: %w", err)
+	}
+
+	var match *zip.File
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() || !matches(f.Name, name) {
+			continue
+		}
+		if match != nil {
+			return fmt.Errorf("'%s' matches more than one entry (%s, %s), give the full path", name, match.Name, f.Name)
+		}
+		match = f
+	}
+
+	if match == nil {
+		return fmt.Errorf("%w: %s", ErrEntryNotFound, name)
+	}
+
+	rc, err := match.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s in archive: %w", match.Name, err)
+	}
+	defer rc.Close()
+
+	tmpPath, err := writeExecutable(rc, destPath)
+	if err != nil {
+		return err
+	}
+	if err := replaceFile(tmpPath, destPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// writeExecutable writes r with executable permissions to a temporary file next to path,
+// returning its path; the caller moves it into place with replaceFile
+// An existing (possibly running) file at path is left untouched
+func writeExecutable(r io.Reader, path string) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	out, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return "", fmt.Errorf("failed to extract to %s: %w", path, err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(out.Name())
+		return "", err
+	}
+
+	// CreateTemp creates the file readable by its owner only
+	if err := os.Chmod(out.Name(), 0755); err != nil {
+		os.Remove(out.Name())
+		return "", err
+	}
+	return out.Name(), nil
+}
+
+// replaceFile moves the extracted file at tmpPath to path, replacing any existing file
+func replaceFile(tmpPath, path string) error {
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to move extracted file to %s: %w", path, err)
+	}
+	return nil
+}
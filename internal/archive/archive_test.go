@@ -0,0 +1,196 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fixtureEntries are the files in every test archive
+var fixtureEntries = map[string]string{
+	"tool-1.2.3/bin/tool":      "#!/bin/sh\necho 1.2.3\n",
+	"tool-1.2.3/bin/helper":    "#!/bin/sh\necho helper\n",
+	"tool-1.2.3/README.md":     "# tool\n",
+	"tool-1.2.3/doc/README.md": "# docs\n",
+}
+
+// writeTar writes the fixture entries as a tar archive, gzipped if requested
+func writeTar(t *testing.T, path string, compress bool) {
+	var buf bytes.Buffer
+	var tw *tar.Writer
+	var gz *gzip.Writer
+	if compress {
+		gz = gzip.NewWriter(&buf)
+		tw = tar.NewWriter(gz)
+	} else {
+		tw = tar.NewWriter(&buf)
+	}
+
+	// A directory entry shouldn't be mistaken for a file
+	if err := tw.WriteHeader(&tar.Header{Name: "./tool-1.2.3/bin/", Typeflag: tar.TypeDir, Mode: 0755}); err != nil {
+		t.Fatalf("Failed to write tar header: %v", err)
+	}
+	for name, content := range fixtureEntries {
+		header := &tar.Header{Name: "./" + name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("Failed to write tar header: %v", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("Failed to write tar entry: %v", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close tar writer: %v", err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			t.Fatalf("Failed to close gzip writer: %v", err)
+		}
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+}
+
+// writeZip writes the fixture entries as a zip archive
+func writeZip(t *testing.T, path string) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if _, err := zw.Create("tool-1.2.3/bin/"); err != nil {
+		t.Fatalf("Failed to write zip directory: %v", err)
+	}
+	for name, content := range fixtureEntries {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to write zip entry: %v", err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("Failed to write zip entry: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to close zip writer: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+}
+
+func TestExtractFile(t *testing.T) {
+	formats := []struct {
+		name  string
+		write func(t *testing.T, path string)
+	}{
+		{name: "tar.gz", write: func(t *testing.T, path string) { writeTar(t, path, true) }},
+		{name: "tar", write: func(t *testing.T, path string) { writeTar(t, path, false) }},
+		{name: "zip", write: writeZip},
+	}
+
+	testCases := []struct {
+		name           string
+		entry          string
+		expectedFile   string
+		expectedBody   string
+		expectError    string
+		expectNotFound bool
+	}{
+		{name: "Full path", entry: "tool-1.2.3/bin/tool", expectedFile: "tool", expectedBody: fixtureEntries["tool-1.2.3/bin/tool"]},
+		{name: "Leading dot slash", entry: "./tool-1.2.3/bin/helper", expectedFile: "helper", expectedBody: fixtureEntries["tool-1.2.3/bin/helper"]},
+		{name: "Base name", entry: "tool", expectedFile: "tool", expectedBody: fixtureEntries["tool-1.2.3/bin/tool"]},
+		{name: "Full path disambiguates", entry: "tool-1.2.3/doc/README.md", expectedFile: "README.md", expectedBody: fixtureEntries["tool-1.2.3/doc/README.md"]},
+		{name: "Ambiguous base name", entry: "README.md", expectError: "matches more than one entry"},
+		{name: "Directory is not a file", entry: "tool-1.2.3/bin", expectNotFound: true},
+		{name: "Missing entry", entry: "missing", expectNotFound: true},
+	}
+
+	for _, format := range formats {
+		for _, tc := range testCases {
+			t.Run(format.name+"/"+tc.name, func(t *testing.T) {
+				dir := t.TempDir()
+				archivePath := filepath.Join(dir, "tool-archive")
+				format.write(t, archivePath)
+				destDir := filepath.Join(dir, "install")
+
+				// A failed extraction must leave an installed file alone
+				existing := filepath.Join(destDir, filepath.Base(tc.entry))
+				if tc.expectError != "" || tc.expectNotFound {
+					if err := os.MkdirAll(destDir, 0755); err != nil {
+						t.Fatalf("Failed to create destination directory: %v", err)
+					}
+					if err := os.WriteFile(existing, []byte("installed"), 0755); err != nil {
+						t.Fatalf("Failed to write existing file: %v", err)
+					}
+				}
+
+				path, err := ExtractFile(archivePath, tc.entry, destDir)
+				if tc.expectError != "" || tc.expectNotFound {
+					if err == nil {
+						t.Fatalf("Expected an error but got none")
+					}
+					if errors.Is(err, ErrEntryNotFound) != tc.expectNotFound {
+						t.Errorf("Expected entry not found %v but got: %v", tc.expectNotFound, err)
+					}
+					if !strings.Contains(err.Error(), tc.expectError) {
+						t.Errorf("Expected error containing %q but got: %v", tc.expectError, err)
+					}
+					if content, err := os.ReadFile(existing); err != nil || string(content) != "installed" {
+						t.Errorf("Expected the existing file to be kept but got %q: %v", content, err)
+					}
+					if entries, _ := os.ReadDir(destDir); len(entries) != 1 {
+						t.Errorf("Expected no temporary files to be left but got %d entries", len(entries))
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Did not expect an error but got: %v", err)
+				}
+
+				if path != filepath.Join(destDir, tc.expectedFile) {
+					t.Errorf("Expected path %s but got %s", filepath.Join(destDir, tc.expectedFile), path)
+				}
+				content, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatalf("Failed to read extracted file: %v", err)
+				}
+				if string(content) != tc.expectedBody {
+					t.Errorf("Expected content %q but got %q", tc.expectedBody, content)
+				}
+
+				// Only the requested entry is extracted
+				entries, _ := os.ReadDir(destDir)
+				if len(entries) != 1 {
+					t.Errorf("Expected only %s to be extracted but got %d entries", tc.expectedFile, len(entries))
+				}
+
+				if runtime.GOOS != "windows" {
+					info, err := os.Stat(path)
+					if err != nil {
+						t.Fatalf("Failed to stat extracted file: %v", err)
+					}
+					if info.Mode().Perm()&0111 == 0 {
+						t.Errorf("Expected extracted file to be executable but mode is %v", info.Mode())
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestExtractFileInvalidArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "not-an-archive")
+	if err := os.WriteFile(path, []byte("plain text that is not an archive"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	if _, err := ExtractFile(path, "tool", t.TempDir()); err == nil {
+		t.Errorf("Expected an error but got none")
+	}
+}
@@ -1,15 +1,27 @@
 package downloader
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha1"
 	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"hash"
 	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // DownloadOptions configures the download operation
@@ -20,6 +32,9 @@ type DownloadOptions struct {
 	// Expected checksum for verification (format: "algorithm:hash")
 	Checksum string
 
+	// Algorithm used to compute Result.Checksum when no Checksum is given (default "sha256")
+	Algorithm string
+
 	// Directory to save the downloaded file
 	DestDir string
 
@@ -28,6 +43,163 @@ type DownloadOptions struct {
 
 	// Whether to show progress
 	ShowProgress bool
+
+	// Called as data is received with the bytes downloaded so far and the total size
+	// (-1 if unknown). A final call with downloaded equal to total marks completion.
+	Progress func(downloaded, total int64)
+
+	// Overall timeout for the HTTP request, including reading the body
+	// (zero uses DefaultTimeout)
+	Timeout time.Duration
+
+	// Extra request headers (e.g. Authorization)
+	Headers map[string]string
+
+	// User-Agent sent with the request (if empty, DefaultUserAgent)
+	UserAgent string
+
+	// HTTP client to use, e.g. with custom root CAs or mTLS (if nil, DefaultClient)
+	Client *http.Client
+
+	// Skip TLS certificate verification (dangerous, only for trusted internal mirrors)
+	InsecureSkipVerify bool
+
+	// PEM file with extra CA certificates to trust, in addition to the system pool
+	CACertFile string
+
+	// Context that aborts the request when cancelled (if nil, context.Background)
+	Context context.Context
+
+	// Maximum number of redirects to follow (zero uses DefaultMaxRedirects, negative disables redirects)
+	MaxRedirects int
+
+	// Follow redirects to a different host than the one requested, which is blocked by default
+	// so a private download can't be bounced to an untrusted host
+	AllowCrossHostRedirect bool
+
+	// Refuse any network access, failing with ErrOffline before a request is made
+	Offline bool
+
+	// Extra attempts of Download after a transient failure (a network error, short read,
+	// 5xx or 429 response); other failures, such as a checksum mismatch, are never retried
+	MaxRetries int
+
+	// Delay before the first retry, doubled for each further one (zero uses DefaultRetryBackoff)
+	RetryBackoff time.Duration
+}
+
+// context returns the context of the download, defaulting to context.Background
+func (opts DownloadOptions) context() context.Context {
+	if opts.Context == nil {
+		return context.Background()
+	}
+	return opts.Context
+}
+
+// DefaultClient is the HTTP client used when DownloadOptions.Client is nil
+// If it is also nil, a plain client is used
+var DefaultClient *http.Client
+
+// ErrChecksumMismatch is matched by ChecksumMismatchError with errors.Is
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ChecksumMismatchError is returned when a download doesn't match its expected checksum
+type ChecksumMismatchError struct {
+	Expected string // Expected hex digest
+	Actual   string // Hex digest of the downloaded file
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("checksum verification failed: expected %s, got %s", e.Expected, e.Actual)
+}
+
+// Is reports whether target is ErrChecksumMismatch
+func (e *ChecksumMismatchError) Is(target error) bool {
+	return target == ErrChecksumMismatch
+}
+
+// DefaultTimeout is the download timeout used when none is specified
+const DefaultTimeout = 5 * time.Minute
+
+// DefaultRetryBackoff is the delay before the first retry when RetryBackoff is zero
+const DefaultRetryBackoff = time.Second
+
+// transientError marks a download failure that may succeed when retried
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *transientError) Unwrap() error {
+	return e.err
+}
+
+// DefaultMaxRedirects is the number of redirects followed when MaxRedirects is zero
+const DefaultMaxRedirects = 10
+
+// ErrOffline is returned for requests made with DownloadOptions.Offline set
+var ErrOffline = errors.New("offline mode: network access is disabled")
+
+// ErrRedirectBlocked is matched by errors from redirects refused by the redirect policy
+var ErrRedirectBlocked = errors.New("redirect blocked")
+
+// DefaultUserAgent is the User-Agent used when none is specified
+const DefaultUserAgent = "depman/dev"
+
+// get performs a GET request with the given User-Agent and headers
+func get(ctx context.Context, client *http.Client, rawURL, userAgent string, headers map[string]string) (*http.Response, error) {
+	return request(ctx, client, http.MethodGet, rawURL, userAgent, headers)
+}
+
+// request performs an HTTP request with the given User-Agent and headers
+func request(ctx context.Context, client *http.Client, method, rawURL, userAgent string, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	return client.Do(req)
+}
+
+// withoutURL drops the request URL from a *url.Error, as it may carry a token in its query
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
+}
+
+// newClient returns a copy of base (or DefaultClient) with the given timeout
+// A zero timeout keeps the client's own timeout, falling back to DefaultTimeout
+func newClient(base *http.Client, timeout time.Duration) *http.Client {
+	if base == nil {
+		base = DefaultClient
+	}
+	if base == nil {
+		base = &http.Client{}
+	}
+
+	client := *base
+	if timeout > 0 {
+		client.Timeout = timeout
+	} else if client.Timeout == 0 {
+		client.Timeout = DefaultTimeout
+	}
+	return &client
 }
 
 // Result contains information about the downloaded file
@@ -38,20 +210,458 @@ type Result struct {
 	// Size of the downloaded file in bytes
 	Size int64
 
-	// Calculated checksum of the file
+	// Calculated checksum of the file (hex digest)
 	Checksum string
+
+	// Algorithm of the calculated checksum (e.g. "sha256")
+	Algorithm string
+}
+
+// DefaultAlgorithm is the checksum algorithm used when none is specified
+const DefaultAlgorithm = "sha256"
+
+// checksumLengths maps supported checksum algorithms to their hex digest length
+var checksumLengths = map[string]int{
+	"sha1":   40,
+	"sha256": 64,
+	"sha512": 128,
 }
 
-// Download downloads a file from a URL with progress reporting and checksum verification
+// algorithmForDigest infers the algorithm of a bare hex digest from its length, failing for
+// lengths that match no supported algorithm
+func algorithmForDigest(digest string) (string, error) {
+	for algorithm, length := range checksumLengths {
+		if len(digest) == length {
+			return algorithm, nil
+		}
+	}
+	return "", fmt.Errorf("cannot infer checksum algorithm from %d hex characters (expected 40 for sha1, 64 for sha256 or 128 for sha512), use 'algorithm:hash'",
+		len(digest))
+}
+
+// SizeAlgorithm is a pseudo-checksum algorithm ("size:<bytes>") that only verifies the
+// downloaded size, for mirrors that publish nothing better. It is much weaker than a hash.
+const SizeAlgorithm = "size"
+
+// ParseChecksum splits a checksum in "algorithm:hexdigest" format and validates it
+// A "size:<bytes>" pseudo-checksum is also accepted, with the byte count as the digest, as is
+// a bare hex digest, whose algorithm is inferred from its length
+func ParseChecksum(checksum string) (algorithm, digest string, err error) {
+	parts := strings.Split(checksum, ":")
+	switch len(parts) {
+	case 1:
+		digest = parts[0]
+		if algorithm, err = algorithmForDigest(digest); err != nil {
+			return "", "", err
+		}
+	case 2:
+		algorithm = strings.ToLower(parts[0])
+		digest = parts[1]
+	default:
+		return "", "", fmt.Errorf("invalid checksum format, expected 'algorithm:hash'")
+	}
+
+	if algorithm == SizeAlgorithm {
+		if size, err := strconv.ParseInt(digest, 10, 64); err != nil || size < 0 {
+			return "", "", fmt.Errorf("invalid size checksum: %q is not a byte count", digest)
+		}
+		return algorithm, digest, nil
+	}
+
+	length, ok := checksumLengths[algorithm]
+	if !ok {
+		return "", "", fmt.Errorf("unsupported checksum algorithm: %s", algorithm)
+	}
+
+	if len(digest) != length {
+		return "", "", fmt.Errorf("invalid %s checksum length: expected %d hex characters, got %d",
+			algorithm, length, len(digest))
+	}
+
+	if _, err := hex.DecodeString(digest); err != nil {
+		return "", "", fmt.Errorf("invalid %s checksum: not a hex string", algorithm)
+	}
+
+	return algorithm, digest, nil
+}
+
+// clientFor returns the HTTP client for the options, applying any TLS settings
+// Every request goes through it, so it also enforces offline mode
+func clientFor(opts DownloadOptions) (*http.Client, error) {
+	if opts.Offline {
+		return nil, ErrOffline
+	}
+
+	client := newClient(opts.Client, opts.Timeout)
+	client.CheckRedirect = redirectPolicy(opts, client.CheckRedirect)
+	if !opts.InsecureSkipVerify && opts.CACertFile == "" {
+		return client, nil
+	}
+
+	// Clone the transport so the caller's client is left untouched
+	var transport *http.Transport
+	switch t := client.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return nil, fmt.Errorf("TLS options require an *http.Transport, got %T", client.Transport)
+	}
+
+	tlsConfig := &tls.Config{}
+	if transport.TLSClientConfig != nil {
+		tlsConfig = transport.TLSClientConfig.Clone()
+	}
+	tlsConfig.InsecureSkipVerify = opts.InsecureSkipVerify
+
+	if opts.CACertFile != "" {
+		pem, err := os.ReadFile(opts.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate file: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA certificate file %s", opts.CACertFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport.TLSClientConfig = tlsConfig
+	client.Transport = transport
+	return client, nil
+}
+
+// redirectPolicy limits the number of redirects and, unless allowed, refuses redirects to
+// another host than the original request's, before deferring to the client's own policy
+func redirectPolicy(opts DownloadOptions, next func(*http.Request, []*http.Request) error) func(*http.Request, []*http.Request) error {
+	maxRedirects := opts.MaxRedirects
+	if maxRedirects == 0 {
+		maxRedirects = DefaultMaxRedirects
+	}
+
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) > maxRedirects || maxRedirects < 0 {
+			return fmt.Errorf("%w: stopped after %d redirects", ErrRedirectBlocked, max(maxRedirects, 0))
+		}
+
+		original := via[0].URL
+		if !opts.AllowCrossHostRedirect && !strings.EqualFold(req.URL.Hostname(), original.Hostname()) {
+			return fmt.Errorf("%w: %s redirected to another host %s (set allow_cross_host_redirect to follow it)",
+				ErrRedirectBlocked, original.Hostname(), req.URL.Hostname())
+		}
+
+		if next != nil {
+			return next(req, via)
+		}
+		return nil
+	}
+}
+
+// Probe checks that opts.URL is reachable without downloading it and returns the
+// advertised size in bytes, or -1 if the server doesn't report one
+// A HEAD request is tried first, falling back to a ranged GET of a single byte for
+// servers that reject HEAD
+func Probe(opts DownloadOptions) (int64, error) {
+	client, err := clientFor(opts)
+	if err != nil {
+		return -1, err
+	}
+
+	resp, err := request(opts.context(), client, http.MethodHead, opts.URL, opts.UserAgent, opts.Headers)
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			return resp.ContentLength, nil
+		}
+	}
+
+	// Fall back to fetching the first byte
+	headers := map[string]string{"Range": "bytes=0-0"}
+	for key, value := range opts.Headers {
+		headers[key] = value
+	}
+
+	resp, err = get(opts.context(), client, opts.URL, opts.UserAgent, headers)
+	if err != nil {
+		return -1, fmt.Errorf("failed to reach server: %w", withoutURL(err))
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.ContentLength, nil
+	case http.StatusPartialContent:
+		// Content-Range is "bytes 0-0/<size>", where size may be "*"
+		contentRange := resp.Header.Get("Content-Range")
+		if i := strings.LastIndex(contentRange, "/"); i >= 0 {
+			if size, err := strconv.ParseInt(contentRange[i+1:], 10, 64); err == nil {
+				return size, nil
+			}
+		}
+		return -1, nil
+	default:
+		return -1, fmt.Errorf("bad status: %s", resp.Status)
+	}
+}
+
+// Fetch downloads a small document (e.g. a version manifest) from opts.URL into memory,
+// reading at most limit bytes
+// Only the request-related options (URL, Timeout, Headers, UserAgent, Client, TLS and Offline) are used
+func Fetch(opts DownloadOptions, limit int64) ([]byte, error) {
+	client, err := clientFor(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := get(opts.context(), client, opts.URL, opts.UserAgent, opts.Headers)
+	if err != nil {
+		return nil, withoutURL(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("bad status: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return data, nil
+}
+
+// FetchChecksum downloads a sidecar checksum file (e.g. "tool.tar.gz.sha256")
+// from opts.URL and returns its checksum in "algorithm:hexdigest" format
+// Only the request-related options (URL, Timeout, Headers, UserAgent, Client, TLS and Offline) are used
+// Both a bare hash and the common "<hash>  <filename>" format are accepted
+// Errors leave out the URL, which may carry credentials
+func FetchChecksum(opts DownloadOptions) (string, error) {
+	// Checksum files are tiny, so cap the read to avoid surprises
+	data, err := Fetch(opts, 64*1024)
+	if err != nil {
+		return "", fmt.Errorf("failed to download checksum file: %w", err)
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("checksum file is empty")
+	}
+	digest := strings.TrimPrefix(fields[0], "*")
+
+	// Determine the algorithm from the digest length
+	algorithm, err := algorithmForDigest(digest)
+	if err != nil {
+		return "", fmt.Errorf("checksum file does not contain a recognized hash")
+	}
+
+	checksum := algorithm + ":" + digest
+	if _, _, err := ParseChecksum(checksum); err != nil {
+		return "", fmt.Errorf("checksum file is malformed: %w", err)
+	}
+
+	return checksum, nil
+}
+
+// filenameFromResponse picks a filename from the Content-Disposition header, falling back
+// to the basename of the final (post-redirect) URL path without its query string
+func filenameFromResponse(resp *http.Response) string {
+	if disposition := resp.Header.Get("Content-Disposition"); disposition != "" {
+		if _, params, err := mime.ParseMediaType(disposition); err == nil && params["filename"] != "" {
+			// Only keep the base name so a hostile header can't escape the destination
+			name := filepath.Base(filepath.FromSlash(params["filename"]))
+			if ValidateFilename(name) == nil {
+				return name
+			}
+		}
+	}
+
+	if resp.Request != nil && resp.Request.URL != nil {
+		if name := path.Base(resp.Request.URL.Path); ValidateFilename(name) == nil {
+			return name
+		}
+	}
+
+	return "download"
+}
+
+// ValidateFilename checks that a download filename is a plain name that stays inside the
+// destination directory
+func ValidateFilename(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("filename is empty")
+	case name == "." || name == "..":
+		return fmt.Errorf("filename '%s' is not a file name", name)
+	case strings.ContainsAny(name, `/\`) || filepath.Base(name) != name:
+		return fmt.Errorf("filename '%s' contains a path separator", name)
+	}
+	return nil
+}
+
+// NormalizeChecksum returns a checksum in "algorithm:hexdigest" format, adding the inferred
+// algorithm to a bare hex digest
+func NormalizeChecksum(checksum string) (string, error) {
+	algorithm, digest, err := ParseChecksum(checksum)
+	if err != nil {
+		return "", err
+	}
+	return algorithm + ":" + digest, nil
+}
+
+// newHasher returns a hash implementation for the given algorithm
+func newHasher(algorithm string) hash.Hash {
+	switch algorithm {
+	case "sha1":
+		return sha1.New()
+	case "sha512":
+		return sha512.New()
+	default:
+		return sha256.New()
+	}
+}
+
+// VerifyFile checks a file on disk against a checksum in "algorithm:hexdigest" format
+// A mismatch is reported as a ChecksumMismatchError
+func VerifyFile(path, checksum string) error {
+	if _, _, err := ParseChecksum(checksum); err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	return verifyReader(file, info.Size(), checksum)
+}
+
+// VerifyData checks data in memory against a checksum in "algorithm:hexdigest" format
+// A mismatch is reported as a ChecksumMismatchError
+func VerifyData(data []byte, checksum string) error {
+	return verifyReader(bytes.NewReader(data), int64(len(data)), checksum)
+}
+
+// verifyReader checks the size bytes read from r against a checksum
+func verifyReader(r io.Reader, size int64, checksum string) error {
+	algorithm, expected, err := ParseChecksum(checksum)
+	if err != nil {
+		return err
+	}
+
+	if algorithm == SizeAlgorithm {
+		if strconv.FormatInt(size, 10) != expected {
+			return fmt.Errorf("size verification failed: expected %s bytes, got %d: %w", expected, size, ErrChecksumMismatch)
+		}
+		return nil
+	}
+
+	hasher := newHasher(algorithm)
+	if _, err := io.Copy(hasher, r); err != nil {
+		return fmt.Errorf("failed to read data: %w", err)
+	}
+
+	actual := hex.EncodeToString(hasher.Sum(nil))
+	if !strings.EqualFold(actual, expected) {
+		return &ChecksumMismatchError{Expected: expected, Actual: actual}
+	}
+
+	return nil
+}
+
+// progressReader reports the bytes read so far to a callback
+type progressReader struct {
+	r     io.Reader
+	total int64
+	read  int64
+	fn    func(downloaded, total int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.read += int64(n)
+		p.fn(p.read, p.total)
+	}
+	return n, err
+}
+
+// Download downloads a file from a URL with progress reporting and checksum verification,
+// retrying transient failures up to opts.MaxRetries times
 func Download(opts DownloadOptions) (*Result, error) {
+	if opts.Offline {
+		return nil, ErrOffline
+	}
+	if opts.Filename != "" {
+		if err := ValidateFilename(opts.Filename); err != nil {
+			return nil, err
+		}
+	}
+
+	backoff := opts.RetryBackoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+
+	for attempt := 1; ; attempt++ {
+		result, err := download(opts)
+		var transient *transientError
+		if err == nil || attempt > opts.MaxRetries || !errors.As(err, &transient) || opts.context().Err() != nil {
+			return result, err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-opts.context().Done():
+			return nil, fmt.Errorf("failed to download file: %w", opts.context().Err())
+		}
+		backoff *= 2
+	}
+}
+
+// download makes a single attempt of Download
+func download(opts DownloadOptions) (*Result, error) {
 	// Create destination directory if it doesn't exist
 	if err := os.MkdirAll(opts.DestDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create destination directory: %w", err)
 	}
 
-	// Determine filename from URL if not specified
+	// Get the data
+	client, err := clientFor(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := get(opts.context(), client, opts.URL, opts.UserAgent, opts.Headers)
+	if err != nil {
+		if errors.Is(err, ErrRedirectBlocked) {
+			return nil, fmt.Errorf("failed to download file: %w", err)
+		}
+		return nil, &transientError{fmt.Errorf("failed to download file: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	// Check server response, retrying server errors and rate limits
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("bad status: %s", resp.Status)
+		if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+			return nil, &transientError{err}
+		}
+		return nil, err
+	}
+
+	// Determine filename from the response if not specified
 	if opts.Filename == "" {
-		opts.Filename = filepath.Base(opts.URL)
+		opts.Filename = filenameFromResponse(resp)
 	}
 
 	// Full path to the downloaded file
@@ -64,65 +674,71 @@ func Download(opts DownloadOptions) (*Result, error) {
 	}
 	defer out.Close()
 
-	// Get the data
-	resp, err := http.Get(opts.URL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to download file: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Check server response
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("bad status: %s", resp.Status)
+	// Always compute a checksum, using the expected checksum's algorithm if given
+	algorithm := opts.Algorithm
+	if algorithm == "" {
+		algorithm = DefaultAlgorithm
 	}
-
-	// Initialize variables for checksum calculation
-	var hasher hash.Hash
-	var resultChecksum string
-	var writer io.Writer = out
-
-	// Set up checksum verification if requested
+	var expectedChecksum, expectedSize string
 	if opts.Checksum != "" {
-		parts := strings.Split(opts.Checksum, ":")
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("invalid checksum format, expected 'algorithm:hash'")
+		expectedAlgorithm, digest, err := ParseChecksum(opts.Checksum)
+		if err != nil {
+			return nil, err
 		}
-
-		algorithm := strings.ToLower(parts[0])
-		if algorithm != "sha256" {
-			return nil, fmt.Errorf("unsupported checksum algorithm: %s", algorithm)
+		if expectedAlgorithm == SizeAlgorithm {
+			expectedSize = digest
+		} else {
+			algorithm, expectedChecksum = expectedAlgorithm, digest
 		}
-
-		// Create SHA-256 hasher
-		hasher = sha256.New()
-		// Write to both file and hasher
-		writer = io.MultiWriter(out, hasher)
+	}
+	if _, ok := checksumLengths[algorithm]; !ok {
+		return nil, fmt.Errorf("unsupported checksum algorithm: %s", algorithm)
 	}
 
+	// Write to both file and hasher
+	hasher := newHasher(algorithm)
+	writer := io.MultiWriter(out, hasher)
+
 	// Copy data with optional progress reporting
-	size, err := io.Copy(writer, resp.Body)
+	var body io.Reader = resp.Body
+	if opts.Progress != nil {
+		body = &progressReader{r: resp.Body, total: resp.ContentLength, fn: opts.Progress}
+	}
+	size, err := io.Copy(writer, body)
+
+	// Make sure we received the whole file when the server told us its length
+	if resp.ContentLength >= 0 && size != resp.ContentLength {
+		out.Close()
+		os.Remove(destPath)
+		return nil, &transientError{fmt.Errorf("short read: expected %d bytes, got %d", resp.ContentLength, size)}
+	}
+
 	if err != nil {
 		return nil, fmt.Errorf("failed to write file: %w", err)
 	}
 
+	// Verify size if that is all that was provided
+	if expectedSize != "" && expectedSize != strconv.FormatInt(size, 10) {
+		os.Remove(destPath)
+		return nil, fmt.Errorf("size verification failed: expected %s bytes, got %d: %w", expectedSize, size, ErrChecksumMismatch)
+	}
+
 	// Verify checksum if provided
-	if opts.Checksum != "" && hasher != nil {
-		parts := strings.Split(opts.Checksum, ":")
-		expectedChecksum := parts[1]
-		actualChecksum := hex.EncodeToString(hasher.Sum(nil))
-		resultChecksum = actualChecksum
+	actualChecksum := hex.EncodeToString(hasher.Sum(nil))
+	if expectedChecksum != "" && !strings.EqualFold(actualChecksum, expectedChecksum) {
+		// Remove the file if checksum verification fails
+		os.Remove(destPath)
+		return nil, &ChecksumMismatchError{Expected: expectedChecksum, Actual: actualChecksum}
+	}
 
-		if !strings.EqualFold(actualChecksum, expectedChecksum) {
-			// Remove the file if checksum verification fails
-			os.Remove(destPath)
-			return nil, fmt.Errorf("checksum verification failed: expected %s, got %s",
-				expectedChecksum, actualChecksum)
-		}
+	if opts.Progress != nil {
+		opts.Progress(size, size)
 	}
 
 	return &Result{
-		FilePath: destPath,
-		Size:     size,
-		Checksum: resultChecksum,
+		FilePath:  destPath,
+		Size:      size,
+		Checksum:  actualChecksum,
+		Algorithm: algorithm,
 	}, nil
 }
@@ -0,0 +1,737 @@
+package downloader
+
+import (
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDownloadTimeout(t *testing.T) {
+	// A server that accepts the request but responds too slowly
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(2 * time.Second):
+		case <-r.Context().Done():
+		}
+		w.Write([]byte("too late"))
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	_, err := Download(DownloadOptions{
+		URL:     server.URL + "/tool.tar.gz",
+		DestDir: t.TempDir(),
+		Timeout: 100 * time.Millisecond,
+	})
+	if err == nil {
+		t.Fatalf("Expected an error but got none")
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Download did not time out promptly, took %s", elapsed)
+	}
+}
+
+func TestNewClientDefaultTimeout(t *testing.T) {
+	if client := newClient(nil, 0); client.Timeout != DefaultTimeout {
+		t.Errorf("Expected default timeout %s but got %s", DefaultTimeout, client.Timeout)
+	}
+}
+
+func TestDownloadShortRead(t *testing.T) {
+	// A server that advertises more bytes than it sends
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("Failed to hijack connection: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 100\r\n\r\n")
+		buf.WriteString("only ten b")
+		buf.Flush()
+	}))
+	defer server.Close()
+
+	destDir := t.TempDir()
+	_, err := Download(DownloadOptions{
+		URL:     server.URL + "/tool.tar.gz",
+		DestDir: destDir,
+	})
+	if err == nil {
+		t.Fatalf("Expected an error but got none")
+	}
+
+	if !strings.Contains(err.Error(), "short read: expected 100 bytes, got 10") {
+		t.Errorf("Expected short read error but got: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(destDir, "tool.tar.gz")); !os.IsNotExist(err) {
+		t.Errorf("Expected partial file to be removed")
+	}
+}
+
+func TestDownloadFilename(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/attachment":
+			w.Header().Set("Content-Disposition", `attachment; filename="tool-1.0.0.tar.gz"`)
+		case "/hostile":
+			w.Header().Set("Content-Disposition", `attachment; filename="../../etc/evil.sh"`)
+		case "/files/parent":
+			w.Header().Set("Content-Disposition", `attachment; filename=".."`)
+		case "/redirect":
+			http.Redirect(w, r, "/releases/tool-2.0.0.zip?sig=abc", http.StatusFound)
+			return
+		}
+		w.Write([]byte("artifact"))
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name     string
+		path     string
+		filename string
+		expected string
+	}{
+		{name: "Explicit filename", path: "/download?id=42", filename: "tool.msi", expected: "tool.msi"},
+		{name: "Content-Disposition", path: "/attachment", expected: "tool-1.0.0.tar.gz"},
+		{name: "Content-Disposition path stripped", path: "/hostile", expected: "evil.sh"},
+		{name: "Content-Disposition parent ignored", path: "/files/parent", expected: "parent"},
+		{name: "URL basename without query", path: "/files/tool.pkg?token=abc", expected: "tool.pkg"},
+		{name: "URL basename after redirect", path: "/redirect", expected: "tool-2.0.0.zip"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			destDir := t.TempDir()
+			result, err := Download(DownloadOptions{
+				URL:      server.URL + tc.path,
+				DestDir:  destDir,
+				Filename: tc.filename,
+			})
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			if expected := filepath.Join(destDir, tc.expected); result.FilePath != expected {
+				t.Errorf("Expected file path %s but got %s", expected, result.FilePath)
+			}
+		})
+	}
+}
+
+func TestDownloadInvalidFilename(t *testing.T) {
+	requested := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+		w.Write([]byte("artifact"))
+	}))
+	defer server.Close()
+
+	for _, filename := range []string{".", "..", "../evil.sh", "dir/tool.tar.gz", `dir\tool.exe`} {
+		t.Run(filename, func(t *testing.T) {
+			_, err := Download(DownloadOptions{
+				URL:      server.URL + "/tool.tar.gz",
+				DestDir:  t.TempDir(),
+				Filename: filename,
+			})
+			if err == nil {
+				t.Fatalf("Expected an error but got none")
+			}
+			if requested {
+				t.Errorf("Expected the download not to be requested")
+			}
+		})
+	}
+}
+
+func TestDownloadUserAgent(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("User-Agent")
+		w.Write([]byte("artifact"))
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name      string
+		userAgent string
+		expected  string
+	}{
+		{name: "Default user agent", expected: DefaultUserAgent},
+		{name: "Custom user agent", userAgent: "depman/1.2.3", expected: "depman/1.2.3"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Download(DownloadOptions{
+				URL:       server.URL + "/tool.tar.gz",
+				DestDir:   t.TempDir(),
+				UserAgent: tc.userAgent,
+			})
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			if got != tc.expected {
+				t.Errorf("Expected User-Agent %q but got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+// roundTripperFunc stubs an http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestDownloadClient(t *testing.T) {
+	var requested []string
+	client := &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			requested = append(requested, req.URL.String())
+			body := "artifact"
+			if strings.HasSuffix(req.URL.Path, ".sha256") {
+				body = "6d8bd1a4ed2ba5e34f6cf1dd6d2c1e3da3e5b4ce4e4a0e63c27e22c0b3fc5d6c  tool.tar.gz\n"
+			}
+			return &http.Response{
+				StatusCode:    http.StatusOK,
+				Status:        "200 OK",
+				Body:          io.NopCloser(strings.NewReader(body)),
+				ContentLength: int64(len(body)),
+				Request:       req,
+			}, nil
+		}),
+	}
+
+	t.Run("Download uses injected client", func(t *testing.T) {
+		requested = nil
+		result, err := Download(DownloadOptions{
+			URL:     "https://artifacts.invalid/tool.tar.gz",
+			DestDir: t.TempDir(),
+			Client:  client,
+		})
+		if err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+
+		if len(requested) != 1 || requested[0] != "https://artifacts.invalid/tool.tar.gz" {
+			t.Errorf("Expected the stub transport to serve the request but got %v", requested)
+		}
+		if filepath.Base(result.FilePath) != "tool.tar.gz" {
+			t.Errorf("Expected tool.tar.gz but got %s", result.FilePath)
+		}
+	})
+
+	t.Run("FetchChecksum uses injected client", func(t *testing.T) {
+		requested = nil
+		checksum, err := FetchChecksum(DownloadOptions{
+			URL:    "https://artifacts.invalid/tool.tar.gz.sha256",
+			Client: client,
+		})
+		if err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+
+		if len(requested) != 1 || !strings.HasPrefix(checksum, "sha256:") {
+			t.Errorf("Expected a sha256 checksum from the stub transport but got %q (requests: %v)", checksum, requested)
+		}
+	})
+
+	t.Run("DefaultClient used when none given", func(t *testing.T) {
+		DefaultClient = client
+		defer func() { DefaultClient = nil }()
+
+		requested = nil
+		if _, err := Download(DownloadOptions{
+			URL:     "https://artifacts.invalid/tool.tar.gz",
+			DestDir: t.TempDir(),
+		}); err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+
+		if len(requested) != 1 {
+			t.Errorf("Expected DefaultClient to serve the request but got %v", requested)
+		}
+	})
+
+	t.Run("Timeout does not modify injected client", func(t *testing.T) {
+		if c := newClient(client, time.Second); c == client || client.Timeout != 0 || c.Timeout != time.Second {
+			t.Errorf("Expected a copy with the timeout applied")
+		}
+	})
+}
+
+func TestDownloadTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("artifact"))
+	}))
+	defer server.Close()
+
+	// Write the server's self-signed certificate as a CA bundle
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0644); err != nil {
+		t.Fatalf("Failed to write CA file: %v", err)
+	}
+
+	testCases := []struct {
+		name        string
+		insecure    bool
+		caCertFile  string
+		expectError bool
+	}{
+		{name: "Untrusted certificate", expectError: true},
+		{name: "Custom CA bundle", caCertFile: caFile, expectError: false},
+		{name: "Skip verify", insecure: true, expectError: false},
+		{name: "Missing CA file", caCertFile: filepath.Join(t.TempDir(), "missing.pem"), expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Download(DownloadOptions{
+				URL:                server.URL + "/tool.tar.gz",
+				DestDir:            t.TempDir(),
+				InsecureSkipVerify: tc.insecure,
+				CACertFile:         tc.caCertFile,
+			})
+			if tc.expectError && err == nil {
+				t.Errorf("Expected an error but got none")
+			}
+			if !tc.expectError && err != nil {
+				t.Errorf("Did not expect an error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestDownloadComputesChecksum(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("artifact"))
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name              string
+		algorithm         string
+		expectedAlgorithm string
+		expected          string
+	}{
+		{name: "Default sha256", expectedAlgorithm: "sha256", expected: "c7c5c1d70c5dec4416ab6158afd0b223ef40c29b1dc1f97ed9428b94d4cadb1c"},
+		{name: "Configured sha512", algorithm: "sha512", expectedAlgorithm: "sha512"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := Download(DownloadOptions{
+				URL:       server.URL + "/tool.tar.gz",
+				DestDir:   t.TempDir(),
+				Algorithm: tc.algorithm,
+			})
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			if result.Algorithm != tc.expectedAlgorithm {
+				t.Errorf("Expected algorithm %s but got %s", tc.expectedAlgorithm, result.Algorithm)
+			}
+			if len(result.Checksum) != checksumLengths[tc.expectedAlgorithm] {
+				t.Errorf("Expected a %s digest but got %q", tc.expectedAlgorithm, result.Checksum)
+			}
+			if tc.expected != "" && result.Checksum != tc.expected {
+				t.Errorf("Expected checksum %s but got %s", tc.expected, result.Checksum)
+			}
+		})
+	}
+}
+
+func TestDownloadProgress(t *testing.T) {
+	content := strings.Repeat("x", 100000)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/sized" {
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		}
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name          string
+		path          string
+		expectedTotal int64
+	}{
+		{name: "Known size", path: "/sized", expectedTotal: int64(len(content))},
+		{name: "Unknown size", path: "/chunked", expectedTotal: -1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls [][2]int64
+			_, err := Download(DownloadOptions{
+				URL:     server.URL + tc.path,
+				DestDir: t.TempDir(),
+				Progress: func(downloaded, total int64) {
+					calls = append(calls, [2]int64{downloaded, total})
+				},
+			})
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			if len(calls) < 2 {
+				t.Fatalf("Expected several progress calls but got %v", calls)
+			}
+			if calls[0][1] != tc.expectedTotal {
+				t.Errorf("Expected total %d but got %d", tc.expectedTotal, calls[0][1])
+			}
+			for i := 1; i < len(calls); i++ {
+				if calls[i][0] < calls[i-1][0] {
+					t.Errorf("Expected progress to be non-decreasing but got %v", calls)
+				}
+			}
+			if last := calls[len(calls)-1]; last[0] != int64(len(content)) || last[1] != last[0] {
+				t.Errorf("Expected a final completion call but got %v", last)
+			}
+		})
+	}
+}
+
+func TestDownloadSizeChecksum(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("artifact"))
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name           string
+		checksum       string
+		expectError    bool
+		expectMismatch bool
+	}{
+		{name: "Matching size", checksum: "size:8", expectError: false},
+		{name: "Mismatching size", checksum: "size:9", expectError: true, expectMismatch: true},
+		{name: "Invalid size", checksum: "size:eight", expectError: true},
+		{name: "Negative size", checksum: "size:-1", expectError: true},
+		{name: "Combined with a hash", checksum: "size:8:sha256:abc", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			destDir := t.TempDir()
+			result, err := Download(DownloadOptions{
+				URL:      server.URL + "/tool.tar.gz",
+				DestDir:  destDir,
+				Checksum: tc.checksum,
+			})
+
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("Expected an error but got none")
+				}
+				if errors.Is(err, ErrChecksumMismatch) != tc.expectMismatch {
+					t.Errorf("Expected checksum mismatch %v but got: %v", tc.expectMismatch, err)
+				}
+				if _, statErr := os.Stat(filepath.Join(destDir, "tool.tar.gz")); tc.expectMismatch && !os.IsNotExist(statErr) {
+					t.Errorf("Expected the mismatched download to be removed")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			// A real hash is still computed for size-verified downloads
+			if result.Algorithm != DefaultAlgorithm || len(result.Checksum) != checksumLengths[DefaultAlgorithm] {
+				t.Errorf("Expected a %s checksum to be computed but got %s:%s", DefaultAlgorithm, result.Algorithm, result.Checksum)
+			}
+		})
+	}
+}
+
+func TestDownloadRedirectPolicy(t *testing.T) {
+	// The CDN is reached through "localhost", so it is another host than the 127.0.0.1 origin
+	cdnHits := 0
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cdnHits++
+		w.Write([]byte("artifact"))
+	}))
+	defer cdn.Close()
+	cdnURL := strings.Replace(cdn.URL, "127.0.0.1", "localhost", 1)
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/cross":
+			http.Redirect(w, r, cdnURL+"/tool.tar.gz", http.StatusFound)
+		case strings.HasPrefix(r.URL.Path, "/chain/"):
+			// Redirect on the same host until the count reaches zero
+			n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/chain/"))
+			if n > 0 {
+				http.Redirect(w, r, "/chain/"+strconv.Itoa(n-1), http.StatusFound)
+				return
+			}
+			w.Write([]byte("artifact"))
+		}
+	}))
+	defer origin.Close()
+
+	testCases := []struct {
+		name         string
+		path         string
+		maxRedirects int
+		allowCross   bool
+		expectError  string
+		expectCDNHit bool
+	}{
+		{name: "Same host redirect", path: "/chain/1"},
+		{name: "Cross host blocked by default", path: "/cross", expectError: "set allow_cross_host_redirect"},
+		{name: "Cross host allowed", path: "/cross", allowCross: true, expectCDNHit: true},
+		{name: "Within redirect limit", path: "/chain/3", maxRedirects: 3},
+		{name: "Over redirect limit", path: "/chain/3", maxRedirects: 2, expectError: "stopped after 2 redirects"},
+		{name: "Redirects disabled", path: "/chain/1", maxRedirects: -1, expectError: "stopped after 0 redirects"},
+		{name: "Over default redirect limit", path: "/chain/11", expectError: "stopped after 10 redirects"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cdnHits = 0
+			_, err := Download(DownloadOptions{
+				URL:                    origin.URL + tc.path,
+				DestDir:                t.TempDir(),
+				Filename:               "tool.tar.gz",
+				MaxRedirects:           tc.maxRedirects,
+				AllowCrossHostRedirect: tc.allowCross,
+			})
+
+			if tc.expectError != "" {
+				if !errors.Is(err, ErrRedirectBlocked) || !strings.Contains(err.Error(), tc.expectError) {
+					t.Errorf("Expected a blocked redirect containing %q but got: %v", tc.expectError, err)
+				}
+			} else if err != nil {
+				t.Errorf("Did not expect an error but got: %v", err)
+			}
+
+			if (cdnHits > 0) != tc.expectCDNHit {
+				t.Errorf("Expected CDN hit %v but got %d requests", tc.expectCDNHit, cdnHits)
+			}
+		})
+	}
+}
+
+func TestOffline(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Write([]byte("1.0.0"))
+	}))
+	defer server.Close()
+
+	destDir := filepath.Join(t.TempDir(), "downloads")
+	opts := DownloadOptions{URL: server.URL + "/tool.tar.gz", DestDir: destDir, Offline: true}
+
+	requests := map[string]func() error{
+		"Download": func() error { _, err := Download(opts); return err },
+		"Fetch":    func() error { _, err := Fetch(opts, 1024); return err },
+		"Probe":    func() error { _, err := Probe(opts); return err },
+		"Checksum": func() error { _, err := FetchChecksum(opts); return err },
+	}
+	for name, request := range requests {
+		t.Run(name, func(t *testing.T) {
+			if err := request(); !errors.Is(err, ErrOffline) {
+				t.Errorf("Expected an offline error but got: %v", err)
+			}
+		})
+	}
+
+	if hits != 0 {
+		t.Errorf("Expected no requests in offline mode but got %d", hits)
+	}
+	if _, err := os.Stat(destDir); !os.IsNotExist(err) {
+		t.Errorf("Expected no destination directory to be created in offline mode")
+	}
+}
+
+// TestProbeErrorsHideURL tests that probe and checksum errors don't leak URL credentials
+func TestProbeErrorsHideURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not a checksum"))
+	}))
+	unreachable := server.URL
+	server.Close()
+
+	checksumServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not-a-hash  tool.tar.gz"))
+	}))
+	defer checksumServer.Close()
+
+	requests := map[string]func() error{
+		"Probe unreachable": func() error {
+			_, err := Probe(DownloadOptions{URL: unreachable + "/tool.tar.gz?token=s3cret"})
+			return err
+		},
+		"Checksum unreachable": func() error {
+			_, err := FetchChecksum(DownloadOptions{URL: unreachable + "/tool.tar.gz.sha256?token=s3cret"})
+			return err
+		},
+		"Checksum malformed": func() error {
+			_, err := FetchChecksum(DownloadOptions{URL: checksumServer.URL + "/tool.tar.gz.sha256?token=s3cret"})
+			return err
+		},
+	}
+	for name, request := range requests {
+		t.Run(name, func(t *testing.T) {
+			err := request()
+			if err == nil {
+				t.Fatalf("Expected an error but got none")
+			}
+			if strings.Contains(err.Error(), "s3cret") {
+				t.Errorf("Expected the error to hide the URL token but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestDownloadRetries(t *testing.T) {
+	testCases := []struct {
+		name          string
+		failures      int
+		status        int
+		maxRetries    int
+		expectError   bool
+		expectAttempt int
+	}{
+		{name: "Succeeds after server errors", failures: 2, status: http.StatusServiceUnavailable, maxRetries: 2, expectAttempt: 3},
+		{name: "Rate limit is retried", failures: 1, status: http.StatusTooManyRequests, maxRetries: 1, expectAttempt: 2},
+		{name: "Gives up after max retries", failures: 3, status: http.StatusBadGateway, maxRetries: 1, expectError: true, expectAttempt: 2},
+		{name: "Not found is not retried", failures: 1, status: http.StatusNotFound, maxRetries: 3, expectError: true, expectAttempt: 1},
+		{name: "No retries by default", failures: 1, status: http.StatusInternalServerError, expectError: true, expectAttempt: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				if attempts <= tc.failures {
+					w.WriteHeader(tc.status)
+					return
+				}
+				w.Write([]byte("artifact"))
+			}))
+			defer server.Close()
+
+			_, err := Download(DownloadOptions{
+				URL:          server.URL + "/tool.tar.gz",
+				DestDir:      t.TempDir(),
+				MaxRetries:   tc.maxRetries,
+				RetryBackoff: time.Millisecond,
+			})
+			if tc.expectError && err == nil {
+				t.Errorf("Expected an error but got none")
+			}
+			if !tc.expectError && err != nil {
+				t.Errorf("Did not expect an error but got: %v", err)
+			}
+			if attempts != tc.expectAttempt {
+				t.Errorf("Expected %d attempts but got %d", tc.expectAttempt, attempts)
+			}
+		})
+	}
+
+	// A checksum mismatch is not transient
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Write([]byte("artifact"))
+	}))
+	defer server.Close()
+
+	_, err := Download(DownloadOptions{
+		URL:          server.URL + "/tool.tar.gz",
+		DestDir:      t.TempDir(),
+		Checksum:     "sha256:" + strings.Repeat("0", 64),
+		MaxRetries:   3,
+		RetryBackoff: time.Millisecond,
+	})
+	if !errors.Is(err, ErrChecksumMismatch) || attempts != 1 {
+		t.Errorf("Expected a single attempt failing the checksum but got %d attempts: %v", attempts, err)
+	}
+}
+
+func TestParseChecksum(t *testing.T) {
+	sha1Digest := strings.Repeat("a", 40)
+	sha256Digest := strings.Repeat("b", 64)
+	sha512Digest := strings.Repeat("c", 128)
+
+	testCases := []struct {
+		name              string
+		checksum          string
+		expectedAlgorithm string
+		expectedDigest    string
+		expectError       bool
+	}{
+		{name: "Bare sha1", checksum: sha1Digest, expectedAlgorithm: "sha1", expectedDigest: sha1Digest},
+		{name: "Bare sha256", checksum: sha256Digest, expectedAlgorithm: "sha256", expectedDigest: sha256Digest},
+		{name: "Bare sha512", checksum: sha512Digest, expectedAlgorithm: "sha512", expectedDigest: sha512Digest},
+		{name: "Explicit algorithm", checksum: "SHA256:" + sha256Digest, expectedAlgorithm: "sha256", expectedDigest: sha256Digest},
+		{name: "Explicit algorithm is authoritative", checksum: "sha512:" + sha256Digest, expectError: true},
+		{name: "Ambiguous length", checksum: strings.Repeat("d", 32), expectError: true},
+		{name: "Bare non-hex", checksum: strings.Repeat("z", 64), expectError: true},
+		{name: "Size", checksum: "size:8", expectedAlgorithm: SizeAlgorithm, expectedDigest: "8"},
+		{name: "Too many parts", checksum: "sha256:" + sha256Digest + ":x", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			algorithm, digest, err := ParseChecksum(tc.checksum)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+			if algorithm != tc.expectedAlgorithm || digest != tc.expectedDigest {
+				t.Errorf("Expected %s:%s but got %s:%s", tc.expectedAlgorithm, tc.expectedDigest, algorithm, digest)
+			}
+		})
+	}
+}
+
+func TestDownloadBareChecksum(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("artifact"))
+	}))
+	defer server.Close()
+
+	for _, algorithm := range []string{"sha1", "sha256", "sha512"} {
+		t.Run(algorithm, func(t *testing.T) {
+			hasher := newHasher(algorithm)
+			hasher.Write([]byte("artifact"))
+			digest := hex.EncodeToString(hasher.Sum(nil))
+
+			result, err := Download(DownloadOptions{URL: server.URL + "/tool.tar.gz", DestDir: t.TempDir(), Checksum: digest})
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+			if result.Algorithm != algorithm {
+				t.Errorf("Expected the download to be verified with %s but got %s", algorithm, result.Algorithm)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 )
 
@@ -15,16 +16,60 @@ type Manager struct {
 
 	// Paths to add to the PATH variable
 	Paths []string
+
+	// Environment variables to remove
+	UnsetVariables []string
+
+	// Paths to remove from the PATH variable
+	RemovedPaths []string
+
+	// Whether variables override values already set in the OS environment
+	// PATH additions are always merged
+	OverrideExisting bool
 }
 
 // NewManager creates a new environment manager
 func NewManager() *Manager {
 	return &Manager{
-		Variables: make(map[string]string),
-		Paths:     []string{},
+		Variables:        make(map[string]string),
+		Paths:            []string{},
+		OverrideExisting: true,
 	}
 }
 
+// PreservesExisting reports whether an already-set value of key in the current
+// process takes precedence over the configured value
+func (m *Manager) PreservesExisting(key string) bool {
+	if m.OverrideExisting {
+		return false
+	}
+
+	_, ok := os.LookupEnv(key)
+	return ok
+}
+
+// UnsetVariable removes an environment variable
+func (m *Manager) UnsetVariable(key string) {
+	delete(m.Variables, key)
+	m.UnsetVariables = append(m.UnsetVariables, key)
+}
+
+// RemovePath removes a path from the PATH variable
+func (m *Manager) RemovePath(path string) {
+	path = NormalizePath(path)
+
+	// Drop it from the paths we would add
+	paths := m.Paths[:0]
+	for _, p := range m.Paths {
+		if p != path {
+			paths = append(paths, p)
+		}
+	}
+	m.Paths = paths
+
+	m.RemovedPaths = append(m.RemovedPaths, path)
+}
+
 // AddVariable adds or updates an environment variable
 func (m *Manager) AddVariable(key, value string) {
 	m.Variables[key] = value
@@ -33,7 +78,7 @@ func (m *Manager) AddVariable(key, value string) {
 // AddPath adds a path to the PATH variable
 func (m *Manager) AddPath(path string) {
 	// Normalize path for the current OS
-	path = filepath.Clean(path)
+	path = NormalizePath(path)
 
 	// Check if path already exists in our list
 	for _, p := range m.Paths {
@@ -45,17 +90,104 @@ func (m *Manager) AddPath(path string) {
 	m.Paths = append(m.Paths, path)
 }
 
+// NormalizePath converts a path from config to the current OS's separators, collapses
+// duplicate separators, and cleans it
+func NormalizePath(path string) string {
+	return filepath.Clean(normalizeSeparators(path, os.PathSeparator))
+}
+
+// normalizeSeparators converts forward slashes to sep and collapses repeated separators
+// When sep is a backslash, a leading "\\" (UNC path) is preserved and drive letters
+// such as "C:" are left untouched
+func normalizeSeparators(path string, sep byte) string {
+	if sep == '\\' {
+		path = strings.ReplaceAll(path, "/", `\`)
+	}
+
+	// Keep the UNC prefix intact
+	prefix := ""
+	if sep == '\\' && strings.HasPrefix(path, `\\`) {
+		prefix = `\`
+		path = path[1:]
+	}
+
+	var b strings.Builder
+	b.WriteString(prefix)
+	for i := 0; i < len(path); i++ {
+		if path[i] == sep && i > 0 && path[i-1] == sep {
+			continue
+		}
+		b.WriteByte(path[i])
+	}
+
+	return b.String()
+}
+
+// baseVariables are kept by BaseEnvironment, as most programs need them to run
+var baseVariables = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "SHELL", "LANG", "TERM", "TZ", "TMPDIR",
+	// Windows
+	"SYSTEMROOT", "SYSTEMDRIVE", "WINDIR", "COMSPEC", "PATHEXT", "TEMP", "TMP",
+	"USERPROFILE", "APPDATA", "LOCALAPPDATA", "PROGRAMDATA", "PROGRAMFILES", "PROGRAMFILES(X86)",
+}
+
+// BaseEnvironment returns a minimal copy of the current environment, keeping only the
+// variables most programs need to run (PATH, HOME, temp directories and the like)
+func BaseEnvironment() []string {
+	keep := make(map[string]bool, len(baseVariables))
+	for _, key := range baseVariables {
+		keep[key] = true
+	}
+
+	var env []string
+	for _, e := range os.Environ() {
+		key, _, ok := strings.Cut(e, "=")
+		if ok && keep[strings.ToUpper(key)] {
+			env = append(env, e)
+		}
+	}
+
+	return env
+}
+
+// Clone returns a copy of the manager that can be changed independently
+func (m *Manager) Clone() *Manager {
+	clone := &Manager{
+		Variables:        make(map[string]string, len(m.Variables)),
+		Paths:            append([]string{}, m.Paths...),
+		UnsetVariables:   append([]string(nil), m.UnsetVariables...),
+		RemovedPaths:     append([]string(nil), m.RemovedPaths...),
+		OverrideExisting: m.OverrideExisting,
+	}
+	for key, value := range m.Variables {
+		clone.Variables[key] = value
+	}
+
+	return clone
+}
+
 // GetUpdatedEnvironment returns a new environment with the applied changes
 func (m *Manager) GetUpdatedEnvironment() []string {
-	// Start with the current environment
-	env := os.Environ()
+	return m.UpdateEnvironment(os.Environ())
+}
+
+// UpdateEnvironment returns a copy of env (in os.Environ form) with the changes applied
+func (m *Manager) UpdateEnvironment(env []string) []string {
 	result := make([]string, 0, len(env))
 
 	// Track which variables we've updated
 	updated := make(map[string]bool)
 
+	// Variables already set in env, which keep their value unless overridden
+	existing := make(map[string]bool, len(env))
+	for _, e := range env {
+		if key, _, ok := strings.Cut(e, "="); ok {
+			existing[key] = true
+		}
+	}
+
 	// Apply path changes
-	if len(m.Paths) > 0 {
+	if len(m.Paths) > 0 || len(m.RemovedPaths) > 0 {
 		pathVar := "PATH"
 		if runtime.GOOS == "windows" {
 			// Windows is case-insensitive for env vars, find the actual case
@@ -68,11 +200,13 @@ func (m *Manager) GetUpdatedEnvironment() []string {
 		}
 
 		// Get current PATH value
-		currentPath := os.Getenv(pathVar)
+		currentPath := m.withoutRemovedPaths(lookup(env, pathVar))
 
 		// Add our paths
 		newPaths := strings.Join(m.Paths, string(os.PathListSeparator))
-		if currentPath != "" {
+		if newPaths == "" {
+			newPaths = currentPath
+		} else if currentPath != "" {
 			newPaths = newPaths + string(os.PathListSeparator) + currentPath
 		}
 
@@ -83,10 +217,18 @@ func (m *Manager) GetUpdatedEnvironment() []string {
 
 	// Apply variable changes
 	for key, value := range m.Variables {
+		if !m.OverrideExisting && existing[key] {
+			continue // Keep the existing value
+		}
 		result = append(result, fmt.Sprintf("%s=%s", key, value))
 		updated[key] = true
 	}
 
+	// Skip variables that were unset
+	for _, key := range m.UnsetVariables {
+		updated[key] = true
+	}
+
 	// Add remaining unchanged variables
 	for _, e := range env {
 		parts := strings.SplitN(e, "=", 2)
@@ -105,10 +247,75 @@ func (m *Manager) GetUpdatedEnvironment() []string {
 	return result
 }
 
+// lookup returns the value of key in env (in os.Environ form), or "" if it is not set
+func lookup(env []string, key string) string {
+	for _, e := range env {
+		if k, v, ok := strings.Cut(e, "="); ok && k == key {
+			return v
+		}
+	}
+	return ""
+}
+
+// WriteDotEnv writes the managed variables and the merged PATH to a .env file
+// Values containing spaces or special characters are double-quoted and escaped
+// The file may hold tokens, so it is only readable by its owner
+func (m *Manager) WriteDotEnv(path string) error {
+	// Resolve values the same way as the updated environment
+	env := make(map[string]string)
+	pathVar := "PATH"
+	for _, e := range m.GetUpdatedEnvironment() {
+		parts := strings.SplitN(e, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		env[parts[0]] = parts[1]
+		if strings.EqualFold(parts[0], "PATH") {
+			pathVar = parts[0]
+		}
+	}
+
+	keys := make([]string, 0, len(m.Variables))
+	for key := range m.Variables {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s=%s\n", key, quoteDotEnv(env[key]))
+	}
+	fmt.Fprintf(&b, "%s=%s\n", pathVar, quoteDotEnv(env[pathVar]))
+
+	if err := os.WriteFile(path, []byte(b.String()), 0600); err != nil {
+		return fmt.Errorf("failed to write .env file: %w", err)
+	}
+
+	// An existing file keeps its mode when overwritten
+	if err := os.Chmod(path, 0600); err != nil {
+		return fmt.Errorf("failed to restrict .env file permissions: %w", err)
+	}
+
+	return nil
+}
+
+// quoteDotEnv quotes a .env value if it contains spaces or special characters
+func quoteDotEnv(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\n\r\"'`\\$#=") {
+		return value
+	}
+
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`, "\r", `\r`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
 // ApplyToCurrentProcess applies the environment changes to the current process
 func (m *Manager) ApplyToCurrentProcess() error {
 	// Set variables
 	for key, value := range m.Variables {
+		if m.PreservesExisting(key) {
+			continue // Keep the existing OS value
+		}
 		if err := os.Setenv(key, value); err != nil {
 			return fmt.Errorf("failed to set environment variable %s: %w", key, err)
 		}
@@ -137,9 +344,44 @@ func (m *Manager) ApplyToCurrentProcess() error {
 		}
 	}
 
+	// Unset removed variables
+	for _, key := range m.UnsetVariables {
+		if err := os.Unsetenv(key); err != nil {
+			return fmt.Errorf("failed to unset environment variable %s: %w", key, err)
+		}
+	}
+
+	// Strip removed paths from PATH
+	if len(m.RemovedPaths) > 0 {
+		if err := os.Setenv("PATH", m.withoutRemovedPaths(os.Getenv("PATH"))); err != nil {
+			return fmt.Errorf("failed to update PATH: %w", err)
+		}
+	}
+
 	return nil
 }
 
+// withoutRemovedPaths returns a PATH value with the removed paths filtered out
+func (m *Manager) withoutRemovedPaths(pathValue string) string {
+	if len(m.RemovedPaths) == 0 {
+		return pathValue
+	}
+
+	removed := make(map[string]bool)
+	for _, p := range m.RemovedPaths {
+		removed[p] = true
+	}
+
+	var kept []string
+	for _, p := range filepath.SplitList(pathValue) {
+		if !removed[NormalizePath(p)] {
+			kept = append(kept, p)
+		}
+	}
+
+	return strings.Join(kept, string(os.PathListSeparator))
+}
+
 // ExpandVariables expands placeholders in a string using the current variables
 func (m *Manager) ExpandVariables(text string) string {
 	result := text
@@ -161,3 +403,101 @@ func (m *Manager) ExpandVariables(text string) string {
 
 	return result
 }
+
+// Diff describes how an environment changed
+type Diff struct {
+	AddedPaths   []string         // PATH entries that were added, in PATH order
+	RemovedPaths []string         // PATH entries that were removed, in their former order
+	Added        []VariableChange // Variables that were not set before, sorted by key
+	Changed      []VariableChange // Variables whose value changed, sorted by key
+	Removed      []VariableChange // Variables that are no longer set, sorted by key
+}
+
+// VariableChange is a variable set or changed between two environments
+type VariableChange struct {
+	Key string // Name of the variable
+	Old string // Previous value (empty if it was not set)
+	New string // Updated value (empty if it was removed)
+}
+
+// Empty reports whether the environment did not change
+func (d *Diff) Empty() bool {
+	return len(d.AddedPaths) == 0 && len(d.RemovedPaths) == 0 &&
+		len(d.Added) == 0 && len(d.Changed) == 0 && len(d.Removed) == 0
+}
+
+// DiffEnvironments compares two environments in os.Environ form
+// PATH is compared entry by entry, so additions show up as added paths rather than as a change
+func DiffEnvironments(before, after []string) Diff {
+	oldVars, newVars := environMap(before), environMap(after)
+
+	var diff Diff
+	diff.AddedPaths = missingPaths(newVars[pathKey(newVars)], oldVars[pathKey(oldVars)])
+	diff.RemovedPaths = missingPaths(oldVars[pathKey(oldVars)], newVars[pathKey(newVars)])
+
+	for key, value := range newVars {
+		if isPathKey(key) {
+			continue
+		}
+		old, ok := oldVars[key]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, VariableChange{Key: key, New: value})
+		case old != value:
+			diff.Changed = append(diff.Changed, VariableChange{Key: key, Old: old, New: value})
+		}
+	}
+	for key, value := range oldVars {
+		if _, ok := newVars[key]; !ok && !isPathKey(key) {
+			diff.Removed = append(diff.Removed, VariableChange{Key: key, Old: value})
+		}
+	}
+
+	for _, changes := range [][]VariableChange{diff.Added, diff.Changed, diff.Removed} {
+		sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	}
+	return diff
+}
+
+// environMap returns the variables of an environment in os.Environ form by key
+func environMap(env []string) map[string]string {
+	vars := make(map[string]string, len(env))
+	for _, e := range env {
+		if key, value, ok := strings.Cut(e, "="); ok {
+			vars[key] = value
+		}
+	}
+	return vars
+}
+
+// isPathKey reports whether key is the PATH variable, whose case doesn't matter on Windows
+func isPathKey(key string) bool {
+	return key == "PATH" || (runtime.GOOS == "windows" && strings.EqualFold(key, "PATH"))
+}
+
+// pathKey returns the key of the PATH variable in vars, in the case it is set with
+func pathKey(vars map[string]string) string {
+	for key := range vars {
+		if isPathKey(key) {
+			return key
+		}
+	}
+	return "PATH"
+}
+
+// missingPaths returns the entries of the PATH value from that are not in the PATH value in
+func missingPaths(from, in string) []string {
+	existing := make(map[string]bool)
+	for _, entry := range filepath.SplitList(in) {
+		existing[entry] = true
+	}
+
+	var missing []string
+	for _, entry := range filepath.SplitList(from) {
+		if entry != "" && !existing[entry] {
+			missing = append(missing, entry)
+			existing[entry] = true
+		}
+	}
+	return missing
+}
@@ -0,0 +1,326 @@
+package environment
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeSeparators(t *testing.T) {
+	testCases := []struct {
+		name     string
+		path     string
+		sep      byte
+		expected string
+	}{
+		{name: "Unix path unchanged", path: "/usr/local/bin", sep: '/', expected: "/usr/local/bin"},
+		{name: "Unix duplicate separators", path: "/usr//local///bin", sep: '/', expected: "/usr/local/bin"},
+		{name: "Windows forward slashes", path: "C:/Program Files/Tool/bin", sep: '\\', expected: `C:\Program Files\Tool\bin`},
+		{name: "Windows mixed separators", path: `C:\Tools//node\\bin`, sep: '\\', expected: `C:\Tools\node\bin`},
+		{name: "Windows drive root", path: "D:/", sep: '\\', expected: `D:\`},
+		{name: "Windows UNC path", path: `\\server\share//tools`, sep: '\\', expected: `\\server\share\tools`},
+		{name: "Windows relative path", path: "tools/bin", sep: '\\', expected: `tools\bin`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := normalizeSeparators(tc.path, tc.sep); result != tc.expected {
+				t.Errorf("Expected %q but got %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestAddPathNormalizes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix path test")
+	}
+
+	m := NewManager()
+	m.AddPath("/opt//tool/bin/")
+	m.AddPath("/opt/tool/bin")
+
+	if len(m.Paths) != 1 || m.Paths[0] != filepath.FromSlash("/opt/tool/bin") {
+		t.Errorf("Expected a single normalized path but got %v", m.Paths)
+	}
+}
+
+func TestOverrideExisting(t *testing.T) {
+	t.Setenv("DEPMAN_TEST_EXISTING", "from-shell")
+
+	testCases := []struct {
+		name     string
+		override bool
+		expected string
+	}{
+		{name: "Config overrides existing", override: true, expected: "from-config"},
+		{name: "Existing value preserved", override: false, expected: "from-shell"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewManager()
+			m.OverrideExisting = tc.override
+			m.AddVariable("DEPMAN_TEST_EXISTING", "from-config")
+			m.AddVariable("DEPMAN_TEST_UNSET", "new")
+			m.AddPath(filepath.FromSlash("/opt/tool/bin"))
+
+			env := make(map[string]string)
+			count := 0
+			for _, e := range m.GetUpdatedEnvironment() {
+				parts := strings.SplitN(e, "=", 2)
+				if parts[0] == "DEPMAN_TEST_EXISTING" {
+					count++
+				}
+				env[parts[0]] = parts[1]
+			}
+
+			if count != 1 {
+				t.Errorf("Expected DEPMAN_TEST_EXISTING once but got %d entries", count)
+			}
+			if env["DEPMAN_TEST_EXISTING"] != tc.expected {
+				t.Errorf("Expected %q but got %q", tc.expected, env["DEPMAN_TEST_EXISTING"])
+			}
+			if env["DEPMAN_TEST_UNSET"] != "new" {
+				t.Errorf("Expected unset variable to be added but got %q", env["DEPMAN_TEST_UNSET"])
+			}
+			if !strings.HasPrefix(env["PATH"], filepath.FromSlash("/opt/tool/bin")) {
+				t.Errorf("Expected PATH to be merged but got %q", env["PATH"])
+			}
+		})
+	}
+}
+
+// TestUpdateEnvironmentKeepsExistingOfInput tests that preserving existing values
+// looks at the given environment rather than the current process
+func TestUpdateEnvironmentKeepsExistingOfInput(t *testing.T) {
+	t.Setenv("DEPMAN_TEST_PROCESS_ONLY", "from-process")
+	os.Unsetenv("DEPMAN_TEST_INPUT_ONLY")
+
+	m := NewManager()
+	m.OverrideExisting = false
+	m.AddVariable("DEPMAN_TEST_PROCESS_ONLY", "from-config")
+	m.AddVariable("DEPMAN_TEST_INPUT_ONLY", "from-config")
+
+	env := make(map[string]string)
+	for _, e := range m.UpdateEnvironment([]string{"DEPMAN_TEST_INPUT_ONLY=from-input"}) {
+		key, value, _ := strings.Cut(e, "=")
+		env[key] = value
+	}
+
+	expected := map[string]string{
+		"DEPMAN_TEST_PROCESS_ONLY": "from-config",
+		"DEPMAN_TEST_INPUT_ONLY":   "from-input",
+	}
+	if !reflect.DeepEqual(env, expected) {
+		t.Errorf("Expected %v but got %v", expected, env)
+	}
+}
+
+// parseDotEnv parses the .env format written by WriteDotEnv
+func parseDotEnv(t *testing.T, data string) map[string]string {
+	t.Helper()
+
+	result := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(data), "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			t.Fatalf("Malformed .env line: %q", line)
+		}
+
+		if strings.HasPrefix(value, `"`) {
+			if len(value) < 2 || !strings.HasSuffix(value, `"`) {
+				t.Fatalf("Unterminated quoted value: %q", line)
+			}
+			var b strings.Builder
+			inner := value[1 : len(value)-1]
+			for i := 0; i < len(inner); i++ {
+				if inner[i] == '\\' && i+1 < len(inner) {
+					i++
+					switch inner[i] {
+					case 'n':
+						b.WriteByte('\n')
+					case 'r':
+						b.WriteByte('\r')
+					default:
+						b.WriteByte(inner[i])
+					}
+					continue
+				}
+				b.WriteByte(inner[i])
+			}
+			value = b.String()
+		}
+
+		result[key] = value
+	}
+
+	return result
+}
+
+func TestBaseEnvironment(t *testing.T) {
+	t.Setenv("DEPMAN_TEST_LEAKED", "secret")
+	t.Setenv("HOME", "/home/depman")
+
+	m := NewManager()
+	m.AddVariable("DEPMAN_TEST_INJECTED", "yes")
+	m.AddPath(filepath.FromSlash("/opt/tool/bin"))
+
+	env := make(map[string]string)
+	for _, e := range m.UpdateEnvironment(BaseEnvironment()) {
+		parts := strings.SplitN(e, "=", 2)
+		env[parts[0]] = parts[1]
+	}
+
+	if _, ok := env["DEPMAN_TEST_LEAKED"]; ok {
+		t.Errorf("Expected unrelated variables to be stripped")
+	}
+	if env["HOME"] != "/home/depman" {
+		t.Errorf("Expected HOME to be kept but got %q", env["HOME"])
+	}
+	if env["DEPMAN_TEST_INJECTED"] != "yes" {
+		t.Errorf("Expected managed variables to be injected but got %q", env["DEPMAN_TEST_INJECTED"])
+	}
+	if !strings.HasPrefix(env["PATH"], filepath.FromSlash("/opt/tool/bin")) {
+		t.Errorf("Expected PATH addition to be prepended but got %q", env["PATH"])
+	}
+}
+
+func TestWriteDotEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix path test")
+	}
+	t.Setenv("PATH", "/usr/bin")
+
+	variables := map[string]string{
+		"SIMPLE":   "value",
+		"SPACES":   "C:/Program Files/Tool",
+		"QUOTES":   `say "hi" it's`,
+		"SPECIAL":  `$HOME\bin #1`,
+		"NEWLINE":  "line1\nline2",
+		"EMPTY":    "",
+		"EQUALS":   "a=b",
+		"BACKTICK": "`cmd`",
+	}
+
+	m := NewManager()
+	for key, value := range variables {
+		m.AddVariable(key, value)
+	}
+	m.AddPath("/opt/tool/bin")
+
+	// An existing world-readable file is restricted when overwritten
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("OLD=1\n"), 0644); err != nil {
+		t.Fatalf("Failed to create .env file: %v", err)
+	}
+	if err := m.WriteDotEnv(path); err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat .env file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected .env file mode 0600 but got %v", info.Mode().Perm())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read .env file: %v", err)
+	}
+
+	if !strings.Contains(string(data), "SIMPLE=value\n") {
+		t.Errorf("Expected simple value to be unquoted but got:\n%s", data)
+	}
+
+	parsed := parseDotEnv(t, string(data))
+	for key, value := range variables {
+		if parsed[key] != value {
+			t.Errorf("Expected %s=%q but got %q", key, value, parsed[key])
+		}
+	}
+
+	if expected := "/opt/tool/bin:/usr/bin"; parsed["PATH"] != expected {
+		t.Errorf("Expected PATH=%q but got %q", expected, parsed["PATH"])
+	}
+}
+
+func TestDiffEnvironments(t *testing.T) {
+	sep := string(os.PathListSeparator)
+	before := []string{
+		"PATH=/usr/bin" + sep + "/bin" + sep + "/opt/old/bin",
+		"HOME=/home/depman",
+		"TOOL_MODE=default",
+		"STALE=1",
+	}
+
+	testCases := []struct {
+		name     string
+		after    []string
+		expected Diff
+	}{
+		{
+			name:     "Unchanged",
+			after:    before,
+			expected: Diff{},
+		},
+		{
+			name: "Added paths and variables",
+			after: []string{
+				"PATH=/opt/tool/bin" + sep + "/opt/other/bin" + sep + "/usr/bin" + sep + "/bin" + sep + "/opt/old/bin",
+				"HOME=/home/depman",
+				"TOOL_MODE=linux",
+				"TOOL_HOME=/opt/tool",
+				"JAVA_HOME=/opt/java",
+				"STALE=1",
+			},
+			expected: Diff{
+				AddedPaths: []string{"/opt/tool/bin", "/opt/other/bin"},
+				Added: []VariableChange{
+					{Key: "JAVA_HOME", New: "/opt/java"},
+					{Key: "TOOL_HOME", New: "/opt/tool"},
+				},
+				Changed: []VariableChange{{Key: "TOOL_MODE", Old: "default", New: "linux"}},
+			},
+		},
+		{
+			name: "Removed paths and variables",
+			after: []string{
+				"PATH=/usr/bin" + sep + "/bin",
+				"HOME=/home/depman",
+				"TOOL_MODE=default",
+			},
+			expected: Diff{
+				RemovedPaths: []string{"/opt/old/bin"},
+				Removed:      []VariableChange{{Key: "STALE", Old: "1"}},
+			},
+		},
+		{
+			name: "Duplicate path entry",
+			after: []string{
+				"PATH=/bin" + sep + "/usr/bin" + sep + "/bin" + sep + "/opt/old/bin",
+				"HOME=/home/depman",
+				"TOOL_MODE=default",
+				"STALE=1",
+			},
+			expected: Diff{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			diff := DiffEnvironments(before, tc.after)
+			if !reflect.DeepEqual(diff, tc.expected) {
+				t.Errorf("Expected diff %+v but got %+v", tc.expected, diff)
+			}
+			if empty := reflect.DeepEqual(tc.expected, Diff{}); diff.Empty() != empty {
+				t.Errorf("Expected Empty() to be %v but got %v", empty, diff.Empty())
+			}
+		})
+	}
+}
@@ -12,7 +12,8 @@ type Level int
 
 // Log levels
 const (
-	LevelDebug Level = iota
+	LevelTrace Level = iota - 1 // Below debug, logs every executed command
+	LevelDebug
 	LevelInfo
 	LevelWarn
 	LevelError
@@ -21,6 +22,8 @@ const (
 // String returns the string representation of the log level
 func (l Level) String() string {
 	switch l {
+	case LevelTrace:
+		return "TRACE"
 	case LevelDebug:
 		return "DEBUG"
 	case LevelInfo:
@@ -34,6 +37,9 @@ func (l Level) String() string {
 	}
 }
 
+// DefaultTimeFormat is the timestamp layout used when no format is configured
+const DefaultTimeFormat = "2006-01-02 15:04:05"
+
 // Options configures the logger
 type Options struct {
 	// Minimum level to log
@@ -45,13 +51,29 @@ type Options struct {
 	// Whether to show timestamps
 	ShowTimestamp bool
 
+	// Layout of timestamps, as accepted by time.Format (defaults to DefaultTimeFormat)
+	TimeFormat string
+
+	// Whether to show timestamps in UTC instead of local time
+	UTC bool
+
 	// Whether to show colors (if the output supports it)
 	ShowColors bool
+
+	// Path of a file to also write logs to (empty disables file logging)
+	FilePath string
+
+	// Maximum size of the log file in megabytes before it is rotated (0 disables rotation)
+	MaxSizeMB int
+
+	// Number of rotated log files to keep
+	MaxBackups int
 }
 
 // Logger provides logging functionality
 type Logger struct {
 	opts Options
+	file *rotatingWriter
 }
 
 // New creates a new logger with the given options
@@ -61,9 +83,15 @@ func New(opts Options) *Logger {
 		opts.Output = os.Stdout
 	}
 
-	return &Logger{
+	l := &Logger{
 		opts: opts,
 	}
+
+	if opts.FilePath != "" {
+		l.file = newRotatingWriter(opts.FilePath, opts.MaxSizeMB, opts.MaxBackups)
+	}
+
+	return l
 }
 
 // Default returns a default logger
@@ -86,13 +114,23 @@ func (l *Logger) log(level Level, format string, args ...interface{}) {
 	// Format timestamp
 	timestamp := ""
 	if l.opts.ShowTimestamp {
-		timestamp = time.Now().Format("2006-01-02 15:04:05") + " "
+		timestamp = l.timestamp(time.Now()) + " "
+	}
+
+	// Format message
+	message := fmt.Sprintf(format, args...)
+
+	// Write an uncolored entry to the log file
+	if l.file != nil {
+		fmt.Fprintf(l.file, "%s[%s] %s\n", timestamp, level.String(), message)
 	}
 
 	// Format level with optional colors
 	levelStr := level.String()
 	if l.opts.ShowColors {
 		switch level {
+		case LevelTrace:
+			levelStr = fmt.Sprintf("\033[90m%s\033[0m", levelStr) // Gray
 		case LevelDebug:
 			levelStr = fmt.Sprintf("\033[36m%s\033[0m", levelStr) // Cyan
 		case LevelInfo:
@@ -104,13 +142,28 @@ func (l *Logger) log(level Level, format string, args ...interface{}) {
 		}
 	}
 
-	// Format message
-	message := fmt.Sprintf(format, args...)
-
 	// Write log entry
 	fmt.Fprintf(l.opts.Output, "%s[%s] %s\n", timestamp, levelStr, message)
 }
 
+// timestamp formats the time of a log entry with the configured layout and zone
+func (l *Logger) timestamp(t time.Time) string {
+	if l.opts.UTC {
+		t = t.UTC()
+	}
+
+	format := l.opts.TimeFormat
+	if format == "" {
+		format = DefaultTimeFormat
+	}
+	return t.Format(format)
+}
+
+// Tracef logs a trace message
+func (l *Logger) Tracef(format string, args ...interface{}) {
+	l.log(LevelTrace, format, args...)
+}
+
 // Debugf logs a debug message
 func (l *Logger) Debugf(format string, args ...interface{}) {
 	l.log(LevelDebug, format, args...)
@@ -132,15 +185,34 @@ func (l *Logger) Errorf(format string, args ...interface{}) {
 }
 
 // WithLevel creates a new logger with the specified minimum level
+// The new logger shares the log file of the original
 func (l *Logger) WithLevel(level Level) *Logger {
 	opts := l.opts
 	opts.Level = level
-	return New(opts)
+	return &Logger{opts: opts, file: l.file}
 }
 
 // WithOutput creates a new logger with the specified output
+// The new logger shares the log file of the original
 func (l *Logger) WithOutput(output io.Writer) *Logger {
 	opts := l.opts
 	opts.Output = output
-	return New(opts)
+	return &Logger{opts: opts, file: l.file}
+}
+
+// WithTimeFormat creates a new logger with the specified timestamp layout, in UTC if requested
+// The new logger shares the log file of the original
+func (l *Logger) WithTimeFormat(format string, utc bool) *Logger {
+	opts := l.opts
+	opts.TimeFormat = format
+	opts.UTC = utc
+	return &Logger{opts: opts, file: l.file}
+}
+
+// Close closes the log file, if any
+func (l *Logger) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
 }
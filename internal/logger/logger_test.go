@@ -0,0 +1,155 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogFileRotation(t *testing.T) {
+	tempDir := t.TempDir()
+	logPath := filepath.Join(tempDir, "depman.log")
+
+	log := New(Options{
+		Level:      LevelInfo,
+		Output:     &bytes.Buffer{},
+		FilePath:   logPath,
+		MaxSizeMB:  1,
+		MaxBackups: 2,
+	})
+	defer log.Close()
+
+	// Write a little over 1MB of log entries
+	line := strings.Repeat("x", 1024)
+	for i := 0; i < 1100; i++ {
+		log.Infof("%s", line)
+	}
+
+	if _, err := os.Stat(logPath + ".1"); err != nil {
+		t.Fatalf("Expected rotated backup file but got: %v", err)
+	}
+
+	info, err := os.Stat(logPath)
+	if err != nil {
+		t.Fatalf("Expected active log file but got: %v", err)
+	}
+	if info.Size() > 1024*1024 {
+		t.Errorf("Expected active log file to be under the limit but got %d bytes", info.Size())
+	}
+
+	if _, err := os.Stat(logPath + ".3"); err == nil {
+		t.Errorf("Expected at most 2 backup files")
+	}
+}
+
+func TestLogFileWithoutColors(t *testing.T) {
+	tempDir := t.TempDir()
+	logPath := filepath.Join(tempDir, "depman.log")
+
+	var console bytes.Buffer
+	log := New(Options{
+		Level:      LevelInfo,
+		Output:     &console,
+		ShowColors: true,
+		FilePath:   logPath,
+	})
+
+	log.WithLevel(LevelDebug).Debugf("hello %s", "file")
+	log.Close()
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+
+	if string(data) != "[DEBUG] hello file\n" {
+		t.Errorf("Expected uncolored entry in log file but got %q", string(data))
+	}
+
+	if !strings.Contains(console.String(), "hello file") {
+		t.Errorf("Expected entry on console output but got %q", console.String())
+	}
+}
+
+func TestTimestampFormat(t *testing.T) {
+	testCases := []struct {
+		name       string
+		opts       Options
+		builder    func(l *Logger) *Logger
+		layout     string
+		expectUTC  bool
+		expectZone string
+	}{
+		{name: "Default layout", layout: DefaultTimeFormat},
+		{name: "RFC3339 in UTC", opts: Options{TimeFormat: time.RFC3339, UTC: true}, layout: time.RFC3339, expectUTC: true, expectZone: "Z"},
+		{name: "Custom layout", opts: Options{TimeFormat: "02/01/2006 15:04:05.000 MST", UTC: true}, layout: "02/01/2006 15:04:05.000 MST", expectUTC: true, expectZone: "UTC"},
+		{name: "Builder", builder: func(l *Logger) *Logger { return l.WithTimeFormat(time.RFC3339, true) }, layout: time.RFC3339, expectUTC: true, expectZone: "Z"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var output bytes.Buffer
+			tc.opts.Level = LevelInfo
+			tc.opts.Output = &output
+			tc.opts.ShowTimestamp = true
+			log := New(tc.opts)
+			if tc.builder != nil {
+				log = tc.builder(log)
+			}
+
+			before := time.Now()
+			log.Infof("hello")
+
+			timestamp, rest, found := strings.Cut(output.String(), " [INFO] ")
+			if !found || rest != "hello\n" {
+				t.Fatalf("Expected a timestamped entry but got %q", output.String())
+			}
+
+			location := time.Local
+			if tc.expectUTC {
+				location = time.UTC
+			}
+			parsed, err := time.ParseInLocation(tc.layout, timestamp, location)
+			if err != nil {
+				t.Fatalf("Expected timestamp %q to match layout %q: %v", timestamp, tc.layout, err)
+			}
+			if tc.expectZone != "" && !strings.HasSuffix(timestamp, tc.expectZone) {
+				t.Errorf("Expected timestamp %q in zone %s", timestamp, tc.expectZone)
+			}
+
+			// The timestamp is the time of the entry in the expected zone
+			if diff := parsed.Sub(before.Truncate(time.Second)); diff < 0 || diff > time.Minute {
+				t.Errorf("Expected timestamp %q close to %s", timestamp, before)
+			}
+		})
+	}
+}
+
+// TestTraceLevel tests that trace messages are only logged at the trace level
+func TestTraceLevel(t *testing.T) {
+	testCases := []struct {
+		name     string
+		level    Level
+		expected string
+	}{
+		{name: "Trace", level: LevelTrace, expected: "[TRACE] run [\"tool\"]\n[DEBUG] debug\n"},
+		{name: "Debug", level: LevelDebug, expected: "[DEBUG] debug\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var output bytes.Buffer
+			log := New(Options{Level: tc.level, Output: &output})
+
+			log.Tracef("run %q", []string{"tool"})
+			log.Debugf("debug")
+
+			if output.String() != tc.expected {
+				t.Errorf("Expected output %q but got %q", tc.expected, output.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// rotatingWriter writes to a file and rolls it over once it exceeds a size limit
+type rotatingWriter struct {
+	mu sync.Mutex
+
+	// Path of the active log file
+	path string
+
+	// Maximum size in bytes before rotating (0 disables rotation)
+	maxSize int64
+
+	// Number of rotated files to keep
+	maxBackups int
+
+	// Currently open file and its size
+	file *os.File
+	size int64
+}
+
+// newRotatingWriter creates a writer for the given path
+// The file is opened lazily on the first write
+func newRotatingWriter(path string, maxSizeMB, maxBackups int) *rotatingWriter {
+	return &rotatingWriter{
+		path:       path,
+		maxSize:    int64(maxSizeMB) * 1024 * 1024,
+		maxBackups: maxBackups,
+	}
+}
+
+// Write writes p to the log file, rotating first if the write would exceed the limit
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close closes the log file
+func (w *rotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// open opens the log file for appending and records its current size
+func (w *rotatingWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	w.file = file
+	w.size = info.Size()
+	return nil
+}
+
+// rotate shifts existing backups (path.1 -> path.2, ...) and starts a new file
+func (w *rotatingWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	w.file = nil
+
+	// Drop the oldest backup and shift the rest
+	os.Remove(w.backupPath(w.maxBackups))
+	for i := w.maxBackups - 1; i >= 1; i-- {
+		os.Rename(w.backupPath(i), w.backupPath(i+1))
+	}
+
+	if w.maxBackups > 0 {
+		if err := os.Rename(w.path, w.backupPath(1)); err != nil {
+			return fmt.Errorf("failed to rotate log file: %w", err)
+		}
+	} else {
+		os.Remove(w.path)
+	}
+
+	return w.open()
+}
+
+// backupPath returns the path of the n-th rotated file
+func (w *rotatingWriter) backupPath(n int) string {
+	return fmt.Sprintf("%s.%d", w.path, n)
+}
@@ -1,94 +1,657 @@
 package depman
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sobhit-avrl/depman-v1/internal/environment"
 )
 
 // EnsureDependencies checks and installs all dependencies if needed
 // This is the main function that most applications should use
-func (m *Manager) EnsureDependencies() (map[string]*DependencyStatus, error) {
+// Dependencies are installed after their prerequisites, ordered by Priority otherwise
+// The returned report summarizes what was done, even when an error occurs
+func (m *Manager) EnsureDependencies() (map[string]*DependencyStatus, *EnsureReport, error) {
+	return m.EnsureDependenciesContext(context.Background())
+}
+
+// EnsureDependenciesContext is EnsureDependencies, stopping when ctx is cancelled
+// A running install command is killed along with its children and no further dependencies
+// are started; the returned error then matches ctx.Err() with errors.Is
+func (m *Manager) EnsureDependenciesContext(ctx context.Context) (map[string]*DependencyStatus, *EnsureReport, error) {
+	start := time.Now()
+	report := newEnsureReport()
+	defer func() { report.Duration = time.Since(start) }()
+
+	// Keep other depman processes out of the install directories and state file
+	release, err := m.acquireRunLock(ctx)
+	if err != nil {
+		return nil, report, err
+	}
+	defer release()
+
 	// First check if dependencies are properly configured
 	if err := m.validateConfiguration(); err != nil {
-		return nil, fmt.Errorf("invalid dependency configuration: %w", err)
+		return nil, report, fmt.Errorf("invalid dependency configuration: %w", err)
 	}
 
-	// Check current status of all dependencies
-	statuses, err := m.CheckAllDependencies()
+	graph, err := m.BuildGraph()
 	if err != nil {
-		return statuses, err
+		return nil, report, fmt.Errorf("invalid dependency configuration: %w", err)
 	}
 
-	// Install or update dependencies as needed
-	for name, status := range statuses {
-		// Skip if already installed and compatible
-		if status.Installed && status.Compatible && status.RequiredUpdate == NoUpdate {
-			continue
+	// Check current status of all dependencies, trusting recently confirmed ones
+	state := m.loadState()
+	statuses, err := m.checkAllDependencies(ctx, state, 1)
+	if err != nil {
+		return statuses, report, err
+	}
+	defer func() { m.saveState(state, statuses) }()
+
+	// Install or update dependencies as needed, prerequisites first
+	// Failures are retried after the rest of the run if ensure retries are configured
+	attempts := make(map[string]*ensureAttempt)
+	pending := graph.TopologicalOrder()
+	for len(pending) > 0 {
+		var failed []string
+		failedSet := make(map[string]bool)
+
+		for i, name := range pending {
+			if err := ctx.Err(); err != nil {
+				report.recordHeldBack(slices.Concat(failed, pending[i:]), attempts, statuses)
+				return statuses, report, fmt.Errorf("ensure cancelled: %w", err)
+			}
+
+			status, ok := statuses[name]
+			if !ok {
+				continue
+			}
+
+			// Find the dependency definition
+			dep := m.findDependency(name)
+			if dep == nil {
+				return statuses, report, fmt.Errorf("dependency '%s' not found in configuration", name)
+			}
+
+			// Wait for prerequisites that failed in this pass
+			if dependsOnAny(graph.Edges[name], failedSet) {
+				failed = append(failed, name)
+				failedSet[name] = true
+				continue
+			}
+
+			a := attempts[name]
+			if a == nil {
+				a = &ensureAttempt{wasInstalled: status.Installed, acted: m.shouldInstall(dep, status)}
+				attempts[name] = a
+			}
+
+			depStart := time.Now()
+			updatedStatus, err := m.ensureDependency(ctx, dep, status)
+			a.elapsed += time.Since(depStart)
+			statuses[name] = updatedStatus
+
+			// A cancelled install or one needing manual action is not worth retrying
+			if err != nil && ctx.Err() == nil && !errors.Is(err, ErrManualAction) && a.retries < m.ensureRetries {
+				a.retries++
+				a.lastErr = err
+				m.logger.Warnf("Failed to ensure %s, will retry (%d/%d): %v", name, a.retries, m.ensureRetries, err)
+				failed = append(failed, name)
+				failedSet[name] = true
+				continue
+			}
+
+			report.record(name, a.wasInstalled, a.acted, updatedStatus, err, a.elapsed)
+			if err != nil {
+				report.recordHeldBack(slices.Concat(failed, pending[i+1:]), attempts, statuses)
+				return statuses, report, err
+			}
+		}
+
+		if len(failed) > 0 {
+			m.logger.Infof("Retrying %d failed dependencies in %s", len(failed), m.ensureRetryDelay)
+			if err := sleepContext(ctx, m.ensureRetryDelay); err != nil {
+				report.recordHeldBack(failed, attempts, statuses)
+				return statuses, report, fmt.Errorf("ensure cancelled: %w", err)
+			}
 		}
+		pending = failed
+	}
+
+	// Apply environment changes to the current process
+	m.applyEnvironment()
+
+	return statuses, report, nil
+}
+
+// EnsureDependenciesParallel installs dependencies concurrently using up to maxWorkers workers
+// A dependency is only scheduled once all of its Dependencies have been ensured, so
+// independent dependencies install simultaneously while the graph order is respected.
+// Failures are retried once other work settles if ensure retries are configured. The first
+// failure without retries left stops new work and is returned once in-flight work finishes.
+// Any custom Logger must be safe for concurrent use.
+func (m *Manager) EnsureDependenciesParallel(maxWorkers int) (map[string]*DependencyStatus, *EnsureReport, error) {
+	return m.EnsureDependenciesParallelContext(context.Background(), maxWorkers)
+}
 
-		// Find the dependency definition
-		var dep *Dependency
-		for i := range m.Config.Dependencies {
-			if m.Config.Dependencies[i].Name == name {
-				dep = &m.Config.Dependencies[i]
+// EnsureDependenciesParallelContext is EnsureDependenciesParallel, stopping when ctx is cancelled
+// Running install commands are killed, no new work is started, and the returned error matches
+// ctx.Err() with errors.Is once in-flight work has finished
+func (m *Manager) EnsureDependenciesParallelContext(ctx context.Context, maxWorkers int) (map[string]*DependencyStatus, *EnsureReport, error) {
+	start := time.Now()
+	report := newEnsureReport()
+	defer func() { report.Duration = time.Since(start) }()
+
+	if maxWorkers < 1 {
+		maxWorkers = 1
+	}
+
+	// Keep other depman processes out of the install directories and state file
+	release, err := m.acquireRunLock(ctx)
+	if err != nil {
+		return nil, report, err
+	}
+	defer release()
+
+	// First check if dependencies are properly configured
+	if err := m.validateConfiguration(); err != nil {
+		return nil, report, fmt.Errorf("invalid dependency configuration: %w", err)
+	}
+
+	graph, err := m.BuildGraph()
+	if err != nil {
+		return nil, report, fmt.Errorf("invalid dependency configuration: %w", err)
+	}
+
+	// Check current status of all dependencies, trusting recently confirmed ones
+	state := m.loadState()
+	// Checks fan out as wide as the check concurrency allows, independently of the workers
+	checkWorkers := maxWorkers
+	if m.checkConcurrency > 0 {
+		checkWorkers = m.checkConcurrency
+	}
+	statuses, err := m.checkAllDependencies(ctx, state, checkWorkers)
+	if err != nil {
+		return statuses, report, err
+	}
+	defer func() { m.saveState(state, statuses) }()
+
+	// Count unfinished prerequisites and record reverse edges
+	pending, dependents := graph.prerequisites()
+
+	var ready []string
+	for _, name := range graph.Nodes {
+		if pending[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	type result struct {
+		name    string
+		status  *DependencyStatus
+		err     error
+		elapsed time.Duration
+	}
+
+	results := make(chan result)
+	inFlight := 0
+	var firstErr error
+	attempts := make(map[string]*ensureAttempt)
+	var retry []string
+
+	for len(ready) > 0 || inFlight > 0 || len(retry) > 0 {
+		// Retry failed dependencies once everything else has settled
+		if len(ready) == 0 && inFlight == 0 {
+			if firstErr != nil {
+				break
+			}
+
+			m.logger.Infof("Retrying %d failed dependencies in %s", len(retry), m.ensureRetryDelay)
+			if err := sleepContext(ctx, m.ensureRetryDelay); err != nil {
+				firstErr = fmt.Errorf("ensure cancelled: %w", err)
 				break
 			}
+			ready, retry = retry, nil
 		}
 
-		if dep == nil {
-			return statuses, fmt.Errorf("dependency '%s' not found in configuration", name)
+		// Stop scheduling once cancelled; in-flight installs are killed through ctx
+		if err := ctx.Err(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("ensure cancelled: %w", err)
 		}
 
-		// Install or update the dependency
-		if err := m.installDependency(dep); err != nil {
-			status.Error = err
-			status.Installed = false
-			return statuses, err
+		// Schedule ready work unless a failure has occurred, lowest priority first
+		graph.sortByPriority(ready)
+		for firstErr == nil && len(ready) > 0 && inFlight < maxWorkers {
+			name := ready[0]
+			ready = ready[1:]
+
+			dep := m.findDependency(name)
+			status, ok := statuses[name]
+			if !ok {
+				// Disabled dependencies are not managed, so release their dependents right away
+				for _, dependent := range dependents[name] {
+					pending[dependent]--
+					if pending[dependent] == 0 {
+						ready = append(ready, dependent)
+					}
+				}
+				continue
+			}
+			inFlight++
+
+			if attempts[name] == nil {
+				attempts[name] = &ensureAttempt{wasInstalled: status.Installed, acted: m.shouldInstall(dep, status)}
+			}
+
+			go func() {
+				r := result{name: name}
+				depStart := time.Now()
+				r.status, r.err = m.ensureDependency(ctx, dep, status)
+				r.elapsed = time.Since(depStart)
+				results <- r
+			}()
 		}
 
-		// Set up environment for the dependency
-		if err := m.setupDependencyEnvironment(dep); err != nil {
-			m.logger.Warnf("Failed to set up environment for dependency %s: %v", dep.Name, err)
+		if inFlight == 0 {
+			break
+		}
+
+		// Wait for a dependency to finish
+		r := <-results
+		inFlight--
+		statuses[r.name] = r.status
+
+		a := attempts[r.name]
+		a.elapsed += r.elapsed
+
+		// Hold failures back for another attempt, keeping their dependents waiting
+		if r.err != nil && firstErr == nil && !errors.Is(r.err, ErrManualAction) && a.retries < m.ensureRetries {
+			a.retries++
+			a.lastErr = r.err
+			m.logger.Warnf("Failed to ensure %s, will retry (%d/%d): %v", r.name, a.retries, m.ensureRetries, r.err)
+			retry = append(retry, r.name)
+			continue
+		}
+
+		report.record(r.name, a.wasInstalled, a.acted, r.status, r.err, a.elapsed)
+
+		if r.err != nil {
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			continue
 		}
 
-		// Verify the installation worked
-		updatedStatus, err := m.CheckDependency(dep)
-		if err != nil {
-			return statuses, err
+		// Release dependents whose prerequisites are now all done
+		for _, dependent := range dependents[r.name] {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
 		}
+	}
 
-		// Update the status in our results
-		statuses[name] = updatedStatus
+	if firstErr != nil {
+		// Failures held back for a retry that will not happen count as failed
+		report.recordHeldBack(slices.Concat(retry, ready), attempts, statuses)
+		return statuses, report, firstErr
 	}
 
 	// Apply environment changes to the current process
-	if err := m.envManager.ApplyToCurrentProcess(); err != nil {
-		m.logger.Warnf("Failed to apply environment changes: %v", err)
+	m.applyEnvironment()
+
+	return statuses, report, nil
+}
+
+// ensureAttempt tracks a dependency across ensure retries
+type ensureAttempt struct {
+	wasInstalled bool          // Whether it was installed before the first attempt
+	acted        bool          // Whether the first attempt needed to install it
+	retries      int           // Retries used so far
+	elapsed      time.Duration // Time spent over all attempts
+	lastErr      error         // Error of the last attempt held back for a retry
+}
+
+// acquire takes a slot of the semaphore sem, waiting until one is free or ctx is cancelled,
+// and returns the function releasing it; a nil semaphore has no limit
+func acquire(ctx context.Context, sem chan struct{}) (func(), error) {
+	if sem == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// sleepContext waits for d, returning early with ctx.Err() if ctx is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// dependsOnAny reports whether any of the required dependencies is in the given names
+func dependsOnAny(required []string, names map[string]bool) bool {
+	for _, name := range required {
+		if names[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureDependency installs or updates a single dependency if its status requires it
+// It returns the status to record for the dependency
+func (m *Manager) ensureDependency(ctx context.Context, dep *Dependency, status *DependencyStatus) (*DependencyStatus, error) {
+	// Skip if skipped, or already installed and compatible (unless forced)
+	if !m.shouldInstall(dep, status) {
+		if !status.Skipped {
+			m.notifyStatus(dep.Name, PhaseVerified, status)
+		}
+		return status, nil
+	}
+
+	// Dependencies that are never installed can only be reported
+	if dep.InstallsManually() {
+		return m.reportManualAction(dep, status)
+	}
+
+	// Install or update the dependency
+	installOutput, err := m.installDependency(ctx, dep)
+	if err != nil {
+		status.Error = err
+		status.Installed = false
+		status.InstallOutput = installOutput
+		m.notifyStatus(dep.Name, PhaseFailed, status)
+
+		// Optional dependencies only warn on failure
+		if dep.Optional {
+			m.logger.Warnf("Failed to install optional dependency %s: %v", dep.Name, err)
+			return status, nil
+		}
+		return status, err
+	}
+
+	// Set up environment for the dependency
+	if err := m.setupDependencyEnvironment(dep); err != nil {
+		m.logger.Warnf("Failed to set up environment for dependency %s: %v", dep.Name, err)
+	}
+
+	// Verify the installation worked, retrying only while the dependency is not
+	// yet detected (a version mismatch will not fix itself by waiting)
+	m.notifyStatus(dep.Name, PhaseVerifying, status)
+	updatedStatus, err := m.verifyDependency(ctx, dep)
+	for attempt := 1; attempt <= m.verifyRetries && !updatedStatus.Installed; attempt++ {
+		m.logger.Infof("Dependency %s not detected yet, retrying verification in %s (attempt %d/%d)",
+			dep.Name, m.verifyDelay, attempt, m.verifyRetries)
+		if sleepContext(ctx, m.verifyDelay) != nil {
+			break
+		}
+		updatedStatus, err = m.verifyDependency(ctx, dep)
+	}
+	updatedStatus.InstallOutput = installOutput
+	updatedStatus.Optional = dep.Optional
+
+	// The install succeeded but didn't produce a version satisfying the constraint
+	if err == nil && updatedStatus.Installed && !updatedStatus.Compatible && dep.Version.Constraint != "" {
+		err = &ConstraintViolatedError{Dependency: dep.Name, Version: updatedStatus.CurrentVersion, Constraint: dep.Version.Constraint}
+		updatedStatus.Error = err
 	}
 
-	return statuses, nil
+	// The install succeeded but left an older version than required behind
+	if err == nil && updatedStatus.Installed && updatedStatus.RequiredUpdate != NoUpdate {
+		err = &VersionMismatchError{Dependency: dep.Name, Version: updatedStatus.CurrentVersion, Required: m.requiredVersion(dep)}
+		updatedStatus.Error = err
+	}
+
+	// The install succeeded but verifying it found a problem, e.g. no version in strict mode
+	if err == nil && updatedStatus.Installed && (updatedStatus.Error != nil || !updatedStatus.Compatible) {
+		err = updatedStatus.Error
+		if err == nil {
+			err = fmt.Errorf("dependency '%s' is not compatible after install", dep.Name)
+			updatedStatus.Error = err
+		}
+	}
+	if err != nil {
+		m.notifyStatus(dep.Name, PhaseFailed, updatedStatus)
+	} else {
+		m.notifyStatus(dep.Name, PhaseVerified, updatedStatus)
+		m.auditInstall(dep, status, updatedStatus)
+	}
+	if err != nil && dep.Optional {
+		m.logger.Warnf("Optional dependency %s failed verification after install: %v", dep.Name, err)
+		return updatedStatus, nil
+	}
+
+	return updatedStatus, err
+}
+
+// shouldInstall reports whether ensure must install a dependency: when it needs installing
+// or updating, or for any dependency that isn't skipped when reinstalls are forced
+// Dependencies installed manually are never reinstalled, whatever the force setting
+func (m *Manager) shouldInstall(dep *Dependency, status *DependencyStatus) bool {
+	if status.Skipped {
+		return false
+	}
+	return (m.forceReinstall && !dep.InstallsManually()) || needsInstall(status)
+}
+
+// reportManualAction reports a check-only or manual dependency that is missing or outdated,
+// which ensure can't fix itself
+func (m *Manager) reportManualAction(dep *Dependency, status *DependencyStatus) (*DependencyStatus, error) {
+	reason := "not installed"
+	switch {
+	case status.Installed && !status.Compatible:
+		reason = fmt.Sprintf("at version %s, which does not satisfy %s", status.CurrentVersion, dep.Version.Constraint)
+	case status.Installed:
+		reason = fmt.Sprintf("at version %s but %s is required", status.CurrentVersion, m.requiredVersion(dep))
+	}
+
+	err := &ManualActionError{Dependency: dep.Name, Mode: dep.InstallMode, Reason: reason}
+	status.ManualAction = true
+	status.Error = err
+	m.notifyStatus(dep.Name, PhaseFailed, status)
+
+	// Optional dependencies only warn
+	if dep.Optional {
+		m.logger.Warnf("%v", err)
+		return status, nil
+	}
+	return status, err
+}
+
+// auditInstall records a successful install in the audit log
+func (m *Manager) auditInstall(dep *Dependency, before, after *DependencyStatus) {
+	entry := AuditEntry{
+		Action:     AuditInstall,
+		Dependency: dep.Name,
+		NewVersion: after.CurrentVersion,
+		Checksum:   m.recordedChecksum(dep.Name),
+	}
+	if before.Installed {
+		entry.OldVersion = before.CurrentVersion
+	}
+	if platformConfig, err := m.GetPlatformConfig(dep); err == nil {
+		entry.Command = platformConfig.Commands.Install
+	}
+	m.audit(entry)
+}
+
+// notifyStatus reports a dependency entering a phase to the status callback, if any
+func (m *Manager) notifyStatus(name string, phase Phase, status *DependencyStatus) {
+	if m.statusCallback != nil {
+		m.statusCallback(name, phase, status)
+	}
+}
+
+// findDependency returns the dependency definition with the given name, or nil
+func (m *Manager) findDependency(name string) *Dependency {
+	for i := range m.Config.Dependencies {
+		if m.Config.Dependencies[i].Name == name {
+			return &m.Config.Dependencies[i]
+		}
+	}
+	return nil
 }
 
 // Add a method to get the updated environment
 func (m *Manager) GetUpdatedEnvironment() []string {
+	m.envMu.Lock()
+	defer m.envMu.Unlock()
 	return m.envManager.GetUpdatedEnvironment()
 }
 
+// EnvironmentChanges returns how the managed environment configured so far, e.g. by ensuring
+// dependencies, changes the process environment as it was before depman applied it
+func (m *Manager) EnvironmentChanges() EnvironmentDiff {
+	m.envMu.Lock()
+	defer m.envMu.Unlock()
+
+	original := m.originalEnv
+	if original == nil {
+		original = os.Environ()
+	}
+	return environment.DiffEnvironments(original, m.envManager.UpdateEnvironment(original))
+}
+
+// PlanEnvironment returns how the environment of all managed dependencies would change the
+// process environment, without installing or verifying anything
+func (m *Manager) PlanEnvironment() (EnvironmentDiff, error) {
+	if err := m.setupAllEnvironments(); err != nil {
+		return EnvironmentDiff{}, err
+	}
+	return m.EnvironmentChanges(), nil
+}
+
+// ExportDotEnv writes the environment of all managed dependencies to a .env file
+func (m *Manager) ExportDotEnv(path string) error {
+	if err := m.setupAllEnvironments(); err != nil {
+		return err
+	}
+
+	m.envMu.Lock()
+	defer m.envMu.Unlock()
+	return m.envManager.WriteDotEnv(path)
+}
+
+// setupAllEnvironments adds the environment of every managed dependency, installed or not
+func (m *Manager) setupAllEnvironments() error {
+	if err := m.validateConfiguration(); err != nil {
+		return fmt.Errorf("invalid dependency configuration: %w", err)
+	}
+
+	for i := range m.Config.Dependencies {
+		dep := &m.Config.Dependencies[i]
+		if m.isSkipped(dep) {
+			continue
+		}
+
+		if err := m.setupDependencyEnvironment(dep); err != nil {
+			return fmt.Errorf("failed to set up environment for dependency %s: %w", dep.Name, err)
+		}
+	}
+	return nil
+}
+
 // CheckAllDependencies checks the status of all dependencies without installing
 // Use this to inspect what would be installed/updated
 func (m *Manager) CheckAllDependencies() (map[string]*DependencyStatus, error) {
+	return m.checkAllDependencies(context.Background(), nil, 1)
+}
+
+// checkAllDependencies checks all dependencies, verifying up to workers at a time and skipping
+// verification of those confirmed in the state within the state TTL (if a state is given)
+func (m *Manager) checkAllDependencies(ctx context.Context, state *State, workers int) (map[string]*DependencyStatus, error) {
 	results := make(map[string]*DependencyStatus)
 
 	// Validate dependencies configuration
-	errors := m.validateDependencies()
-	if len(errors) > 0 {
-		return nil, fmt.Errorf("dependency configuration errors: %v", errors)
+	if errors := m.validateDependencies(); len(errors) > 0 {
+		return nil, &ValidationError{errs: errors}
 	}
 
-	// Check each dependency
+	// Dependencies tracking the latest version need it resolved before they can be checked
+	if err := m.resolveLatestVersions(); err != nil {
+		return nil, err
+	}
+
+	// Check each dependency, leaving out disabled ones entirely
+	var toCheck []*Dependency
 	for _, dep := range m.Config.Dependencies {
-		status, _ := m.CheckDependency(&dep) // We still want to return status even if there's an error
-		results[dep.Name] = status
+		if !dep.IsEnabled() {
+			m.logger.Debugf("Dependency %s is disabled", dep.Name)
+			continue
+		}
+
+		if m.isSkipped(&dep) {
+			m.logger.Infof("Skipping dependency: %s", dep.Name)
+			results[dep.Name] = &DependencyStatus{Name: dep.Name, Skipped: true, Optional: dep.Optional}
+			m.notifyStatus(dep.Name, PhaseSkipped, results[dep.Name])
+			continue
+		}
+
+		if dep.Deprecated != "" {
+			m.logger.Warnf("Dependency %s is deprecated: %s", dep.Name, dep.Deprecated)
+		}
+
+		if state != nil {
+			if entry, ok := state.lookup(&dep, m.requiredVersion(&dep), m.stateTTL, time.Now()); ok {
+				m.logger.Infof("Dependency %s was confirmed at version %s on %s, skipping verification",
+					dep.Name, entry.Version, entry.ConfirmedAt.Format(time.RFC3339))
+				results[dep.Name] = &DependencyStatus{
+					Name:               dep.Name,
+					Installed:          true,
+					CurrentVersion:     entry.Version,
+					Compatible:         true,
+					Optional:           dep.Optional,
+					Cached:             true,
+					Deprecated:         dep.Deprecated != "",
+					DeprecationMessage: dep.Deprecated,
+				}
+				continue
+			}
+		}
+
+		toCheck = append(toCheck, &dep)
+	}
+
+	// Verify the remaining dependencies, up to workers at a time
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, max(workers, 1))
+	for _, dep := range toCheck {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			m.notifyStatus(dep.Name, PhaseChecking, &DependencyStatus{Name: dep.Name, Optional: dep.Optional})
+			status, _ := m.verifyDependency(ctx, dep) // We still want to return status even if there's an error
+			status.Optional = dep.Optional
+
+			mu.Lock()
+			results[dep.Name] = status
+			mu.Unlock()
+		}()
 	}
+	wg.Wait()
 
 	return results, nil
 }
@@ -101,9 +664,8 @@ func (m *Manager) validateConfiguration() error {
 	}
 
 	// Validate dependencies
-	errors := m.validateDependencies()
-	if len(errors) > 0 {
-		return fmt.Errorf("dependency validation errors: %v", errors)
+	if errors := m.validateDependencies(); len(errors) > 0 {
+		return &ValidationError{errs: errors}
 	}
 
 	return nil
@@ -0,0 +1,1469 @@
+package depman
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sobhit-avrl/depman-v1/internal/environment"
+)
+
+// newScriptDependency creates a dependency whose install appends start/end markers to a log
+// and whose verify succeeds only after install has run
+func newScriptDependency(dir, name string, deps []string, installScript string) Dependency {
+	logPath := filepath.Join(dir, "install.log")
+	marker := filepath.Join(dir, name+".installed")
+
+	if installScript == "" {
+		installScript = fmt.Sprintf("echo start %[1]s >> %[2]s; sleep 0.2; echo end %[1]s >> %[2]s; touch %[3]s",
+			name, logPath, marker)
+	}
+
+	return Dependency{
+		Name:         name,
+		Version:      Version{Required: "1.0.0"},
+		Dependencies: deps,
+		Platforms: map[string]PlatformConfig{
+			runtime.GOOS: {
+				Commands: Commands{
+					Install: []string{"sh", "-c", installScript},
+					Verify:  []string{"sh", "-c", fmt.Sprintf("test -f %s && echo 1.0.0", marker)},
+				},
+			},
+		},
+	}
+}
+
+// TestEnsureDependenciesParallel tests scheduling of a diamond-shaped graph
+func TestEnsureDependenciesParallel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	t.Run("Diamond graph ordering and concurrency", func(t *testing.T) {
+		dir := t.TempDir()
+
+		// app depends on left and right, which both depend on base
+		manager := &Manager{
+			Config: &DependencyConfig{
+				Dependencies: []Dependency{
+					newScriptDependency(dir, "app", []string{"left", "right"}, ""),
+					newScriptDependency(dir, "left", []string{"base"}, ""),
+					newScriptDependency(dir, "right", []string{"base"}, ""),
+					newScriptDependency(dir, "base", nil, ""),
+				},
+			},
+			Platform:   runtime.GOOS,
+			logger:     &mockLogger{},
+			envManager: environment.NewManager(),
+		}
+
+		statuses, _, err := manager.EnsureDependenciesParallel(4)
+		if err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+
+		for name, status := range statuses {
+			if !status.Installed {
+				t.Errorf("Expected %s to be installed", name)
+			}
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, "install.log"))
+		if err != nil {
+			t.Fatalf("Failed to read install log: %v", err)
+		}
+
+		events := strings.Split(strings.TrimSpace(string(data)), "\n")
+		position := make(map[string]int)
+		for i, event := range events {
+			position[event] = i
+		}
+
+		// Prerequisites must finish before dependents start
+		before := [][2]string{
+			{"end base", "start left"},
+			{"end base", "start right"},
+			{"end left", "start app"},
+			{"end right", "start app"},
+		}
+		for _, pair := range before {
+			if position[pair[0]] > position[pair[1]] {
+				t.Errorf("Expected '%s' before '%s' but got order: %v", pair[0], pair[1], events)
+			}
+		}
+
+		// Independent dependencies must overlap
+		if position["start left"] > position["end right"] || position["start right"] > position["end left"] {
+			t.Errorf("Expected left and right to install concurrently but got order: %v", events)
+		}
+	})
+
+	t.Run("Failure stops scheduling", func(t *testing.T) {
+		dir := t.TempDir()
+
+		manager := &Manager{
+			Config: &DependencyConfig{
+				Dependencies: []Dependency{
+					newScriptDependency(dir, "app", []string{"left", "right"}, ""),
+					newScriptDependency(dir, "left", []string{"base"}, ""),
+					newScriptDependency(dir, "right", []string{"base"}, ""),
+					newScriptDependency(dir, "base", nil, "echo broken; exit 1"),
+				},
+			},
+			Platform:   runtime.GOOS,
+			logger:     &mockLogger{},
+			envManager: environment.NewManager(),
+		}
+
+		statuses, _, err := manager.EnsureDependenciesParallel(4)
+		if err == nil {
+			t.Fatalf("Expected an error but got none")
+		}
+
+		if statuses["base"].Error == nil {
+			t.Errorf("Expected base status to record the error")
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, "install.log")); err == nil {
+			t.Errorf("Expected no dependents to be installed after failure")
+		}
+	})
+}
+
+// TestOptionalAndSkippedDependencies tests that optional failures don't abort the run while required ones do
+func TestOptionalAndSkippedDependencies(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	t.Run("Optional failure does not abort", func(t *testing.T) {
+		dir := t.TempDir()
+
+		optional := newScriptDependency(dir, "extra", nil, "echo broken; exit 1")
+		optional.Optional = true
+
+		manager := &Manager{
+			Config: &DependencyConfig{
+				Dependencies: []Dependency{
+					optional,
+					newScriptDependency(dir, "core", nil, ""),
+				},
+			},
+			Platform:   runtime.GOOS,
+			logger:     &mockLogger{},
+			envManager: environment.NewManager(),
+		}
+
+		statuses, _, err := manager.EnsureDependencies()
+		if err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+
+		if statuses["extra"].Error == nil || statuses["extra"].Installed {
+			t.Errorf("Expected optional dependency to record its failure")
+		}
+
+		if !statuses["core"].Installed {
+			t.Errorf("Expected required dependency to be installed")
+		}
+	})
+
+	t.Run("Required failure aborts", func(t *testing.T) {
+		dir := t.TempDir()
+
+		manager := &Manager{
+			Config: &DependencyConfig{
+				Dependencies: []Dependency{
+					newScriptDependency(dir, "core", nil, "echo broken; exit 1"),
+				},
+			},
+			Platform:   runtime.GOOS,
+			logger:     &mockLogger{},
+			envManager: environment.NewManager(),
+		}
+
+		if _, _, err := manager.EnsureDependencies(); err == nil {
+			t.Errorf("Expected an error but got none")
+		}
+	})
+
+	t.Run("Skipped and platform-less optional dependencies", func(t *testing.T) {
+		dir := t.TempDir()
+
+		// An optional dependency with no configuration for this platform
+		otherPlatform := Dependency{
+			Name:     "windows-only",
+			Optional: true,
+			Platforms: map[string]PlatformConfig{
+				"plan9": {},
+			},
+		}
+
+		manager := &Manager{
+			Config: &DependencyConfig{
+				Dependencies: []Dependency{
+					newScriptDependency(dir, "broken", nil, "echo broken; exit 1"),
+					otherPlatform,
+					newScriptDependency(dir, "core", nil, ""),
+				},
+			},
+			Platform:   runtime.GOOS,
+			logger:     &mockLogger{},
+			envManager: environment.NewManager(),
+		}
+		WithSkip("broken")(manager)
+
+		statuses, _, err := manager.EnsureDependencies()
+		if err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+
+		for _, name := range []string{"broken", "windows-only"} {
+			if !statuses[name].Skipped {
+				t.Errorf("Expected %s to be skipped", name)
+			}
+		}
+
+		if !statuses["core"].Installed {
+			t.Errorf("Expected core to be installed")
+		}
+	})
+}
+
+// TestVerifyRetries tests retrying post-install verification
+func TestVerifyRetries(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	// newFlakyManager creates a manager whose verify fails before install and on
+	// the first attempt after install, then succeeds reporting the given version
+	newFlakyManager := func(dir, version string) *Manager {
+		installed := filepath.Join(dir, "installed")
+		counter := filepath.Join(dir, "attempts")
+		verify := fmt.Sprintf(`test -f %[1]s || exit 1; echo x >> %[2]s; test $(wc -l < %[2]s) -ge 2 || exit 1; echo %[3]s`,
+			installed, counter, version)
+
+		return &Manager{
+			Config: &DependencyConfig{
+				Dependencies: []Dependency{
+					{
+						Name:    "flaky",
+						Version: Version{Required: "1.0.0"},
+						Platforms: map[string]PlatformConfig{
+							runtime.GOOS: {
+								Commands: Commands{
+									Install: []string{"touch", installed},
+									Verify:  []string{"sh", "-c", verify},
+								},
+							},
+						},
+					},
+				},
+			},
+			Platform:   runtime.GOOS,
+			logger:     &mockLogger{},
+			envManager: environment.NewManager(),
+		}
+	}
+
+	t.Run("Succeeds on second attempt", func(t *testing.T) {
+		manager := newFlakyManager(t.TempDir(), "1.0.0")
+		WithVerifyRetries(2, 10*time.Millisecond)(manager)
+
+		statuses, _, err := manager.EnsureDependencies()
+		if err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+		if !statuses["flaky"].Installed {
+			t.Errorf("Expected dependency to be installed after retry")
+		}
+	})
+
+	t.Run("Fails without retries", func(t *testing.T) {
+		manager := newFlakyManager(t.TempDir(), "1.0.0")
+
+		if _, _, err := manager.EnsureDependencies(); err == nil {
+			t.Errorf("Expected an error but got none")
+		}
+	})
+
+	t.Run("No retry on outdated version", func(t *testing.T) {
+		dir := t.TempDir()
+		manager := newFlakyManager(dir, "0.9.0")
+		WithVerifyRetries(3, 10*time.Millisecond)(manager)
+
+		// Make the first post-install attempt succeed so only the version is wrong
+		if err := os.WriteFile(filepath.Join(dir, "attempts"), []byte("x\n"), 0644); err != nil {
+			t.Fatalf("Failed to seed attempts file: %v", err)
+		}
+
+		statuses, _, err := manager.EnsureDependencies()
+		if !errors.Is(err, ErrVersionMismatch) {
+			t.Fatalf("Expected a version mismatch error but got: %v", err)
+		}
+		if statuses["flaky"].RequiredUpdate == NoUpdate {
+			t.Errorf("Expected an outdated version to be reported")
+		}
+
+		data, _ := os.ReadFile(filepath.Join(dir, "attempts"))
+		if attempts := strings.Count(string(data), "x") - 1; attempts != 1 {
+			t.Errorf("Expected 1 verification attempt after install but got %d", attempts)
+		}
+	})
+}
+
+// TestEnsureReport tests the summary report returned from EnsureDependencies
+func TestEnsureReport(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	dir := t.TempDir()
+
+	optional := newScriptDependency(dir, "extra", nil, "exit 1")
+	optional.Optional = true
+
+	upToDate := newScriptDependency(dir, "current", nil, "")
+	upToDate.Platforms[runtime.GOOS].Commands.Verify[2] = "echo 1.0.0"
+
+	// Reports 0.9.0 until installed, then 1.0.0
+	outdatedMarker := filepath.Join(dir, "outdated.installed")
+	outdated := newScriptDependency(dir, "outdated", nil, "")
+	outdated.Platforms[runtime.GOOS].Commands.Verify[2] = fmt.Sprintf("test -f %s && echo 1.0.0 || echo 0.9.0", outdatedMarker)
+
+	manager := &Manager{
+		Config: &DependencyConfig{
+			Dependencies: []Dependency{
+				newScriptDependency(dir, "core", nil, ""),
+				optional,
+				upToDate,
+				outdated,
+				newScriptDependency(dir, "ignored", nil, ""),
+			},
+		},
+		Platform:   runtime.GOOS,
+		logger:     &mockLogger{},
+		envManager: environment.NewManager(),
+	}
+	WithSkip("ignored")(manager)
+
+	_, report, err := manager.EnsureDependencies()
+	if err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+
+	expected := EnsureReport{Installed: 1, Updated: 1, Failed: 1, Skipped: 1, UpToDate: 1}
+	if report.Installed != expected.Installed || report.Updated != expected.Updated ||
+		report.Failed != expected.Failed || report.Skipped != expected.Skipped || report.UpToDate != expected.UpToDate {
+		t.Errorf("Expected counts %+v but got %+v", expected, *report)
+	}
+
+	if report.Duration < 0 {
+		t.Errorf("Expected non-negative duration but got %s", report.Duration)
+	}
+
+	if len(report.Timings) != 3 {
+		t.Errorf("Expected timings for 3 dependencies but got %v", report.Timings)
+	}
+	for name, d := range report.Timings {
+		if d < 0 {
+			t.Errorf("Expected non-negative timing for %s but got %s", name, d)
+		}
+	}
+}
+
+// TestDisabledDependencies tests that disabled dependencies are left out of check and ensure
+func TestDisabledDependencies(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	disabled := false
+	newManager := func(dir string) *Manager {
+		legacy := newScriptDependency(dir, "legacy", nil, "echo should not run; exit 1")
+		legacy.Enabled = &disabled
+
+		return &Manager{
+			Config: &DependencyConfig{
+				Dependencies: []Dependency{
+					legacy,
+					newScriptDependency(dir, "core", []string{"legacy"}, ""),
+				},
+			},
+			Platform:   runtime.GOOS,
+			logger:     &mockLogger{},
+			envManager: environment.NewManager(),
+		}
+	}
+
+	t.Run("Excluded from statuses", func(t *testing.T) {
+		statuses, err := newManager(t.TempDir()).CheckAllDependencies()
+		if err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+
+		if _, ok := statuses["legacy"]; ok {
+			t.Errorf("Expected disabled dependency to be excluded from statuses")
+		}
+		if _, ok := statuses["core"]; !ok {
+			t.Errorf("Expected enabled dependency to be checked")
+		}
+	})
+
+	t.Run("Not installed by ensure", func(t *testing.T) {
+		statuses, _, err := newManager(t.TempDir()).EnsureDependencies()
+		if err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+		if _, ok := statuses["legacy"]; ok || !statuses["core"].Installed {
+			t.Errorf("Expected only core to be ensured but got %v", statuses)
+		}
+	})
+
+	t.Run("Not installed by parallel ensure", func(t *testing.T) {
+		statuses, _, err := newManager(t.TempDir()).EnsureDependenciesParallel(2)
+		if err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+		if _, ok := statuses["legacy"]; ok || statuses["core"] == nil || !statuses["core"].Installed {
+			t.Errorf("Expected only core to be ensured but got %v", statuses)
+		}
+	})
+
+	t.Run("Still listed in configuration", func(t *testing.T) {
+		manager := newManager(t.TempDir())
+		if dep := manager.findDependency("legacy"); dep == nil || dep.IsEnabled() {
+			t.Errorf("Expected disabled dependency to remain in the configuration")
+		}
+		if dep := manager.findDependency("core"); dep == nil || !dep.IsEnabled() {
+			t.Errorf("Expected dependency without enabled field to be enabled")
+		}
+	})
+}
+
+// TestEnsureRetries tests retrying dependencies that fail their first install
+func TestEnsureRetries(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	newManager := func(dir string, retries int) *Manager {
+		// Fails the first time, then installs
+		attempted := filepath.Join(dir, "flaky.attempted")
+		flaky := newScriptDependency(dir, "flaky", nil, fmt.Sprintf(
+			"if [ -f %[1]s ]; then touch %[2]s; else touch %[1]s; exit 1; fi",
+			attempted, filepath.Join(dir, "flaky.installed")))
+
+		manager := &Manager{
+			Config: &DependencyConfig{
+				Dependencies: []Dependency{
+					newScriptDependency(dir, "core", nil, ""),
+					flaky,
+					newScriptDependency(dir, "app", []string{"flaky"}, ""),
+				},
+			},
+			Platform:   runtime.GOOS,
+			logger:     &mockLogger{},
+			envManager: environment.NewManager(),
+		}
+		WithEnsureRetries(retries, 10*time.Millisecond)(manager)
+		return manager
+	}
+
+	ensures := map[string]func(*Manager) (map[string]*DependencyStatus, *EnsureReport, error){
+		"Sequential": (*Manager).EnsureDependencies,
+		"Parallel": func(m *Manager) (map[string]*DependencyStatus, *EnsureReport, error) {
+			return m.EnsureDependenciesParallel(2)
+		},
+	}
+
+	for name, ensure := range ensures {
+		t.Run(name+" succeeds on retry", func(t *testing.T) {
+			dir := t.TempDir()
+
+			statuses, report, err := ensure(newManager(dir, 1))
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			for _, dep := range []string{"core", "flaky", "app"} {
+				if !statuses[dep].Installed {
+					t.Errorf("Expected %s to be installed", dep)
+				}
+			}
+
+			if report.Installed != 3 || report.Failed != 0 {
+				t.Errorf("Expected 3 installed and no failures but got %+v", *report)
+			}
+
+			// Successful dependencies must not be reinstalled
+			data, err := os.ReadFile(filepath.Join(dir, "install.log"))
+			if err != nil {
+				t.Fatalf("Failed to read install log: %v", err)
+			}
+			if count := strings.Count(string(data), "start core"); count != 1 {
+				t.Errorf("Expected core to be installed once but got %d installs", count)
+			}
+		})
+
+		t.Run(name+" fails without retries", func(t *testing.T) {
+			statuses, report, err := ensure(newManager(t.TempDir(), 0))
+			if err == nil {
+				t.Fatalf("Expected an error but got none")
+			}
+
+			if statuses["app"].Installed {
+				t.Errorf("Expected app not to be installed after its prerequisite failed")
+			}
+			if report.Failed != 1 {
+				t.Errorf("Expected 1 failure but got %+v", *report)
+			}
+		})
+	}
+
+	// A failure without retries left stops the run while another is still held back
+	heldBackEnsures := map[string]func(*Manager) (map[string]*DependencyStatus, *EnsureReport, error){
+		"Sequential": (*Manager).EnsureDependencies,
+		"Parallel": func(m *Manager) (map[string]*DependencyStatus, *EnsureReport, error) {
+			return m.EnsureDependenciesParallel(1)
+		},
+	}
+	for name, ensure := range heldBackEnsures {
+		t.Run(name+" counts held back failures", func(t *testing.T) {
+			dir := t.TempDir()
+			manager := &Manager{
+				Config: &DependencyConfig{
+					Dependencies: []Dependency{
+						newScriptDependency(dir, "broken", nil, "exit 1"),
+						newScriptDependency(dir, "also-broken", nil, "exit 1"),
+					},
+				},
+				Platform:   runtime.GOOS,
+				logger:     &mockLogger{},
+				envManager: environment.NewManager(),
+			}
+			WithEnsureRetries(1, 0)(manager)
+
+			_, report, err := ensure(manager)
+			if err == nil {
+				t.Fatalf("Expected an error but got none")
+			}
+			if report.Failed != 2 {
+				t.Errorf("Expected 2 failures but got %+v", *report)
+			}
+		})
+	}
+}
+
+// TestTagFiltering tests selecting dependencies by tag
+func TestTagFiltering(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	testCases := []struct {
+		name        string
+		tags        []string
+		excludeTags []string
+		expected    []string
+	}{
+		{name: "No filters", expected: []string{"compiler", "node", "pytest", "untagged"}},
+		{name: "Include one tag", tags: []string{"build"}, expected: []string{"compiler", "node"}},
+		{name: "Include tags are OR-combined", tags: []string{"runtime", "test"}, expected: []string{"node", "pytest"}},
+		{name: "Exclude tag", excludeTags: []string{"build"}, expected: []string{"pytest", "untagged"}},
+		{name: "Include and exclude", tags: []string{"build"}, excludeTags: []string{"runtime"}, expected: []string{"compiler"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			compiler := newScriptDependency(dir, "compiler", nil, "")
+			compiler.Tags = []string{"build"}
+			node := newScriptDependency(dir, "node", nil, "")
+			node.Tags = []string{"build", "runtime"}
+			pytest := newScriptDependency(dir, "pytest", nil, "")
+			pytest.Tags = []string{"test"}
+
+			manager := &Manager{
+				Config: &DependencyConfig{
+					Dependencies: []Dependency{compiler, node, pytest, newScriptDependency(dir, "untagged", nil, "")},
+				},
+				Platform:   runtime.GOOS,
+				logger:     &mockLogger{},
+				envManager: environment.NewManager(),
+			}
+			WithTags(tc.tags...)(manager)
+			WithExcludeTags(tc.excludeTags...)(manager)
+
+			statuses, _, err := manager.EnsureDependencies()
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			var ensured []string
+			for _, dep := range manager.Config.Dependencies {
+				if status := statuses[dep.Name]; status != nil && !status.Skipped && status.Installed {
+					ensured = append(ensured, dep.Name)
+				}
+			}
+
+			if strings.Join(ensured, ",") != strings.Join(tc.expected, ",") {
+				t.Errorf("Expected %v to be ensured but got %v", tc.expected, ensured)
+			}
+		})
+	}
+}
+
+// TestProvides tests a prerequisite met by a dependency that provides it
+func TestProvides(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	dir := t.TempDir()
+	compose := newScriptDependency(dir, "compose", []string{"docker"}, "")
+	podman := newScriptDependency(dir, "podman", nil, "")
+	podman.Provides = []string{"docker"}
+
+	manager := &Manager{
+		Config: &DependencyConfig{
+			Dependencies: []Dependency{compose, podman},
+		},
+		Platform:   runtime.GOOS,
+		logger:     &mockLogger{},
+		envManager: environment.NewManager(),
+	}
+
+	if errors := manager.validateDependencies(); len(errors) > 0 {
+		t.Fatalf("Expected no validation errors but got: %v", errors)
+	}
+
+	statuses, _, err := manager.EnsureDependencies()
+	if err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+	if !statuses["compose"].Installed || !statuses["podman"].Installed {
+		t.Errorf("Expected both dependencies to be installed but got %v", statuses)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "install.log"))
+	if err != nil {
+		t.Fatalf("Failed to read install log: %v", err)
+	}
+	if log := string(data); strings.Index(log, "end podman") > strings.Index(log, "start compose") {
+		t.Errorf("Expected podman to be installed before compose but got:\n%s", log)
+	}
+}
+
+// TestStatusCallback tests the phases reported while ensuring dependencies
+func TestStatusCallback(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("artifact"))
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name     string
+		url      string
+		install  string
+		skip     bool
+		expected []Phase
+	}{
+		{
+			name:     "Download and install",
+			url:      server.URL + "/tool.tar.gz",
+			expected: []Phase{PhaseChecking, PhaseDownloading, PhaseInstalling, PhaseVerifying, PhaseVerified},
+		},
+		{
+			name:     "Install without download",
+			expected: []Phase{PhaseChecking, PhaseInstalling, PhaseVerifying, PhaseVerified},
+		},
+		{
+			name:     "Install fails",
+			install:  "exit 1",
+			expected: []Phase{PhaseChecking, PhaseInstalling, PhaseFailed},
+		},
+		{
+			name:     "Skipped",
+			skip:     true,
+			expected: []Phase{PhaseSkipped},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			dep := newScriptDependency(dir, "tool", nil, tc.install)
+			platform := dep.Platforms[runtime.GOOS]
+			platform.Installer.URL = tc.url
+			dep.Platforms[runtime.GOOS] = platform
+
+			var phases []Phase
+			manager := &Manager{
+				Config:     &DependencyConfig{Dependencies: []Dependency{dep}},
+				Platform:   runtime.GOOS,
+				logger:     &mockLogger{},
+				envManager: environment.NewManager(),
+			}
+			WithStatusCallback(func(name string, phase Phase, status *DependencyStatus) {
+				if name != "tool" {
+					t.Errorf("Unexpected dependency %s", name)
+				}
+				if status == nil && phase != PhaseDownloading && phase != PhaseInstalling {
+					t.Errorf("Expected a status for phase %s", phase)
+				}
+				phases = append(phases, phase)
+			})(manager)
+			if tc.skip {
+				WithSkip("tool")(manager)
+			}
+
+			manager.EnsureDependencies()
+
+			if fmt.Sprint(phases) != fmt.Sprint(tc.expected) {
+				t.Errorf("Expected phases %v but got %v", tc.expected, phases)
+			}
+		})
+	}
+
+	t.Run("Nil callback", func(t *testing.T) {
+		manager := &Manager{logger: &mockLogger{}}
+		manager.notifyStatus("tool", PhaseChecking, nil)
+	})
+}
+
+// TestForceReinstall tests reinstalling dependencies that are already up to date
+func TestForceReinstall(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	testCases := []struct {
+		name        string
+		force       bool
+		only        []string
+		expected    string // Dependencies installed, in order
+		expectError bool
+	}{
+		{name: "Up to date without force", force: false, expected: ""},
+		{name: "Force reinstalls everything", force: true, expected: "a,b"},
+		{name: "Force a single dependency", force: true, only: []string{"b"}, expected: "b"},
+		{name: "Unknown selected dependency", force: true, only: []string{"c"}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			logPath := filepath.Join(dir, "install.log")
+
+			// Both dependencies start out installed and up to date
+			var deps []Dependency
+			for _, name := range []string{"a", "b"} {
+				marker := filepath.Join(dir, name+".installed")
+				if err := os.WriteFile(marker, nil, 0644); err != nil {
+					t.Fatalf("Failed to create marker: %v", err)
+				}
+				deps = append(deps, newScriptDependency(dir, name, nil, fmt.Sprintf("echo %s >> %s", name, logPath)))
+			}
+
+			manager := &Manager{
+				Config:     &DependencyConfig{Dependencies: deps},
+				Platform:   runtime.GOOS,
+				logger:     &mockLogger{},
+				envManager: environment.NewManager(),
+			}
+			WithForceReinstall(tc.force)(manager)
+			if len(tc.only) > 0 {
+				WithOnly(tc.only...)(manager)
+			}
+
+			statuses, report, err := manager.EnsureDependencies()
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			data, _ := os.ReadFile(logPath)
+			if got := strings.Join(strings.Fields(string(data)), ","); got != tc.expected {
+				t.Errorf("Expected installs [%s] but got [%s]", tc.expected, got)
+			}
+
+			// Forced reinstalls of installed dependencies count as updates
+			if installs := len(strings.Fields(string(data))); report.Updated != installs {
+				t.Errorf("Expected %d updates in the report but got %d", installs, report.Updated)
+			}
+			for _, name := range tc.only {
+				for other, status := range statuses {
+					if other != name && !status.Skipped {
+						t.Errorf("Expected %s to be skipped", other)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestEnsureCancellation tests that cancelling the context kills a running install,
+// starts no further dependencies, and cleans up temporary directories
+func TestEnsureCancellation(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	testCases := []struct {
+		name     string
+		parallel bool
+	}{
+		{name: "Sequential", parallel: false},
+		{name: "Parallel", parallel: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tempDir := filepath.Join(dir, "tmp")
+			started := filepath.Join(dir, "slow.started")
+
+			// after depends on slow, so it must never start once slow is cancelled
+			manager := &Manager{
+				Config: &DependencyConfig{
+					Dependencies: []Dependency{
+						newScriptDependency(dir, "slow", nil, fmt.Sprintf("touch %s; sleep 30", started)),
+						newScriptDependency(dir, "after", []string{"slow"}, ""),
+					},
+				},
+				Platform:   runtime.GOOS,
+				logger:     &mockLogger{},
+				envManager: environment.NewManager(),
+			}
+			WithTempDir(tempDir)(manager)
+			WithEnsureRetries(2, 0)(manager)
+
+			// Cancel once the slow install is running
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				for {
+					if _, err := os.Stat(started); err == nil {
+						cancel()
+						return
+					}
+					time.Sleep(10 * time.Millisecond)
+				}
+			}()
+
+			start := time.Now()
+			var statuses map[string]*DependencyStatus
+			var err error
+			if tc.parallel {
+				statuses, _, err = manager.EnsureDependenciesParallelContext(ctx, 2)
+			} else {
+				statuses, _, err = manager.EnsureDependenciesContext(ctx)
+			}
+
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("Expected a cancellation error but got: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 10*time.Second {
+				t.Errorf("Expected the install to be killed promptly but it took %s", elapsed)
+			}
+			if statuses["slow"].Installed {
+				t.Errorf("Expected the cancelled dependency not to be installed")
+			}
+			if _, err := os.Stat(filepath.Join(dir, "after.installed")); err == nil {
+				t.Errorf("Expected no dependency to start after cancellation")
+			}
+
+			entries, err := os.ReadDir(tempDir)
+			if err != nil {
+				t.Fatalf("Failed to read temp directory: %v", err)
+			}
+			if len(entries) != 0 {
+				t.Errorf("Expected temporary directories to be cleaned up but found %d", len(entries))
+			}
+		})
+	}
+}
+
+// TestEnsureCancellationDuringVerify tests that cancelling the context kills a hung verify command
+func TestEnsureCancellationDuringVerify(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	dir := t.TempDir()
+	started := filepath.Join(dir, "verify.started")
+	dep := newScriptDependency(dir, "hung", nil, "")
+	dep.Platforms[runtime.GOOS] = PlatformConfig{
+		Commands: Commands{
+			Install: []string{"true"},
+			Verify:  []string{"sh", "-c", fmt.Sprintf("touch %s; sleep 30", started)},
+		},
+	}
+
+	manager := &Manager{
+		Config:     &DependencyConfig{Dependencies: []Dependency{dep}},
+		Platform:   runtime.GOOS,
+		logger:     &mockLogger{},
+		envManager: environment.NewManager(),
+	}
+
+	// Cancel once the verify command is running
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		for {
+			if _, err := os.Stat(started); err == nil {
+				cancel()
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	start := time.Now()
+	_, _, err := manager.EnsureDependenciesContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancellation error but got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Expected the verify command to be killed promptly but it took %s", elapsed)
+	}
+}
+
+// TestInstallModes tests that check-only and manual dependencies are verified but never installed
+func TestInstallModes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	testCases := []struct {
+		name          string
+		mode          string
+		present       bool
+		optional      bool
+		force         bool
+		expectInstall bool
+		expectError   bool
+	}{
+		{name: "Auto installs", mode: InstallModeAuto, expectInstall: true},
+		{name: "Check-only absent", mode: InstallModeCheckOnly, expectError: true},
+		{name: "Manual absent", mode: InstallModeManual, expectError: true},
+		{name: "Manual absent and optional", mode: InstallModeManual, optional: true},
+		{name: "Check-only present", mode: InstallModeCheckOnly, present: true},
+		{name: "Manual present with force", mode: InstallModeManual, present: true, force: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			logPath := filepath.Join(dir, "install.log")
+			if tc.present {
+				if err := os.WriteFile(filepath.Join(dir, "tool.installed"), nil, 0644); err != nil {
+					t.Fatalf("Failed to create marker: %v", err)
+				}
+			}
+
+			dep := newScriptDependency(dir, "tool", nil, "")
+			dep.InstallMode = tc.mode
+			dep.Optional = tc.optional
+			dep.Platforms[runtime.GOOS] = PlatformConfig{
+				Commands: Commands{
+					Install: []string{"sh", "-c", fmt.Sprintf("echo tool >> %s; touch %s", logPath, filepath.Join(dir, "tool.installed"))},
+					Verify:  dep.Platforms[runtime.GOOS].Commands.Verify,
+				},
+			}
+
+			manager := &Manager{
+				Config:     &DependencyConfig{Dependencies: []Dependency{dep}},
+				Platform:   runtime.GOOS,
+				logger:     &mockLogger{},
+				envManager: environment.NewManager(),
+			}
+			WithForceReinstall(tc.force)(manager)
+			WithEnsureRetries(2, 0)(manager)
+
+			statuses, report, err := manager.EnsureDependencies()
+			if tc.expectError {
+				if !errors.Is(err, ErrManualAction) {
+					t.Errorf("Expected a manual action error but got: %v", err)
+				}
+			} else if err != nil {
+				t.Errorf("Did not expect an error but got: %v", err)
+			}
+
+			installs, _ := os.ReadFile(logPath)
+			if installed := len(installs) > 0; installed != tc.expectInstall {
+				t.Errorf("Expected install command run %v but got %v", tc.expectInstall, installed)
+			}
+
+			status := statuses["tool"]
+			manual := tc.mode != InstallModeAuto && !tc.present
+			if status.ManualAction != manual {
+				t.Errorf("Expected manual action %v but got %v", manual, status.ManualAction)
+			}
+			if manual && !errors.Is(status.Error, ErrManualAction) {
+				t.Errorf("Expected the status to explain the manual action but got: %v", status.Error)
+			}
+			if tc.present && report.UpToDate != 1 {
+				t.Errorf("Expected the present dependency to be up to date but got %+v", report)
+			}
+		})
+	}
+}
+
+// TestDownloadConcurrency tests that the download limit caps simultaneous downloads even
+// when more workers are available
+func TestDownloadConcurrency(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	testCases := []struct {
+		name        string
+		limit       int
+		expectedMax int32
+	}{
+		{name: "One at a time", limit: 1, expectedMax: 1},
+		{name: "Two at a time", limit: 2, expectedMax: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var active, maxActive atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := active.Add(1)
+				defer active.Add(-1)
+				for {
+					current := maxActive.Load()
+					if n <= current || maxActive.CompareAndSwap(current, n) {
+						break
+					}
+				}
+				time.Sleep(100 * time.Millisecond)
+				w.Write([]byte("1.0.0"))
+			}))
+			defer server.Close()
+
+			dir := t.TempDir()
+			var deps []Dependency
+			for _, name := range []string{"a", "b", "c", "d", "e"} {
+				dep := newScriptDependency(dir, name, nil, "touch "+filepath.Join(dir, name+".installed"))
+				platform := dep.Platforms[runtime.GOOS]
+				platform.Installer = Installer{URL: server.URL + "/" + name, Checksum: "size:5"}
+				dep.Platforms[runtime.GOOS] = platform
+				deps = append(deps, dep)
+			}
+
+			manager := &Manager{
+				Config:     &DependencyConfig{Dependencies: deps},
+				Platform:   runtime.GOOS,
+				logger:     &mockLogger{},
+				envManager: environment.NewManager(),
+			}
+			WithDownloadConcurrency(tc.limit)(manager)
+			WithCheckConcurrency(5)(manager)
+
+			statuses, _, err := manager.EnsureDependenciesParallel(5)
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+			for name, status := range statuses {
+				if !status.Installed {
+					t.Errorf("Expected %s to be installed but got %+v", name, status)
+				}
+			}
+
+			if got := maxActive.Load(); got != tc.expectedMax {
+				t.Errorf("Expected at most %d simultaneous downloads but got %d", tc.expectedMax, got)
+			}
+		})
+	}
+}
+
+// TestDeprecatedDependency tests that deprecated dependencies are warned about and flagged
+func TestDeprecatedDependency(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	const message = "use newtool instead"
+
+	testCases := []struct {
+		name    string
+		present bool
+		ensure  bool
+	}{
+		{name: "Check", present: true},
+		{name: "Ensure installs", ensure: true},
+		{name: "Ensure up to date", present: true, ensure: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tc.present {
+				if err := os.WriteFile(filepath.Join(dir, "tool.installed"), nil, 0644); err != nil {
+					t.Fatalf("Failed to create marker: %v", err)
+				}
+			}
+
+			tool := newScriptDependency(dir, "tool", nil, "")
+			tool.Deprecated = message
+			other := newScriptDependency(dir, "other", nil, "")
+
+			logger := &mockLogger{}
+			manager := &Manager{
+				Config:     &DependencyConfig{Dependencies: []Dependency{tool, other}},
+				Platform:   runtime.GOOS,
+				logger:     logger,
+				envManager: environment.NewManager(),
+			}
+
+			var statuses map[string]*DependencyStatus
+			var err error
+			if tc.ensure {
+				statuses, _, err = manager.EnsureDependencies()
+			} else {
+				statuses, err = manager.CheckAllDependencies()
+			}
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			if status := statuses["tool"]; !status.Deprecated || status.DeprecationMessage != message {
+				t.Errorf("Expected tool to be flagged deprecated but got %+v", status)
+			}
+			if status := statuses["other"]; status.Deprecated || status.DeprecationMessage != "" {
+				t.Errorf("Did not expect other to be flagged deprecated but got %+v", status)
+			}
+
+			warnings := 0
+			for _, log := range logger.warnLogs {
+				if strings.Contains(log, "is deprecated") {
+					warnings++
+				}
+			}
+			if warnings != 1 {
+				t.Errorf("Expected one deprecation warning but got %d: %v", warnings, logger.warnLogs)
+			}
+		})
+	}
+}
+
+// TestOffline tests that offline mode refuses downloads up front while verification still works
+func TestOffline(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Write([]byte("1.0.0"))
+	}))
+	defer server.Close()
+
+	newDep := func(name, verify string) Dependency {
+		return Dependency{
+			Name:    name,
+			Version: Version{Required: "1.0.0"},
+			Platforms: map[string]PlatformConfig{
+				runtime.GOOS: {
+					Installer: Installer{URL: server.URL + "/" + name + ".tar.gz", ChecksumURL: server.URL + "/" + name + ".sha256"},
+					Commands:  Commands{Install: []string{"true"}, Verify: []string{"sh", "-c", verify}},
+				},
+			},
+		}
+	}
+	installed := newDep("installed", "echo 1.0.0")
+	missing := newDep("missing", "exit 127")
+	missing.VersionSource = &VersionSource{URL: server.URL + "/latest"}
+
+	manager := &Manager{
+		Config:     &DependencyConfig{Dependencies: []Dependency{installed, missing}},
+		Platform:   runtime.GOOS,
+		logger:     &mockLogger{},
+		envManager: environment.NewManager(),
+	}
+	WithOffline(true)(manager)
+
+	// Verifying an installed dependency needs no network
+	status, err := manager.VerifyDependency(&installed)
+	if err != nil || !status.Installed {
+		t.Errorf("Expected installed to verify offline but got: %v", err)
+	}
+
+	// The latest version isn't resolved offline, the configured version applies instead
+	statuses, err := manager.CheckAllDependencies()
+	if err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+	if status := statuses["missing"]; status == nil || status.Installed {
+		t.Errorf("Expected missing to be reported as not installed but got %+v", status)
+	}
+	if required := manager.requiredVersion(&missing); required != "1.0.0" {
+		t.Errorf("Expected missing to require its configured version 1.0.0 but got %q", required)
+	}
+
+	// Installing needs the network
+	start := time.Now()
+	if _, err := manager.installDependency(context.Background(), &missing); !errors.Is(err, ErrOffline) {
+		t.Errorf("Expected an offline error installing but got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the install to fail fast but it took %s", elapsed)
+	}
+
+	// Package managers need the network
+	pkg := newDep("pkg", "exit 127")
+	pkg.Platforms[runtime.GOOS] = PlatformConfig{
+		Installer: Installer{Type: InstallerTypePackage, Package: "pkg", PackageManager: "apt"},
+		Commands:  Commands{Verify: []string{"sh", "-c", "exit 127"}},
+	}
+	if _, err := manager.installDependency(context.Background(), &pkg); !errors.Is(err, ErrOffline) {
+		t.Errorf("Expected an offline error installing a package but got: %v", err)
+	}
+
+	// URL checks need the network
+	checks := manager.CheckDownloadURLs()
+	if len(checks) != 2 {
+		t.Errorf("Expected both download URLs to be checked but got %d", len(checks))
+	}
+	for name, check := range checks {
+		if !errors.Is(check.Error, ErrOffline) {
+			t.Errorf("Expected an offline error checking %s but got: %v", name, check.Error)
+		}
+	}
+
+	if n := hits.Load(); n != 0 {
+		t.Errorf("Expected no requests in offline mode but got %d", n)
+	}
+}
+
+// TestDownloadLimits tests that a dependency's download timeout and retries override the manager's
+func TestDownloadLimits(t *testing.T) {
+	zero, two := 0, 2
+	manager := &Manager{}
+	WithDownloadTimeout(time.Minute)(manager)
+	WithDownloadRetries(3)(manager)
+
+	testCases := []struct {
+		name            string
+		installer       Installer
+		expectedTimeout time.Duration
+		expectedRetries int
+	}{
+		{name: "Manager defaults", expectedTimeout: time.Minute, expectedRetries: 3},
+		{name: "Installer timeout", installer: Installer{Timeout: time.Hour}, expectedTimeout: time.Hour, expectedRetries: 3},
+		{name: "Installer retries", installer: Installer{MaxRetries: &two}, expectedTimeout: time.Minute, expectedRetries: 2},
+		{name: "Installer disables retries", installer: Installer{MaxRetries: &zero}, expectedTimeout: time.Minute, expectedRetries: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			timeout, retries := manager.downloadLimits(&tc.installer)
+			if timeout != tc.expectedTimeout || retries != tc.expectedRetries {
+				t.Errorf("Expected timeout %s and %d retries but got %s and %d",
+					tc.expectedTimeout, tc.expectedRetries, timeout, retries)
+			}
+		})
+	}
+
+	// The effective values reach the download
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path == "/slow.tar.gz" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Write([]byte("artifact"))
+	}))
+	defer server.Close()
+
+	install := func(manager *Manager, path string, installer Installer) error {
+		installer.URL = server.URL + path
+		dep := &Dependency{
+			Name:      "tool",
+			Platforms: map[string]PlatformConfig{runtime.GOOS: {Installer: installer}},
+		}
+		manager.Platform = runtime.GOOS
+		manager.logger = &mockLogger{}
+		_, err := manager.installDependency(context.Background(), dep)
+		return err
+	}
+
+	t.Run("Installer retries override", func(t *testing.T) {
+		attempts.Store(0)
+		manager := &Manager{}
+		WithDownloadRetries(3)(manager)
+		if err := install(manager, "/tool.tar.gz", Installer{MaxRetries: &zero}); err == nil {
+			t.Errorf("Expected an error but got none")
+		}
+		if n := attempts.Load(); n != 1 {
+			t.Errorf("Expected a single attempt but got %d", n)
+		}
+	})
+
+	t.Run("Installer timeout override", func(t *testing.T) {
+		attempts.Store(1) // Skip the failure
+		manager := &Manager{}
+		WithDownloadTimeout(50 * time.Millisecond)(manager)
+		if err := install(manager, "/slow.tar.gz", Installer{Timeout: 5 * time.Second}); err != nil {
+			t.Errorf("Did not expect an error but got: %v", err)
+		}
+
+		attempts.Store(1)
+		WithDownloadTimeout(5 * time.Second)(manager)
+		if err := install(manager, "/slow.tar.gz", Installer{Timeout: 50 * time.Millisecond}); err == nil {
+			t.Errorf("Expected the installer timeout to apply but got no error")
+		}
+	})
+}
+
+// TestEnvironmentChanges tests the environment diff before and after the managed environment
+// is applied to the process
+func TestEnvironmentChanges(t *testing.T) {
+	testCases := []struct {
+		name            string
+		keepExisting    bool
+		expectedChanged []EnvironmentVariableChange
+	}{
+		{
+			name:            "Config overrides existing",
+			expectedChanged: []EnvironmentVariableChange{{Key: "DEPMAN_TEST_MODE", Old: "default", New: "tool"}},
+		},
+		{
+			name:         "Existing values kept",
+			keepExisting: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			binDir := filepath.Join(t.TempDir(), "bin")
+			t.Setenv("PATH", os.Getenv("PATH"))
+			t.Setenv("DEPMAN_TEST_MODE", "default")
+			t.Setenv("DEPMAN_TEST_HOME", "")
+			os.Unsetenv("DEPMAN_TEST_HOME")
+
+			manager := &Manager{
+				Config: &DependencyConfig{
+					Dependencies: []Dependency{
+						{
+							Name:    "tool",
+							Version: Version{Required: "1.0.0"},
+							Platforms: map[string]PlatformConfig{
+								runtime.GOOS: {Commands: testCommands},
+							},
+							Environment: Environment{
+								Path:      []string{binDir},
+								Variables: map[string]string{"DEPMAN_TEST_HOME": "/opt/tool", "DEPMAN_TEST_MODE": "tool"},
+							},
+						},
+					},
+				},
+				Platform:   runtime.GOOS,
+				logger:     &mockLogger{},
+				envManager: environment.NewManager(),
+			}
+			WithOverrideExistingEnv(!tc.keepExisting)(manager)
+
+			diff, err := manager.PlanEnvironment()
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			check := func(diff EnvironmentDiff) {
+				t.Helper()
+				expectedPaths := []string{environment.NormalizePath(binDir)}
+				if len(diff.AddedPaths) != 1 || diff.AddedPaths[0] != expectedPaths[0] {
+					t.Errorf("Expected added paths %v but got %v", expectedPaths, diff.AddedPaths)
+				}
+				if len(diff.Added) != 1 || diff.Added[0] != (EnvironmentVariableChange{Key: "DEPMAN_TEST_HOME", New: "/opt/tool"}) {
+					t.Errorf("Expected DEPMAN_TEST_HOME to be added but got %v", diff.Added)
+				}
+				if !reflect.DeepEqual(diff.Changed, tc.expectedChanged) {
+					t.Errorf("Expected changed variables %v but got %v", tc.expectedChanged, diff.Changed)
+				}
+			}
+			check(diff)
+
+			// Once applied, the changes are still reported against the original environment
+			manager.applyEnvironment()
+			if os.Getenv("DEPMAN_TEST_HOME") != "/opt/tool" {
+				t.Fatalf("Expected the environment to be applied to the process")
+			}
+			check(manager.EnvironmentChanges())
+		})
+	}
+}
+
+// TestEnsureStrictVersionParse tests that an install whose verify output has no version
+// fails ensure in strict mode instead of being reported as installed
+func TestEnsureStrictVersionParse(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "tool.installed")
+	manager := &Manager{
+		Config: &DependencyConfig{
+			Dependencies: []Dependency{
+				{
+					Name:               "tool",
+					Version:            Version{Required: "1.0.0"},
+					StrictVersionParse: true,
+					Platforms: map[string]PlatformConfig{
+						runtime.GOOS: {
+							Commands: Commands{
+								Install: []string{"touch", marker},
+								Verify:  []string{"sh", "-c", "test -f " + marker + " && echo OK"},
+							},
+						},
+					},
+				},
+			},
+		},
+		Platform:   runtime.GOOS,
+		logger:     &mockLogger{},
+		envManager: environment.NewManager(),
+	}
+
+	statuses, report, err := manager.EnsureDependencies()
+	if err == nil || !strings.Contains(err.Error(), "partially installed") {
+		t.Fatalf("Expected a partial install error but got: %v", err)
+	}
+	if statuses["tool"].Error == nil {
+		t.Errorf("Expected the status to carry the error")
+	}
+	if report.Failed != 1 || report.Installed != 0 {
+		t.Errorf("Expected 1 failure and no installs but got %+v", *report)
+	}
+}
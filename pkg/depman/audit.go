@@ -0,0 +1,62 @@
+package depman
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Audit log actions
+const (
+	AuditInstall   = "install"
+	AuditUninstall = "uninstall"
+)
+
+// AuditEntry is one line of the audit log, recording a completed install or uninstall
+type AuditEntry struct {
+	Time       time.Time `json:"time"`                  // When the operation completed
+	Action     string    `json:"action"`                // AuditInstall or AuditUninstall
+	Dependency string    `json:"dependency"`            // Name of the dependency
+	OldVersion string    `json:"old_version,omitempty"` // Version before the operation, if installed
+	NewVersion string    `json:"new_version,omitempty"` // Version after an install
+	Checksum   string    `json:"checksum,omitempty"`    // Checksum of the downloaded artifact, if any
+	Command    []string  `json:"command,omitempty"`     // Install or uninstall command as configured
+}
+
+// audit appends an entry to the audit log, if one is configured
+// Failures only warn, as the operation itself has already completed
+func (m *Manager) audit(entry AuditEntry) {
+	if m.auditLog == "" {
+		return
+	}
+
+	entry.Time = time.Now().UTC()
+	data, err := json.Marshal(entry)
+	if err != nil {
+		m.logger.Warnf("Failed to encode audit entry for %s: %v", entry.Dependency, err)
+		return
+	}
+
+	if err := m.appendAuditLine(append(data, '\n')); err != nil {
+		m.logger.Warnf("Failed to write audit log: %v", err)
+	}
+}
+
+// appendAuditLine appends a line to the audit log with a single write, serialized
+// so parallel installs never interleave their entries
+func (m *Manager) appendAuditLine(line []byte) error {
+	m.auditMu.Lock()
+	defer m.auditMu.Unlock()
+
+	file, err := os.OpenFile(m.auditLog, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	if _, err := file.Write(line); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to append to audit log: %w", err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,145 @@
+package depman
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/sobhit-avrl/depman-v1/internal/environment"
+)
+
+// readAuditLog returns the entries of an audit log, failing on malformed lines
+func readAuditLog(t *testing.T, path string) []AuditEntry {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %v", err)
+	}
+	defer file.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("Malformed audit line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// TestAuditLog tests recording installs and uninstalls in the audit log
+func TestAuditLog(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("artifact"))
+	}))
+	defer server.Close()
+
+	t.Run("Install and uninstall", func(t *testing.T) {
+		dir := t.TempDir()
+		auditPath := filepath.Join(dir, "audit.jsonl")
+
+		// Reports 0.9.0 until installed, then 1.0.0, and is removed again by uninstall
+		marker := filepath.Join(dir, "tool.installed")
+		dep := Dependency{
+			Name:    "tool",
+			Version: Version{Required: "1.0.0"},
+			Platforms: map[string]PlatformConfig{
+				runtime.GOOS: {
+					Installer: Installer{URL: server.URL + "/tool.tar.gz"},
+					Commands: Commands{
+						Install:   []string{"touch", marker},
+						Verify:    []string{"sh", "-c", "test -f " + marker + " && echo 1.0.0 || echo 0.9.0"},
+						Uninstall: []string{"rm", marker},
+					},
+				},
+			},
+		}
+
+		manager := &Manager{
+			Config:     &DependencyConfig{Dependencies: []Dependency{dep}},
+			Platform:   runtime.GOOS,
+			logger:     &mockLogger{},
+			envManager: environment.NewManager(),
+		}
+		WithAuditLog(auditPath)(manager)
+
+		if _, _, err := manager.EnsureDependencies(); err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+		// Nothing to do the second time, so nothing is recorded
+		if _, _, err := manager.EnsureDependencies(); err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+		if _, err := manager.Clean("tool"); err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+
+		entries := readAuditLog(t, auditPath)
+		if len(entries) != 2 {
+			t.Fatalf("Expected 2 audit entries but got %d: %+v", len(entries), entries)
+		}
+
+		install := entries[0]
+		if install.Action != AuditInstall || install.Dependency != "tool" || install.OldVersion != "0.9.0" || install.NewVersion != "1.0.0" {
+			t.Errorf("Unexpected install entry: %+v", install)
+		}
+		if install.Checksum == "" || len(install.Command) != 2 || install.Command[0] != "touch" || install.Time.IsZero() {
+			t.Errorf("Expected checksum, command and time in install entry: %+v", install)
+		}
+
+		uninstall := entries[1]
+		if uninstall.Action != AuditUninstall || uninstall.OldVersion != "1.0.0" || uninstall.NewVersion != "" || uninstall.Command[0] != "rm" {
+			t.Errorf("Unexpected uninstall entry: %+v", uninstall)
+		}
+	})
+
+	t.Run("Parallel installs", func(t *testing.T) {
+		dir := t.TempDir()
+		auditPath := filepath.Join(dir, "audit.jsonl")
+
+		var deps []Dependency
+		for i := 0; i < 8; i++ {
+			deps = append(deps, newScriptDependency(dir, fmt.Sprintf("tool%d", i), nil, ""))
+		}
+
+		manager := &Manager{
+			Config:     &DependencyConfig{Dependencies: deps},
+			Platform:   runtime.GOOS,
+			logger:     &mockLogger{},
+			envManager: environment.NewManager(),
+		}
+		WithAuditLog(auditPath)(manager)
+
+		if _, _, err := manager.EnsureDependenciesParallel(8); err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+
+		entries := readAuditLog(t, auditPath)
+		if len(entries) != len(deps) {
+			t.Fatalf("Expected %d audit entries but got %d", len(deps), len(entries))
+		}
+		seen := make(map[string]bool)
+		for _, entry := range entries {
+			if entry.Action != AuditInstall || entry.NewVersion != "1.0.0" || entry.OldVersion != "" {
+				t.Errorf("Unexpected entry: %+v", entry)
+			}
+			seen[entry.Dependency] = true
+		}
+		if len(seen) != len(deps) {
+			t.Errorf("Expected one entry per dependency but got: %+v", entries)
+		}
+	})
+}
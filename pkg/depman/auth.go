@@ -0,0 +1,68 @@
+package depman
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Headers returns the HTTP headers for the credentials, expanding environment variables
+func (a *Auth) Headers() (map[string]string, error) {
+	if a == nil {
+		return nil, nil
+	}
+
+	switch strings.ToLower(a.Type) {
+	case "basic":
+		credentials := os.ExpandEnv(a.Username) + ":" + os.ExpandEnv(a.Password)
+		return map[string]string{
+			"Authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials)),
+		}, nil
+	case "bearer":
+		token := os.ExpandEnv(a.Token)
+		if token == "" {
+			return nil, fmt.Errorf("bearer auth requires a token")
+		}
+		return map[string]string{
+			"Authorization": "Bearer " + token,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported auth type: %s", a.Type)
+	}
+}
+
+// secrets returns the expanded credential values that must never be logged
+func (a *Auth) secrets() []string {
+	if a == nil {
+		return nil
+	}
+
+	var secrets []string
+	for _, value := range []string{a.Password, a.Token} {
+		if expanded := os.ExpandEnv(value); expanded != "" {
+			secrets = append(secrets, expanded)
+		}
+	}
+	return secrets
+}
+
+// maskURL hides credentials in a URL so it can be logged safely: any password in the
+// user info is replaced, as is any occurrence of the given secrets
+func maskURL(rawURL string, secrets []string) string {
+	masked := rawURL
+	if u, err := url.Parse(rawURL); err == nil && u.User != nil {
+		if _, hasPassword := u.User.Password(); hasPassword {
+			u.User = url.UserPassword(u.User.Username(), "xxxxx")
+			masked = u.String()
+		}
+	}
+
+	for _, secret := range secrets {
+		masked = strings.ReplaceAll(masked, secret, "xxxxx")
+		masked = strings.ReplaceAll(masked, url.QueryEscape(secret), "xxxxx")
+	}
+
+	return masked
+}
@@ -0,0 +1,95 @@
+package depman
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/sobhit-avrl/depman-v1/internal/logger"
+)
+
+// TestAuthHeaders tests that the correct Authorization header is sent and secrets aren't logged
+func TestAuthHeaders(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("true command not available on Windows")
+	}
+
+	t.Setenv("DEPMAN_TEST_TOKEN", "s3cret-token")
+	t.Setenv("DEPMAN_TEST_PASSWORD", "s3cret-password")
+
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("Authorization")
+		w.Write([]byte("artifact"))
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name           string
+		auth           *Auth
+		url            string
+		expectedHeader string
+		secret         string
+	}{
+		{
+			name:           "Bearer token",
+			auth:           &Auth{Type: "bearer", Token: "${DEPMAN_TEST_TOKEN}"},
+			url:            server.URL + "/tool.tar.gz?token=s3cret-token",
+			expectedHeader: "Bearer s3cret-token",
+			secret:         "s3cret-token",
+		},
+		{
+			name:           "Basic auth",
+			auth:           &Auth{Type: "basic", Username: "deploy", Password: "${DEPMAN_TEST_PASSWORD}"},
+			url:            strings.Replace(server.URL, "http://", "http://deploy:s3cret-password@", 1) + "/tool.tar.gz",
+			expectedHeader: "Basic " + base64.StdEncoding.EncodeToString([]byte("deploy:s3cret-password")),
+			secret:         "s3cret-password",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			received = ""
+
+			var logs bytes.Buffer
+			manager := &Manager{
+				Platform: runtime.GOOS,
+				logger:   logger.New(logger.Options{Level: logger.LevelDebug, Output: &logs}),
+			}
+
+			dep := &Dependency{
+				Name: "private-tool",
+				Platforms: map[string]PlatformConfig{
+					runtime.GOOS: {
+						Installer: Installer{URL: tc.url, Auth: tc.auth},
+						Commands:  Commands{Install: []string{"true"}},
+					},
+				},
+			}
+
+			if _, err := manager.installDependency(context.Background(), dep); err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			if received != tc.expectedHeader {
+				t.Errorf("Expected Authorization header %q but got %q", tc.expectedHeader, received)
+			}
+
+			if strings.Contains(logs.String(), tc.secret) {
+				t.Errorf("Logs leaked the secret:\n%s", logs.String())
+			}
+		})
+	}
+
+	// Test an unsupported auth type
+	t.Run("Unsupported type", func(t *testing.T) {
+		if _, err := (&Auth{Type: "digest"}).Headers(); err == nil {
+			t.Errorf("Expected an error but got none")
+		}
+	})
+}
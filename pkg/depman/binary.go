@@ -0,0 +1,57 @@
+package depman
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sobhit-avrl/depman-v1/internal/downloader"
+)
+
+// verifyBinary hashes the installed binary and compares it with the configured checksum
+// The binary defaults to the verify executable, and bare names are looked up on the
+// dependency's PATH
+func (m *Manager) verifyBinary(dep *Dependency, platformConfig *PlatformConfig, installDir string) error {
+	path, err := m.resolveBinary(dep, platformConfig, installDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve binary of dependency %s: %w", dep.Name, err)
+	}
+
+	m.logger.Infof("Verifying checksum of %s binary %s", dep.Name, path)
+	if err := downloader.VerifyFile(path, platformConfig.BinaryChecksum); err != nil {
+		return fmt.Errorf("binary verification of dependency %s failed: %w", dep.Name, err)
+	}
+
+	return nil
+}
+
+// resolveBinary returns the path of the binary to hash for a dependency
+func (m *Manager) resolveBinary(dep *Dependency, platformConfig *PlatformConfig, installDir string) (string, error) {
+	path := platformConfig.BinaryPath
+	if path == "" {
+		if len(platformConfig.Commands.Verify) == 0 {
+			return "", fmt.Errorf("no binary_path or verify command to find the binary")
+		}
+		path = platformConfig.Commands.Verify[0]
+	}
+	path = os.ExpandEnv(strings.ReplaceAll(path, "{install_dir}", installDir))
+
+	if strings.ContainsRune(path, filepath.Separator) || strings.ContainsRune(path, '/') {
+		return path, nil
+	}
+
+	// Look bare names up on the PATH the dependency's commands run with
+	env := m.commandEnv(dep)
+	for _, entry := range env {
+		if value, ok := strings.CutPrefix(entry, "PATH="); ok {
+			for _, dir := range filepath.SplitList(value) {
+				if resolved, err := exec.LookPath(filepath.Join(dir, path)); err == nil {
+					return resolved, nil
+				}
+			}
+		}
+	}
+	return exec.LookPath(path)
+}
@@ -0,0 +1,82 @@
+package depman
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sobhit-avrl/depman-v1/internal/environment"
+)
+
+// TestBinaryChecksum tests verifying the checksum of the installed binary
+func TestBinaryChecksum(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	// The fixture binary written by the install command
+	script := "#!/bin/sh\necho 1.0.0\n"
+	sum := sha256.Sum256([]byte(script))
+	checksum := "sha256:" + hex.EncodeToString(sum[:])
+
+	testCases := []struct {
+		name           string
+		binaryPath     string
+		checksum       string
+		expectError    bool
+		expectMismatch bool
+	}{
+		{name: "Matching binary path", binaryPath: "{install_dir}/bin/tool", checksum: checksum},
+		{name: "Defaults to verify executable on PATH", checksum: checksum},
+		{name: "Size checksum", binaryPath: "{install_dir}/bin/tool", checksum: "size:" + strconv.Itoa(len(script))},
+		{name: "Mismatch", binaryPath: "{install_dir}/bin/tool", checksum: "sha256:" + strings.Repeat("0", 64), expectError: true, expectMismatch: true},
+		{name: "Missing binary", binaryPath: "{install_dir}/bin/other", checksum: checksum, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			installDir := filepath.Join(t.TempDir(), "tool")
+			dep := &Dependency{
+				Name:        "tool",
+				Environment: Environment{Path: []string{filepath.Join(installDir, "bin")}},
+				Platforms: map[string]PlatformConfig{
+					runtime.GOOS: {
+						InstallDir: installDir,
+						Commands: Commands{
+							Install: []string{"sh", "-c", "mkdir -p {install_dir}/bin && printf '" + strings.ReplaceAll(script, "\n", `\n`) + "' > {install_dir}/bin/tool && chmod +x {install_dir}/bin/tool"},
+							Verify:  []string{"tool"},
+						},
+						BinaryPath:     tc.binaryPath,
+						BinaryChecksum: tc.checksum,
+					},
+				},
+			}
+
+			manager := &Manager{
+				Platform:   runtime.GOOS,
+				logger:     &mockLogger{},
+				envManager: environment.NewManager(),
+			}
+
+			_, err := manager.installDependency(context.Background(), dep)
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("Expected an error but got none")
+				}
+				if errors.Is(err, ErrChecksumMismatch) != tc.expectMismatch {
+					t.Errorf("Expected checksum mismatch %v but got: %v", tc.expectMismatch, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+		})
+	}
+}
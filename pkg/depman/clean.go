@@ -0,0 +1,134 @@
+package depman
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CleanResult describes what was removed for a single dependency
+type CleanResult struct {
+	Name             string   // Name of the dependency
+	Uninstalled      bool     // Whether an uninstall command ran successfully
+	RemovedPaths     []string // Paths removed from PATH
+	RemovedVariables []string // Environment variables unset
+	Output           string   // Raw output of the uninstall command
+	Error            error    // Any error from the uninstall command
+}
+
+// Clean uninstalls the named dependencies (or all enabled ones if none are named) in reverse
+// topological order, so dependents are removed before what they depend on, and removes
+// their environment contributions. Individual failures don't stop the run, and a dependency
+// whose uninstall failed keeps its environment; if any occurred, an error summarizing them
+// is returned alongside the results.
+// The environment is only removed from the current process, as depman never persists it;
+// a .env file written by ExportDotEnv has to be exported again.
+func (m *Manager) Clean(names ...string) ([]*CleanResult, error) {
+	return m.CleanContext(context.Background(), names...)
+}
+
+// CleanContext is Clean, stopping when ctx is cancelled
+// Uninstall commands run like install commands, under the install timeout and killed along
+// with their children on cancellation
+func (m *Manager) CleanContext(ctx context.Context, names ...string) ([]*CleanResult, error) {
+	graph, err := m.BuildGraph()
+	if err != nil {
+		return nil, err
+	}
+
+	// Determine which dependencies to clean
+	selected := make(map[string]bool)
+	for _, name := range names {
+		if m.findDependency(name) == nil {
+			return nil, fmt.Errorf("dependency '%s' not found in configuration", name)
+		}
+		selected[name] = true
+	}
+
+	order := graph.TopologicalOrder()
+	var results []*CleanResult
+	var failed []string
+
+	for i := len(order) - 1; i >= 0; i-- {
+		name := order[i]
+		if len(selected) > 0 && !selected[name] {
+			continue
+		}
+		if len(selected) == 0 && !m.findDependency(name).IsEnabled() {
+			continue // Disabled dependencies are only cleaned when named
+		}
+		if ctx.Err() != nil {
+			return results, fmt.Errorf("clean cancelled: %w", ctx.Err())
+		}
+
+		result := m.cleanDependency(ctx, m.findDependency(name))
+		if result.Error != nil {
+			m.logger.Errorf("Failed to uninstall %s: %v", name, result.Error)
+			failed = append(failed, name)
+		}
+		results = append(results, result)
+	}
+
+	// Apply environment removals to the current process
+	m.applyEnvironment()
+
+	if len(failed) > 0 {
+		return results, fmt.Errorf("%d of %d dependencies failed to uninstall: %s",
+			len(failed), len(results), strings.Join(failed, ", "))
+	}
+
+	return results, nil
+}
+
+// cleanDependency runs the uninstall command for a dependency and removes its environment
+// The environment is kept when the uninstall command fails
+func (m *Manager) cleanDependency(ctx context.Context, dep *Dependency) *CleanResult {
+	result := &CleanResult{Name: dep.Name}
+
+	// Run the uninstall command if there is one for this platform
+	if platformConfig, err := m.GetPlatformConfig(dep); err == nil && len(platformConfig.Commands.Uninstall) > 0 {
+		uninstall := platformConfig.Commands.Uninstall
+
+		// Find the version being removed for the audit log
+		var oldVersion string
+		if m.auditLog != "" {
+			if status, err := m.verifyDependency(ctx, dep); err == nil && status.Installed {
+				oldVersion = status.CurrentVersion
+			}
+		}
+
+		m.logger.Infof("Uninstalling %s using command: %s", dep.Name, strings.Join(uninstall, " "))
+
+		output, err := m.runChangeCommand(ctx, dep, uninstall, platformConfig.RunAs, "uninstall")
+		result.Output = output
+		if err != nil {
+			// The dependency is likely still installed, so keep it usable
+			result.Error = err
+			return result
+		}
+		result.Uninstalled = true
+		m.audit(AuditEntry{Action: AuditUninstall, Dependency: dep.Name, OldVersion: oldVersion, Command: uninstall})
+	} else {
+		m.logger.Infof("No uninstall command for %s, only removing environment", dep.Name)
+	}
+
+	// Remove environment contributions
+	m.envMu.Lock()
+	defer m.envMu.Unlock()
+
+	depEnv := m.dependencyEnvironment(dep)
+	for _, path := range depEnv.Path {
+		expandedPath := m.envManager.ExpandVariables(path)
+		m.envManager.RemovePath(expandedPath)
+		result.RemovedPaths = append(result.RemovedPaths, expandedPath)
+	}
+
+	for key := range depEnv.Variables {
+		m.envManager.UnsetVariable(key)
+		result.RemovedVariables = append(result.RemovedVariables, key)
+	}
+	sort.Strings(result.RemovedVariables)
+
+	return result
+}
@@ -0,0 +1,160 @@
+package depman
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sobhit-avrl/depman-v1/internal/environment"
+)
+
+// newCleanTestManager creates a manager whose uninstall commands append to a log
+func newCleanTestManager(dir string, failing string) *Manager {
+	logPath := filepath.Join(dir, "uninstall.log")
+	newDep := func(name string, deps ...string) Dependency {
+		script := fmt.Sprintf("echo %s >> %s", name, logPath)
+		if name == failing {
+			script = "exit 1"
+		}
+
+		return Dependency{
+			Name:         name,
+			Version:      Version{Required: "1.0.0"},
+			Dependencies: deps,
+			Platforms: map[string]PlatformConfig{
+				runtime.GOOS: {
+					Commands: Commands{
+						Uninstall: []string{"sh", "-c", script},
+					},
+				},
+			},
+			Environment: Environment{
+				Path:      []string{filepath.Join(dir, name, "bin")},
+				Variables: map[string]string{strings.ToUpper(name) + "_HOME": filepath.Join(dir, name)},
+			},
+		}
+	}
+
+	return &Manager{
+		Config: &DependencyConfig{
+			Dependencies: []Dependency{
+				newDep("app", "runtime"),
+				newDep("runtime", "base"),
+				newDep("base"),
+			},
+		},
+		Platform:   runtime.GOOS,
+		logger:     &mockLogger{},
+		envManager: environment.NewManager(),
+	}
+}
+
+// TestClean tests reverse topological uninstall order and continuing past failures
+func TestClean(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	t.Run("Reverse topological order", func(t *testing.T) {
+		dir := t.TempDir()
+		manager := newCleanTestManager(dir, "")
+
+		results, err := manager.Clean()
+		if err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, "uninstall.log"))
+		if err != nil {
+			t.Fatalf("Failed to read uninstall log: %v", err)
+		}
+
+		if order := strings.Fields(string(data)); strings.Join(order, ",") != "app,runtime,base" {
+			t.Errorf("Expected uninstall order app,runtime,base but got %v", order)
+		}
+
+		if len(results) != 3 || len(results[0].RemovedVariables) != 1 || results[0].RemovedVariables[0] != "APP_HOME" {
+			t.Errorf("Expected environment removals to be reported but got %+v", results)
+		}
+	})
+
+	t.Run("Continue on error", func(t *testing.T) {
+		dir := t.TempDir()
+		manager := newCleanTestManager(dir, "runtime")
+
+		results, err := manager.Clean()
+		if err == nil {
+			t.Fatalf("Expected an error but got none")
+		}
+
+		if !strings.Contains(err.Error(), "1 of 3 dependencies failed to uninstall: runtime") {
+			t.Errorf("Expected failure summary but got: %v", err)
+		}
+
+		if len(results) != 3 || !results[2].Uninstalled {
+			t.Errorf("Expected base to be uninstalled after runtime failed but got %+v", results)
+		}
+
+		// The environment of the dependency that failed to uninstall is kept
+		if runtimeResult := results[1]; len(runtimeResult.RemovedPaths) != 0 || len(runtimeResult.RemovedVariables) != 0 {
+			t.Errorf("Expected the environment of runtime to be kept but got %+v", runtimeResult)
+		}
+		if slices.Contains(manager.envManager.UnsetVariables, "RUNTIME_HOME") {
+			t.Errorf("Expected RUNTIME_HOME not to be unset")
+		}
+	})
+
+	t.Run("Selected dependencies only", func(t *testing.T) {
+		dir := t.TempDir()
+		manager := newCleanTestManager(dir, "")
+
+		results, err := manager.Clean("base")
+		if err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+
+		if len(results) != 1 || results[0].Name != "base" {
+			t.Errorf("Expected only base to be cleaned but got %+v", results)
+		}
+	})
+
+	t.Run("Uninstall timeout", func(t *testing.T) {
+		dir := t.TempDir()
+		manager := newCleanTestManager(dir, "")
+		manager.Config.Dependencies[2].Platforms[runtime.GOOS].Commands.Uninstall[2] = "sleep 5"
+		WithInstallTimeout(100 * time.Millisecond)(manager)
+
+		start := time.Now()
+		results, err := manager.Clean("base")
+		if err == nil {
+			t.Fatalf("Expected an error but got none")
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("Expected the uninstall to be killed by the timeout but it took %s", elapsed)
+		}
+		if len(results) != 1 || results[0].Error == nil || !strings.Contains(results[0].Error.Error(), "uninstall timed out") {
+			t.Errorf("Expected an uninstall timeout but got %+v", results)
+		}
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		dir := t.TempDir()
+		manager := newCleanTestManager(dir, "")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := manager.CleanContext(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected a cancellation error but got: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "uninstall.log")); !os.IsNotExist(err) {
+			t.Errorf("Expected no uninstall command to run after cancellation")
+		}
+	})
+}
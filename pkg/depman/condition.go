@@ -0,0 +1,64 @@
+package depman
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// A dependency's when condition is deliberately small, one of:
+//
+//	VAR           VAR is set to a truthy value (anything but empty, 0, false, no or off)
+//	!VAR          VAR is unset or falsy
+//	VAR==value    VAR is set to exactly value
+//	VAR!=value    VAR is unset or set to anything but value
+//
+// Spaces around the operator are ignored; there are no quotes, and/or or parentheses
+
+// falsyValues are environment values that make a bare VAR condition false
+var falsyValues = map[string]bool{"": true, "0": true, "false": true, "no": true, "off": true}
+
+// evaluateCondition evaluates a when condition, looking variables up with lookup
+// An empty condition is always met
+func evaluateCondition(expr string, lookup func(string) (string, bool)) (bool, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return true, nil
+	}
+
+	for _, op := range []string{"==", "!="} {
+		name, want, ok := strings.Cut(expr, op)
+		if !ok {
+			continue
+		}
+		name, want = strings.TrimSpace(name), strings.TrimSpace(want)
+		if err := validateConditionVar(name, expr); err != nil {
+			return false, err
+		}
+		value, set := lookup(name)
+		equal := set && value == want
+		return equal == (op == "=="), nil
+	}
+
+	negate := strings.HasPrefix(expr, "!")
+	name := strings.TrimSpace(strings.TrimPrefix(expr, "!"))
+	if err := validateConditionVar(name, expr); err != nil {
+		return false, err
+	}
+	value, _ := lookup(name)
+	truthy := !falsyValues[strings.ToLower(strings.TrimSpace(value))]
+	return truthy != negate, nil
+}
+
+// validateConditionVar checks that a condition names a plausible environment variable
+func validateConditionVar(name, expr string) error {
+	if name == "" || strings.ContainsAny(name, "=! \t") {
+		return fmt.Errorf("invalid when condition '%s' (expected VAR, !VAR, VAR==value or VAR!=value)", expr)
+	}
+	return nil
+}
+
+// ConditionMet reports whether the dependency's when condition holds in the process environment
+func (d *Dependency) ConditionMet() (bool, error) {
+	return evaluateCondition(d.When, os.LookupEnv)
+}
@@ -0,0 +1,93 @@
+package depman
+
+import (
+	"testing"
+
+	"github.com/sobhit-avrl/depman-v1/internal/environment"
+)
+
+func TestEvaluateCondition(t *testing.T) {
+	env := map[string]string{"ENABLE_GPU": "1", "CI": "false", "MODE": "release", "EMPTY": ""}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	testCases := []struct {
+		expr        string
+		expected    bool
+		expectError bool
+	}{
+		{expr: "", expected: true},
+		{expr: "ENABLE_GPU", expected: true},
+		{expr: "CI", expected: false},
+		{expr: "EMPTY", expected: false},
+		{expr: "UNSET", expected: false},
+		{expr: "!UNSET", expected: true},
+		{expr: "!ENABLE_GPU", expected: false},
+		{expr: "ENABLE_GPU==1", expected: true},
+		{expr: "MODE == release", expected: true},
+		{expr: "MODE==debug", expected: false},
+		{expr: "UNSET==", expected: false},
+		{expr: "EMPTY==", expected: true},
+		{expr: "MODE!=debug", expected: true},
+		{expr: "UNSET!=debug", expected: true},
+		{expr: "MODE!=release", expected: false},
+		{expr: "==1", expectError: true},
+		{expr: "!", expectError: true},
+		{expr: "A B", expectError: true},
+		{expr: "!!CI", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			met, err := evaluateCondition(tc.expr, lookup)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+			if met != tc.expected {
+				t.Errorf("Expected %v but got %v", tc.expected, met)
+			}
+		})
+	}
+}
+
+// TestConditionalDependency tests that dependencies whose condition doesn't hold are skipped
+func TestConditionalDependency(t *testing.T) {
+	t.Setenv("DEPMAN_TEST_GPU", "1")
+	t.Setenv("DEPMAN_TEST_MODE", "debug")
+
+	manager := &Manager{
+		Config: &DependencyConfig{Dependencies: []Dependency{
+			{Name: "gpu-driver", When: "DEPMAN_TEST_GPU"},
+			{Name: "profiler", When: "DEPMAN_TEST_MODE==release"},
+			{Name: "cpu-fallback", When: "!DEPMAN_TEST_GPU"},
+			{Name: "always"},
+		}},
+		logger:     &mockLogger{},
+		envManager: environment.NewManager(),
+	}
+
+	expected := map[string]bool{"gpu-driver": false, "profiler": true, "cpu-fallback": true, "always": false}
+	for i := range manager.Config.Dependencies {
+		dep := &manager.Config.Dependencies[i]
+		if skipped := manager.isSkipped(dep); skipped != expected[dep.Name] {
+			t.Errorf("Expected %s skipped to be %v but got %v", dep.Name, expected[dep.Name], skipped)
+		}
+	}
+
+	// An invalid condition is reported rather than silently skipping the dependency
+	manager.Config.Dependencies = []Dependency{{Name: "broken", When: "==1"}}
+	if manager.isSkipped(&manager.Config.Dependencies[0]) {
+		t.Errorf("Expected a dependency with an invalid condition not to be skipped")
+	}
+	if errors := manager.validateDependencies(); len(errors) != 1 {
+		t.Errorf("Expected one validation error but got %v", errors)
+	}
+}
@@ -1,18 +1,57 @@
 package depman
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
 	"fmt"
+	"io"
+	"maps"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 
 	"github.com/Masterminds/semver/v3"
+	"github.com/sobhit-avrl/depman-v1/internal/downloader"
 	"gopkg.in/yaml.v3"
 )
 
+// MaxConfigSize is the largest configuration, after decompression, that will be parsed.
+// It guards against oversized files and gzip decompression bombs.
+var MaxConfigSize int64 = 10 << 20
+
+// gzipMagic is the header that identifies gzip-compressed data
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// Supported configuration formats
+const (
+	ConfigFormatYAML = "yaml"
+	ConfigFormatJSON = "json"
+	ConfigFormatTOML = "toml"
+)
+
+// StdinConfigPath is the configuration path that reads from standard input
+const StdinConfigPath = "-"
+
 // LoadDependencyConfig loads and parses the dependency configuration file
+// The format is detected from the file extension, defaulting to YAML
 func LoadDependencyConfig(path string) (*DependencyConfig, error) {
+	return LoadDependencyConfigFormat(path, "")
+}
+
+// LoadDependencyConfigFormat loads and parses the dependency configuration file in the given
+// format, or detects it from the file extension if format is empty
+func LoadDependencyConfigFormat(path, format string) (*DependencyConfig, error) {
+	return LoadVerifiedDependencyConfig(path, format, "")
+}
+
+// LoadVerifiedDependencyConfig is LoadDependencyConfigFormat, first verifying the raw file
+// bytes (before any decompression) against a checksum in "algorithm:hash" format
+// An empty checksum skips verification; a mismatch matches ErrChecksumMismatch with errors.Is
+func LoadVerifiedDependencyConfig(path, format, checksum string) (*DependencyConfig, error) {
 	// Find the file if path is not provided
 	if path == "" {
 		var err error
@@ -21,24 +60,339 @@ func LoadDependencyConfig(path string) (*DependencyConfig, error) {
 			return nil, err
 		}
 	}
+	if format == "" {
+		format = detectConfigFormat(path)
+	}
 
-	// Read the file
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read dependency file: %w", err)
+	// Read the file, or standard input
+	var data []byte
+	if path == StdinConfigPath {
+		var err error
+		if data, err = readConfigData(os.Stdin, checksum); err != nil {
+			return nil, err
+		}
+	} else {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dependency file: %w", err)
+		}
+		defer file.Close()
+
+		if data, err = readConfigData(file, checksum); err != nil {
+			return nil, err
+		}
 	}
 
-	// Parse YAML
 	var config DependencyConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse dependency file: %w", err)
+	if err := parseConfigData(data, format, &config); err != nil {
+		return nil, err
+	}
+
+	// Reject newer schemas and upgrade older ones in memory
+	outdated, err := checkConfigVersion(config.Version)
+	if err != nil {
+		return nil, err
+	}
+	if outdated {
+		if data, err = MigrateConfigData(data, config.Version, CurrentConfigVersion); err != nil {
+			return nil, err
+		}
+		config = DependencyConfig{}
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse migrated dependency file: %w", err)
+		}
+	}
+
+	config.applyDefaults()
+
+	if err := config.resolveURLs(); err != nil {
+		return nil, err
 	}
 
 	return &config, nil
 }
 
+// applyDefaults merges the defaults section into every dependency
+// Anything a dependency sets itself takes precedence over the defaults, and default platforms
+// only fill in platforms a dependency declares, never adding support for new ones
+func (c *DependencyConfig) applyDefaults() {
+	d := c.Defaults
+	defaultPlatforms := Dependency{Platforms: d.Platforms}
+	for i := range c.Dependencies {
+		dep := &c.Dependencies[i]
+
+		// The dependency's own commands win over default platform commands,
+		// which in turn win over the default commands
+		for platform, config := range dep.Platforms {
+			defaults, ok := defaultPlatforms.PlatformConfigFor(platform)
+			if !ok {
+				continue
+			}
+			defaults.Commands = mergeCommands(defaults.Commands, dep.Commands)
+			dep.Platforms[platform] = mergePlatformConfig(defaults, config)
+		}
+		dep.Commands = mergeCommands(d.Commands, dep.Commands)
+
+		if len(dep.Environment.Path) == 0 {
+			dep.Environment.Path = d.Environment.Path
+		}
+		for key, value := range d.Environment.Variables {
+			if _, ok := dep.Environment.Variables[key]; ok {
+				continue
+			}
+			if dep.Environment.Variables == nil {
+				dep.Environment.Variables = make(map[string]string)
+			}
+			dep.Environment.Variables[key] = value
+		}
+
+		if len(dep.Tags) == 0 {
+			dep.Tags = d.Tags
+		}
+	}
+}
+
+// mergePlatformConfig returns the override platform configuration, using defaults for any empty fields
+func mergePlatformConfig(defaults, override PlatformConfig) PlatformConfig {
+	if override.Installer.Type == "" {
+		override.Installer.Type = defaults.Installer.Type
+	}
+	if override.Installer.Timeout == 0 {
+		override.Installer.Timeout = defaults.Installer.Timeout
+	}
+	if override.Installer.MaxRetries == nil {
+		override.Installer.MaxRetries = defaults.Installer.MaxRetries
+	}
+	if override.Installer.MaxRedirects == 0 {
+		override.Installer.MaxRedirects = defaults.Installer.MaxRedirects
+	}
+	override.Commands = mergeCommands(defaults.Commands, override.Commands)
+	if override.InstallDir == "" {
+		override.InstallDir = defaults.InstallDir
+	}
+	if len(override.Symlinks) == 0 {
+		override.Symlinks = maps.Clone(defaults.Symlinks)
+	}
+	if override.RunAs == "" {
+		override.RunAs = defaults.RunAs
+	}
+	if len(override.Environment.Path) == 0 && len(override.Environment.Variables) == 0 {
+		override.Environment = defaults.Environment
+	}
+
+	return override
+}
+
+// resolveURLs resolves relative installer and checksum URLs against the base URL
+// Absolute URLs are left unchanged, as are all URLs when no base URL is set
+func (c *DependencyConfig) resolveURLs() error {
+	if c.BaseURL == "" {
+		return nil
+	}
+
+	base, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return fmt.Errorf("invalid base_url '%s': %w", c.BaseURL, err)
+	}
+	if !base.IsAbs() {
+		return fmt.Errorf("base_url '%s' must be an absolute URL", c.BaseURL)
+	}
+
+	resolve := func(dep, raw string) (string, error) {
+		if raw == "" {
+			return "", nil
+		}
+		ref, err := url.Parse(raw)
+		if err != nil {
+			return "", fmt.Errorf("dependency '%s' has an invalid URL '%s': %w", dep, raw, err)
+		}
+		return base.ResolveReference(ref).String(), nil
+	}
+
+	for i := range c.Dependencies {
+		dep := &c.Dependencies[i]
+		for name, platform := range dep.Platforms {
+			if platform.Installer.URL, err = resolve(dep.Name, platform.Installer.URL); err != nil {
+				return err
+			}
+			if platform.Installer.ChecksumURL, err = resolve(dep.Name, platform.Installer.ChecksumURL); err != nil {
+				return err
+			}
+			dep.Platforms[name] = platform
+		}
+	}
+
+	return nil
+}
+
+// detectConfigFormat returns the configuration format implied by the file extension
+// Compressed files are detected by the extension before ".gz"
+func detectConfigFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(strings.TrimSuffix(path, ".gz"))) {
+	case ".json":
+		return ConfigFormatJSON
+	case ".toml":
+		return ConfigFormatTOML
+	default:
+		return ConfigFormatYAML
+	}
+}
+
+// parseConfigData parses configuration data in the given format into config
+func parseConfigData(data []byte, format string, config *DependencyConfig) error {
+	switch format {
+	case ConfigFormatYAML:
+	case ConfigFormatJSON:
+		// JSON is a subset of YAML, so once it is known to be valid JSON the YAML
+		// parser and field names apply
+		if err := json.Unmarshal(data, new(any)); err != nil {
+			return fmt.Errorf("failed to parse dependency file as JSON: %w", err)
+		}
+	case ConfigFormatTOML:
+		return fmt.Errorf("TOML dependency files are not supported yet")
+	default:
+		return fmt.Errorf("unsupported config format '%s' (expected %s, %s or %s)",
+			format, ConfigFormatYAML, ConfigFormatJSON, ConfigFormatTOML)
+	}
+
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return fmt.Errorf("failed to parse dependency file: %w", err)
+	}
+	return nil
+}
+
+// readConfigData reads configuration data, transparently decompressing gzip input.
+// An error is returned if the (uncompressed) data exceeds MaxConfigSize, or if the raw data
+// doesn't match the checksum, if one is given.
+func readConfigData(r io.Reader, checksum string) ([]byte, error) {
+	data, err := readLimited(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dependency file: %w", err)
+	}
+
+	if checksum != "" {
+		if err := downloader.VerifyData(data, checksum); err != nil {
+			return nil, fmt.Errorf("dependency file failed checksum verification: %w", err)
+		}
+	}
+
+	if !bytes.HasPrefix(data, gzipMagic) {
+		return data, nil
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress dependency file: %w", err)
+	}
+	defer gz.Close()
+
+	data, err = readLimited(gz)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress dependency file: %w", err)
+	}
+	return data, nil
+}
+
+// readLimited reads all of r, failing once more than MaxConfigSize bytes have been read
+func readLimited(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxConfigSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > MaxConfigSize {
+		return nil, fmt.Errorf("configuration exceeds the maximum size of %d bytes", MaxConfigSize)
+	}
+	return data, nil
+}
+
+// LoadDependencyConfigs loads every configuration file matching the glob pattern and merges
+// their dependency lists into a single configuration. A dependency defined in more than one
+// file must have the same version requirements in each, otherwise an error is returned.
+func LoadDependencyConfigs(pattern string) (*DependencyConfig, error) {
+	return loadDependencyConfigs(pattern, "")
+}
+
+// loadDependencyConfigs merges the files matching pattern, parsing each in the given format
+// or the one detected from its extension
+func loadDependencyConfigs(pattern, format string) (*DependencyConfig, error) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config glob '%s': %w", pattern, err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no dependency configuration files match '%s'", pattern)
+	}
+	sort.Strings(paths)
+
+	merged := &DependencyConfig{}
+	sources := make(map[string]string) // dependency name -> file that defined it
+
+	for _, path := range paths {
+		config, err := LoadDependencyConfigFormat(path, format)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		// The first file provides the top-level metadata
+		if merged.Version == "" {
+			merged.Version = config.Version
+			merged.Name = config.Name
+			merged.Description = config.Description
+		}
+
+		for _, dep := range config.Dependencies {
+			source, exists := sources[dep.Name]
+			if !exists {
+				sources[dep.Name] = path
+				merged.Dependencies = append(merged.Dependencies, dep)
+				continue
+			}
+
+			// Same dependency defined again: versions must agree
+			for _, existing := range merged.Dependencies {
+				if existing.Name == dep.Name && existing.Version != dep.Version {
+					return nil, fmt.Errorf("conflicting definitions of dependency '%s': %s requires %s, %s requires %s",
+						dep.Name, source, formatVersion(existing.Version), path, formatVersion(dep.Version))
+				}
+			}
+		}
+	}
+
+	return merged, nil
+}
+
+// JSON encodes the configuration as indented JSON, with the same field names as the
+// YAML configuration and map keys sorted so the output is stable across runs
+func (c *DependencyConfig) JSON() ([]byte, error) {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode configuration: %w", err)
+	}
+
+	// Round-trip through a generic document so the YAML field names are used
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to encode configuration: %w", err)
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// formatVersion returns a human-readable form of a version requirement
+func formatVersion(v Version) string {
+	if v.Constraint != "" {
+		return fmt.Sprintf("%s (%s)", v.Required, v.Constraint)
+	}
+	return v.Required
+}
+
 // FindDependencyFile looks for the app-dependencies.yml file in standard locations
 func FindDependencyFile(customPath string) (string, error) {
+	// Standard input has no file to find
+	if customPath == StdinConfigPath {
+		return customPath, nil
+	}
+
 	// If a custom path is provided, check it first
 	if customPath != "" {
 		if _, err := os.Stat(customPath); err == nil {
@@ -92,8 +446,8 @@ func CheckVersionUpdate(currentVersion, requiredVersion string) (UpdateType, err
 		return NoUpdate, fmt.Errorf("invalid required version '%s': %w", requiredVersion, err)
 	}
 
-	// Compare versions
-	if current.Equal(required) {
+	// Nothing to update when the current version is the required one or newer
+	if current.Compare(required) >= 0 {
 		return NoUpdate, nil
 	}
 
@@ -106,7 +460,7 @@ func CheckVersionUpdate(currentVersion, requiredVersion string) (UpdateType, err
 		return PatchUpdate, nil
 	}
 
-	// Current version is newer than required
+	// Only the prerelease differs, which is not treated as an update
 	return NoUpdate, nil
 }
 
@@ -1,8 +1,15 @@
 package depman
 
 import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -67,6 +74,12 @@ func TestFindDependencyFile(t *testing.T) {
 			expectError:  false,
 			expectedPath: filepath.Join("config", "app-dependencies.yml"),
 		},
+		{
+			name:         "Stdin is used as is",
+			customPath:   "-",
+			expectError:  false,
+			expectedPath: "-",
+		},
 		{
 			name:         "Error on non-existent file",
 			customPath:   "not-exists.yml",
@@ -150,6 +163,19 @@ dependencies:
 		t.Fatalf("Failed to create invalid test file: %v", err)
 	}
 
+	// Create a gzipped copy of the valid file
+	gzipFile := filepath.Join(tempDir, "valid.yml.gz")
+	if err := os.WriteFile(gzipFile, gzipData(t, []byte(validYAML)), 0644); err != nil {
+		t.Fatalf("Failed to create gzipped test file: %v", err)
+	}
+
+	// Create a small gzip file that expands past the size limit
+	bombFile := filepath.Join(tempDir, "bomb.yml.gz")
+	bomb := bytes.Repeat([]byte("#"), int(MaxConfigSize)+1)
+	if err := os.WriteFile(bombFile, gzipData(t, bomb), 0644); err != nil {
+		t.Fatalf("Failed to create bomb test file: %v", err)
+	}
+
 	// Test cases
 	testCases := []struct {
 		name        string
@@ -163,6 +189,18 @@ dependencies:
 			expectError: false,
 			appName:     "Test App",
 		},
+		{
+			name:        "Load gzipped config",
+			path:        gzipFile,
+			expectError: false,
+			appName:     "Test App",
+		},
+		{
+			name:        "Error on decompression bomb",
+			path:        bombFile,
+			expectError: true,
+			appName:     "",
+		},
 		{
 			name:        "Error on invalid config",
 			path:        invalidFile,
@@ -201,3 +239,592 @@ dependencies:
 		})
 	}
 }
+
+// gzipData compresses data with gzip
+func gzipData(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatalf("Failed to compress data: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("Failed to compress data: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestLoadDependencyConfigStdin(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	defer reader.Close()
+
+	original := os.Stdin
+	os.Stdin = reader
+	defer func() { os.Stdin = original }()
+
+	// Pipe a generated manifest in, as a CI step would
+	go func() {
+		writer.Write([]byte("version: \"1.0\"\nname: \"Piped App\"\ndependencies:\n  - name: \"tool\"\n    version:\n      required: \"2.0.0\"\n"))
+		writer.Close()
+	}()
+
+	config, err := LoadDependencyConfig(StdinConfigPath)
+	if err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+	if config.Name != "Piped App" {
+		t.Errorf("Expected name Piped App but got %s", config.Name)
+	}
+	if len(config.Dependencies) != 1 || config.Dependencies[0].Version.Required != "2.0.0" {
+		t.Errorf("Expected tool 2.0.0 but got %+v", config.Dependencies)
+	}
+}
+
+func TestLoadDependencyConfigs(t *testing.T) {
+	writeManifest := func(t *testing.T, dir, service, body string) {
+		serviceDir := filepath.Join(dir, service)
+		if err := os.MkdirAll(serviceDir, 0755); err != nil {
+			t.Fatalf("Failed to create service directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(serviceDir, "app-dependencies.yml"), []byte(body), 0644); err != nil {
+			t.Fatalf("Failed to create manifest: %v", err)
+		}
+	}
+
+	apiYAML := `
+version: "1.0"
+name: "API"
+dependencies:
+  - name: "go"
+    version:
+      required: "1.22.0"
+  - name: "protoc"
+    version:
+      required: "25.1.0"
+`
+
+	// Test merging two manifests that share an identical dependency
+	t.Run("Merge cleanly", func(t *testing.T) {
+		tempDir := t.TempDir()
+		writeManifest(t, tempDir, "api", apiYAML)
+		writeManifest(t, tempDir, "worker", `
+version: "1.0"
+name: "Worker"
+dependencies:
+  - name: "go"
+    version:
+      required: "1.22.0"
+  - name: "redis"
+    version:
+      required: "7.2.0"
+`)
+
+		config, err := LoadDependencyConfigs(filepath.Join(tempDir, "*", "app-dependencies.yml"))
+		if err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+
+		var names []string
+		for _, dep := range config.Dependencies {
+			names = append(names, dep.Name)
+		}
+		if strings.Join(names, ",") != "go,protoc,redis" {
+			t.Errorf("Expected merged dependencies go,protoc,redis but got %v", names)
+		}
+	})
+
+	// Test two manifests that require different versions of the same dependency
+	t.Run("Conflicting versions", func(t *testing.T) {
+		tempDir := t.TempDir()
+		writeManifest(t, tempDir, "api", apiYAML)
+		writeManifest(t, tempDir, "worker", `
+version: "1.0"
+name: "Worker"
+dependencies:
+  - name: "go"
+    version:
+      required: "1.21.0"
+`)
+
+		_, err := LoadDependencyConfigs(filepath.Join(tempDir, "*", "app-dependencies.yml"))
+		if err == nil {
+			t.Fatalf("Expected an error but got none")
+		}
+		if !strings.Contains(err.Error(), "conflicting definitions of dependency 'go'") {
+			t.Errorf("Expected conflict error but got: %v", err)
+		}
+	})
+
+	// Test a glob that matches nothing
+	t.Run("No matches", func(t *testing.T) {
+		if _, err := LoadDependencyConfigs(filepath.Join(t.TempDir(), "*.yml")); err == nil {
+			t.Errorf("Expected an error but got none")
+		}
+	})
+}
+
+func TestConfigFormat(t *testing.T) {
+	yamlConfig := "version: \"1.0\"\nname: \"Format App\"\ndependencies:\n  - name: \"tool\"\n    version:\n      required: \"1.0.0\"\n"
+	jsonConfig := `{"version": "1.0", "name": "Format App", "dependencies": [{"name": "tool", "version": {"required": "1.0.0"}}]}`
+	tomlConfig := "version = \"1.0\"\nname = \"Format App\"\n\n[[dependencies]]\nname = \"tool\"\n"
+
+	testCases := []struct {
+		name        string
+		file        string
+		content     string
+		format      string
+		expectError string
+	}{
+		{name: "Forced YAML without extension", file: "deps", content: yamlConfig, format: ConfigFormatYAML},
+		{name: "Forced JSON without extension", file: "deps", content: jsonConfig, format: ConfigFormatJSON},
+		{name: "Forced TOML without extension", file: "deps", content: tomlConfig, format: ConfigFormatTOML, expectError: "TOML dependency files are not supported"},
+		{name: "Forced format overrides extension", file: "deps.toml", content: yamlConfig, format: ConfigFormatYAML},
+		{name: "Detected JSON", file: "deps.json", content: jsonConfig},
+		{name: "Detected compressed JSON extension", file: "deps.json.gz", content: jsonConfig},
+		{name: "Detected TOML", file: "deps.toml", content: tomlConfig, expectError: "TOML dependency files are not supported"},
+		{name: "Forced JSON rejects YAML", file: "deps", content: yamlConfig, format: ConfigFormatJSON, expectError: "failed to parse dependency file as JSON"},
+		{name: "Unknown format", file: "deps", content: yamlConfig, format: "ini", expectError: "unsupported config format 'ini'"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.file)
+			if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			manager, err := NewManager(path, WithConfigFormat(tc.format), WithLogger(&mockLogger{}))
+			if tc.expectError != "" {
+				if err == nil {
+					t.Fatalf("Expected an error but got none")
+				}
+				if !strings.Contains(err.Error(), tc.expectError) {
+					t.Errorf("Expected error containing %q but got: %v", tc.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			config := manager.Config
+			if config.Name != "Format App" || len(config.Dependencies) != 1 || config.Dependencies[0].Version.Required != "1.0.0" {
+				t.Errorf("Expected the Format App configuration but got %+v", config)
+			}
+		})
+	}
+
+	// Test reading the configuration from standard input
+	t.Run("Stdin", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "stdin")
+		if err := os.WriteFile(path, []byte(jsonConfig), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		stdin, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("Failed to open config: %v", err)
+		}
+		defer stdin.Close()
+
+		original := os.Stdin
+		os.Stdin = stdin
+		defer func() { os.Stdin = original }()
+
+		config, err := LoadDependencyConfigFormat(StdinConfigPath, ConfigFormatJSON)
+		if err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+		if config.Name != "Format App" {
+			t.Errorf("Expected the Format App configuration but got %+v", config)
+		}
+	})
+}
+
+func TestConfigJSON(t *testing.T) {
+	content := `version: "1.0"
+name: "JSON App"
+dependencies:
+  - name: "runtime"
+    version:
+      required: "2.0.0"
+    platforms:
+      linux:
+        commands:
+          verify: ["runtime", "--version"]
+  - name: "tool"
+    description: "Build tool"
+    version:
+      required: "1.2.0"
+      constraint: "^1.2.0"
+    dependencies: ["runtime"]
+    environment:
+      variables:
+        TOOL_MODE: "fast"
+        TOOL_HOME: "/opt/tool"
+    platforms:
+      windows:
+        commands:
+          verify: ["tool.exe", "--version"]
+      linux:
+        installer:
+          url: "https://example.com/tool.tar.gz"
+        commands:
+          verify: ["tool", "--version"]
+`
+	path := filepath.Join(t.TempDir(), "app-dependencies.yml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	config, err := LoadDependencyConfig(path)
+	if err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+
+	data, err := config.JSON()
+	if err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+
+	var doc struct {
+		Name         string `json:"name"`
+		Dependencies []struct {
+			Name         string   `json:"name"`
+			Dependencies []string `json:"dependencies"`
+			Version      struct {
+				Required   string `json:"required"`
+				Constraint string `json:"constraint"`
+			} `json:"version"`
+			Environment struct {
+				Variables map[string]string `json:"variables"`
+			} `json:"environment"`
+			Platforms map[string]struct {
+				Installer struct {
+					URL string `json:"url"`
+				} `json:"installer"`
+				Commands struct {
+					Verify []string `json:"verify"`
+				} `json:"commands"`
+			} `json:"platforms"`
+		} `json:"dependencies"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, data)
+	}
+
+	if doc.Name != "JSON App" || len(doc.Dependencies) != 2 {
+		t.Fatalf("Expected the JSON App configuration but got %s", data)
+	}
+	tool := doc.Dependencies[1]
+	if tool.Name != "tool" || tool.Version.Required != "1.2.0" || tool.Version.Constraint != "^1.2.0" {
+		t.Errorf("Expected tool 1.2.0 (^1.2.0) but got %+v", tool)
+	}
+	if len(tool.Dependencies) != 1 || tool.Dependencies[0] != "runtime" {
+		t.Errorf("Expected tool to depend on runtime but got %v", tool.Dependencies)
+	}
+	if tool.Environment.Variables["TOOL_MODE"] != "fast" {
+		t.Errorf("Expected environment variables but got %v", tool.Environment.Variables)
+	}
+	if tool.Platforms["linux"].Installer.URL != "https://example.com/tool.tar.gz" || tool.Platforms["windows"].Commands.Verify[0] != "tool.exe" {
+		t.Errorf("Expected linux and windows platforms but got %+v", tool.Platforms)
+	}
+
+	// Map keys are sorted, so the output is stable
+	output := string(data)
+	if strings.Index(output, `"TOOL_HOME"`) > strings.Index(output, `"TOOL_MODE"`) ||
+		strings.Index(output, `"linux": {`) > strings.Index(output, `"windows": {`) {
+		t.Errorf("Expected sorted map keys but got %s", output)
+	}
+	again, err := config.JSON()
+	if err != nil || !bytes.Equal(data, again) {
+		t.Errorf("Expected identical output on every run")
+	}
+
+	// The configuration keeps the standard encoding for json.Marshal
+	if _, ok := any(config).(json.Marshaler); ok {
+		t.Errorf("Expected DependencyConfig not to implement json.Marshaler")
+	}
+}
+
+func TestConfigChecksum(t *testing.T) {
+	content := []byte("version: \"1.0\"\nname: \"Checksum App\"\ndependencies:\n  - name: \"tool\"\n    version:\n      required: \"1.0.0\"\n")
+	sum := sha256.Sum256(content)
+	checksum := "sha256:" + hex.EncodeToString(sum[:])
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write(content)
+	gz.Close()
+	gzSum := sha256.Sum256(compressed.Bytes())
+
+	tampered := bytes.Replace(content, []byte("1.0.0"), []byte("6.6.6"), 1)
+
+	testCases := []struct {
+		name           string
+		file           string
+		data           []byte
+		checksum       string
+		expectMismatch bool
+		expectError    bool
+	}{
+		{name: "Matching", file: "app-dependencies.yml", data: content, checksum: checksum},
+		{name: "Uppercase digest", file: "app-dependencies.yml", data: content, checksum: strings.ToUpper(checksum)},
+		{name: "Compressed file is verified as stored", file: "app-dependencies.yml.gz", data: compressed.Bytes(), checksum: "sha256:" + hex.EncodeToString(gzSum[:])},
+		{name: "Tampered", file: "app-dependencies.yml", data: tampered, checksum: checksum, expectMismatch: true},
+		{name: "Size mismatch", file: "app-dependencies.yml", data: content, checksum: "size:1", expectMismatch: true},
+		{name: "Invalid checksum", file: "app-dependencies.yml", data: content, checksum: "sha256:abc", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.file)
+			if err := os.WriteFile(path, tc.data, 0644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			manager, err := NewManager(path, WithConfigChecksum(tc.checksum), WithLogger(&mockLogger{}))
+			if tc.expectMismatch || tc.expectError {
+				if err == nil {
+					t.Fatalf("Expected an error but got none")
+				}
+				if errors.Is(err, ErrChecksumMismatch) != tc.expectMismatch {
+					t.Errorf("Expected checksum mismatch %v but got: %v", tc.expectMismatch, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+			if manager.Config.Name != "Checksum App" {
+				t.Errorf("Expected the Checksum App configuration but got %+v", manager.Config)
+			}
+		})
+	}
+
+	t.Run("Glob is refused", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "app-dependencies.yml"), content, 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		if _, err := NewManagerFromGlob(filepath.Join(dir, "*.yml"), WithConfigChecksum(checksum)); err == nil {
+			t.Errorf("Expected an error but got none")
+		}
+	})
+}
+
+func TestResolveURLs(t *testing.T) {
+	testCases := []struct {
+		name        string
+		baseURL     string
+		url         string
+		checksumURL string
+		expected    string
+		expectedSum string
+		expectError bool
+	}{
+		{
+			name:     "Relative URL with base",
+			baseURL:  "https://mirror.example.com/tools/",
+			url:      "node/node-1.0.0.tar.gz",
+			expected: "https://mirror.example.com/tools/node/node-1.0.0.tar.gz",
+		},
+		{
+			name:     "Root-relative URL with base",
+			baseURL:  "https://mirror.example.com/tools/",
+			url:      "/other/node.tar.gz",
+			expected: "https://mirror.example.com/other/node.tar.gz",
+		},
+		{
+			name:     "Absolute URL overrides base",
+			baseURL:  "https://mirror.example.com/tools/",
+			url:      "https://example.org/node.tar.gz",
+			expected: "https://example.org/node.tar.gz",
+		},
+		{
+			name:        "Checksum URL is resolved too",
+			baseURL:     "https://mirror.example.com/tools/",
+			url:         "node.tar.gz",
+			checksumURL: "node.tar.gz.sha256",
+			expected:    "https://mirror.example.com/tools/node.tar.gz",
+			expectedSum: "https://mirror.example.com/tools/node.tar.gz.sha256",
+		},
+		{
+			name:     "Relative URL without base is unchanged",
+			url:      "node.tar.gz",
+			expected: "node.tar.gz",
+		},
+		{
+			name:        "Error on relative base",
+			baseURL:     "tools/",
+			url:         "node.tar.gz",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &DependencyConfig{
+				BaseURL: tc.baseURL,
+				Dependencies: []Dependency{
+					{
+						Name: "node",
+						Platforms: map[string]PlatformConfig{
+							"linux": {Installer: Installer{URL: tc.url, ChecksumURL: tc.checksumURL}},
+						},
+					},
+				},
+			}
+
+			err := config.resolveURLs()
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			installer := config.Dependencies[0].Platforms["linux"].Installer
+			if installer.URL != tc.expected {
+				t.Errorf("Expected URL %s but got %s", tc.expected, installer.URL)
+			}
+			if installer.ChecksumURL != tc.expectedSum {
+				t.Errorf("Expected checksum URL %s but got %s", tc.expectedSum, installer.ChecksumURL)
+			}
+		})
+	}
+}
+
+// TestApplyDefaults tests merging the defaults section into dependencies
+func TestApplyDefaults(t *testing.T) {
+	configYAML := `version: "1.1"
+name: "Defaults App"
+defaults:
+  commands:
+    verify: ["default-verify"]
+  environment:
+    path: ["/opt/default/bin"]
+    variables:
+      SHARED: "default"
+      OVERRIDDEN: "default"
+  platforms:
+    linux:
+      install_dir: "/opt/default"
+      commands:
+        install: ["default-install"]
+  tags: ["build"]
+dependencies:
+  - name: "plain"
+    version:
+      required: "1.0.0"
+    platforms:
+      linux: {}
+  - name: "custom"
+    version:
+      required: "1.0.0"
+    commands:
+      verify: ["custom-verify"]
+      install: ["custom-install"]
+    environment:
+      path: ["/opt/custom/bin"]
+      variables:
+        OVERRIDDEN: "custom"
+    platforms:
+      linux:
+        install_dir: "/opt/custom"
+    tags: ["test"]
+  - name: "darwin-only"
+    version:
+      required: "1.0.0"
+    platforms:
+      darwin:
+        install_dir: "/opt/darwin"
+`
+	path := filepath.Join(t.TempDir(), "app-dependencies.yml")
+	if err := os.WriteFile(path, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	config, err := LoadDependencyConfig(path)
+	if err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+
+	testCases := []struct {
+		name       string
+		dep        Dependency
+		verify     string
+		install    string
+		installDir string
+		path       string
+		overridden string
+		tag        string
+	}{
+		{
+			name:       "Defaults apply",
+			dep:        config.Dependencies[0],
+			verify:     "default-verify",
+			install:    "default-install",
+			installDir: "/opt/default",
+			path:       "/opt/default/bin",
+			overridden: "default",
+			tag:        "build",
+		},
+		{
+			name:       "Per-dependency overrides win",
+			dep:        config.Dependencies[1],
+			verify:     "custom-verify",
+			install:    "custom-install",
+			installDir: "/opt/custom",
+			path:       "/opt/custom/bin",
+			overridden: "custom",
+			tag:        "test",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			manager := &Manager{Platform: "linux"}
+			platform, err := manager.GetPlatformConfig(&tc.dep)
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			if got := platform.Commands.Verify; len(got) != 1 || got[0] != tc.verify {
+				t.Errorf("Expected verify command %s but got %v", tc.verify, got)
+			}
+			if got := platform.Commands.Install; len(got) != 1 || got[0] != tc.install {
+				t.Errorf("Expected install command %s but got %v", tc.install, got)
+			}
+			if platform.InstallDir != tc.installDir {
+				t.Errorf("Expected install dir %s but got %s", tc.installDir, platform.InstallDir)
+			}
+			if got := tc.dep.Environment.Path; len(got) != 1 || got[0] != tc.path {
+				t.Errorf("Expected path %s but got %v", tc.path, got)
+			}
+			if got := tc.dep.Environment.Variables["SHARED"]; got != "default" {
+				t.Errorf("Expected SHARED to default to 'default' but got '%s'", got)
+			}
+			if got := tc.dep.Environment.Variables["OVERRIDDEN"]; got != tc.overridden {
+				t.Errorf("Expected OVERRIDDEN '%s' but got '%s'", tc.overridden, got)
+			}
+			if len(tc.dep.Tags) != 1 || !tc.dep.HasTag(tc.tag) {
+				t.Errorf("Expected tags [%s] but got %v", tc.tag, tc.dep.Tags)
+			}
+		})
+	}
+
+	// Default platforms don't make a dependency support platforms it doesn't declare
+	darwinOnly := config.Dependencies[2]
+	if _, ok := darwinOnly.Platforms["linux"]; ok {
+		t.Errorf("Expected darwin-only not to gain the default linux platform but got %v", darwinOnly.Platforms)
+	}
+	manager := &Manager{Platform: "linux"}
+	var unsupported *PlatformUnsupportedError
+	if _, err := manager.GetPlatformConfig(&darwinOnly); !errors.As(err, &unsupported) {
+		t.Errorf("Expected darwin-only to be unsupported on linux but got: %v", err)
+	}
+}
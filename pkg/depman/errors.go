@@ -0,0 +1,142 @@
+package depman
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sobhit-avrl/depman-v1/internal/downloader"
+)
+
+// Sentinel errors for use with errors.Is
+var (
+	ErrPlatformUnsupported = errors.New("platform unsupported")
+	ErrVerifyFailed        = errors.New("verification failed")
+	ErrConstraintViolated  = errors.New("version constraint violated")
+	ErrVersionMismatch     = errors.New("installed version mismatch")
+	ErrManualAction        = errors.New("manual action required")
+	ErrInvalidConfig       = errors.New("invalid dependency configuration")
+	ErrLocked              = errors.New("another depman process is running")
+	ErrChecksumMismatch    = downloader.ErrChecksumMismatch
+	ErrOffline             = downloader.ErrOffline
+)
+
+// ChecksumMismatchError is returned when a download doesn't match its expected checksum
+type ChecksumMismatchError = downloader.ChecksumMismatchError
+
+// PlatformUnsupportedError is returned when a dependency has no configuration for the platform
+type PlatformUnsupportedError struct {
+	Dependency string // Name of the dependency
+	Platform   string // Platform without configuration
+}
+
+func (e *PlatformUnsupportedError) Error() string {
+	return fmt.Sprintf("no configuration available for platform: %s", e.Platform)
+}
+
+// Is reports whether target is ErrPlatformUnsupported
+func (e *PlatformUnsupportedError) Is(target error) bool {
+	return target == ErrPlatformUnsupported
+}
+
+// VerifyFailedError is returned when a dependency's verify command fails
+type VerifyFailedError struct {
+	Dependency string // Name of the dependency
+	Output     string // Raw output of the verify command
+	Err        error  // Underlying command error
+	NotFound   bool   // Whether the verify executable was not found, rather than ran and failed
+}
+
+func (e *VerifyFailedError) Error() string {
+	if e.NotFound {
+		return fmt.Sprintf("dependency not installed: verify command not found: %v", e.Err)
+	}
+	return fmt.Sprintf("dependency verification failed: %v, output: %s", e.Err, e.Output)
+}
+
+// Unwrap returns the underlying command error
+func (e *VerifyFailedError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrVerifyFailed
+func (e *VerifyFailedError) Is(target error) bool {
+	return target == ErrVerifyFailed
+}
+
+// ConstraintViolatedError is returned when an installed version doesn't satisfy its constraint
+type ConstraintViolatedError struct {
+	Dependency string // Name of the dependency
+	Version    string // Installed version
+	Constraint string // Constraint that was violated
+}
+
+func (e *ConstraintViolatedError) Error() string {
+	return fmt.Sprintf("dependency '%s' version %s does not satisfy constraint %s", e.Dependency, e.Version, e.Constraint)
+}
+
+// Is reports whether target is ErrConstraintViolated
+func (e *ConstraintViolatedError) Is(target error) bool {
+	return target == ErrConstraintViolated
+}
+
+// VersionMismatchError is returned when an install produces a version older than the required one
+type VersionMismatchError struct {
+	Dependency string // Name of the dependency
+	Version    string // Installed version
+	Required   string // Required version
+}
+
+func (e *VersionMismatchError) Error() string {
+	return fmt.Sprintf("dependency '%s' installed version %s does not satisfy required %s", e.Dependency, e.Version, e.Required)
+}
+
+// Is reports whether target is ErrVersionMismatch
+func (e *VersionMismatchError) Is(target error) bool {
+	return target == ErrVersionMismatch
+}
+
+// ManualActionError is returned when a dependency that ensure never installs is missing or outdated
+type ManualActionError struct {
+	Dependency string // Name of the dependency
+	Mode       string // Install mode (check-only or manual)
+	Reason     string // What is wrong, e.g. "not installed"
+}
+
+func (e *ManualActionError) Error() string {
+	return fmt.Sprintf("dependency '%s' is %s, manual action required (install mode %s)", e.Dependency, e.Reason, e.Mode)
+}
+
+// Is reports whether target is ErrManualAction
+func (e *ManualActionError) Is(target error) bool {
+	return target == ErrManualAction
+}
+
+// ValidationError is returned when the configuration fails validation, collecting every problem
+// found so they can be reported together
+type ValidationError struct {
+	errs []error // Problems found, in the order they were detected
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("dependency configuration errors: %s", strings.Join(messages, "; "))
+}
+
+// Errors returns the individual validation errors
+func (e *ValidationError) Errors() []error {
+	return e.errs
+}
+
+// Unwrap returns the individual validation errors, for errors.Is and errors.As
+func (e *ValidationError) Unwrap() []error {
+	return e.errs
+}
+
+// Is reports whether target is ErrInvalidConfig
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidConfig
+}
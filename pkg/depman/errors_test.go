@@ -0,0 +1,296 @@
+package depman
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sobhit-avrl/depman-v1/internal/environment"
+)
+
+// TestStructuredErrors tests extracting details from typed errors
+func TestStructuredErrors(t *testing.T) {
+	t.Run("Platform unsupported", func(t *testing.T) {
+		manager := &Manager{Platform: "plan9", logger: &mockLogger{}}
+		_, err := manager.GetPlatformConfig(&Dependency{Name: "tool", Platforms: map[string]PlatformConfig{"linux": {}}})
+
+		var platformErr *PlatformUnsupportedError
+		if !errors.As(err, &platformErr) {
+			t.Fatalf("Expected a PlatformUnsupportedError but got: %v", err)
+		}
+		if platformErr.Dependency != "tool" || platformErr.Platform != "plan9" {
+			t.Errorf("Unexpected error details: %+v", platformErr)
+		}
+		if !errors.Is(err, ErrPlatformUnsupported) {
+			t.Errorf("Expected errors.Is to match ErrPlatformUnsupported")
+		}
+	})
+
+	t.Run("Checksum mismatch", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("artifact"))
+		}))
+		defer server.Close()
+
+		expected := strings.Repeat("0", 64)
+		dep := &Dependency{
+			Name: "tool",
+			Platforms: map[string]PlatformConfig{
+				runtime.GOOS: {
+					Installer: Installer{URL: server.URL + "/tool.tar.gz", Checksum: "sha256:" + expected},
+				},
+			},
+		}
+
+		manager := &Manager{Platform: runtime.GOOS, logger: &mockLogger{}}
+		_, err := manager.installDependency(context.Background(), dep)
+
+		var mismatch *ChecksumMismatchError
+		if !errors.As(err, &mismatch) {
+			t.Fatalf("Expected a ChecksumMismatchError but got: %v", err)
+		}
+		if mismatch.Expected != expected || mismatch.Actual != "c7c5c1d70c5dec4416ab6158afd0b223ef40c29b1dc1f97ed9428b94d4cadb1c" {
+			t.Errorf("Unexpected error details: %+v", mismatch)
+		}
+		if !errors.Is(err, ErrChecksumMismatch) {
+			t.Errorf("Expected errors.Is to match ErrChecksumMismatch")
+		}
+	})
+
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	t.Run("Verify failed", func(t *testing.T) {
+		dep := &Dependency{
+			Name: "tool",
+			Platforms: map[string]PlatformConfig{
+				runtime.GOOS: {Commands: Commands{Verify: []string{"sh", "-c", "echo boom; exit 2"}}},
+			},
+		}
+
+		manager := &Manager{Platform: runtime.GOOS, logger: &mockLogger{}}
+		_, err := manager.VerifyDependency(dep)
+
+		var verifyErr *VerifyFailedError
+		if !errors.As(err, &verifyErr) {
+			t.Fatalf("Expected a VerifyFailedError but got: %v", err)
+		}
+		if verifyErr.Dependency != "tool" || verifyErr.Output != "boom" {
+			t.Errorf("Unexpected error details: %+v", verifyErr)
+		}
+		if !errors.Is(err, ErrVerifyFailed) {
+			t.Errorf("Expected errors.Is to match ErrVerifyFailed")
+		}
+
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 2 {
+			t.Errorf("Expected the underlying exit error to be reachable but got: %v", err)
+		}
+	})
+
+	t.Run("Verify executable missing vs failing", func(t *testing.T) {
+		testCases := []struct {
+			name           string
+			verify         []string
+			expectNotFound bool
+		}{
+			{name: "Missing from PATH", verify: []string{"depman-no-such-tool", "--version"}, expectNotFound: true},
+			{name: "Missing absolute path", verify: []string{"/nonexistent/depman-tool", "--version"}, expectNotFound: true},
+			{name: "Ran and failed", verify: []string{"sh", "-c", "exit 1"}, expectNotFound: false},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				dep := &Dependency{
+					Name: "tool",
+					Platforms: map[string]PlatformConfig{
+						runtime.GOOS: {Commands: Commands{Verify: tc.verify}},
+					},
+				}
+
+				log := &mockLogger{}
+				manager := &Manager{Platform: runtime.GOOS, logger: log}
+				WithVerifyCommandRetries(2, time.Millisecond)(manager)
+
+				status, err := manager.VerifyDependency(dep)
+
+				var verifyErr *VerifyFailedError
+				if !errors.As(err, &verifyErr) {
+					t.Fatalf("Expected a VerifyFailedError but got: %v", err)
+				}
+				if verifyErr.NotFound != tc.expectNotFound {
+					t.Errorf("Expected NotFound %v but got %v", tc.expectNotFound, verifyErr.NotFound)
+				}
+				if status.Installed {
+					t.Errorf("Expected dependency to be reported as not installed")
+				}
+				if status.Broken == tc.expectNotFound {
+					t.Errorf("Expected Broken %v but got %v", !tc.expectNotFound, status.Broken)
+				}
+
+				// A missing executable is never retried
+				if tc.expectNotFound && len(log.debugLogs) > 0 {
+					t.Errorf("Expected no retries but got: %v", log.debugLogs)
+				}
+			})
+		}
+	})
+
+	t.Run("Constraint violated", func(t *testing.T) {
+		manager := &Manager{
+			Config: &DependencyConfig{
+				Dependencies: []Dependency{
+					{
+						Name:    "tool",
+						Version: Version{Required: "1.0.0", Constraint: ">=2.0.0"},
+						Platforms: map[string]PlatformConfig{
+							runtime.GOOS: {
+								Commands: Commands{
+									Install: []string{"true"},
+									Verify:  []string{"echo", "1.0.0"},
+								},
+							},
+						},
+					},
+				},
+			},
+			Platform:   runtime.GOOS,
+			logger:     &mockLogger{},
+			envManager: environment.NewManager(),
+		}
+
+		_, _, err := manager.EnsureDependencies()
+
+		var constraintErr *ConstraintViolatedError
+		if !errors.As(err, &constraintErr) {
+			t.Fatalf("Expected a ConstraintViolatedError but got: %v", err)
+		}
+		if constraintErr.Version != "1.0.0" || constraintErr.Constraint != ">=2.0.0" {
+			t.Errorf("Unexpected error details: %+v", constraintErr)
+		}
+		if !errors.Is(err, ErrConstraintViolated) {
+			t.Errorf("Expected errors.Is to match ErrConstraintViolated")
+		}
+	})
+
+	t.Run("Installed version mismatch", func(t *testing.T) {
+		testCases := []struct {
+			name      string
+			installed string
+			optional  bool
+			expectErr bool
+		}{
+			{name: "Older than required", installed: "1.0.0", expectErr: true},
+			{name: "Matches required", installed: "2.0.0", expectErr: false},
+			{name: "Newer than required", installed: "2.1.0", expectErr: false},
+			{name: "Optional older than required", installed: "1.0.0", optional: true, expectErr: false},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				// The fake installer leaves a marker so the first verify fails and the
+				// post-install verify reports the installed version
+				marker := t.TempDir() + "/installed"
+				manager := &Manager{
+					Config: &DependencyConfig{
+						Dependencies: []Dependency{
+							{
+								Name:     "tool",
+								Optional: tc.optional,
+								Version:  Version{Required: "2.0.0"},
+								Platforms: map[string]PlatformConfig{
+									runtime.GOOS: {
+										Commands: Commands{
+											Install: []string{"touch", marker},
+											Verify:  []string{"sh", "-c", "test -f " + marker + " && echo " + tc.installed},
+										},
+									},
+								},
+							},
+						},
+					},
+					Platform:   runtime.GOOS,
+					logger:     &mockLogger{},
+					envManager: environment.NewManager(),
+				}
+
+				statuses, _, err := manager.EnsureDependencies()
+
+				var mismatchErr *VersionMismatchError
+				if tc.expectErr {
+					if !errors.As(err, &mismatchErr) {
+						t.Fatalf("Expected a VersionMismatchError but got: %v", err)
+					}
+					if mismatchErr.Version != tc.installed || mismatchErr.Required != "2.0.0" {
+						t.Errorf("Unexpected error details: %+v", mismatchErr)
+					}
+					if !errors.Is(err, ErrVersionMismatch) {
+						t.Errorf("Expected errors.Is to match ErrVersionMismatch")
+					}
+					if !strings.Contains(err.Error(), "installed version 1.0.0 does not satisfy required 2.0.0") {
+						t.Errorf("Unexpected error message: %v", err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Did not expect an error but got: %v", err)
+				}
+
+				// Optional dependencies keep the mismatch on their status
+				if tc.optional && !errors.As(statuses["tool"].Error, &mismatchErr) {
+					t.Errorf("Expected the status to record a VersionMismatchError but got: %v", statuses["tool"].Error)
+				}
+			})
+		}
+	})
+}
+
+// TestValidationError tests pulling the individual problems out of a failed validation
+func TestValidationError(t *testing.T) {
+	manager := &Manager{
+		Config: &DependencyConfig{Dependencies: []Dependency{
+			{Name: "tool", Version: Version{Required: "1.0.0"}, Platforms: map[string]PlatformConfig{"linux": {Commands: testCommands}}},
+			{Name: "tool", Version: Version{Required: "1.0.0"}, Platforms: map[string]PlatformConfig{"linux": {Commands: testCommands}}},
+			{Name: "app", Version: Version{Required: "1.0.0"}, Platforms: map[string]PlatformConfig{"windows": {Commands: testCommands}}},
+		}},
+		Platform:   "linux",
+		logger:     &mockLogger{},
+		envManager: environment.NewManager(),
+	}
+
+	_, checkErr := manager.CheckAllDependencies()
+	_, _, ensureErr := manager.EnsureDependencies()
+
+	for name, err := range map[string]error{"Check": checkErr, "Ensure": ensureErr} {
+		t.Run(name, func(t *testing.T) {
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Expected a ValidationError but got: %v", err)
+			}
+			if !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("Expected errors.Is to match ErrInvalidConfig")
+			}
+
+			expected := []string{
+				"dependency #2 has duplicate name 'tool'",
+				"dependency 'app' has no configuration for platform 'linux'",
+			}
+			problems := validationErr.Errors()
+			if len(problems) != len(expected) {
+				t.Fatalf("Expected %d errors but got %d: %v", len(expected), len(problems), problems)
+			}
+			for i, problem := range problems {
+				if problem.Error() != expected[i] {
+					t.Errorf("Expected error %q but got %q", expected[i], problem)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package depman
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// moveFile moves src to dst, copying when a rename is not possible (e.g. across devices)
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if err := copyFile(src, dst, info.Mode()); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// copyFile copies the contents of src to a new file at dst
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it into place,
+// so concurrent readers and writers never see a partially written file
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// installMarker is the file marking a directory as created by an atomic install, which a later
+// atomic install may replace as a whole
+const installMarker = ".depman-install"
+
+// rename is os.Rename, replaceable in tests to simulate failures
+var rename = os.Rename
+
+// checkOwnedDir returns an error unless dir may be replaced by an atomic install: it doesn't
+// exist, is empty, or was created by a previous atomic install, so a shared directory such as
+// /usr/local/bin never has its unrelated files deleted
+func checkOwnedDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && len(entries) == 0) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, installMarker)); err != nil {
+		return fmt.Errorf("atomic install would replace %s, which has files not installed by depman; use a dedicated install_dir or remove its contents", dir)
+	}
+	return nil
+}
+
+// swapDir replaces dst with the staged directory, keeping the previous dst aside until the
+// new one is in place so it can be restored if the swap fails
+// The staged directory is first moved next to dst, copying it if it's on another device, so
+// the swap itself is a rename on the same file system
+func swapDir(staged, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create parent directory: %w", err)
+	}
+
+	next := dst + ".staging"
+	if err := os.RemoveAll(next); err != nil {
+		return err
+	}
+	if err := rename(staged, next); err != nil {
+		if err := copyDir(staged, next); err != nil {
+			os.RemoveAll(next)
+			return fmt.Errorf("failed to copy staged install: %w", err)
+		}
+	}
+
+	previous := dst + ".previous"
+	if err := os.RemoveAll(previous); err != nil {
+		os.RemoveAll(next)
+		return err
+	}
+	_, err := os.Lstat(dst)
+	hasPrevious := err == nil
+	if hasPrevious {
+		if err := rename(dst, previous); err != nil {
+			os.RemoveAll(next)
+			return fmt.Errorf("failed to move previous install aside: %w", err)
+		}
+	}
+
+	if err := rename(next, dst); err != nil {
+		os.RemoveAll(next)
+		if hasPrevious {
+			if restoreErr := rename(previous, dst); restoreErr != nil {
+				return fmt.Errorf("failed to move staged install into place: %w (previous install left at %s: %v)",
+					err, previous, restoreErr)
+			}
+		}
+		return fmt.Errorf("failed to move staged install into place: %w", err)
+	}
+
+	if hasPrevious {
+		return os.RemoveAll(previous)
+	}
+	return nil
+}
+
+// copyDir recursively copies the directory src to dst, preserving modes and symlinks
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		default:
+			return copyFile(path, target, info.Mode().Perm())
+		}
+	})
+}
@@ -0,0 +1,306 @@
+package depman
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Graph represents the dependency graph built from the configuration
+type Graph struct {
+	Nodes      []string            // Dependency names in configuration order
+	Edges      map[string][]string // Adjacency list: dependency -> dependencies it requires
+	Priorities map[string]int      // Install priority of each dependency (lower first)
+}
+
+// BuildGraph builds the dependency graph from each Dependency.Dependencies list
+// It returns an error if a dependency references an unknown name or if a cycle is found
+func (m *Manager) BuildGraph() (*Graph, error) {
+	if m.Config == nil {
+		return nil, fmt.Errorf("no dependency configuration loaded")
+	}
+
+	graph := &Graph{
+		Nodes:      make([]string, 0, len(m.Config.Dependencies)),
+		Edges:      make(map[string][]string),
+		Priorities: make(map[string]int),
+	}
+
+	// Register all nodes first so references can be checked
+	known := make(map[string]bool)
+	for _, dep := range m.Config.Dependencies {
+		if known[dep.Name] {
+			return nil, fmt.Errorf("duplicate dependency name '%s'", dep.Name)
+		}
+		known[dep.Name] = true
+		graph.Nodes = append(graph.Nodes, dep.Name)
+		graph.Priorities[dep.Name] = dep.Priority
+	}
+
+	// Add edges, resolving names satisfied through Provides to their provider
+	for _, dep := range m.Config.Dependencies {
+		for _, required := range dep.Dependencies {
+			provider := m.resolveProvider(required)
+			if provider == "" {
+				return nil, fmt.Errorf("dependency '%s' depends on unknown dependency '%s'", dep.Name, required)
+			}
+			graph.Edges[dep.Name] = append(graph.Edges[dep.Name], provider)
+		}
+	}
+
+	// Reject cycles
+	if cycle := graph.FindCycle(); cycle != nil {
+		return graph, fmt.Errorf("dependency cycle detected: %s", strings.Join(cycle, " -> "))
+	}
+
+	return graph, nil
+}
+
+// Dependents returns the dependencies that list name in their Dependencies, either by its own
+// name or through a name it provides
+func (m *Manager) Dependents(name string) ([]string, error) {
+	return m.dependents(name, false)
+}
+
+// TransitiveDependents returns every dependency that requires name directly or indirectly
+func (m *Manager) TransitiveDependents(name string) ([]string, error) {
+	return m.dependents(name, true)
+}
+
+// dependents looks up the dependents of name in the dependency graph
+func (m *Manager) dependents(name string, recursive bool) ([]string, error) {
+	graph, err := m.BuildGraph()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build dependency graph: %w", err)
+	}
+
+	provider := m.resolveProvider(name)
+	if provider == "" {
+		return nil, fmt.Errorf("dependency '%s' not found in configuration", name)
+	}
+	return graph.Dependents(provider, recursive), nil
+}
+
+// resolveProvider returns the dependency that satisfies a required name: the dependency with
+// that name if there is one, otherwise the first dependency listing it in Provides, preferring
+// providers that are not skipped. It returns "" if nothing satisfies the name.
+func (m *Manager) resolveProvider(name string) string {
+	if m.findDependency(name) != nil {
+		return name
+	}
+
+	provider := ""
+	for i := range m.Config.Dependencies {
+		dep := &m.Config.Dependencies[i]
+		if !dep.provides(name) {
+			continue
+		}
+		if !m.isSkipped(dep) {
+			return dep.Name
+		}
+		if provider == "" {
+			provider = dep.Name
+		}
+	}
+
+	return provider
+}
+
+// FindCycle returns the first cycle found in the graph, or nil if the graph is acyclic
+// The returned path starts and ends with the same dependency name
+func (g *Graph) FindCycle() []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make(map[string]int)
+	var stack []string
+	var cycle []string
+
+	var visit func(name string) bool
+	visit = func(name string) bool {
+		state[name] = visiting
+		stack = append(stack, name)
+
+		for _, next := range g.Edges[name] {
+			switch state[next] {
+			case visiting:
+				// Found a back edge, extract the cycle from the stack
+				for i, n := range stack {
+					if n == next {
+						cycle = append(append([]string{}, stack[i:]...), next)
+						break
+					}
+				}
+				return true
+			case unvisited:
+				if visit(next) {
+					return true
+				}
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[name] = visited
+		return false
+	}
+
+	for _, name := range g.Nodes {
+		if state[name] == unvisited && visit(name) {
+			return cycle
+		}
+	}
+
+	return nil
+}
+
+// TopologicalOrder returns the dependencies ordered so that each one comes after
+// everything it depends on. Among dependencies whose prerequisites are all done,
+// lower priority comes first, then configuration order. The graph must be acyclic.
+func (g *Graph) TopologicalOrder() []string {
+	order := make([]string, 0, len(g.Nodes))
+	pending, dependents := g.prerequisites()
+
+	var ready []string
+	for _, name := range g.Nodes {
+		if pending[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	for len(ready) > 0 {
+		g.sortByPriority(ready)
+		name := ready[0]
+		ready = ready[1:]
+		order = append(order, name)
+
+		for _, dependent := range dependents[name] {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	return order
+}
+
+// prerequisites returns the number of unfinished prerequisites of each dependency
+// and the reverse edges (dependency -> dependencies that require it)
+func (g *Graph) prerequisites() (map[string]int, map[string][]string) {
+	pending := make(map[string]int)
+	dependents := make(map[string][]string)
+	for _, name := range g.Nodes {
+		pending[name] = len(g.Edges[name])
+		for _, required := range g.Edges[name] {
+			dependents[required] = append(dependents[required], name)
+		}
+	}
+
+	return pending, dependents
+}
+
+// sortByPriority sorts names by priority, keeping configuration order for ties
+func (g *Graph) sortByPriority(names []string) {
+	index := make(map[string]int, len(g.Nodes))
+	for i, name := range g.Nodes {
+		index[name] = i
+	}
+
+	sort.SliceStable(names, func(i, j int) bool {
+		a, b := names[i], names[j]
+		if g.Priorities[a] != g.Priorities[b] {
+			return g.Priorities[a] < g.Priorities[b]
+		}
+		return index[a] < index[b]
+	})
+}
+
+// Dependents returns the dependencies that require name directly, or also through other
+// dependencies when recursive, in configuration order
+func (g *Graph) Dependents(name string, recursive bool) []string {
+	_, reverse := g.prerequisites()
+
+	found := make(map[string]bool)
+	queue := []string{name}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependent := range reverse[current] {
+			if found[dependent] || dependent == name {
+				continue
+			}
+			found[dependent] = true
+			if recursive {
+				queue = append(queue, dependent)
+			}
+		}
+	}
+
+	dependents := []string{}
+	for _, node := range g.Nodes {
+		if found[node] {
+			dependents = append(dependents, node)
+		}
+	}
+	return dependents
+}
+
+// Roots returns the dependencies that no other dependency requires
+func (g *Graph) Roots() []string {
+	required := make(map[string]bool)
+	for _, deps := range g.Edges {
+		for _, dep := range deps {
+			required[dep] = true
+		}
+	}
+
+	var roots []string
+	for _, name := range g.Nodes {
+		if !required[name] {
+			roots = append(roots, name)
+		}
+	}
+
+	return roots
+}
+
+// Text renders the graph as an indented text tree starting from the roots
+func (g *Graph) Text() string {
+	var b strings.Builder
+
+	var write func(name string, depth int)
+	write = func(name string, depth int) {
+		fmt.Fprintf(&b, "%s%s\n", strings.Repeat("  ", depth), name)
+		for _, dep := range g.Edges[name] {
+			write(dep, depth+1)
+		}
+	}
+
+	for _, root := range g.Roots() {
+		write(root, 0)
+	}
+
+	return b.String()
+}
+
+// DOT renders the graph in Graphviz DOT format
+func (g *Graph) DOT() string {
+	var b strings.Builder
+
+	b.WriteString("digraph dependencies {\n")
+	for _, name := range g.Nodes {
+		fmt.Fprintf(&b, "  %q;\n", name)
+	}
+
+	for _, name := range g.Nodes {
+		for _, dep := range g.Edges[name] {
+			fmt.Fprintf(&b, "  %q -> %q;\n", name, dep)
+		}
+	}
+	b.WriteString("}\n")
+
+	return b.String()
+}
@@ -0,0 +1,249 @@
+package depman
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestBuildGraph tests building the dependency graph and detecting cycles
+func TestBuildGraph(t *testing.T) {
+	// Test edges for a small configuration
+	t.Run("Edges for small config", func(t *testing.T) {
+		manager := &Manager{
+			Config: &DependencyConfig{
+				Dependencies: []Dependency{
+					{Name: "app", Dependencies: []string{"runtime", "tool"}},
+					{Name: "runtime", Dependencies: []string{"libc"}},
+					{Name: "tool"},
+					{Name: "libc"},
+				},
+			},
+		}
+
+		graph, err := manager.BuildGraph()
+		if err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+
+		dot := graph.DOT()
+		for _, edge := range []string{
+			`"app" -> "runtime";`,
+			`"app" -> "tool";`,
+			`"runtime" -> "libc";`,
+		} {
+			if !strings.Contains(dot, edge) {
+				t.Errorf("Expected DOT output to contain %s but got:\n%s", edge, dot)
+			}
+		}
+
+		if strings.Count(dot, "->") != 3 {
+			t.Errorf("Expected 3 edges but got:\n%s", dot)
+		}
+
+		expectedText := "app\n  runtime\n    libc\n  tool\n"
+		if text := graph.Text(); text != expectedText {
+			t.Errorf("Expected text tree:\n%s\nbut got:\n%s", expectedText, text)
+		}
+	})
+
+	// Test cycle detection
+	t.Run("Cycle detection", func(t *testing.T) {
+		manager := &Manager{
+			Config: &DependencyConfig{
+				Dependencies: []Dependency{
+					{Name: "a", Dependencies: []string{"b"}},
+					{Name: "b", Dependencies: []string{"c"}},
+					{Name: "c", Dependencies: []string{"a"}},
+				},
+			},
+		}
+
+		_, err := manager.BuildGraph()
+		if err == nil {
+			t.Fatalf("Expected an error but got none")
+		}
+
+		if !strings.Contains(err.Error(), "a -> b -> c -> a") {
+			t.Errorf("Expected cycle path in error but got: %v", err)
+		}
+	})
+
+	// Test a prerequisite satisfied through Provides
+	t.Run("Provided dependency", func(t *testing.T) {
+		manager := &Manager{
+			Config: &DependencyConfig{
+				Dependencies: []Dependency{
+					{Name: "compose", Dependencies: []string{"docker"}},
+					{Name: "moby", Provides: []string{"docker"}, Enabled: new(bool)},
+					{Name: "podman", Provides: []string{"docker"}},
+				},
+			},
+		}
+
+		graph, err := manager.BuildGraph()
+		if err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+
+		// The disabled provider is passed over
+		if edges := graph.Edges["compose"]; len(edges) != 1 || edges[0] != "podman" {
+			t.Errorf("Expected compose to depend on podman but got %v", edges)
+		}
+		if order := strings.Join(graph.TopologicalOrder(), ","); order != "moby,podman,compose" {
+			t.Errorf("Expected podman before compose but got %s", order)
+		}
+	})
+
+	// Test a literal name taking precedence over providers
+	t.Run("Literal name wins over provider", func(t *testing.T) {
+		manager := &Manager{
+			Config: &DependencyConfig{
+				Dependencies: []Dependency{
+					{Name: "compose", Dependencies: []string{"docker"}},
+					{Name: "podman", Provides: []string{"docker"}},
+					{Name: "docker"},
+				},
+			},
+		}
+
+		graph, err := manager.BuildGraph()
+		if err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+		if edges := graph.Edges["compose"]; len(edges) != 1 || edges[0] != "docker" {
+			t.Errorf("Expected compose to depend on docker but got %v", edges)
+		}
+	})
+
+	// Test unknown dependency reference
+	t.Run("Unknown dependency", func(t *testing.T) {
+		manager := &Manager{
+			Config: &DependencyConfig{
+				Dependencies: []Dependency{
+					{Name: "a", Dependencies: []string{"missing"}},
+				},
+			},
+		}
+
+		if _, err := manager.BuildGraph(); err == nil {
+			t.Errorf("Expected an error but got none")
+		}
+	})
+}
+
+// TestTopologicalOrderPriority tests that priority orders independents but never overrides edges
+func TestTopologicalOrderPriority(t *testing.T) {
+	testCases := []struct {
+		name         string
+		dependencies []Dependency
+		expected     string
+	}{
+		{
+			name: "Configuration order without priorities",
+			dependencies: []Dependency{
+				{Name: "app", Dependencies: []string{"runtime"}},
+				{Name: "runtime"},
+				{Name: "tool"},
+			},
+			expected: "runtime,app,tool",
+		},
+		{
+			name: "Priority orders independents",
+			dependencies: []Dependency{
+				{Name: "tool"},
+				{Name: "certs", Priority: -10},
+				{Name: "editor", Priority: 5},
+			},
+			expected: "certs,tool,editor",
+		},
+		{
+			name: "Edges win over priority",
+			dependencies: []Dependency{
+				{Name: "certs", Priority: -10, Dependencies: []string{"openssl"}},
+				{Name: "openssl", Priority: 10},
+				{Name: "tool"},
+			},
+			expected: "tool,openssl,certs",
+		},
+		{
+			name: "Priority among released dependents",
+			dependencies: []Dependency{
+				{Name: "base"},
+				{Name: "late", Priority: 2, Dependencies: []string{"base"}},
+				{Name: "early", Priority: 1, Dependencies: []string{"base"}},
+			},
+			expected: "base,early,late",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			manager := &Manager{
+				Config: &DependencyConfig{Dependencies: tc.dependencies},
+			}
+
+			graph, err := manager.BuildGraph()
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			if order := strings.Join(graph.TopologicalOrder(), ","); order != tc.expected {
+				t.Errorf("Expected order %s but got %s", tc.expected, order)
+			}
+		})
+	}
+}
+
+func TestDependents(t *testing.T) {
+	manager := &Manager{
+		Config: &DependencyConfig{
+			Dependencies: []Dependency{
+				{Name: "app", Dependencies: []string{"runtime", "tool"}},
+				{Name: "runtime", Dependencies: []string{"libc"}},
+				{Name: "tool", Dependencies: []string{"libc", "docker"}},
+				{Name: "libc"},
+				{Name: "podman", Provides: []string{"docker"}},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name        string
+		dependency  string
+		recursive   bool
+		expected    string
+		expectError bool
+	}{
+		{name: "Direct", dependency: "libc", expected: "runtime,tool"},
+		{name: "Transitive", dependency: "libc", recursive: true, expected: "app,runtime,tool"},
+		{name: "Root has none", dependency: "app", recursive: true, expected: ""},
+		{name: "Through provided name", dependency: "docker", recursive: true, expected: "app,tool"},
+		{name: "Provider", dependency: "podman", expected: "tool"},
+		{name: "Unknown", dependency: "missing", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var dependents []string
+			var err error
+			if tc.recursive {
+				dependents, err = manager.TransitiveDependents(tc.dependency)
+			} else {
+				dependents, err = manager.Dependents(tc.dependency)
+			}
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			if got := strings.Join(dependents, ","); got != tc.expected {
+				t.Errorf("Expected dependents %q but got %q", tc.expected, got)
+			}
+		})
+	}
+}
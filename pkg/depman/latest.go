@@ -0,0 +1,175 @@
+package depman
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sobhit-avrl/depman-v1/internal/downloader"
+)
+
+// maxVersionSourceSize is the largest version source response that will be parsed
+const maxVersionSourceSize = 1 << 20
+
+// latestVersion is what a version source reports
+type latestVersion struct {
+	Version string // Latest version
+	URL     string // Download URL template, if the source reports one
+}
+
+// resolveLatestVersions queries the version source of every managed dependency not resolved
+// yet, whose reported version is then used as its required version by the manager
+// The configuration is left untouched, and each endpoint is queried once, even when several
+// dependencies share it
+func (m *Manager) resolveLatestVersions() error {
+	m.latestMu.Lock()
+	defer m.latestMu.Unlock()
+	if m.latest == nil {
+		m.latest = make(map[string]*latestVersion)
+	}
+	responses := make(map[string][]byte) // Response bodies by endpoint URL
+
+	for i := range m.Config.Dependencies {
+		dep := &m.Config.Dependencies[i]
+		if dep.VersionSource == nil || !dep.IsEnabled() || m.isSkipped(dep) || m.latest[dep.Name] != nil {
+			continue
+		}
+
+		// Version sources can't be queried offline, so the configured version applies
+		if m.offline {
+			m.logger.Warnf("Offline mode: not resolving the latest version of %s, using its configured version", dep.Name)
+			continue
+		}
+
+		latest, err := m.queryVersionSource(dep.VersionSource, responses)
+		if err != nil {
+			err = fmt.Errorf("failed to resolve latest version of %s: %w", dep.Name, err)
+			if dep.Optional {
+				m.logger.Warnf("%v", err)
+				continue
+			}
+			return err
+		}
+
+		m.logger.Infof("Latest version of %s is %s", dep.Name, latest.Version)
+		m.latest[dep.Name] = latest
+	}
+
+	return nil
+}
+
+// ForgetLatestVersions drops the versions resolved from version sources, so that the next
+// check or ensure queries them again, e.g. on each cycle of a long-running watch
+func (m *Manager) ForgetLatestVersions() {
+	m.latestMu.Lock()
+	defer m.latestMu.Unlock()
+	m.latest = nil
+}
+
+// latestFor returns what the version source of a dependency reported, or nil if it has none
+// or it wasn't resolved
+func (m *Manager) latestFor(dep *Dependency) *latestVersion {
+	m.latestMu.Lock()
+	defer m.latestMu.Unlock()
+	return m.latest[dep.Name]
+}
+
+// requiredVersion returns the version a dependency must be at: the one reported by its version
+// source if resolved, otherwise the configured one
+func (m *Manager) requiredVersion(dep *Dependency) string {
+	if latest := m.latestFor(dep); latest != nil {
+		return latest.Version
+	}
+	return dep.Version.Required
+}
+
+// queryVersionSource fetches a version source, reusing responses already fetched this run
+func (m *Manager) queryVersionSource(source *VersionSource, responses map[string][]byte) (*latestVersion, error) {
+	data, ok := responses[source.URL]
+	if !ok {
+		headers, err := source.Auth.Headers()
+		if err != nil {
+			return nil, fmt.Errorf("invalid auth: %w", err)
+		}
+
+		data, err = downloader.Fetch(downloader.DownloadOptions{
+			URL:                source.URL,
+			Headers:            headers,
+			UserAgent:          m.userAgent,
+			Client:             m.httpClient,
+			InsecureSkipVerify: m.insecureSkipVerify,
+			CACertFile:         m.caCertFile,
+			Offline:            m.offline,
+		}, maxVersionSourceSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s: %w", maskURL(source.URL, source.Auth.secrets()), err)
+		}
+		responses[source.URL] = data
+	}
+
+	return parseVersionSource(data, source)
+}
+
+// parseVersionSource extracts the latest version, and download URL if configured, from a
+// version source response: the first non-empty line of plain text, or fields of a JSON document
+func parseVersionSource(data []byte, source *VersionSource) (*latestVersion, error) {
+	if source.Field == "" {
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				return &latestVersion{Version: line}, nil
+			}
+		}
+		return nil, fmt.Errorf("response is empty")
+	}
+
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("response is not valid JSON: %w", err)
+	}
+
+	latest := &latestVersion{}
+	var err error
+	if latest.Version, err = jsonField(doc, source.Field); err != nil {
+		return nil, err
+	}
+	if source.URLField != "" {
+		if latest.URL, err = jsonField(doc, source.URLField); err != nil {
+			return nil, err
+		}
+	}
+	return latest, nil
+}
+
+// jsonField returns the non-empty string or number at a dot-separated path in a JSON document
+// Numeric path segments index into arrays, e.g. "0.tag_name" for the first release of a list
+func jsonField(doc any, path string) (string, error) {
+	value := doc
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			value = v[key]
+		case []any:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(v) {
+				return "", fmt.Errorf("field '%s' not found in response", path)
+			}
+			value = v[index]
+		default:
+			value = nil
+		}
+		if value == nil {
+			return "", fmt.Errorf("field '%s' not found in response", path)
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		if v != "" {
+			return v, nil
+		}
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	}
+	return "", fmt.Errorf("field '%s' is not a non-empty string", path)
+}
@@ -0,0 +1,159 @@
+package depman
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sobhit-avrl/depman-v1/internal/environment"
+)
+
+func TestParseVersionSource(t *testing.T) {
+	testCases := []struct {
+		name            string
+		body            string
+		source          VersionSource
+		expectedVersion string
+		expectedURL     string
+		expectError     bool
+	}{
+		{name: "Plain text", body: "\n  1.4.2\n", expectedVersion: "1.4.2"},
+		{name: "JSON field", body: `{"tag_name": "v2.0.0"}`, source: VersionSource{Field: "tag_name"}, expectedVersion: "v2.0.0"},
+		{name: "Nested field with URL", body: `{"latest": {"version": "3.1.0", "url": "https://example.com/{version}/tool.zip"}}`,
+			source: VersionSource{Field: "latest.version", URLField: "latest.url"}, expectedVersion: "3.1.0", expectedURL: "https://example.com/{version}/tool.zip"},
+		{name: "Array index", body: `[{"name": "5.0.0"}, {"name": "4.9.0"}]`, source: VersionSource{Field: "0.name"}, expectedVersion: "5.0.0"},
+		{name: "Numeric version", body: `{"version": 7}`, source: VersionSource{Field: "version"}, expectedVersion: "7"},
+		{name: "Missing field", body: `{"tag_name": "v2.0.0"}`, source: VersionSource{Field: "version"}, expectError: true},
+		{name: "Empty field", body: `{"version": ""}`, source: VersionSource{Field: "version"}, expectError: true},
+		{name: "Invalid JSON", body: "2.0.0", source: VersionSource{Field: "version"}, expectError: true},
+		{name: "Empty text", body: " \n", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			latest, err := parseVersionSource([]byte(tc.body), &tc.source)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			if latest.Version != tc.expectedVersion {
+				t.Errorf("Expected version %s but got %s", tc.expectedVersion, latest.Version)
+			}
+			if latest.URL != tc.expectedURL {
+				t.Errorf("Expected URL %s but got %s", tc.expectedURL, latest.URL)
+			}
+		})
+	}
+}
+
+// TestLatestVersion tests installing the version reported by a version source
+func TestLatestVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	var mu sync.Mutex
+	manifestHits := 0
+	var downloads []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case r.URL.Path == "/latest.json":
+			manifestHits++
+			w.Write([]byte(`{"tag_name": "2.1.0", "cli_url": "http://` + r.Host + `/cli/{version}/cli.tar.gz"}`))
+		case strings.HasSuffix(r.URL.Path, ".tar.gz"):
+			downloads = append(downloads, r.URL.Path)
+			w.Write([]byte("2.1.0"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+
+	// Both dependencies track the same manifest, one templating its own URL and one using
+	// the URL template from the manifest
+	newDep := func(name string, source *VersionSource) Dependency {
+		marker := filepath.Join(dir, name+".version")
+		return Dependency{
+			Name:          name,
+			VersionSource: source,
+			Platforms: map[string]PlatformConfig{
+				runtime.GOOS: {
+					Installer: Installer{URL: server.URL + "/tool/{version}/tool-{version}.tar.gz", Checksum: "size:5"},
+					Commands: Commands{
+						Install: []string{"cp", "{download_path}", marker},
+						Verify:  []string{"sh", "-c", "cat " + marker},
+					},
+				},
+			},
+		}
+	}
+	tool := newDep("tool", &VersionSource{URL: server.URL + "/latest.json", Field: "tag_name"})
+	cli := newDep("cli", &VersionSource{URL: server.URL + "/latest.json", Field: "tag_name", URLField: "cli_url"})
+
+	manager := &Manager{
+		Config:     &DependencyConfig{Dependencies: []Dependency{tool, cli}},
+		Platform:   runtime.GOOS,
+		logger:     &mockLogger{},
+		envManager: environment.NewManager(),
+	}
+
+	statuses, _, err := manager.EnsureDependencies()
+	if err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+
+	if _, err := manager.CheckAllDependencies(); err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+
+	if manifestHits != 1 {
+		t.Errorf("Expected the shared manifest to be queried once but got %d", manifestHits)
+	}
+	for _, dep := range manager.Config.Dependencies {
+		if dep.Version.Required != "" {
+			t.Errorf("Expected the configuration of %s to be left untouched but it requires %q", dep.Name, dep.Version.Required)
+		}
+		if required := manager.requiredVersion(&dep); required != "2.1.0" {
+			t.Errorf("Expected %s to require 2.1.0 but got %q", dep.Name, required)
+		}
+		if status := statuses[dep.Name]; !status.Installed || status.CurrentVersion != "2.1.0" {
+			t.Errorf("Expected %s 2.1.0 to be installed but got %+v", dep.Name, status)
+		}
+	}
+
+	expected := "/cli/2.1.0/cli.tar.gz,/tool/2.1.0/tool-2.1.0.tar.gz"
+	sort.Strings(downloads)
+	if got := strings.Join(downloads, ","); got != expected {
+		t.Errorf("Expected downloads %s but got %s", expected, got)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "tool.version")); err != nil {
+		t.Errorf("Expected tool to be installed: %v", err)
+	}
+
+	// Forgetting the resolved versions queries the manifest again on the next check
+	manager.ForgetLatestVersions()
+	if _, err := manager.CheckAllDependencies(); err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+	if manifestHits != 2 {
+		t.Errorf("Expected the manifest to be queried again but got %d queries", manifestHits)
+	}
+}
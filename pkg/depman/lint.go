@@ -0,0 +1,107 @@
+package depman
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SupportedPlatforms lists the platforms dependencies can be configured for
+var SupportedPlatforms = []string{"windows", "linux", "darwin"}
+
+// Lint validates the configuration without touching the system, returning every problem found
+// In addition to the checks run before check and ensure, it verifies that the graph is acyclic,
+// that required versions parse, and that each dependency has install and verify commands on the
+// current platform and on any extra platforms given
+func (m *Manager) Lint(platforms ...string) []error {
+	errors := m.validateDependencies()
+
+	// Unknown and duplicate names are already reported, so only cycles are of interest here
+	if graph, err := m.BuildGraph(); err != nil && graph != nil {
+		errors = append(errors, err)
+	}
+
+	for _, dep := range m.Config.Dependencies {
+		if !dep.IsEnabled() {
+			continue
+		}
+
+		// An unknown scheme is reported by validateDependencies
+		if comparator, err := ComparatorFor(dep.VersionScheme); err == nil && dep.Version.Required != "" {
+			if err := comparator.Validate(dep.Version.Required); err != nil {
+				errors = append(errors, fmt.Errorf("dependency '%s' has invalid required version '%s': %w",
+					dep.Name, dep.Version.Required, err))
+			}
+		}
+
+		for _, platform := range m.lintPlatforms(platforms) {
+			platformConfig, ok := dep.PlatformConfigFor(platform)
+			if !ok {
+				// Coverage of the current platform is reported by validateDependencies
+				if platform != m.Platform && !dep.Optional {
+					errors = append(errors, fmt.Errorf("dependency '%s' has no configuration for platform '%s'",
+						dep.Name, platform))
+				}
+				continue
+			}
+
+			// Commands for the current platform are checked by validateDependencies
+			if platform != m.Platform {
+				errors = append(errors, commandErrors(dep, platformConfig, platform)...)
+			}
+
+			installer := platformConfig.Installer
+			for _, url := range []string{installer.URL, installer.ChecksumURL} {
+				if unknown := unknownPlaceholders(url); len(unknown) > 0 {
+					errors = append(errors, fmt.Errorf("dependency '%s' has unknown placeholders %s in a URL for platform '%s' (supported are {version}, {os} and {arch})",
+						dep.Name, strings.Join(unknown, ", "), platform))
+				}
+			}
+		}
+	}
+
+	return errors
+}
+
+// PlatformCoverage returns, for each supported platform and any other platform declared by a
+// dependency, the enabled dependencies (in configuration order) missing configuration for it
+func (m *Manager) PlatformCoverage() map[string][]string {
+	coverage := make(map[string][]string)
+	for _, platform := range SupportedPlatforms {
+		coverage[platform] = []string{}
+	}
+	for _, dep := range m.Config.Dependencies {
+		for platform := range dep.Platforms {
+			if platform != PlatformUnix && platform != PlatformAll {
+				coverage[platform] = []string{}
+			}
+		}
+	}
+
+	for _, dep := range m.Config.Dependencies {
+		if !dep.IsEnabled() {
+			continue
+		}
+		for platform := range coverage {
+			if _, ok := dep.PlatformConfigFor(platform); !ok {
+				coverage[platform] = append(coverage[platform], dep.Name)
+			}
+		}
+	}
+
+	return coverage
+}
+
+// lintPlatforms returns the current platform followed by the extra platforms, without duplicates
+func (m *Manager) lintPlatforms(extra []string) []string {
+	platforms := []string{m.Platform}
+	seen := map[string]bool{m.Platform: true}
+
+	for _, platform := range extra {
+		if !seen[platform] {
+			seen[platform] = true
+			platforms = append(platforms, platform)
+		}
+	}
+
+	return platforms
+}
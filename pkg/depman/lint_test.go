@@ -0,0 +1,249 @@
+package depman
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	// platforms renders a platforms block with install and verify commands for each name
+	platforms := func(names ...string) string {
+		var b strings.Builder
+		b.WriteString("    platforms:\n")
+		for _, name := range names {
+			b.WriteString("      " + name + ":\n")
+			b.WriteString("        commands:\n")
+			b.WriteString("          install: [\"install-tool\"]\n")
+			b.WriteString("          verify: [\"tool\", \"--version\"]\n")
+		}
+		return b.String()
+	}
+
+	testCases := []struct {
+		name      string
+		config    string
+		platforms []string
+		expected  []string
+	}{
+		{
+			name: "Valid config",
+			config: `
+  - name: "tool"
+    version:
+      required: "1.0.0"
+      constraint: "^1.0.0"
+` + platforms("linux", "windows"),
+			platforms: []string{"windows"},
+		},
+		{
+			name: "Missing extra platform",
+			config: `
+  - name: "tool"
+    version:
+      required: "1.0.0"
+` + platforms("linux"),
+			platforms: []string{"windows", "darwin"},
+			expected: []string{
+				"no configuration for platform 'windows'",
+				"no configuration for platform 'darwin'",
+			},
+		},
+		{
+			name: "Optional dependency may omit platforms",
+			config: `
+  - name: "tool"
+    optional: true
+    version:
+      required: "1.0.0"
+` + platforms("linux"),
+			platforms: []string{"windows"},
+		},
+		{
+			name: "Invalid versions",
+			config: `
+  - name: "tool"
+    version:
+      required: "latest"
+      constraint: "not a constraint"
+` + platforms("linux"),
+			expected: []string{
+				"invalid required version 'latest'",
+				"invalid version constraint 'not a constraint'",
+			},
+		},
+		{
+			name: "Invalid checksum",
+			config: `
+  - name: "tool"
+    version:
+      required: "1.0.0"
+    platforms:
+      linux:
+        installer:
+          url: "https://example.com/tool.tar.gz"
+          checksum: "md5"
+        commands:
+          install: ["install-tool"]
+          verify: ["tool", "--version"]
+`,
+			expected: []string{"invalid checksum for platform 'linux'"},
+		},
+		{
+			name: "Cycle",
+			config: `
+  - name: "a"
+    version:
+      required: "1.0.0"
+    dependencies: ["b"]
+` + platforms("linux") + `
+  - name: "b"
+    version:
+      required: "1.0.0"
+    dependencies: ["a"]
+` + platforms("linux"),
+			expected: []string{"dependency cycle detected"},
+		},
+		{
+			name: "Manual dependency needs no install command",
+			config: `
+  - name: "vpn"
+    install_mode: "manual"
+    version:
+      required: "1.0.0"
+    platforms:
+      linux:
+        commands:
+          verify: ["vpn", "--version"]
+`,
+		},
+		{
+			name: "Unknown URL placeholder",
+			config: `
+  - name: "tool"
+    version:
+      required: "1.0.0"
+    platforms:
+      all:
+        installer:
+          url: "https://example.com/{version}/tool-{platform}-{arch}.tar.gz"
+          checksum_url: "https://example.com/{version}/SHA256SUMS"
+        commands:
+          install: ["install-tool"]
+          verify: ["tool", "--version"]
+`,
+			platforms: []string{"windows"},
+			expected: []string{
+				"unknown placeholders {platform} in a URL for platform 'linux'",
+				"unknown placeholders {platform} in a URL for platform 'windows'",
+			},
+		},
+		{
+			name: "Atomic install without install dir",
+			config: `
+  - name: "tool"
+    version:
+      required: "1.0.0"
+    platforms:
+      linux:
+        installer:
+          type: "pkg"
+          atomic: true
+        commands:
+          install: ["install-tool"]
+          verify: ["tool", "--version"]
+`,
+			expected: []string{
+				"sets atomic but installer type 'pkg' is not archive or binary",
+				"sets atomic but has no install_dir",
+			},
+		},
+		{
+			name: "Empty commands",
+			config: `
+  - name: "tool"
+    version:
+      required: "1.0.0"
+    platforms:
+      linux:
+        installer:
+          type: "binary"
+`,
+			expected: []string{
+				"no install command for platform 'linux'",
+				"no verify command for platform 'linux'",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app-dependencies.yml")
+			config := "version: \"1.0\"\nname: \"Lint App\"\ndependencies:" + tc.config
+			if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			manager, err := NewManager(path, WithPlatform("linux"), WithLogger(&mockLogger{}))
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			errors := manager.Lint(tc.platforms...)
+			if len(errors) != len(tc.expected) {
+				t.Fatalf("Expected %d errors but got %d: %v", len(tc.expected), len(errors), errors)
+			}
+
+			for _, expected := range tc.expected {
+				found := false
+				for _, err := range errors {
+					if strings.Contains(err.Error(), expected) {
+						found = true
+					}
+				}
+				if !found {
+					t.Errorf("Expected an error containing %q but got: %v", expected, errors)
+				}
+			}
+		})
+	}
+}
+
+func TestPlatformCoverage(t *testing.T) {
+	disabled := false
+	manager := &Manager{
+		Config: &DependencyConfig{
+			Dependencies: []Dependency{
+				{Name: "everywhere", Platforms: map[string]PlatformConfig{"windows": {}, "linux": {}, "darwin": {}}},
+				{Name: "unix-only", Platforms: map[string]PlatformConfig{"linux": {}, "darwin": {}}},
+				{Name: "bsd-tool", Platforms: map[string]PlatformConfig{"freebsd": {}, "linux": {}}},
+				{Name: "legacy", Platforms: map[string]PlatformConfig{}, Enabled: &disabled},
+				{Name: "shared-unix", Platforms: map[string]PlatformConfig{"unix": {}}},
+				{Name: "anywhere", Platforms: map[string]PlatformConfig{"all": {}}},
+			},
+		},
+	}
+
+	expected := map[string]string{
+		"windows": "unix-only,bsd-tool,shared-unix",
+		"linux":   "",
+		"darwin":  "bsd-tool",
+		"freebsd": "everywhere,unix-only,shared-unix",
+	}
+
+	coverage := manager.PlatformCoverage()
+	if len(coverage) != len(expected) {
+		t.Errorf("Expected %d platforms but got %d: %v", len(expected), len(coverage), coverage)
+	}
+	for platform, missing := range expected {
+		got, ok := coverage[platform]
+		if !ok {
+			t.Errorf("Expected platform %s in coverage", platform)
+			continue
+		}
+		if strings.Join(got, ",") != missing {
+			t.Errorf("Expected %s to be missing [%s] but got %v", platform, missing, got)
+		}
+	}
+}
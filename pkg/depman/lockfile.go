@@ -0,0 +1,231 @@
+package depman
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sobhit-avrl/depman-v1/internal/downloader"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultLockfileName is the standard lockfile name
+const DefaultLockfileName = "app-dependencies.lock.yml"
+
+// Lockfile records the resolved versions and checksums of installed dependencies
+type Lockfile struct {
+	Version      string             `yaml:"version"`      // Lockfile format version
+	Platform     string             `yaml:"platform"`     // Platform the lockfile was generated on
+	Dependencies []LockedDependency `yaml:"dependencies"` // Locked dependencies
+}
+
+// LockedDependency records the resolved state of a single dependency
+type LockedDependency struct {
+	Name     string `yaml:"name"`               // Name of the dependency
+	Version  string `yaml:"version"`            // Installed version
+	Checksum string `yaml:"checksum,omitempty"` // Checksum of the downloaded artifact (format: "algorithm:hash")
+}
+
+// LockDrift describes a dependency whose installed state differs from the lockfile
+type LockDrift struct {
+	Name             string // Name of the dependency
+	LockedVersion    string // Version recorded in the lockfile (empty if not locked)
+	InstalledVersion string // Currently installed version (empty if not installed)
+}
+
+func (d LockDrift) String() string {
+	switch {
+	case d.LockedVersion == "":
+		return fmt.Sprintf("%s is not in the lockfile", d.Name)
+	case d.InstalledVersion == "":
+		return fmt.Sprintf("%s is locked at %s but not installed", d.Name, d.LockedVersion)
+	default:
+		return fmt.Sprintf("%s is locked at %s but %s is installed", d.Name, d.LockedVersion, d.InstalledVersion)
+	}
+}
+
+// LoadLockfile reads a lockfile from disk
+func LoadLockfile(path string) (*Lockfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lockfile: %w", err)
+	}
+
+	var lock Lockfile
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile: %w", err)
+	}
+
+	return &lock, nil
+}
+
+// Save writes the lockfile to disk
+func (l *Lockfile) Save(path string) error {
+	data, err := yaml.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("failed to render lockfile: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write lockfile: %w", err)
+	}
+
+	return nil
+}
+
+// BuildLockfile verifies every dependency and records its installed version and checksum
+// The checksum is the one verified during this run's download, or the one already locked with
+// WithLockfile, or the configured one
+func (m *Manager) BuildLockfile() (*Lockfile, error) {
+	statuses, err := m.CheckAllDependencies()
+	if err != nil {
+		return nil, err
+	}
+
+	return m.BuildLockfileFrom(statuses), nil
+}
+
+// BuildLockfileFrom is BuildLockfile for statuses already verified, e.g. those returned by
+// an ensure run, without running any verify command again
+func (m *Manager) BuildLockfileFrom(statuses map[string]*DependencyStatus) *Lockfile {
+	lock := &Lockfile{
+		Version:  "1.0",
+		Platform: m.Platform,
+	}
+
+	for _, dep := range m.Config.Dependencies {
+		status := statuses[dep.Name]
+		if status == nil || !status.Installed {
+			continue
+		}
+
+		locked := LockedDependency{
+			Name:     dep.Name,
+			Version:  status.CurrentVersion,
+			Checksum: m.recordedChecksum(dep.Name),
+		}
+		if locked.Checksum == "" {
+			locked.Checksum = m.lockEntry(&dep).Checksum
+		}
+		if locked.Checksum == "" {
+			if platformConfig, err := m.GetPlatformConfig(&dep); err == nil {
+				locked.Checksum = platformConfig.Installer.Checksum
+			}
+		}
+
+		lock.Dependencies = append(lock.Dependencies, locked)
+	}
+
+	return lock
+}
+
+// WriteLockfile records the installed version and checksum of every dependency at path
+func (m *Manager) WriteLockfile(path string) error {
+	lock, err := m.BuildLockfile()
+	if err != nil {
+		return err
+	}
+
+	return lock.Save(path)
+}
+
+// VerifyLockfile checks that every dependency is installed at its locked version
+// It never installs or updates anything; any differences are returned as drift
+func (m *Manager) VerifyLockfile(lock *Lockfile) ([]LockDrift, error) {
+	statuses, err := m.CheckAllDependencies()
+	if err != nil {
+		return nil, err
+	}
+
+	locked := make(map[string]string)
+	for _, dep := range lock.Dependencies {
+		locked[dep.Name] = dep.Version
+	}
+
+	var drift []LockDrift
+	for _, dep := range m.Config.Dependencies {
+		status := statuses[dep.Name]
+		if status == nil || status.Skipped {
+			continue
+		}
+
+		installed := ""
+		if status.Installed {
+			installed = status.CurrentVersion
+		}
+
+		if lockedVersion := locked[dep.Name]; lockedVersion == "" || lockedVersion != installed {
+			drift = append(drift, LockDrift{
+				Name:             dep.Name,
+				LockedVersion:    lockedVersion,
+				InstalledVersion: installed,
+			})
+		}
+	}
+
+	return drift, nil
+}
+
+// lockEntry returns the entry locking a dependency in the lockfile set with WithLockfile, if it
+// applies: the lockfile is for this platform and locks the required version
+func (m *Manager) lockEntry(dep *Dependency) LockedDependency {
+	if m.lock == nil || (m.lock.Platform != "" && m.lock.Platform != m.Platform) {
+		return LockedDependency{}
+	}
+
+	for _, locked := range m.lock.Dependencies {
+		if locked.Name != dep.Name {
+			continue
+		}
+		if required := m.requiredVersion(dep); required != "" && locked.Version != required {
+			m.logger.Debugf("Lockfile entry of %s is for version %s, not %s, ignoring it", dep.Name, locked.Version, required)
+			return LockedDependency{}
+		}
+		return locked
+	}
+
+	return LockedDependency{}
+}
+
+// lockedChecksum returns the checksum the lockfile pins a dependency's download to, if any
+// A configured checksum of the same algorithm must agree with it
+func (m *Manager) lockedChecksum(dep *Dependency, configured string) (string, error) {
+	locked := m.lockEntry(dep).Checksum
+	if locked == "" || configured == "" {
+		return locked, nil
+	}
+
+	lockedAlgorithm, lockedDigest, err := downloader.ParseChecksum(locked)
+	if err != nil {
+		return "", fmt.Errorf("invalid checksum for %s in the lockfile: %w", dep.Name, err)
+	}
+	algorithm, digest, err := downloader.ParseChecksum(configured)
+	if err == nil && algorithm == lockedAlgorithm && digest != lockedDigest {
+		return "", fmt.Errorf("%w: dependency %s has checksum %s in the configuration but %s in the lockfile",
+			ErrChecksumMismatch, dep.Name, configured, locked)
+	}
+
+	// A size is no substitute for a configured hash
+	if lockedAlgorithm == downloader.SizeAlgorithm && algorithm != downloader.SizeAlgorithm {
+		return "", nil
+	}
+	return locked, nil
+}
+
+// recordChecksum remembers the checksum verified for a dependency's download
+func (m *Manager) recordChecksum(name, checksum string) {
+	m.checksumMu.Lock()
+	defer m.checksumMu.Unlock()
+
+	if m.checksums == nil {
+		m.checksums = make(map[string]string)
+	}
+	m.checksums[name] = checksum
+}
+
+// recordedChecksum returns the checksum verified for a dependency's download, if any
+func (m *Manager) recordedChecksum(name string) string {
+	m.checksumMu.Lock()
+	defer m.checksumMu.Unlock()
+
+	return m.checksums[name]
+}
@@ -0,0 +1,243 @@
+package depman
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/sobhit-avrl/depman-v1/internal/environment"
+)
+
+// newLockTestManager creates a manager whose dependencies report the given installed versions
+func newLockTestManager(versions map[string]string) *Manager {
+	config := &DependencyConfig{}
+	for _, name := range []string{"node", "python"} {
+		config.Dependencies = append(config.Dependencies, Dependency{
+			Name:    name,
+			Version: Version{Required: "1.0.0"},
+			Platforms: map[string]PlatformConfig{
+				runtime.GOOS: {
+					Installer: Installer{Checksum: "sha256:" + strings.Repeat("a", 64)},
+					Commands: Commands{
+						Install: []string{"true"},
+						Verify:  []string{"echo", versions[name]},
+					},
+				},
+			},
+		})
+	}
+
+	return &Manager{
+		Config:   config,
+		Platform: runtime.GOOS,
+		logger:   &mockLogger{},
+	}
+}
+
+// TestLockfileRoundTrip tests writing and reading back a lockfile
+func TestLockfileRoundTrip(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("echo command not available on Windows")
+	}
+
+	manager := newLockTestManager(map[string]string{"node": "1.0.0", "python": "1.2.0"})
+	manager.recordChecksum("python", "sha256:"+strings.Repeat("b", 64))
+
+	path := filepath.Join(t.TempDir(), DefaultLockfileName)
+	if err := manager.WriteLockfile(path); err != nil {
+		t.Fatalf("Failed to write lockfile: %v", err)
+	}
+
+	lock, err := LoadLockfile(path)
+	if err != nil {
+		t.Fatalf("Failed to load lockfile: %v", err)
+	}
+
+	if lock.Platform != runtime.GOOS {
+		t.Errorf("Expected platform '%s' but got '%s'", runtime.GOOS, lock.Platform)
+	}
+
+	expected := []LockedDependency{
+		{Name: "node", Version: "1.0.0", Checksum: "sha256:" + strings.Repeat("a", 64)},
+		{Name: "python", Version: "1.2.0", Checksum: "sha256:" + strings.Repeat("b", 64)},
+	}
+	if len(lock.Dependencies) != len(expected) {
+		t.Fatalf("Expected %d locked dependencies but got %d", len(expected), len(lock.Dependencies))
+	}
+	for i, dep := range expected {
+		if lock.Dependencies[i] != dep {
+			t.Errorf("Expected locked dependency %+v but got %+v", dep, lock.Dependencies[i])
+		}
+	}
+
+	// Verifying against the same state reports no drift
+	drift, err := manager.VerifyLockfile(lock)
+	if err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+	if len(drift) != 0 {
+		t.Errorf("Expected no drift but got: %v", drift)
+	}
+}
+
+// TestBuildLockfileFrom tests building a lockfile from existing statuses without verifying again
+func TestBuildLockfileFrom(t *testing.T) {
+	manager := newLockTestManager(nil)
+	for i := range manager.Config.Dependencies {
+		manager.Config.Dependencies[i].Platforms[runtime.GOOS] = PlatformConfig{
+			Installer: Installer{Checksum: "sha256:" + strings.Repeat("a", 64)},
+			Commands: Commands{
+				Install: []string{"true"},
+				Verify:  []string{"verify-command-that-must-not-run"},
+			},
+		}
+	}
+
+	statuses := map[string]*DependencyStatus{
+		"node":   {Name: "node", Installed: true, CurrentVersion: "1.0.0"},
+		"python": {Name: "python", Installed: true, CurrentVersion: "1.2.0"},
+	}
+
+	lock := manager.BuildLockfileFrom(statuses)
+
+	expected := []LockedDependency{
+		{Name: "node", Version: "1.0.0", Checksum: "sha256:" + strings.Repeat("a", 64)},
+		{Name: "python", Version: "1.2.0", Checksum: "sha256:" + strings.Repeat("a", 64)},
+	}
+	if len(lock.Dependencies) != len(expected) {
+		t.Fatalf("Expected %d locked dependencies but got %d", len(expected), len(lock.Dependencies))
+	}
+	for i, dep := range expected {
+		if lock.Dependencies[i] != dep {
+			t.Errorf("Expected locked dependency %+v but got %+v", dep, lock.Dependencies[i])
+		}
+	}
+}
+
+// TestVerifyLockfileDrift tests detecting installed versions that differ from the lockfile
+func TestVerifyLockfileDrift(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("echo command not available on Windows")
+	}
+
+	lock := &Lockfile{
+		Version:  "1.0",
+		Platform: runtime.GOOS,
+		Dependencies: []LockedDependency{
+			{Name: "node", Version: "1.0.0"},
+		},
+	}
+
+	manager := newLockTestManager(map[string]string{"node": "1.1.0", "python": "1.2.0"})
+
+	drift, err := manager.VerifyLockfile(lock)
+	if err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+
+	if len(drift) != 2 {
+		t.Fatalf("Expected 2 drifted dependencies but got: %v", drift)
+	}
+
+	if drift[0].String() != "node is locked at 1.0.0 but 1.1.0 is installed" {
+		t.Errorf("Unexpected drift for node: %s", drift[0])
+	}
+
+	if drift[1].String() != "python is not in the lockfile" {
+		t.Errorf("Unexpected drift for python: %s", drift[1])
+	}
+}
+
+// TestLockedChecksum tests verifying downloads against checksums from a lockfile
+func TestLockedChecksum(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Write([]byte("artifact"))
+	}))
+	defer server.Close()
+
+	good := "sha256:c7c5c1d70c5dec4416ab6158afd0b223ef40c29b1dc1f97ed9428b94d4cadb1c"
+	bad := "sha256:" + strings.Repeat("0", 64)
+
+	testCases := []struct {
+		name          string
+		configured    string
+		locked        string
+		lockedVersion string
+		lockPlatform  string
+		expectError   bool
+		expectHits    int
+		expectPinned  string
+	}{
+		{name: "Lockfile supplies the checksum", locked: good, expectHits: 1, expectPinned: good},
+		{name: "Lockfile checksum is enforced", locked: bad, expectError: true, expectHits: 1},
+		{name: "Lockfile agrees with the configuration", configured: good, locked: good, expectHits: 1, expectPinned: good},
+		{name: "Lockfile conflicts with the configuration", configured: good, locked: bad, expectError: true},
+		{name: "Lockfile hash preferred over a size", configured: "size:8", locked: good, expectHits: 1, expectPinned: good},
+		{name: "Entry for another version is ignored", locked: bad, lockedVersion: "0.9.0", expectHits: 1},
+		{name: "Lockfile for another platform is ignored", locked: bad, lockPlatform: "plan9", expectHits: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hits = 0
+			dep := Dependency{
+				Name:    "tool",
+				Version: Version{Required: "1.0.0"},
+				Platforms: map[string]PlatformConfig{
+					runtime.GOOS: {
+						Installer: Installer{URL: server.URL + "/tool.tar.gz", Checksum: tc.configured},
+						Commands:  Commands{Install: []string{"true"}, Verify: []string{"echo", "1.0.0"}},
+					},
+				},
+			}
+
+			lockedVersion := "1.0.0"
+			if tc.lockedVersion != "" {
+				lockedVersion = tc.lockedVersion
+			}
+			lockPlatform := runtime.GOOS
+			if tc.lockPlatform != "" {
+				lockPlatform = tc.lockPlatform
+			}
+
+			manager := &Manager{
+				Config:     &DependencyConfig{Dependencies: []Dependency{dep}},
+				Platform:   runtime.GOOS,
+				logger:     &mockLogger{},
+				envManager: environment.NewManager(),
+			}
+			WithLockfile(&Lockfile{
+				Version:      "1.0",
+				Platform:     lockPlatform,
+				Dependencies: []LockedDependency{{Name: "tool", Version: lockedVersion, Checksum: tc.locked}},
+			})(manager)
+
+			_, err := manager.installDependency(context.Background(), &dep)
+			if tc.expectError {
+				if !errors.Is(err, ErrChecksumMismatch) {
+					t.Errorf("Expected a checksum mismatch but got: %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			if hits != tc.expectHits {
+				t.Errorf("Expected %d downloads but got %d", tc.expectHits, hits)
+			}
+			if tc.expectPinned != "" && manager.recordedChecksum("tool") != tc.expectPinned {
+				t.Errorf("Expected the download to be verified against %s but got %s", tc.expectPinned, manager.recordedChecksum("tool"))
+			}
+		})
+	}
+}
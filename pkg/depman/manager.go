@@ -2,16 +2,21 @@ package depman
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"maps"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
 	"strings"
 	"time"
+	"unicode"
 
-	"github.com/Masterminds/semver/v3"
-
+	"github.com/sobhit-avrl/depman-v1/internal/archive"
 	"github.com/sobhit-avrl/depman-v1/internal/downloader"
 	"github.com/sobhit-avrl/depman-v1/internal/environment"
 	"github.com/sobhit-avrl/depman-v1/internal/logger"
@@ -19,12 +24,40 @@ import (
 
 // NewManager creates a new dependency manager with optional configuration
 func NewManager(configPath string, opts ...Option) (*Manager, error) {
-	// Load dependency configuration
-	config, err := LoadDependencyConfig(configPath)
+	manager := newManager(nil, configPath, opts...)
+
+	// Load dependency configuration, in the format forced by the options if any
+	config, err := LoadVerifiedDependencyConfig(configPath, manager.configFormat, manager.configChecksum)
+	if err != nil {
+		return nil, err
+	}
+
+	manager.Config = config
+	return manager, nil
+}
+
+// errConfigChecksumGlob is returned when a config checksum is given for a glob of files
+var errConfigChecksumGlob = errors.New("a config checksum can only verify a single configuration file, not a glob")
+
+// NewManagerFromGlob creates a new dependency manager from every configuration file
+// matching the glob pattern, operating on the union of their dependencies
+func NewManagerFromGlob(pattern string, opts ...Option) (*Manager, error) {
+	manager := newManager(nil, pattern, opts...)
+	if manager.configChecksum != "" {
+		return nil, errConfigChecksumGlob
+	}
+
+	config, err := loadDependencyConfigs(pattern, manager.configFormat)
 	if err != nil {
 		return nil, err
 	}
 
+	manager.Config = config
+	return manager, nil
+}
+
+// newManager creates a manager for an already-loaded configuration
+func newManager(config *DependencyConfig, configPath string, opts ...Option) *Manager {
 	// Create a new manager with defaults
 	manager := &Manager{
 		Config:     config,
@@ -39,20 +72,121 @@ func NewManager(configPath string, opts ...Option) (*Manager, error) {
 		opt(manager)
 	}
 
-	return manager, nil
+	return manager
 }
 
 // GetPlatformConfig returns platform-specific configuration for a dependency
+// Commands missing from the platform fall back to the dependency-level defaults
 func (m *Manager) GetPlatformConfig(dep *Dependency) (*PlatformConfig, error) {
 	// Check if we have configuration for current platform
-	platform, ok := dep.Platforms[m.Platform]
+	platform, ok := dep.PlatformConfigFor(m.Platform)
 	if !ok {
-		return nil, fmt.Errorf("no configuration available for platform: %s", m.Platform)
+		return nil, &PlatformUnsupportedError{Dependency: dep.Name, Platform: m.Platform}
+	}
+
+	platform.Commands = mergeCommands(dep.Commands, platform.Commands)
+
+	// Point downloads at the required version, as reported by the version source if any
+	if latest := m.latestFor(dep); latest != nil && latest.URL != "" {
+		platform.Installer.URL = latest.URL
+	}
+	vars := m.urlTemplateVars(dep)
+	platform.Installer.URL = expandURLTemplate(platform.Installer.URL, vars)
+	platform.Installer.ChecksumURL = expandURLTemplate(platform.Installer.ChecksumURL, vars)
+
+	// Isolated installs go to the dependency's own directory under the prefix
+	if prefixDir := m.prefixDir(dep); prefixDir != "" {
+		platform.InstallDir = prefixDir
 	}
 
 	return &platform, nil
 }
 
+// prefixDir returns the directory a dependency is installed into under the prefix, named after
+// its required version, or "latest" without one (empty without a prefix)
+func (m *Manager) prefixDir(dep *Dependency) string {
+	if m.prefix == "" {
+		return ""
+	}
+
+	version := m.requiredVersion(dep)
+	if version == "" {
+		version = "latest"
+	}
+	return filepath.Join(os.ExpandEnv(m.prefix), dep.Name, version)
+}
+
+// urlTemplateVars returns the values of the placeholders supported in download URLs
+func (m *Manager) urlTemplateVars(dep *Dependency) map[string]string {
+	arch := m.Arch
+	if arch == "" {
+		arch = runtime.GOARCH
+	}
+	return map[string]string{
+		"version": m.requiredVersion(dep),
+		"os":      m.Platform,
+		"arch":    arch,
+	}
+}
+
+// urlPlaceholder matches a {name} placeholder, also in its escaped form left behind by
+// resolving a URL against the base URL
+var urlPlaceholder = regexp.MustCompile(`\{([a-z_]+)\}|%7[Bb]([a-z_]+)%7[Dd]`)
+
+// expandURLTemplate replaces the placeholders in a URL with their values
+// Placeholders without a value, such as {version} with no required version, are left as is
+func expandURLTemplate(rawURL string, vars map[string]string) string {
+	return urlPlaceholder.ReplaceAllStringFunc(rawURL, func(match string) string {
+		if value := vars[placeholderName(match)]; value != "" {
+			return value
+		}
+		return match
+	})
+}
+
+// unknownPlaceholders returns the placeholders in a URL that are not supported
+func unknownPlaceholders(rawURL string) []string {
+	var unknown []string
+	for _, match := range urlPlaceholder.FindAllString(rawURL, -1) {
+		switch placeholderName(match) {
+		case "version", "os", "arch":
+		default:
+			unknown = append(unknown, match)
+		}
+	}
+	return unknown
+}
+
+// placeholderName returns the name of a placeholder matched by urlPlaceholder
+func placeholderName(match string) string {
+	submatches := urlPlaceholder.FindStringSubmatch(match)
+	if submatches[1] != "" {
+		return submatches[1]
+	}
+	return submatches[2]
+}
+
+// mergeCommands returns the override commands, using defaults for any that are empty
+func mergeCommands(defaults, override Commands) Commands {
+	if len(override.Install) == 0 {
+		override.Install = defaults.Install
+	}
+	if len(override.Verify) == 0 {
+		override.Verify = defaults.Verify
+	}
+	if len(override.Uninstall) == 0 {
+		override.Uninstall = defaults.Uninstall
+	}
+	if len(override.HealthCheck) == 0 {
+		override.HealthCheck = defaults.HealthCheck
+	}
+	if override.VerifyExpect == "" {
+		override.VerifyExpect = defaults.VerifyExpect
+	}
+
+	return override
+}
+
 // CheckDependency verifies if a dependency is installed and if it needs updating
 func (m *Manager) CheckDependency(dep *Dependency) (*DependencyStatus, error) {
 	// Use the more thorough verification
@@ -69,74 +203,458 @@ func (m *Manager) validateDependencies() []error {
 		return errors
 	}
 
+	// Validate names and references, including skipped dependencies
+	errors = append(errors, m.validateDependencyNames()...)
+
+	// Dependencies selected by name must exist
+	for _, name := range slices.Sorted(maps.Keys(m.only)) {
+		if m.findDependency(name) == nil {
+			errors = append(errors, fmt.Errorf("selected dependency '%s' not found in configuration", name))
+		}
+	}
+
+	if _, ok := packageManagers[m.packageManager]; m.packageManager != "" && !ok {
+		errors = append(errors, fmt.Errorf("unknown package manager '%s'", m.packageManager))
+	}
+
 	// Validate each dependency
 	for _, dep := range m.Config.Dependencies {
+		if _, err := dep.ConditionMet(); err != nil && dep.IsEnabled() {
+			errors = append(errors, fmt.Errorf("dependency '%s' has %w", dep.Name, err))
+			continue
+		}
+
+		// Skipped dependencies are not validated
+		if m.isSkipped(&dep) {
+			continue
+		}
+
 		// Check if platform-specific config exists
-		if _, ok := dep.Platforms[m.Platform]; !ok {
+		platformConfig, ok := dep.PlatformConfigFor(m.Platform)
+		if !ok {
 			errors = append(errors, fmt.Errorf("dependency '%s' has no configuration for platform '%s'",
 				dep.Name, m.Platform))
 			continue
 		}
 
-		// Validate version information
-		if dep.Version.Required == "" {
+		// Validate version information, which is optional when the verify output is matched instead
+		// or the dependency is only checked for presence
+		commands := mergeCommands(dep.Commands, platformConfig.Commands)
+		if dep.Version.Required == "" && commands.VerifyExpect == "" && dep.VersionSource == nil &&
+			dep.InstallMode != InstallModeCheckOnly {
 			errors = append(errors, fmt.Errorf("dependency '%s' has no required version", dep.Name))
 		}
+		if dep.VersionSource != nil && dep.VersionSource.URL == "" {
+			errors = append(errors, fmt.Errorf("dependency '%s' has a version source without a URL", dep.Name))
+		}
 
-		// If constraint is provided, make sure it's valid
-		if dep.Version.Constraint != "" {
-			if _, err := semver.NewConstraint(dep.Version.Constraint); err != nil {
+		// If constraint is provided, make sure it's valid for the version scheme
+		comparator, err := ComparatorFor(dep.VersionScheme)
+		if err != nil {
+			errors = append(errors, fmt.Errorf("dependency '%s': %w", dep.Name, err))
+		} else if dep.Version.Constraint != "" {
+			if err := comparator.ValidateConstraint(dep.Version.Constraint); err != nil {
 				errors = append(errors, fmt.Errorf("dependency '%s' has invalid version constraint '%s': %w",
 					dep.Name, dep.Version.Constraint, err))
 			}
 		}
+
+		// Commands for the current platform must have a program to run; lint covers other platforms
+		errors = append(errors, commandErrors(dep, platformConfig, m.Platform)...)
+
+		switch dep.InstallMode {
+		case "", InstallModeAuto, InstallModeCheckOnly, InstallModeManual:
+		default:
+			errors = append(errors, fmt.Errorf("dependency '%s' has unknown install mode '%s' (expected %s, %s or %s)",
+				dep.Name, dep.InstallMode, InstallModeAuto, InstallModeCheckOnly, InstallModeManual))
+		}
+
+		if commands.VerifyExpect != "" {
+			if _, err := verifyOutputMatches("", commands.VerifyExpect); err != nil {
+				errors = append(errors, fmt.Errorf("dependency '%s' has invalid verify_expect: %w", dep.Name, err))
+			}
+		}
+
+		if installer := platformConfig.Installer; strings.EqualFold(installer.Type, InstallerTypePackage) {
+			if installer.Package == "" {
+				errors = append(errors, fmt.Errorf("dependency '%s' uses the package installer but sets no package", dep.Name))
+			}
+			if _, ok := packageManagers[installer.PackageManager]; installer.PackageManager != "" && !ok {
+				errors = append(errors, fmt.Errorf("dependency '%s' has unknown package manager '%s'", dep.Name, installer.PackageManager))
+			}
+		}
+		if installer := platformConfig.Installer; installer.Timeout < 0 || (installer.MaxRetries != nil && *installer.MaxRetries < 0) {
+			errors = append(errors, fmt.Errorf("dependency '%s' has a negative download timeout or max_retries", dep.Name))
+		}
+
+		if installer := platformConfig.Installer; installer.Atomic {
+			if t := strings.ToLower(installer.Type); t != "archive" && t != "binary" {
+				errors = append(errors, fmt.Errorf("dependency '%s' sets atomic but installer type '%s' is not archive or binary",
+					dep.Name, installer.Type))
+			}
+			if platformConfig.InstallDir == "" {
+				errors = append(errors, fmt.Errorf("dependency '%s' sets atomic but has no install_dir", dep.Name))
+			}
+		}
+
+		// Downloads for the current platform must be pinned in strict mode; a checksum_url is
+		// served by the same origin as the download, so it is not enough on its own
+		if m.requireChecksum && platformConfig.Installer.URL != "" {
+			switch checksum := platformConfig.Installer.Checksum; {
+			case checksum == "" && m.lockEntry(&dep).Checksum == "":
+				errors = append(errors, fmt.Errorf("dependency '%s' has no checksum for platform '%s'", dep.Name, m.Platform))
+			case checksum != "" && !isStrongChecksum(checksum):
+				errors = append(errors, fmt.Errorf("dependency '%s' has a weak checksum for platform '%s' (only sha256 and sha512 are accepted when checksums are required)",
+					dep.Name, m.Platform))
+			}
+		}
+
+		// Validate checksums and auth for every platform, not just the current one
+		for platform, platformConfig := range dep.Platforms {
+			if platformConfig.Installer.Checksum != "" {
+				if _, _, err := downloader.ParseChecksum(platformConfig.Installer.Checksum); err != nil {
+					errors = append(errors, fmt.Errorf("dependency '%s' has invalid checksum for platform '%s': %w",
+						dep.Name, platform, err))
+				}
+			}
+			if platformConfig.BinaryChecksum != "" {
+				if _, _, err := downloader.ParseChecksum(platformConfig.BinaryChecksum); err != nil {
+					errors = append(errors, fmt.Errorf("dependency '%s' has invalid binary checksum for platform '%s': %w",
+						dep.Name, platform, err))
+				}
+			}
+
+			if filename := platformConfig.Installer.Filename; filename != "" {
+				if err := downloader.ValidateFilename(filename); err != nil {
+					errors = append(errors, fmt.Errorf("dependency '%s' has invalid installer filename for platform '%s': %w",
+						dep.Name, platform, err))
+				}
+			}
+
+			if auth := platformConfig.Installer.Auth; auth != nil {
+				if t := strings.ToLower(auth.Type); t != "basic" && t != "bearer" {
+					errors = append(errors, fmt.Errorf("dependency '%s' has unsupported auth type '%s' for platform '%s'",
+						dep.Name, auth.Type, platform))
+				}
+			}
+		}
 	}
 
 	return errors
 }
 
-// installDependency handles the actual installation of a dependency
+// commandErrors reports missing install and verify commands in a dependency's configuration for
+// a platform; an install command isn't needed when an extracted file is the install, the package
+// manager installs it or the dependency is installed by hand
+func commandErrors(dep Dependency, platformConfig PlatformConfig, platform string) []error {
+	var errors []error
+	commands := mergeCommands(dep.Commands, platformConfig.Commands)
+	installer := platformConfig.Installer
+	installed := installer.ExtractFile != "" || strings.EqualFold(installer.Type, InstallerTypePackage) || dep.InstallsManually()
+	if isEmptyCommand(commands.Install) && !installed {
+		errors = append(errors, fmt.Errorf("dependency '%s' has no install command for platform '%s'", dep.Name, platform))
+	}
+	if isEmptyCommand(commands.Verify) {
+		errors = append(errors, fmt.Errorf("dependency '%s' has no verify command for platform '%s'", dep.Name, platform))
+	}
+	return errors
+}
+
+// isEmptyCommand reports whether a command has no program to run
+func isEmptyCommand(command []string) bool {
+	return len(command) == 0 || strings.TrimSpace(command[0]) == ""
+}
+
+// isSizeChecksum reports whether a checksum is the weak "size:<bytes>" pseudo-checksum
+func isSizeChecksum(checksum string) bool {
+	algorithm, _, err := downloader.ParseChecksum(checksum)
+	return err == nil && algorithm == downloader.SizeAlgorithm
+}
+
+// isStrongChecksum reports whether a checksum is a sha256 or sha512 hash, the only ones trusted
+// when checksums are required
+func isStrongChecksum(checksum string) bool {
+	algorithm, _, err := downloader.ParseChecksum(checksum)
+	return err == nil && (algorithm == "sha256" || algorithm == "sha512")
+}
+
+// hasChecksum reports whether a download can be verified against a checksum
+func hasChecksum(installer *Installer) bool {
+	return installer.Checksum != "" || installer.ChecksumURL != ""
+}
+
+// validateDependencyNames checks that names are non-empty, unique, and free of whitespace,
+// and that every entry in a Dependencies list refers to a known dependency or provided name
+func (m *Manager) validateDependencyNames() []error {
+	var errors []error
+
+	seen := make(map[string]bool)
+	for i, dep := range m.Config.Dependencies {
+		switch {
+		case dep.Name == "":
+			errors = append(errors, fmt.Errorf("dependency #%d has an empty name", i+1))
+			continue
+		case strings.ContainsFunc(dep.Name, unicode.IsSpace):
+			errors = append(errors, fmt.Errorf("dependency #%d has a name containing whitespace: '%s'", i+1, dep.Name))
+		case seen[dep.Name]:
+			errors = append(errors, fmt.Errorf("dependency #%d has duplicate name '%s'", i+1, dep.Name))
+		}
+		seen[dep.Name] = true
+	}
+
+	for _, dep := range m.Config.Dependencies {
+		for _, required := range dep.Dependencies {
+			if !seen[required] && m.resolveProvider(required) == "" {
+				errors = append(errors, fmt.Errorf("dependency '%s' depends on unknown dependency '%s'", dep.Name, required))
+			}
+		}
+	}
+
+	return errors
+}
+
+// isSkipped reports whether a dependency should be skipped, either because it was
+// explicitly skipped, disabled, filtered out by tag or its when condition doesn't hold, or
+// because it is optional and has no configuration for this platform
+// An invalid when condition doesn't skip the dependency, it is reported by validation instead
+func (m *Manager) isSkipped(dep *Dependency) bool {
+	if m.skip[dep.Name] || !dep.IsEnabled() || !m.matchesTags(dep) || (len(m.only) > 0 && !m.only[dep.Name]) {
+		return true
+	}
+
+	if met, err := dep.ConditionMet(); err == nil && !met {
+		return true
+	}
+
+	if dep.Optional {
+		if _, ok := dep.PlatformConfigFor(m.Platform); !ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesTags reports whether a dependency passes the tag filters
+// It must carry one of the selected tags (if any) and none of the excluded ones
+func (m *Manager) matchesTags(dep *Dependency) bool {
+	for _, tag := range m.excludeTags {
+		if dep.HasTag(tag) {
+			return false
+		}
+	}
+
+	if len(m.tags) == 0 {
+		return true
+	}
+	for _, tag := range m.tags {
+		if dep.HasTag(tag) {
+			return true
+		}
+	}
+	return false
+}
 
 // installDependency handles the actual installation of a dependency
-func (m *Manager) installDependency(dep *Dependency) error {
+// It returns the combined output of the install command
+func (m *Manager) installDependency(ctx context.Context, dep *Dependency) (string, error) {
 	// Get platform config
 	platformConfig, err := m.GetPlatformConfig(dep)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	// Create a temporary directory for downloads
-	tempDir, err := os.MkdirTemp("", "depman-download-*")
+	// The package manager downloads and installs the package itself
+	if strings.EqualFold(platformConfig.Installer.Type, InstallerTypePackage) {
+		return m.installPackage(ctx, dep, platformConfig)
+	}
+
+	// Create a temporary directory for downloads, under the configured location if any
+	if m.tempDir != "" {
+		if err := os.MkdirAll(m.tempDir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+	}
+	tempDir, err := os.MkdirTemp(m.tempDir, "depman-download-*")
 	if err != nil {
-		return fmt.Errorf("failed to create temporary directory: %w", err)
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
 	}
 	defer os.RemoveAll(tempDir) // Clean up when done
 
 	// Download dependency if URL is specified
 	downloadPath := ""
 	if platformConfig.Installer.URL != "" {
-		m.logger.Infof("Downloading %s from %s", dep.Name, platformConfig.Installer.URL)
+		// Resolve credentials for private URLs
+		headers, err := platformConfig.Installer.Auth.Headers()
+		if err != nil {
+			return "", fmt.Errorf("invalid auth for dependency %s: %w", dep.Name, err)
+		}
+		secrets := platformConfig.Installer.Auth.secrets()
+
+		// A lockfile pins the download, in agreement with any configured checksum
+		lockedChecksum, err := m.lockedChecksum(dep, platformConfig.Installer.Checksum)
+		if err != nil {
+			return "", err
+		}
+
+		// Refuse or warn about downloads that cannot be verified
+		// Strict mode needs a pinned checksum, as a checksum_url shares the download's origin
+		if m.requireChecksum && platformConfig.Installer.Checksum == "" && lockedChecksum == "" {
+			return "", fmt.Errorf("dependency %s has no checksum and checksums are required (a checksum_url alone is not trusted)", dep.Name)
+		}
+		if !hasChecksum(&platformConfig.Installer) && lockedChecksum == "" {
+			m.logger.Warnf("Dependency %s has no checksum, the download will not be verified", dep.Name)
+		}
+
+		if unknown := unknownPlaceholders(platformConfig.Installer.URL); len(unknown) > 0 {
+			m.logger.Warnf("Download URL of %s has unknown placeholders %s (supported are {version}, {os} and {arch})",
+				dep.Name, strings.Join(unknown, ", "))
+		}
+
+		m.notifyStatus(dep.Name, PhaseDownloading, nil)
+		m.logger.Infof("Downloading %s from %s", dep.Name, maskURL(platformConfig.Installer.URL, secrets))
+		if m.insecureSkipVerify {
+			m.logger.Warnf("TLS certificate verification is disabled for the %s download", dep.Name)
+		}
 
 		// Set up download options
+		timeout, retries := m.downloadLimits(&platformConfig.Installer)
 		opts := downloader.DownloadOptions{
-			URL:          platformConfig.Installer.URL,
-			DestDir:      tempDir,
-			ShowProgress: true,
+			URL:                    platformConfig.Installer.URL,
+			Timeout:                timeout,
+			MaxRetries:             retries,
+			DestDir:                tempDir,
+			Filename:               platformConfig.Installer.Filename,
+			ShowProgress:           true,
+			Headers:                headers,
+			UserAgent:              m.userAgent,
+			Client:                 m.httpClient,
+			InsecureSkipVerify:     m.insecureSkipVerify,
+			CACertFile:             m.caCertFile,
+			Offline:                m.offline,
+			Context:                ctx,
+			AllowCrossHostRedirect: platformConfig.Installer.AllowCrossHostRedirect,
+			MaxRedirects:           platformConfig.Installer.MaxRedirects,
+		}
+
+		// Report progress if requested
+		if m.downloadProgress != nil {
+			opts.Progress = func(downloaded, total int64) {
+				m.downloadProgress(dep.Name, downloaded, total)
+			}
 		}
 
-		// Add checksum if provided
-		if platformConfig.Installer.Checksum != "" {
+		// Add checksum if locked or provided, otherwise fetch it from the sidecar URL
+		if lockedChecksum != "" {
+			opts.Checksum = lockedChecksum
+			m.logger.Debugf("Verifying %s against the checksum in the lockfile", dep.Name)
+		} else if platformConfig.Installer.Checksum != "" {
 			opts.Checksum = platformConfig.Installer.Checksum
+			if isSizeChecksum(opts.Checksum) {
+				m.logger.Warnf("Dependency %s is only verified by its size, which is much weaker than a cryptographic hash", dep.Name)
+			}
+		} else if platformConfig.Installer.ChecksumURL != "" {
+			m.logger.Infof("Fetching checksum for %s from %s", dep.Name, maskURL(platformConfig.Installer.ChecksumURL, secrets))
+			checksum, err := downloader.FetchChecksum(downloader.DownloadOptions{
+				URL:                    platformConfig.Installer.ChecksumURL,
+				Headers:                headers,
+				UserAgent:              m.userAgent,
+				Client:                 m.httpClient,
+				InsecureSkipVerify:     m.insecureSkipVerify,
+				CACertFile:             m.caCertFile,
+				Offline:                m.offline,
+				Context:                ctx,
+				AllowCrossHostRedirect: platformConfig.Installer.AllowCrossHostRedirect,
+				MaxRedirects:           platformConfig.Installer.MaxRedirects,
+			})
+			if err != nil {
+				return "", fmt.Errorf("failed to fetch checksum: %w", err)
+			}
+			opts.Checksum = checksum
 		}
 
-		// Download the file
+		// Sizes and sha1 don't protect against tampering, so strict mode refuses them
+		if opts.Checksum != "" && !isStrongChecksum(opts.Checksum) {
+			algorithm, _, _ := downloader.ParseChecksum(opts.Checksum)
+			if m.requireChecksum {
+				return "", fmt.Errorf("dependency %s is only verified by %s and checksums are required (use sha256 or sha512)", dep.Name, algorithm)
+			}
+			if algorithm == "sha1" {
+				m.logger.Warnf("Dependency %s is verified with sha1, which is no longer considered secure", dep.Name)
+			}
+		}
+
+		// Download the file once a download slot is free
+		release, err := acquire(ctx, m.downloadSem)
+		if err != nil {
+			return "", fmt.Errorf("download cancelled: %w", err)
+		}
 		result, err := downloader.Download(opts)
+		release()
 		if err != nil {
-			return fmt.Errorf("failed to download dependency: %w", err)
+			return "", fmt.Errorf("failed to download dependency: %w", err)
 		}
 
 		downloadPath = result.FilePath
 		m.logger.Infof("Downloaded %s (%d bytes)", dep.Name, result.Size)
+
+		// Retain the artifact once the install has finished, before the temp dir is removed
+		if m.keepDownloads != "" {
+			defer m.keepDownload(dep, downloadPath)
+		}
+
+		// Remember the verified checksum for the lockfile, or the computed one for unpinned
+		// downloads and those only verified by size
+		if opts.Checksum != "" && !isSizeChecksum(opts.Checksum) {
+			checksum, _ := downloader.NormalizeChecksum(opts.Checksum) // Verified by the download
+			m.recordChecksum(dep.Name, checksum)
+		} else {
+			m.recordChecksum(dep.Name, result.Algorithm+":"+result.Checksum)
+		}
+	}
+
+	m.notifyStatus(dep.Name, PhaseInstalling, nil)
+
+	// Atomic installs go to a staging directory that replaces the install directory only once
+	// the install succeeded, so a failure leaves the previous install untouched
+	// As the whole directory is replaced, it must be one depman owns, never a shared one
+	installDir := os.ExpandEnv(platformConfig.InstallDir)
+	targetDir := installDir
+	if platformConfig.Installer.Atomic {
+		if installDir == "" {
+			return "", fmt.Errorf("dependency %s sets atomic but has no install_dir", dep.Name)
+		}
+		if err := checkOwnedDir(installDir); err != nil {
+			return "", fmt.Errorf("failed to install %s: %w", dep.Name, err)
+		}
+		targetDir = filepath.Join(tempDir, "staging")
+		if err := os.Mkdir(targetDir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create staging directory: %w", err)
+		}
+	}
+
+	// Extract the requested entry from the downloaded archive
+	if platformConfig.Installer.ExtractFile != "" {
+		if downloadPath == "" {
+			return "", fmt.Errorf("dependency %s sets extract_file but has no installer URL", dep.Name)
+		}
+		if installDir == "" {
+			return "", fmt.Errorf("dependency %s sets extract_file but has no install_dir", dep.Name)
+		}
+
+		extracted, err := archive.ExtractFile(downloadPath, platformConfig.Installer.ExtractFile, targetDir)
+		if err != nil {
+			return "", fmt.Errorf("failed to extract %s: %w", dep.Name, err)
+		}
+		m.logger.Infof("Extracted %s to %s", platformConfig.Installer.ExtractFile, extracted)
+	}
+
+	// The download and staging directories belong to the invoking user, so hand them over to
+	// the user the install command runs as
+	if platformConfig.RunAs != "" {
+		if err := shareWithUser(tempDir, platformConfig.RunAs); err != nil {
+			return "", fmt.Errorf("failed to install %s: %w", dep.Name, err)
+		}
 	}
 
 	// Prepare install command with replacements
@@ -144,33 +662,181 @@ func (m *Manager) installDependency(dep *Dependency) error {
 	for i, arg := range platformConfig.Commands.Install {
 		// Replace placeholders in command arguments
 		arg = strings.ReplaceAll(arg, "{download_path}", downloadPath)
+		arg = strings.ReplaceAll(arg, "{temp_dir}", tempDir)
+		arg = strings.ReplaceAll(arg, "{install_dir}", targetDir)
+		arg = strings.ReplaceAll(arg, "{final_install_dir}", installDir)
 
 		// Add more replacements as needed:
-		// - {install_dir} for installation directory
 		// - {product_id} for product ID
 		// - etc.
 
 		installCmd[i] = arg
 	}
 
+	// An extracted file may need no further install step
+	output := ""
+	if len(installCmd) > 0 {
+		var err error
+		if output, err = m.runInstallCommand(ctx, dep, installCmd, platformConfig.RunAs); err != nil {
+			return output, err
+		}
+	}
+
+	if platformConfig.Installer.Atomic {
+		if err := os.WriteFile(filepath.Join(targetDir, installMarker), []byte(dep.Name+"\n"), 0644); err != nil {
+			return output, fmt.Errorf("failed to mark staged install of %s: %w", dep.Name, err)
+		}
+		if err := swapDir(targetDir, installDir); err != nil {
+			return output, fmt.Errorf("failed to install %s: %w", dep.Name, err)
+		}
+		m.logger.Infof("Moved staged install of %s into %s", dep.Name, installDir)
+	}
+
+	// Point stable links at the freshly installed files
+	if err := m.createSymlinks(dep, platformConfig.Symlinks, installDir); err != nil {
+		return output, fmt.Errorf("failed to create symlinks: %w", err)
+	}
+
+	// Confirm the installed binary is exactly the expected one
+	if platformConfig.BinaryChecksum != "" {
+		if err := m.verifyBinary(dep, platformConfig, installDir); err != nil {
+			return output, err
+		}
+	}
+
+	m.logger.Infof("Successfully installed %s", dep.Name)
+	return output, nil
+}
+
+// downloadLimits returns the timeout and retries of a download: the installer's if set,
+// otherwise the manager's
+func (m *Manager) downloadLimits(installer *Installer) (time.Duration, int) {
+	timeout, retries := m.downloadTimeout, m.downloadRetries
+	if installer.Timeout > 0 {
+		timeout = installer.Timeout
+	}
+	if installer.MaxRetries != nil {
+		retries = *installer.MaxRetries
+	}
+	return timeout, retries
+}
+
+// runInstallCommand runs an install command, as the runAs user if set, killing it when ctx is
+// cancelled or the install timeout, if one is configured, expires
+func (m *Manager) runInstallCommand(ctx context.Context, dep *Dependency, installCmd []string, runAs string) (string, error) {
 	m.logger.Infof("Installing %s using command: %s", dep.Name, strings.Join(installCmd, " "))
+	return m.runChangeCommand(ctx, dep, installCmd, runAs, "installation")
+}
 
-	// Execute installation command
-	cmd := exec.Command(installCmd[0], installCmd[1:]...)
+// runChangeCommand runs a command installing or removing a dependency the way install commands
+// run: in its own process group, as the runAs user if set, and killed along with its children
+// when ctx is cancelled or the install timeout expires
+// The operation names the command in errors
+func (m *Manager) runChangeCommand(ctx context.Context, dep *Dependency, command []string, runAs, operation string) (string, error) {
+	// Apply the install timeout if one is configured
+	parent := ctx
+	if m.installTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.installTimeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Env = m.commandEnv(dep)
+	configureProcessGroup(cmd)
+	if runAs != "" {
+		if err := runAsUser(cmd, runAs); err != nil {
+			return "", fmt.Errorf("%s of %s failed: %w", operation, dep.Name, err)
+		}
+	}
+	m.traceCommand(dep, cmd)
 	output, err := cmd.CombinedOutput()
+
+	// Handle cancellation and timeout separately
+	if parent.Err() != nil {
+		return string(output), fmt.Errorf("%s cancelled: %w", operation, parent.Err())
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return string(output), fmt.Errorf("%s timed out after %s", operation, m.installTimeout)
+	}
+
 	if err != nil {
-		return fmt.Errorf("installation failed: %w, output: %s", err, output)
+		return string(output), fmt.Errorf("%s failed: %w, output: %s", operation, err, output)
 	}
 
-	m.logger.Infof("Successfully installed %s", dep.Name)
-	return nil
+	return string(output), nil
+}
+
+// keepDownload moves a downloaded artifact into the keep downloads directory
+// Failures only warn, as they must not affect the install result
+func (m *Manager) keepDownload(dep *Dependency, downloadPath string) {
+	dir := filepath.Join(m.keepDownloads, dep.Name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		m.logger.Warnf("Failed to create directory to keep download of %s: %v", dep.Name, err)
+		return
+	}
+
+	kept := filepath.Join(dir, filepath.Base(downloadPath))
+	if err := moveFile(downloadPath, kept); err != nil {
+		m.logger.Warnf("Failed to keep download of %s: %v", dep.Name, err)
+		return
+	}
+
+	m.logger.Infof("Kept download of %s at %s", dep.Name, kept)
 }
 
 // VerifyDependency performs a thorough check of an installed dependency
+// By default a version older than Required is flagged for update even if it satisfies
+// the Constraint; with WithConstraintFirst a satisfied Constraint takes precedence
 func (m *Manager) VerifyDependency(dep *Dependency) (*DependencyStatus, error) {
+	return m.verifyDependency(context.Background(), dep)
+}
+
+// verifyDependency is VerifyDependency, killing the verify and health check commands when
+// ctx is cancelled
+func (m *Manager) verifyDependency(ctx context.Context, dep *Dependency) (*DependencyStatus, error) {
+	status, err := m.verifyInstallation(ctx, dep)
+	if m.deepCheck && status.Installed {
+		m.runHealthCheck(ctx, dep, status)
+	}
+	return status, err
+}
+
+// runHealthCheck runs the health check of an installed dependency, if it has one, recording
+// the result on its status
+func (m *Manager) runHealthCheck(ctx context.Context, dep *Dependency, status *DependencyStatus) {
+	platformConfig, err := m.GetPlatformConfig(dep)
+	if err != nil || len(platformConfig.Commands.HealthCheck) == 0 {
+		return
+	}
+
+	timeout := m.healthCheckTimeout
+	if timeout <= 0 {
+		timeout = DefaultHealthCheckTimeout
+	}
+
+	m.logger.Infof("Running health check of %s", dep.Name)
+	output, timedOut, err := m.runCheckCommand(ctx, dep, platformConfig.Commands.HealthCheck, m.commandEnv(dep), platformConfig.RunAs, timeout)
+	status.HealthChecked = true
+	switch {
+	case timedOut:
+		status.HealthError = fmt.Errorf("health check timed out after %s", timeout)
+	case err != nil:
+		status.HealthError = fmt.Errorf("health check failed: %w: %s", err, output)
+	default:
+		status.Healthy = true
+		return
+	}
+	m.logger.Warnf("Dependency %s is unhealthy: %v", dep.Name, status.HealthError)
+}
+
+// verifyInstallation runs the verify command of a dependency and compares the version it reports
+func (m *Manager) verifyInstallation(ctx context.Context, dep *Dependency) (*DependencyStatus, error) {
 	status := &DependencyStatus{
-		Name:      dep.Name,
-		Installed: false,
+		Name:               dep.Name,
+		Installed:          false,
+		Deprecated:         dep.Deprecated != "",
+		DeprecationMessage: dep.Deprecated,
 	}
 
 	// Get platform-specific configuration
@@ -188,30 +854,54 @@ func (m *Manager) VerifyDependency(dep *Dependency) (*DependencyStatus, error) {
 
 	// Log the verification attempt
 	m.logger.Infof("Verifying dependency: %s", dep.Name)
+	env := m.commandEnv(dep)
 
-	// Run verify command with timeout to avoid hanging
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	// Create the command
-	cmd := exec.CommandContext(ctx, platformConfig.Commands.Verify[0], platformConfig.Commands.Verify[1:]...)
+	// Run verify command, retrying transient failures with backoff
+	// A missing executable is not transient, so it is never retried
+	outputStr, timedOut, err := m.runVerifyCommand(ctx, dep, platformConfig.Commands.Verify, env, platformConfig.RunAs)
+	backoff := m.verifyCommandBackoff
+	for attempt := 1; err != nil && !timedOut && !isNotFound(err) && attempt <= m.verifyCommandRetries; attempt++ {
+		m.logger.Debugf("Verify command for %s failed, retrying in %s (attempt %d/%d)",
+			dep.Name, backoff, attempt, m.verifyCommandRetries)
+		if sleepContext(ctx, backoff) != nil {
+			break
+		}
+		backoff *= 2
+		outputStr, timedOut, err = m.runVerifyCommand(ctx, dep, platformConfig.Commands.Verify, env, platformConfig.RunAs)
+	}
 
-	// Capture output
-	output, err := cmd.CombinedOutput()
-	outputStr := strings.TrimSpace(string(output))
+	// Keep the raw output for callers
+	status.VerifyOutput = outputStr
 
 	// Handle timeout separately
-	if ctx.Err() == context.DeadlineExceeded {
-		status.Error = fmt.Errorf("verification command timed out after 30 seconds")
+	if timedOut {
+		status.Error = fmt.Errorf("verification command timed out after %s", verifyCommandTimeout)
 		return status, status.Error
 	}
 
-	// Handle command errors
+	// Handle command errors, distinguishing a missing executable (not installed)
+	// from one that ran and failed (installed but broken)
 	if err != nil {
-		status.Error = fmt.Errorf("dependency verification failed: %w, output: %s", err, outputStr)
+		notFound := isNotFound(err)
+		status.Broken = !notFound
+		status.Error = &VerifyFailedError{Dependency: dep.Name, Output: outputStr, Err: err, NotFound: notFound}
 		return status, status.Error
 	}
 
+	// A tool that prints a known success string must have printed it
+	expect := platformConfig.Commands.VerifyExpect
+	if expect != "" {
+		matched, err := verifyOutputMatches(outputStr, expect)
+		if err == nil && !matched {
+			err = fmt.Errorf("output does not contain expected %q", expect)
+		}
+		if err != nil {
+			status.Broken = true
+			status.Error = &VerifyFailedError{Dependency: dep.Name, Output: outputStr, Err: err}
+			return status, status.Error
+		}
+	}
+
 	// Dependency is installed
 	status.Installed = true
 	m.logger.Infof("Dependency %s is installed", dep.Name)
@@ -220,14 +910,38 @@ func (m *Manager) VerifyDependency(dep *Dependency) (*DependencyStatus, error) {
 	status.CurrentVersion = outputStr
 
 	// Check if we can extract a cleaner version
-	version := extractVersion(outputStr)
-	if version != "" {
+	version, found := findVersionWith(outputStr, dep.Version.Match, versionPatternsFor(dep.VersionScheme))
+	if found {
 		status.CurrentVersion = version
 	}
 
+	// The expected output is enough when the tool reports no version
+	if !found && expect != "" {
+		status.CurrentVersion = ""
+		status.Compatible = true
+		return status, nil
+	}
+
+	// A working install must report a version we can compare against
+	required := m.requiredVersion(dep)
+	if !found && dep.StrictVersionParse && (required != "" || dep.Version.Constraint != "") {
+		status.Compatible = false
+		status.Error = fmt.Errorf("dependency '%s' may be partially installed: no version found in verify output %q",
+			dep.Name, outputStr)
+		m.logger.Warnf("%v", status.Error)
+		return status, nil
+	}
+
+	// Compare versions using the dependency's scheme
+	comparator, err := ComparatorFor(dep.VersionScheme)
+	if err != nil {
+		status.Error = fmt.Errorf("dependency '%s': %w", dep.Name, err)
+		return status, status.Error
+	}
+
 	// Check if update is needed
-	if dep.Version.Required != "" {
-		updateType, err := CheckVersionUpdate(status.CurrentVersion, dep.Version.Required)
+	if required != "" {
+		updateType, err := comparator.UpdateType(status.CurrentVersion, required)
 		if err != nil {
 			status.Error = err
 			m.logger.Errorf("Failed to check version update: %v", err)
@@ -235,14 +949,14 @@ func (m *Manager) VerifyDependency(dep *Dependency) (*DependencyStatus, error) {
 			status.RequiredUpdate = updateType
 			if updateType != NoUpdate {
 				m.logger.Infof("Dependency %s requires a %s (current: %s, required: %s)",
-					dep.Name, updateType, status.CurrentVersion, dep.Version.Required)
+					dep.Name, updateType, status.CurrentVersion, required)
 			}
 		}
 	}
 
 	// Check if current version is compatible with constraint
 	if dep.Version.Constraint != "" {
-		compatible, err := IsVersionCompatible(status.CurrentVersion, dep.Version.Constraint)
+		compatible, err := comparator.Satisfies(status.CurrentVersion, dep.Version.Constraint)
 		if err != nil {
 			status.Error = err
 			m.logger.Errorf("Failed to check version compatibility: %v", err)
@@ -258,37 +972,263 @@ func (m *Manager) VerifyDependency(dep *Dependency) (*DependencyStatus, error) {
 		status.Compatible = true
 	}
 
+	// In constraint-first mode a satisfied constraint is enough, whatever the required version
+	if m.constraintFirst && dep.Version.Constraint != "" && status.Compatible && status.RequiredUpdate != NoUpdate {
+		m.logger.Infof("Dependency %s version %s satisfies constraint %s, not updating to %s",
+			dep.Name, status.CurrentVersion, dep.Version.Constraint, required)
+		status.RequiredUpdate = NoUpdate
+	}
+
 	return status, nil
 }
 
-// extractVersion tries to extract a clean semantic version from output text
-// This helps with commands that return more than just a version number
-func extractVersion(output string) string {
-	// Common version patterns
-	patterns := []*regexp.Regexp{
-		regexp.MustCompile(`v?(\d+\.\d+\.\d+)`),                     // Matches: 1.2.3, v1.2.3
-		regexp.MustCompile(`version\s+v?(\d+\.\d+\.\d+)`),           // Matches: version 1.2.3
-		regexp.MustCompile(`v?(\d+\.\d+\.\d+)[\-+]([0-9A-Za-z-]+)`), // Matches: 1.2.3-alpha, v1.2.3+build
+// VerifyDependencies runs the verify command of the named dependencies (or all enabled ones if
+// none are named) and returns their statuses in configuration order
+func (m *Manager) VerifyDependencies(names ...string) ([]*DependencyStatus, error) {
+	deps := make([]*Dependency, 0, len(m.Config.Dependencies))
+	if len(names) == 0 {
+		for i := range m.Config.Dependencies {
+			if m.Config.Dependencies[i].IsEnabled() {
+				deps = append(deps, &m.Config.Dependencies[i])
+			}
+		}
+	} else {
+		for _, name := range names {
+			dep := m.findDependency(name)
+			if dep == nil {
+				return nil, fmt.Errorf("dependency '%s' not found in configuration", name)
+			}
+			deps = append(deps, dep)
+		}
+	}
+
+	statuses := make([]*DependencyStatus, 0, len(deps))
+	for _, dep := range deps {
+		status, _ := m.VerifyDependency(dep) // The error is recorded on the status
+		statuses = append(statuses, status)
 	}
 
-	for _, pattern := range patterns {
-		match := pattern.FindStringSubmatch(output)
-		if len(match) >= 2 {
-			return match[1] // Return the captured version
+	return statuses, nil
+}
+
+// runVerifyCommand runs a verify command, as the runAs user if set, with a timeout to avoid hanging
+// and killed when ctx is cancelled
+// It returns the trimmed combined output and whether the command timed out
+func (m *Manager) runVerifyCommand(ctx context.Context, dep *Dependency, args []string, env []string, runAs string) (string, bool, error) {
+	return m.runCheckCommand(ctx, dep, args, env, runAs, verifyCommandTimeout)
+}
+
+// runCheckCommand runs a verify or health check command like runVerifyCommand, with the given timeout
+func (m *Manager) runCheckCommand(ctx context.Context, dep *Dependency, args []string, env []string, runAs string, timeout time.Duration) (string, bool, error) {
+	release, err := acquire(ctx, m.checkSem)
+	if err != nil {
+		return "", false, err
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = env
+	configureProcessGroup(cmd)
+	if runAs != "" {
+		if err := runAsUser(cmd, runAs); err != nil {
+			return "", false, err
 		}
 	}
+	m.traceCommand(dep, cmd)
+	output, err := cmd.CombinedOutput()
+
+	return strings.TrimSpace(string(output)), ctx.Err() == context.DeadlineExceeded, err
+}
+
+// isNotFound reports whether a command failed to start because its executable does not exist
+func isNotFound(err error) bool {
+	return errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist)
+}
+
+// ExtractVersion extracts a clean semantic version (e.g. "1.2.3" from "tool v1.2.3 (linux)")
+// from command output, returning the output unchanged when no version is found
+// Additional patterns are tried before the built-in ones, each capturing the version in its
+// first group, or as the whole match if it has no groups
+func ExtractVersion(output string, patterns ...*regexp.Regexp) string {
+	if len(patterns) == 0 {
+		return extractVersionWith(output, VersionMatch{})
+	}
+
+	if version, ok := findVersionWith(output, VersionMatch{}, append(slices.Clip(patterns), versionPatterns...)); ok {
+		return version
+	}
+	return output
+}
+
+// versionPatterns are the common version patterns, most general first
+var versionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`v?(\d+\.\d+\.\d+)`),                     // Matches: 1.2.3, v1.2.3
+	regexp.MustCompile(`version\s+v?(\d+\.\d+\.\d+)`),           // Matches: version 1.2.3
+	regexp.MustCompile(`v?(\d+\.\d+\.\d+)[\-+]([0-9A-Za-z-]+)`), // Matches: 1.2.3-alpha, v1.2.3+build
+}
 
+// extractVersionWith extracts a version like ExtractVersion, matching line by line
+// Lines not containing match.Line are ignored, and with match.MatchLast the last
+// matching line wins
+func extractVersionWith(output string, match VersionMatch) string {
+	if version, ok := findVersion(output, match); ok {
+		return version
+	}
 	return output // Return the original if no pattern matches
 }
 
+// findVersion returns the version found in output and whether any pattern matched
+func findVersion(output string, match VersionMatch) (string, bool) {
+	return findVersionWith(output, match, versionPatterns)
+}
+
+// verifyOutputMatches reports whether verify output contains expect, or matches it as a
+// regular expression when it is written as "/pattern/"
+func verifyOutputMatches(output, expect string) (bool, error) {
+	if len(expect) > 2 && strings.HasPrefix(expect, "/") && strings.HasSuffix(expect, "/") {
+		pattern, err := regexp.Compile(expect[1 : len(expect)-1])
+		if err != nil {
+			return false, fmt.Errorf("invalid expected output pattern: %w", err)
+		}
+		return pattern.MatchString(output), nil
+	}
+	return strings.Contains(output, expect), nil
+}
+
+// findVersionWith finds a version like findVersion using the given patterns
+// Without patterns, the first non-empty (trimmed) line is the version
+func findVersionWith(output string, match VersionMatch, patterns []*regexp.Regexp) (string, bool) {
+	if match.Line == "" && !match.MatchLast && len(patterns) > 0 {
+		for _, pattern := range patterns {
+			if m := pattern.FindStringSubmatch(output); m != nil {
+				return matchedVersion(m), true
+			}
+		}
+		return "", false
+	}
+
+	lines := strings.Split(output, "\n")
+	if match.MatchLast {
+		for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+			lines[i], lines[j] = lines[j], lines[i]
+		}
+	}
+
+	for _, line := range lines {
+		if match.Line != "" && !strings.Contains(line, match.Line) {
+			continue
+		}
+
+		if len(patterns) == 0 {
+			if line = strings.TrimSpace(line); line != "" {
+				return line, true
+			}
+			continue
+		}
+
+		for _, pattern := range patterns {
+			if m := pattern.FindStringSubmatch(line); m != nil {
+				return matchedVersion(m), true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// matchedVersion returns the version in a pattern match: the first group, or the whole match
+func matchedVersion(m []string) string {
+	if len(m) >= 2 {
+		return m[1]
+	}
+	return m[0]
+}
+
+// applyEnvironment applies the managed environment to the current process
+// Failures only warn, as the dependencies themselves are installed either way
+func (m *Manager) applyEnvironment() {
+	m.envMu.Lock()
+	defer m.envMu.Unlock()
+	if m.originalEnv == nil {
+		m.originalEnv = os.Environ()
+	}
+	if err := m.envManager.ApplyToCurrentProcess(); err != nil {
+		m.logger.Warnf("Failed to apply environment changes: %v", err)
+	}
+}
+
+// commandEnv returns the environment for a dependency's install and verify commands: the
+// parent (or, with WithCleanInstallEnv, a minimal) environment plus the environment configured
+// so far and the dependency's own, which is otherwise only registered once it is installed
+func (m *Manager) commandEnv(dep *Dependency) []string {
+	env := environment.NewManager()
+	if m.envManager != nil {
+		m.envMu.Lock()
+		env = m.envManager.Clone()
+		m.envMu.Unlock()
+	}
+
+	depEnv := m.dependencyEnvironment(dep)
+	for _, path := range depEnv.Path {
+		env.AddPath(env.ExpandVariables(path))
+	}
+	for key, value := range depEnv.Variables {
+		if env.PreservesExisting(key) {
+			env.AddVariable(key, os.Getenv(key))
+			continue
+		}
+		env.AddVariable(key, env.ExpandVariables(value))
+	}
+
+	if m.cleanInstallEnv {
+		return env.UpdateEnvironment(environment.BaseEnvironment())
+	}
+	return env.GetUpdatedEnvironment()
+}
+
+// dependencyEnvironment returns the environment of a dependency on the current platform: the
+// dependency-level paths followed by the platform's and the bin directory under the prefix, and
+// the dependency-level variables with those of the platform taking precedence
+func (m *Manager) dependencyEnvironment(dep *Dependency) Environment {
+	var prefixPath []string
+	if prefixDir := m.prefixDir(dep); prefixDir != "" {
+		prefixPath = []string{filepath.Join(prefixDir, "bin")}
+	}
+
+	platform, ok := dep.PlatformConfigFor(m.Platform)
+	if !ok || (len(platform.Environment.Path) == 0 && len(platform.Environment.Variables) == 0) {
+		if prefixPath == nil {
+			return dep.Environment
+		}
+		return Environment{Path: slices.Concat(dep.Environment.Path, prefixPath), Variables: dep.Environment.Variables}
+	}
+
+	env := Environment{
+		Path:      slices.Concat(dep.Environment.Path, platform.Environment.Path, prefixPath),
+		Variables: maps.Clone(dep.Environment.Variables),
+	}
+	if env.Variables == nil {
+		env.Variables = make(map[string]string)
+	}
+	maps.Copy(env.Variables, platform.Environment.Variables)
+	return env
+}
+
 func (m *Manager) setupDependencyEnvironment(dep *Dependency) error {
 	// Check if dependency has environment settings
-	if dep.Environment.Path == nil && len(dep.Environment.Variables) == 0 {
+	depEnv := m.dependencyEnvironment(dep)
+	if depEnv.Path == nil && len(depEnv.Variables) == 0 {
 		return nil // No environment to set up
 	}
 
+	m.envMu.Lock()
+	defer m.envMu.Unlock()
+
 	// Add paths to PATH
-	for _, path := range dep.Environment.Path {
+	for _, path := range depEnv.Path {
 		// Expand variables in path
 		expandedPath := m.envManager.ExpandVariables(path)
 		m.envManager.AddPath(expandedPath)
@@ -296,9 +1236,16 @@ func (m *Manager) setupDependencyEnvironment(dep *Dependency) error {
 	}
 
 	// Add environment variables
-	for key, value := range dep.Environment.Variables {
+	for key, value := range depEnv.Variables {
 		// Expand variables in value
 		expandedValue := m.envManager.ExpandVariables(value)
+		if m.envManager.PreservesExisting(key) {
+			// Registered with the kept value, which a clean install environment lacks and
+			// later {VAR} references must expand to
+			m.envManager.AddVariable(key, os.Getenv(key))
+			m.logger.Debugf("Keeping existing value of %s, ignoring config value for dependency %s", key, dep.Name)
+			continue
+		}
 		m.envManager.AddVariable(key, expandedValue)
 		m.logger.Debugf("Set environment variable %s=%s for dependency %s", key, expandedValue, dep.Name)
 	}
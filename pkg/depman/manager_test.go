@@ -1,13 +1,34 @@
 package depman
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/sobhit-avrl/depman-v1/internal/downloader"
+	"github.com/sobhit-avrl/depman-v1/internal/environment"
 )
 
 // mockLogger is a simple logger for testing
 type mockLogger struct {
+	mu        sync.Mutex
 	infoLogs  []string
 	errorLogs []string
 	debugLogs []string
@@ -15,19 +36,27 @@ type mockLogger struct {
 }
 
 func (l *mockLogger) Infof(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	// No need to actually format for tests
 	l.infoLogs = append(l.infoLogs, format)
 }
 
 func (l *mockLogger) Errorf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	l.errorLogs = append(l.errorLogs, format)
 }
 
 func (l *mockLogger) Debugf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	l.debugLogs = append(l.debugLogs, format)
 }
 
 func (l *mockLogger) Warnf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	l.warnLogs = append(l.warnLogs, format)
 }
 
@@ -223,8 +252,201 @@ func TestGetPlatformConfig(t *testing.T) {
 			t.Errorf("Expected an error but got none")
 		}
 	})
+
+	// Test dependency-level default commands
+	t.Run("Default commands", func(t *testing.T) {
+		dep := &Dependency{
+			Name: "test-dep",
+			Commands: Commands{
+				Verify:    []string{"test-dep", "--version"},
+				Uninstall: []string{"rm", "-f", "/usr/local/bin/test-dep"},
+			},
+			Platforms: map[string]PlatformConfig{
+				"linux": {
+					Commands: Commands{
+						Install:   []string{"tar", "-xzf", "{download_path}"},
+						Uninstall: []string{"apt-get", "remove", "test-dep"},
+					},
+				},
+			},
+		}
+
+		config, err := managerLinux.GetPlatformConfig(dep)
+		if err != nil {
+			t.Fatalf("Failed to get platform config: %v", err)
+		}
+
+		if strings.Join(config.Commands.Verify, " ") != "test-dep --version" {
+			t.Errorf("Expected inherited verify command but got %v", config.Commands.Verify)
+		}
+		if strings.Join(config.Commands.Install, " ") != "tar -xzf {download_path}" {
+			t.Errorf("Expected platform install command but got %v", config.Commands.Install)
+		}
+		if strings.Join(config.Commands.Uninstall, " ") != "apt-get remove test-dep" {
+			t.Errorf("Expected platform uninstall command to override default but got %v", config.Commands.Uninstall)
+		}
+	})
+}
+
+// TestPlatformFallback tests resolving the unix and all platform keys
+func TestPlatformFallback(t *testing.T) {
+	verify := func(name string) PlatformConfig {
+		return PlatformConfig{Commands: Commands{Install: []string{"install"}, Verify: []string{name}}}
+	}
+
+	testCases := []struct {
+		name        string
+		platforms   map[string]PlatformConfig
+		platform    string
+		expected    string
+		expectError bool
+	}{
+		{
+			name:      "Exact platform wins",
+			platforms: map[string]PlatformConfig{"linux": verify("linux"), "unix": verify("unix"), "all": verify("all")},
+			platform:  "linux",
+			expected:  "linux",
+		},
+		{
+			name:      "Linux falls back to unix",
+			platforms: map[string]PlatformConfig{"unix": verify("unix"), "all": verify("all")},
+			platform:  "linux",
+			expected:  "unix",
+		},
+		{
+			name:      "Darwin falls back to unix",
+			platforms: map[string]PlatformConfig{"unix": verify("unix")},
+			platform:  "darwin",
+			expected:  "unix",
+		},
+		{
+			name:      "Windows skips unix for all",
+			platforms: map[string]PlatformConfig{"unix": verify("unix"), "all": verify("all")},
+			platform:  "windows",
+			expected:  "all",
+		},
+		{
+			name:      "Linux falls back to all without unix",
+			platforms: map[string]PlatformConfig{"windows": verify("windows"), "all": verify("all")},
+			platform:  "linux",
+			expected:  "all",
+		},
+		{
+			name:        "Windows without fallback",
+			platforms:   map[string]PlatformConfig{"unix": verify("unix")},
+			platform:    "windows",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dep := Dependency{Name: "tool", Version: Version{Required: "1.0.0"}, Platforms: tc.platforms}
+			manager := &Manager{
+				Config:   &DependencyConfig{Dependencies: []Dependency{dep}},
+				Platform: tc.platform,
+				logger:   &mockLogger{},
+			}
+
+			config, err := manager.GetPlatformConfig(&dep)
+			errs := manager.validateDependencies()
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected an error but got none")
+				}
+				if len(errs) == 0 {
+					t.Errorf("Expected missing coverage to be reported")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+			if config.Commands.Verify[0] != tc.expected {
+				t.Errorf("Expected the %s configuration but got %s", tc.expected, config.Commands.Verify[0])
+			}
+			if len(errs) > 0 {
+				t.Errorf("Did not expect validation errors but got: %v", errs)
+			}
+		})
+	}
 }
 
+// TestURLTemplate tests expanding a single templated download URL for each platform
+func TestURLTemplate(t *testing.T) {
+	dep := Dependency{
+		Name:    "tool",
+		Version: Version{Required: "1.4.0"},
+		Platforms: map[string]PlatformConfig{
+			"all": {
+				Installer: Installer{
+					URL:         "https://example.com/v{version}/tool-{version}-{os}-{arch}.tar.gz",
+					ChecksumURL: "https://example.com/v{version}/tool-%7Bos%7D-%7Barch%7D.sha256",
+				},
+			},
+		},
+	}
+
+	testCases := []struct {
+		platform            string
+		arch                string
+		expectedURL         string
+		expectedChecksumURL string
+	}{
+		{platform: "linux", arch: "amd64",
+			expectedURL:         "https://example.com/v1.4.0/tool-1.4.0-linux-amd64.tar.gz",
+			expectedChecksumURL: "https://example.com/v1.4.0/tool-linux-amd64.sha256"},
+		{platform: "darwin", arch: "arm64",
+			expectedURL:         "https://example.com/v1.4.0/tool-1.4.0-darwin-arm64.tar.gz",
+			expectedChecksumURL: "https://example.com/v1.4.0/tool-darwin-arm64.sha256"},
+		{platform: "windows", arch: "386",
+			expectedURL:         "https://example.com/v1.4.0/tool-1.4.0-windows-386.tar.gz",
+			expectedChecksumURL: "https://example.com/v1.4.0/tool-windows-386.sha256"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.platform, func(t *testing.T) {
+			manager := &Manager{Platform: tc.platform, Arch: tc.arch, logger: &mockLogger{}}
+
+			config, err := manager.GetPlatformConfig(&dep)
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+			if config.Installer.URL != tc.expectedURL {
+				t.Errorf("Expected URL %s but got %s", tc.expectedURL, config.Installer.URL)
+			}
+			if config.Installer.ChecksumURL != tc.expectedChecksumURL {
+				t.Errorf("Expected checksum URL %s but got %s", tc.expectedChecksumURL, config.Installer.ChecksumURL)
+			}
+		})
+	}
+
+	t.Run("Defaults to the runtime architecture", func(t *testing.T) {
+		manager := &Manager{Platform: "linux", logger: &mockLogger{}}
+		config, err := manager.GetPlatformConfig(&dep)
+		if err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+		if !strings.HasSuffix(config.Installer.URL, "-linux-"+runtime.GOARCH+".tar.gz") {
+			t.Errorf("Expected the runtime architecture in %s", config.Installer.URL)
+		}
+	})
+
+	t.Run("Unknown placeholders are kept", func(t *testing.T) {
+		url := expandURLTemplate("https://example.com/{version}/{flavor}", map[string]string{"version": "1.0.0"})
+		if url != "https://example.com/1.0.0/{flavor}" {
+			t.Errorf("Expected unknown placeholder to be kept but got %s", url)
+		}
+		if unknown := unknownPlaceholders(url); len(unknown) != 1 || unknown[0] != "{flavor}" {
+			t.Errorf("Expected {flavor} to be reported but got %v", unknown)
+		}
+	})
+}
+
+// testCommands are placeholder install and verify commands for dependencies that are only validated
+var testCommands = Commands{Install: []string{"install-tool"}, Verify: []string{"tool", "--version"}}
+
 // TestValidateDependencies tests the dependency validation
 func TestValidateDependencies(t *testing.T) {
 	// Test with no dependencies
@@ -278,7 +500,7 @@ func TestValidateDependencies(t *testing.T) {
 							Required: "1.0.0",
 						},
 						Platforms: map[string]PlatformConfig{
-							"windows": {},
+							"windows": {Commands: testCommands},
 						},
 					},
 				},
@@ -291,4 +513,1713 @@ func TestValidateDependencies(t *testing.T) {
 			t.Errorf("Expected no errors but got: %v", errors)
 		}
 	})
+
+	// Test with malformed checksums
+	validSHA256 := "sha256:" + strings.Repeat("a", 64)
+	checksumCases := []struct {
+		name        string
+		checksum    string
+		expectError bool
+	}{
+		{name: "Valid sha256", checksum: validSHA256, expectError: false},
+		{name: "Valid sha512", checksum: "sha512:" + strings.Repeat("0", 128), expectError: false},
+		{name: "Bare sha256 hash", checksum: strings.Repeat("a", 64), expectError: false},
+		{name: "Bare hash of unknown length", checksum: strings.Repeat("a", 32), expectError: true},
+		{name: "Too-short hash", checksum: "sha256:xyz", expectError: true},
+		{name: "Non-hex hash", checksum: "sha256:" + strings.Repeat("z", 64), expectError: true},
+		{name: "Unknown algorithm", checksum: "md5:" + strings.Repeat("a", 32), expectError: true},
+		{name: "sha512 with sha256 length", checksum: "sha512:" + strings.Repeat("a", 64), expectError: true},
+	}
+
+	for _, tc := range checksumCases {
+		t.Run("Checksum "+tc.name, func(t *testing.T) {
+			manager := &Manager{
+				Config: &DependencyConfig{
+					Name: "Test App",
+					Dependencies: []Dependency{
+						{
+							Name: "test-dep",
+							Version: Version{
+								Required: "1.0.0",
+							},
+							Platforms: map[string]PlatformConfig{
+								"windows": {Commands: testCommands},
+								"linux": {
+									Installer: Installer{Checksum: tc.checksum},
+								},
+							},
+						},
+					},
+				},
+				Platform: "windows",
+			}
+
+			errors := manager.validateDependencies()
+			if tc.expectError && len(errors) == 0 {
+				t.Errorf("Expected an error but got none")
+			}
+			if !tc.expectError && len(errors) > 0 {
+				t.Errorf("Expected no errors but got: %v", errors)
+			}
+		})
+	}
+
+	// Test download filenames stay inside the download directory
+	filenameCases := []struct {
+		name        string
+		filename    string
+		expectError bool
+	}{
+		{name: "Plain name", filename: "tool.tar.gz", expectError: false},
+		{name: "Current directory", filename: ".", expectError: true},
+		{name: "Parent directory", filename: "..", expectError: true},
+		{name: "Path", filename: "../tool.tar.gz", expectError: true},
+	}
+
+	for _, tc := range filenameCases {
+		t.Run("Filename "+tc.name, func(t *testing.T) {
+			manager := &Manager{
+				Config: &DependencyConfig{
+					Name: "Test App",
+					Dependencies: []Dependency{
+						{
+							Name: "test-dep",
+							Version: Version{
+								Required: "1.0.0",
+							},
+							Platforms: map[string]PlatformConfig{
+								"windows": {Commands: testCommands},
+								"linux": {
+									Installer: Installer{Filename: tc.filename},
+								},
+							},
+						},
+					},
+				},
+				Platform: "windows",
+			}
+
+			errors := manager.validateDependencies()
+			if tc.expectError && len(errors) == 0 {
+				t.Errorf("Expected an error but got none")
+			}
+			if !tc.expectError && len(errors) > 0 {
+				t.Errorf("Expected no errors but got: %v", errors)
+			}
+		})
+	}
+
+	// Test name and reference validation
+	newDep := func(name string, deps ...string) Dependency {
+		return Dependency{
+			Name:         name,
+			Version:      Version{Required: "1.0.0"},
+			Platforms:    map[string]PlatformConfig{"windows": {Commands: testCommands}},
+			Dependencies: deps,
+		}
+	}
+
+	nameCases := []struct {
+		name         string
+		dependencies []Dependency
+		expected     string
+	}{
+		{name: "Empty name", dependencies: []Dependency{newDep("")}, expected: "dependency #1 has an empty name"},
+		{name: "Whitespace in name", dependencies: []Dependency{newDep("test dep")}, expected: "containing whitespace"},
+		{name: "Duplicate name", dependencies: []Dependency{newDep("test-dep"), newDep("test-dep")}, expected: "dependency #2 has duplicate name 'test-dep'"},
+		{name: "Dangling reference", dependencies: []Dependency{newDep("test-dep", "missing")}, expected: "depends on unknown dependency 'missing'"},
+		{name: "Valid reference", dependencies: []Dependency{newDep("app", "test-dep"), newDep("test-dep")}},
+	}
+
+	for _, tc := range nameCases {
+		t.Run("Names "+tc.name, func(t *testing.T) {
+			manager := &Manager{
+				Config: &DependencyConfig{
+					Name:         "Test App",
+					Dependencies: tc.dependencies,
+				},
+				Platform: "windows",
+			}
+
+			errors := manager.validateDependencies()
+			if tc.expected == "" {
+				if len(errors) > 0 {
+					t.Errorf("Expected no errors but got: %v", errors)
+				}
+				return
+			}
+
+			found := false
+			for _, err := range errors {
+				if strings.Contains(err.Error(), tc.expected) {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("Expected an error containing %q but got: %v", tc.expected, errors)
+			}
+		})
+	}
+
+	// Test that the current platform has commands to run
+	commandCases := []struct {
+		name     string
+		dep      Dependency
+		expected []string
+	}{
+		{
+			name:     "Empty install command",
+			dep:      Dependency{Platforms: map[string]PlatformConfig{"windows": {Commands: Commands{Install: []string{}, Verify: []string{"tool"}}}}},
+			expected: []string{"no install command for platform 'windows'"},
+		},
+		{
+			name:     "Missing verify command",
+			dep:      Dependency{Platforms: map[string]PlatformConfig{"windows": {Commands: Commands{Install: []string{"install-tool"}}}}},
+			expected: []string{"no verify command for platform 'windows'"},
+		},
+		{
+			name: "Blank program",
+			dep:  Dependency{Platforms: map[string]PlatformConfig{"windows": {Commands: Commands{Install: []string{" ", "arg"}, Verify: []string{""}}}}},
+			expected: []string{
+				"no install command for platform 'windows'",
+				"no verify command for platform 'windows'",
+			},
+		},
+		{
+			name: "Default commands apply",
+			dep:  Dependency{Commands: testCommands, Platforms: map[string]PlatformConfig{"windows": {}}},
+		},
+		{
+			name: "Extracted file needs no install command",
+			dep:  Dependency{Platforms: map[string]PlatformConfig{"windows": {Installer: Installer{ExtractFile: "tool"}, Commands: Commands{Verify: []string{"tool"}}}}},
+		},
+		{
+			name: "Other platforms are left to lint",
+			dep:  Dependency{Platforms: map[string]PlatformConfig{"windows": {Commands: testCommands}, "linux": {}}},
+		},
+	}
+
+	for _, tc := range commandCases {
+		t.Run("Commands "+tc.name, func(t *testing.T) {
+			tc.dep.Name = "test-dep"
+			tc.dep.Version = Version{Required: "1.0.0"}
+			manager := &Manager{
+				Config:   &DependencyConfig{Dependencies: []Dependency{tc.dep}},
+				Platform: "windows",
+			}
+
+			errors := manager.validateDependencies()
+			if len(errors) != len(tc.expected) {
+				t.Fatalf("Expected %d errors but got %d: %v", len(tc.expected), len(errors), errors)
+			}
+			for i, expected := range tc.expected {
+				if !strings.Contains(errors[i].Error(), expected) {
+					t.Errorf("Expected an error containing %q but got: %v", expected, errors[i])
+				}
+			}
+		})
+	}
+}
+
+// TestEmptyInstallCommand tests that an empty install command is rejected before ensure
+// reaches the install step, rather than panicking there
+func TestEmptyInstallCommand(t *testing.T) {
+	manager := &Manager{
+		Config: &DependencyConfig{Dependencies: []Dependency{{
+			Name:    "tool",
+			Version: Version{Required: "1.0.0"},
+			Platforms: map[string]PlatformConfig{
+				runtime.GOOS: {Commands: Commands{Install: []string{""}, Verify: []string{"depman-missing-tool"}}},
+			},
+		}}},
+		Platform:   runtime.GOOS,
+		logger:     &mockLogger{},
+		envManager: environment.NewManager(),
+	}
+
+	_, _, err := manager.EnsureDependencies()
+	if err == nil {
+		t.Fatalf("Expected an error but got none")
+	}
+	if !strings.Contains(err.Error(), "no install command") {
+		t.Errorf("Expected a validation error for the install command but got: %v", err)
+	}
+}
+
+// TestInstallTimeout tests that a hanging install command is killed after the timeout
+func TestInstallTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sleep command not available on Windows")
+	}
+
+	dep := &Dependency{
+		Name: "slow-dep",
+		Platforms: map[string]PlatformConfig{
+			runtime.GOOS: {
+				Commands: Commands{
+					Install: []string{"sleep", "5"},
+				},
+			},
+		},
+	}
+
+	manager := &Manager{
+		Platform:       runtime.GOOS,
+		logger:         &mockLogger{},
+		installTimeout: 100 * time.Millisecond,
+	}
+
+	start := time.Now()
+	_, err := manager.installDependency(context.Background(), dep)
+	if err == nil {
+		t.Fatalf("Expected an error but got none")
+	}
+
+	if !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected timeout error but got: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Install was not killed promptly, took %s", elapsed)
+	}
+}
+
+// TestCommandOutputCapture tests that install and verify output is captured on success and failure
+func TestCommandOutputCapture(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	newDep := func(install, verify string) *Dependency {
+		return &Dependency{
+			Name: "test-dep",
+			Platforms: map[string]PlatformConfig{
+				runtime.GOOS: {
+					Commands: Commands{
+						Install: []string{"sh", "-c", install},
+						Verify:  []string{"sh", "-c", verify},
+					},
+				},
+			},
+		}
+	}
+
+	manager := &Manager{
+		Platform: runtime.GOOS,
+		logger:   &mockLogger{},
+	}
+
+	t.Run("Install success", func(t *testing.T) {
+		output, err := manager.installDependency(context.Background(), newDep("echo installed ok", "true"))
+		if err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+		if !strings.Contains(output, "installed ok") {
+			t.Errorf("Expected install output to be captured but got %q", output)
+		}
+	})
+
+	t.Run("Install failure", func(t *testing.T) {
+		output, err := manager.installDependency(context.Background(), newDep("echo install broke; exit 1", "true"))
+		if err == nil {
+			t.Fatalf("Expected an error but got none")
+		}
+		if !strings.Contains(output, "install broke") {
+			t.Errorf("Expected install output to be captured but got %q", output)
+		}
+	})
+
+	t.Run("Verify success", func(t *testing.T) {
+		status, err := manager.VerifyDependency(newDep("true", "echo tool version 1.2.3"))
+		if err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+		if status.VerifyOutput != "tool version 1.2.3" {
+			t.Errorf("Expected verify output 'tool version 1.2.3' but got %q", status.VerifyOutput)
+		}
+		if status.CurrentVersion != "1.2.3" {
+			t.Errorf("Expected current version '1.2.3' but got '%s'", status.CurrentVersion)
+		}
+	})
+
+	t.Run("Verify failure", func(t *testing.T) {
+		status, err := manager.VerifyDependency(newDep("true", "echo command not found; exit 127"))
+		if err == nil {
+			t.Fatalf("Expected an error but got none")
+		}
+		if status.VerifyOutput != "command not found" {
+			t.Errorf("Expected verify output 'command not found' but got %q", status.VerifyOutput)
+		}
+	})
+}
+
+// TestChecksumURL tests verifying a download against a sidecar checksum file
+func TestChecksumURL(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	content := []byte("release artifact contents")
+	sum := sha256.Sum256(content)
+	digest := hex.EncodeToString(sum[:])
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tool.tar.gz":
+			w.Write(content)
+		case "/tool.tar.gz.sha256":
+			fmt.Fprintf(w, "%s  tool.tar.gz\n", digest)
+		case "/bare.sha256":
+			fmt.Fprintf(w, "%s\n", digest)
+		case "/wrong.sha256":
+			fmt.Fprintf(w, "%s  tool.tar.gz\n", strings.Repeat("0", 64))
+		case "/malformed.sha256":
+			fmt.Fprint(w, "not-a-checksum  tool.tar.gz\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name        string
+		checksumURL string
+		expectError bool
+	}{
+		{name: "Hash with filename", checksumURL: server.URL + "/tool.tar.gz.sha256", expectError: false},
+		{name: "Bare hash", checksumURL: server.URL + "/bare.sha256", expectError: false},
+		{name: "Mismatched hash", checksumURL: server.URL + "/wrong.sha256", expectError: true},
+		{name: "Malformed checksum file", checksumURL: server.URL + "/malformed.sha256", expectError: true},
+		{name: "Missing checksum file", checksumURL: server.URL + "/missing.sha256", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dep := &Dependency{
+				Name: "tool",
+				Platforms: map[string]PlatformConfig{
+					runtime.GOOS: {
+						Installer: Installer{
+							URL:         server.URL + "/tool.tar.gz",
+							ChecksumURL: tc.checksumURL,
+						},
+						Commands: Commands{
+							Install: []string{"sh", "-c", "test -f {download_path}"},
+						},
+					},
+				},
+			}
+
+			manager := &Manager{
+				Platform: runtime.GOOS,
+				logger:   &mockLogger{},
+			}
+
+			_, err := manager.installDependency(context.Background(), dep)
+			if tc.expectError && err == nil {
+				t.Errorf("Expected an error but got none")
+			}
+			if !tc.expectError && err != nil {
+				t.Errorf("Did not expect an error but got: %v", err)
+			}
+		})
+	}
+}
+
+// TestRequireChecksum tests refusing or warning about downloads without a strong checksum
+func TestRequireChecksum(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	content := []byte("release artifact contents")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	sha1Sum := sha1.Sum(content)
+	sha1Checksum := "sha1:" + hex.EncodeToString(sha1Sum[:])
+	sizeChecksum := fmt.Sprintf("size:%d", len(content))
+
+	testCases := []struct {
+		name            string
+		require         bool
+		checksum        string
+		checksumURL     bool
+		expectError     bool
+		warning         string
+		expectValidated bool
+	}{
+		{name: "Missing checksum allowed with warning", require: false, warning: "has no checksum", expectValidated: true},
+		{name: "Missing checksum refused", require: true, expectError: true},
+		{name: "Checksum URL alone refused", require: true, checksumURL: true, expectError: true},
+		{name: "Checksum present", require: true, checksum: "sha256:" + strings.Repeat("0", 64), expectError: true, expectValidated: true},
+		{name: "Size allowed with warning", require: false, checksum: sizeChecksum, warning: "only verified by its size", expectValidated: true},
+		{name: "Size refused", require: true, checksum: sizeChecksum, expectError: true, warning: "only verified by its size"},
+		{name: "Sha1 allowed with warning", require: false, checksum: sha1Checksum, warning: "verified with sha1", expectValidated: true},
+		{name: "Sha1 refused", require: true, checksum: sha1Checksum, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			installer := Installer{URL: server.URL + "/tool.tar.gz", Checksum: tc.checksum}
+			if tc.checksumURL {
+				installer.ChecksumURL = server.URL + "/tool.tar.gz.sha256"
+			}
+			dep := Dependency{
+				Name:    "tool",
+				Version: Version{Required: "1.0.0"},
+				Platforms: map[string]PlatformConfig{
+					runtime.GOOS: {
+						Installer: installer,
+						Commands:  Commands{Install: []string{"sh", "-c", "true"}, Verify: []string{"tool", "--version"}},
+					},
+				},
+			}
+
+			log := &mockLogger{}
+			manager := &Manager{
+				Config:   &DependencyConfig{Dependencies: []Dependency{dep}},
+				Platform: runtime.GOOS,
+				logger:   log,
+			}
+			WithRequireChecksum(tc.require)(manager)
+
+			// The present sha256 checksum deliberately mismatches, so only unrequired checksums may succeed
+			_, err := manager.installDependency(context.Background(), &dep)
+			if tc.expectError && err == nil {
+				t.Errorf("Expected an error but got none")
+			}
+			if !tc.expectError && err != nil {
+				t.Errorf("Did not expect an error but got: %v", err)
+			}
+
+			warned := false
+			for _, msg := range log.warnLogs {
+				if tc.warning != "" && strings.Contains(msg, tc.warning) {
+					warned = true
+				}
+			}
+			if tc.warning != "" && !warned {
+				t.Errorf("Expected a warning containing %q but got %v", tc.warning, log.warnLogs)
+			}
+			if tc.warning == "" && len(log.warnLogs) > 0 {
+				t.Errorf("Did not expect warnings but got %v", log.warnLogs)
+			}
+
+			errors := manager.validateDependencies()
+			if tc.expectValidated && len(errors) > 0 {
+				t.Errorf("Expected no validation errors but got: %v", errors)
+			}
+			if !tc.expectValidated && len(errors) == 0 {
+				t.Errorf("Expected a validation error but got none")
+			}
+		})
+	}
+}
+
+// TestInstallerMaxRedirects tests that an installer's max_redirects limits its download
+func TestInstallerMaxRedirects(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/first":
+			http.Redirect(w, r, "/second", http.StatusFound)
+		case "/second":
+			http.Redirect(w, r, "/tool.tar.gz", http.StatusFound)
+		default:
+			w.Write([]byte("release artifact contents"))
+		}
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name         string
+		maxRedirects int
+		expectError  bool
+	}{
+		{name: "Default limit", maxRedirects: 0},
+		{name: "Within limit", maxRedirects: 2},
+		{name: "Over limit", maxRedirects: 1, expectError: true},
+		{name: "Redirects disabled", maxRedirects: -1, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dep := Dependency{
+				Name: "tool",
+				Platforms: map[string]PlatformConfig{
+					runtime.GOOS: {
+						Installer: Installer{URL: server.URL + "/first", Checksum: "size:25", MaxRedirects: tc.maxRedirects},
+						Commands:  Commands{Install: []string{"true"}},
+					},
+				},
+			}
+			manager := &Manager{Platform: runtime.GOOS, logger: &mockLogger{}}
+
+			_, err := manager.installDependency(context.Background(), &dep)
+			if tc.expectError {
+				if !errors.Is(err, downloader.ErrRedirectBlocked) {
+					t.Errorf("Expected a blocked redirect but got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Did not expect an error but got: %v", err)
+			}
+		})
+	}
+}
+
+// TestKeepDownloads tests retaining downloaded artifacts after install
+func TestKeepDownloads(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("release artifact contents"))
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name        string
+		install     string
+		expectError bool
+	}{
+		{name: "Kept after successful install", install: "test -f {download_path}", expectError: false},
+		{name: "Kept after failed install", install: "exit 1", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			keepDir := t.TempDir()
+
+			dep := &Dependency{
+				Name: "tool",
+				Platforms: map[string]PlatformConfig{
+					runtime.GOOS: {
+						Installer: Installer{URL: server.URL + "/tool.tar.gz"},
+						Commands:  Commands{Install: []string{"sh", "-c", tc.install}},
+					},
+				},
+			}
+
+			manager := &Manager{
+				Platform: runtime.GOOS,
+				logger:   &mockLogger{},
+			}
+			WithKeepDownloads(keepDir)(manager)
+
+			_, err := manager.installDependency(context.Background(), dep)
+			if tc.expectError && err == nil {
+				t.Errorf("Expected an error but got none")
+			}
+			if !tc.expectError && err != nil {
+				t.Errorf("Did not expect an error but got: %v", err)
+			}
+
+			data, err := os.ReadFile(filepath.Join(keepDir, "tool", "tool.tar.gz"))
+			if err != nil {
+				t.Fatalf("Expected the download to be kept: %v", err)
+			}
+			if string(data) != "release artifact contents" {
+				t.Errorf("Expected kept download to have the downloaded contents but got %q", data)
+			}
+		})
+	}
+}
+
+// TestExtractFile tests installing a single file extracted from a downloaded archive
+func TestExtractFile(t *testing.T) {
+	// A release archive with the binary alongside files that shouldn't be installed
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range map[string]string{
+		"tool-1.0.0/bin/tool":  "#!/bin/sh\necho 1.0.0\n",
+		"tool-1.0.0/README.md": "# tool\n",
+		"tool-1.0.0/LICENSE":   "MIT\n",
+	} {
+		tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	fixture := buf.Bytes()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(fixture)
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name        string
+		extractFile string
+		installDir  bool
+		expectError string
+	}{
+		{name: "Extracts only the named entry", extractFile: "tool", installDir: true},
+		{name: "Entry not found", extractFile: "missing", installDir: true, expectError: "entry not found in archive: missing"},
+		{name: "Missing install dir", extractFile: "tool", expectError: "no install_dir"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			installDir := ""
+			if tc.installDir {
+				installDir = filepath.Join(t.TempDir(), "tool")
+			}
+
+			dep := &Dependency{
+				Name: "tool",
+				Platforms: map[string]PlatformConfig{
+					runtime.GOOS: {
+						Installer:  Installer{URL: server.URL + "/tool.tar.gz", ExtractFile: tc.extractFile},
+						InstallDir: installDir,
+					},
+				},
+			}
+
+			manager := &Manager{
+				Platform: runtime.GOOS,
+				logger:   &mockLogger{},
+			}
+
+			_, err := manager.installDependency(context.Background(), dep)
+			if tc.expectError != "" {
+				if err == nil {
+					t.Fatalf("Expected an error but got none")
+				}
+				if !strings.Contains(err.Error(), tc.expectError) {
+					t.Errorf("Expected error containing %q but got: %v", tc.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			entries, err := os.ReadDir(installDir)
+			if err != nil {
+				t.Fatalf("Failed to read install directory: %v", err)
+			}
+			if len(entries) != 1 || entries[0].Name() != "tool" {
+				t.Errorf("Expected only tool to be extracted but got %v", entries)
+			}
+
+			info, err := os.Stat(filepath.Join(installDir, "tool"))
+			if err != nil {
+				t.Fatalf("Expected tool to be extracted: %v", err)
+			}
+			if runtime.GOOS != "windows" && info.Mode().Perm()&0111 == 0 {
+				t.Errorf("Expected tool to be executable but mode is %v", info.Mode())
+			}
+		})
+	}
+}
+
+// TestAtomicInstall tests that atomic installs only replace the install directory on success
+func TestAtomicInstall(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	testCases := []struct {
+		name        string
+		script      string
+		previous    bool
+		shared      bool
+		expected    string
+		expectError bool
+	}{
+		{name: "Fresh install", script: "echo new > {install_dir}/tool", expected: "new\n"},
+		{name: "Replaces previous install", script: "echo new > {install_dir}/tool", previous: true, expected: "new\n"},
+		{name: "Failure after staging keeps previous install", script: "echo new > {install_dir}/tool; exit 1",
+			previous: true, expected: "old\n", expectError: true},
+		{name: "Refuses directory not installed by depman", script: "echo new > {install_dir}/tool",
+			previous: true, shared: true, expected: "old\n", expectError: true},
+		{name: "Final install dir", script: "echo {final_install_dir} > {install_dir}/tool", expected: "<install_dir>\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			parent := t.TempDir()
+			installDir := filepath.Join(parent, "tool")
+			if tc.previous {
+				if err := os.MkdirAll(installDir, 0755); err != nil {
+					t.Fatalf("Failed to create install directory: %v", err)
+				}
+				files := map[string]string{"tool": "old\n", "stale": "old\n"}
+				if !tc.shared {
+					files[installMarker] = "tool\n"
+				}
+				for name, content := range files {
+					if err := os.WriteFile(filepath.Join(installDir, name), []byte(content), 0644); err != nil {
+						t.Fatalf("Failed to write previous install: %v", err)
+					}
+				}
+			}
+
+			tempDir := t.TempDir()
+			dep := &Dependency{
+				Name: "tool",
+				Platforms: map[string]PlatformConfig{
+					runtime.GOOS: {
+						Installer:  Installer{Type: "archive", Atomic: true},
+						Commands:   Commands{Install: []string{"sh", "-c", tc.script}},
+						InstallDir: installDir,
+					},
+				},
+			}
+			manager := &Manager{Platform: runtime.GOOS, logger: &mockLogger{}, tempDir: tempDir}
+
+			_, err := manager.installDependency(context.Background(), dep)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected an error but got none")
+				}
+			} else if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			content, err := os.ReadFile(filepath.Join(installDir, "tool"))
+			if err != nil {
+				t.Fatalf("Failed to read installed tool: %v", err)
+			}
+			expected := strings.ReplaceAll(tc.expected, "<install_dir>", installDir)
+			if string(content) != expected {
+				t.Errorf("Expected tool content %q but got %q", expected, content)
+			}
+
+			// A successful swap replaces the whole directory, a failed one leaves it as it was
+			_, err = os.Stat(filepath.Join(installDir, "stale"))
+			if stale := err == nil; stale != (tc.previous && tc.expectError) {
+				t.Errorf("Expected stale file present %v but got %v", tc.previous && tc.expectError, stale)
+			}
+
+			// Neither staging nor the set-aside previous install is left behind
+			for _, dir := range []string{parent, tempDir} {
+				entries, _ := os.ReadDir(dir)
+				for _, entry := range entries {
+					if entry.Name() != "tool" {
+						t.Errorf("Expected no leftovers but found %s in %s", entry.Name(), dir)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestSwapDirRollback tests that the previous install is restored when moving the staged
+// install into place fails
+func TestSwapDirRollback(t *testing.T) {
+	parent := t.TempDir()
+	staged := filepath.Join(t.TempDir(), "staging")
+	dst := filepath.Join(parent, "tool")
+	for dir, content := range map[string]string{staged: "new\n", dst: "old\n"} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "tool"), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	// Fail the final move of the staged install into place
+	defer func(original func(string, string) error) { rename = original }(rename)
+	rename = func(oldpath, newpath string) error {
+		if oldpath == dst+".staging" {
+			return errors.New("simulated failure")
+		}
+		return os.Rename(oldpath, newpath)
+	}
+
+	if err := swapDir(staged, dst); err == nil {
+		t.Fatalf("Expected an error but got none")
+	}
+
+	content, err := os.ReadFile(filepath.Join(dst, "tool"))
+	if err != nil {
+		t.Fatalf("Expected the previous install to be restored: %v", err)
+	}
+	if string(content) != "old\n" {
+		t.Errorf("Expected the previous install to be restored but got %q", content)
+	}
+	entries, _ := os.ReadDir(parent)
+	for _, entry := range entries {
+		if entry.Name() != "tool" {
+			t.Errorf("Expected no leftovers but found %s", entry.Name())
+		}
+	}
+}
+
+// TestCleanInstallEnv tests the environment seen by install and verify commands
+func TestCleanInstallEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	t.Setenv("DEPMAN_LEAKED", "secret")
+
+	testCases := []struct {
+		name         string
+		clean        bool
+		expectLeaked bool
+	}{
+		{name: "Inherited environment", clean: false, expectLeaked: true},
+		{name: "Clean environment", clean: true, expectLeaked: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			envFile := filepath.Join(t.TempDir(), "env")
+
+			dep := &Dependency{
+				Name:    "tool",
+				Version: Version{Required: "1.0.0"},
+				Environment: Environment{
+					Path:      []string{"/opt/depman-test/bin"},
+					Variables: map[string]string{"DEPMAN_INJECTED": "yes"},
+				},
+				Platforms: map[string]PlatformConfig{
+					runtime.GOOS: {
+						Commands: Commands{
+							Install: []string{"sh", "-c", "env > " + envFile},
+							Verify:  []string{"sh", "-c", `test "$DEPMAN_INJECTED" = yes && echo 1.0.0`},
+						},
+					},
+				},
+			}
+
+			manager := &Manager{
+				Platform:   runtime.GOOS,
+				logger:     &mockLogger{},
+				envManager: environment.NewManager(),
+			}
+			WithCleanInstallEnv(tc.clean)(manager)
+
+			if _, err := manager.installDependency(context.Background(), dep); err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			data, err := os.ReadFile(envFile)
+			if err != nil {
+				t.Fatalf("Failed to read install environment: %v", err)
+			}
+			env := string(data)
+
+			if !strings.Contains(env, "DEPMAN_INJECTED=yes") {
+				t.Errorf("Expected the injected variable in the install environment")
+			}
+			if !strings.Contains(env, "/opt/depman-test/bin") {
+				t.Errorf("Expected the PATH addition in the install environment")
+			}
+			if leaked := strings.Contains(env, "DEPMAN_LEAKED=secret"); leaked != tc.expectLeaked {
+				t.Errorf("Expected leaked variable present %v but got %v", tc.expectLeaked, leaked)
+			}
+
+			status, err := manager.VerifyDependency(dep)
+			if err != nil || !status.Installed {
+				t.Errorf("Expected the verify command to see the injected variable but got: %v", err)
+			}
+		})
+	}
+}
+
+// TestCleanInstallEnvKeepExisting tests that a clean install environment gets the existing
+// value of configured variables kept from the parent environment, and that later
+// dependencies expand references to them to that value
+func TestCleanInstallEnvKeepExisting(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	t.Setenv("DEPMAN_SHARED", "from-shell")
+	t.Setenv("DEPMAN_INJECTED", "from-shell")
+
+	envFile := filepath.Join(t.TempDir(), "env")
+
+	base := &Dependency{
+		Name:        "base",
+		Version:     Version{Required: "1.0.0"},
+		Environment: Environment{Variables: map[string]string{"DEPMAN_SHARED": "from-config"}},
+	}
+	dep := &Dependency{
+		Name:    "tool",
+		Version: Version{Required: "1.0.0"},
+		Environment: Environment{Variables: map[string]string{
+			"DEPMAN_INJECTED": "from-config",
+			"DEPMAN_DERIVED":  "{DEPMAN_SHARED}/derived",
+		}},
+		Platforms: map[string]PlatformConfig{
+			runtime.GOOS: {
+				Commands: Commands{
+					Install: []string{"sh", "-c", "env > " + envFile},
+					Verify:  []string{"sh", "-c", "echo 1.0.0"},
+				},
+			},
+		},
+	}
+
+	manager := &Manager{
+		Platform:   runtime.GOOS,
+		logger:     &mockLogger{},
+		envManager: environment.NewManager(),
+	}
+	WithCleanInstallEnv(true)(manager)
+	WithOverrideExistingEnv(false)(manager)
+
+	// An already installed dependency registered its environment
+	if err := manager.setupDependencyEnvironment(base); err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+
+	if _, err := manager.installDependency(context.Background(), dep); err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+
+	data, err := os.ReadFile(envFile)
+	if err != nil {
+		t.Fatalf("Failed to read install environment: %v", err)
+	}
+	env := string(data)
+
+	for _, expected := range []string{"DEPMAN_SHARED=from-shell", "DEPMAN_INJECTED=from-shell", "DEPMAN_DERIVED=from-shell/derived"} {
+		if !strings.Contains(env, expected) {
+			t.Errorf("Expected %s in the install environment", expected)
+		}
+	}
+
+	// The parent environment keeps its own value
+	if value := os.Getenv("DEPMAN_SHARED"); value != "from-shell" {
+		t.Errorf("Expected the existing value to be kept but got %q", value)
+	}
+}
+
+// TestPrefix tests that a prefix gives each dependency version its own install_dir and PATH entry
+func TestPrefix(t *testing.T) {
+	prefix := t.TempDir()
+	newDep := func(version string) *Dependency {
+		return &Dependency{
+			Name:        "tool",
+			Version:     Version{Required: version},
+			Environment: Environment{Path: []string{"/opt/tool/share"}},
+			Platforms: map[string]PlatformConfig{
+				runtime.GOOS: {InstallDir: "/opt/tool", Commands: testCommands},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name            string
+		prefix          string
+		version         string
+		expectedDir     string
+		expectedPathDir string
+	}{
+		{name: "Without prefix", version: "1.0.0", expectedDir: "/opt/tool"},
+		{name: "Version", prefix: prefix, version: "1.0.0",
+			expectedDir: filepath.Join(prefix, "tool", "1.0.0"), expectedPathDir: filepath.Join(prefix, "tool", "1.0.0", "bin")},
+		{name: "Other version", prefix: prefix, version: "2.0.0",
+			expectedDir: filepath.Join(prefix, "tool", "2.0.0"), expectedPathDir: filepath.Join(prefix, "tool", "2.0.0", "bin")},
+		{name: "No required version", prefix: prefix,
+			expectedDir: filepath.Join(prefix, "tool", "latest"), expectedPathDir: filepath.Join(prefix, "tool", "latest", "bin")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			manager := &Manager{
+				Platform:   runtime.GOOS,
+				logger:     &mockLogger{},
+				envManager: environment.NewManager(),
+			}
+			WithPrefix(tc.prefix)(manager)
+			dep := newDep(tc.version)
+
+			platformConfig, err := manager.GetPlatformConfig(dep)
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+			if platformConfig.InstallDir != tc.expectedDir {
+				t.Errorf("Expected install_dir %s but got %s", tc.expectedDir, platformConfig.InstallDir)
+			}
+
+			if err := manager.setupDependencyEnvironment(dep); err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+			expectedPaths := []string{environment.NormalizePath("/opt/tool/share")}
+			if tc.expectedPathDir != "" {
+				expectedPaths = append(expectedPaths, environment.NormalizePath(tc.expectedPathDir))
+			}
+			if !slices.Equal(manager.envManager.Paths, expectedPaths) {
+				t.Errorf("Expected paths %v but got %v", expectedPaths, manager.envManager.Paths)
+			}
+		})
+	}
+
+	t.Run("Install command", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("sh command not available on Windows")
+		}
+
+		dep := newDep("1.0.0")
+		dep.Platforms[runtime.GOOS] = PlatformConfig{
+			InstallDir: "/opt/tool",
+			Commands:   Commands{Install: []string{"sh", "-c", "mkdir -p {install_dir}/bin"}},
+		}
+		manager := &Manager{
+			Platform:   runtime.GOOS,
+			logger:     &mockLogger{},
+			envManager: environment.NewManager(),
+		}
+		WithPrefix(prefix)(manager)
+
+		if _, err := manager.installDependency(t.Context(), dep); err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(prefix, "tool", "1.0.0", "bin")); err != nil {
+			t.Errorf("Expected the install command to create the bin directory under the prefix: %v", err)
+		}
+	})
+}
+
+// TestPlatformEnvironment tests merging platform environment blocks into the dependency's
+func TestPlatformEnvironment(t *testing.T) {
+	dep := &Dependency{
+		Name:    "tool",
+		Version: Version{Required: "1.0.0"},
+		Environment: Environment{
+			Path:      []string{"/opt/tool/share"},
+			Variables: map[string]string{"TOOL_HOME": "/opt/tool", "TOOL_MODE": "default"},
+		},
+		Platforms: map[string]PlatformConfig{
+			"linux": {
+				Environment: Environment{
+					Path:      []string{"/usr/local/bin"},
+					Variables: map[string]string{"TOOL_MODE": "linux"},
+				},
+			},
+			"windows": {
+				Environment: Environment{
+					Path:      []string{`C:\Program Files\Tool`},
+					Variables: map[string]string{"TOOL_MODE": "windows", "TOOL_SHELL": "cmd"},
+				},
+			},
+			"darwin": {},
+		},
+	}
+
+	testCases := []struct {
+		platform          string
+		expectedPaths     []string
+		expectedVariables map[string]string
+	}{
+		{
+			platform:          "linux",
+			expectedPaths:     []string{"/opt/tool/share", "/usr/local/bin"},
+			expectedVariables: map[string]string{"TOOL_HOME": "/opt/tool", "TOOL_MODE": "linux"},
+		},
+		{
+			platform:          "windows",
+			expectedPaths:     []string{"/opt/tool/share", `C:\Program Files\Tool`},
+			expectedVariables: map[string]string{"TOOL_HOME": "/opt/tool", "TOOL_MODE": "windows", "TOOL_SHELL": "cmd"},
+		},
+		{
+			platform:          "darwin",
+			expectedPaths:     []string{"/opt/tool/share"},
+			expectedVariables: map[string]string{"TOOL_HOME": "/opt/tool", "TOOL_MODE": "default"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.platform, func(t *testing.T) {
+			manager := &Manager{
+				Platform:   tc.platform,
+				logger:     &mockLogger{},
+				envManager: environment.NewManager(),
+			}
+
+			if err := manager.setupDependencyEnvironment(dep); err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			var expectedPaths []string
+			for _, path := range tc.expectedPaths {
+				expectedPaths = append(expectedPaths, environment.NormalizePath(path))
+			}
+			if !slices.Equal(manager.envManager.Paths, expectedPaths) {
+				t.Errorf("Expected paths %v but got %v", expectedPaths, manager.envManager.Paths)
+			}
+			if !maps.Equal(manager.envManager.Variables, tc.expectedVariables) {
+				t.Errorf("Expected variables %v but got %v", tc.expectedVariables, manager.envManager.Variables)
+			}
+		})
+	}
+
+	// The dependency-level environment is left untouched
+	if len(dep.Environment.Path) != 1 || dep.Environment.Variables["TOOL_MODE"] != "default" {
+		t.Errorf("Expected the dependency environment to be unchanged but got %+v", dep.Environment)
+	}
+}
+
+// TestVerifyCommandRetries tests retrying a verify command that fails transiently
+func TestVerifyCommandRetries(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	// newFlakyDep creates a dependency whose verify fails on the first run only
+	newFlakyDep := func(dir string) *Dependency {
+		counter := filepath.Join(dir, "attempts")
+		verify := fmt.Sprintf(`echo x >> %[1]s; test $(wc -l < %[1]s) -ge 2 || exit 1; echo 1.0.0`, counter)
+
+		return &Dependency{
+			Name:    "flaky",
+			Version: Version{Required: "1.0.0"},
+			Platforms: map[string]PlatformConfig{
+				runtime.GOOS: {
+					Commands: Commands{
+						Verify: []string{"sh", "-c", verify},
+					},
+				},
+			},
+		}
+	}
+
+	t.Run("Succeeds after one failure", func(t *testing.T) {
+		manager := &Manager{
+			Platform:             runtime.GOOS,
+			logger:               &mockLogger{},
+			verifyCommandRetries: 2,
+			verifyCommandBackoff: 10 * time.Millisecond,
+		}
+
+		status, err := manager.VerifyDependency(newFlakyDep(t.TempDir()))
+		if err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+		if !status.Installed || status.CurrentVersion != "1.0.0" {
+			t.Errorf("Expected installed version 1.0.0 but got %+v", status)
+		}
+	})
+
+	t.Run("Fails without retries", func(t *testing.T) {
+		manager := &Manager{
+			Platform: runtime.GOOS,
+			logger:   &mockLogger{},
+		}
+
+		status, err := manager.VerifyDependency(newFlakyDep(t.TempDir()))
+		if err == nil {
+			t.Fatalf("Expected an error but got none")
+		}
+		if status.Installed {
+			t.Errorf("Expected dependency to be reported as not installed")
+		}
+	})
+}
+
+// TestVerifyCommandTimeout tests that a hung verify command is killed and reported with its timeout
+func TestVerifyCommandTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	original := verifyCommandTimeout
+	verifyCommandTimeout = 100 * time.Millisecond
+	defer func() { verifyCommandTimeout = original }()
+
+	dep := &Dependency{
+		Name: "hung",
+		Platforms: map[string]PlatformConfig{
+			runtime.GOOS: {Commands: Commands{Verify: []string{"sh", "-c", "sleep 5"}}},
+		},
+	}
+	manager := &Manager{Platform: runtime.GOOS, logger: &mockLogger{}}
+
+	status, err := manager.VerifyDependency(dep)
+	if err == nil {
+		t.Fatalf("Expected an error but got none")
+	}
+	if !strings.Contains(err.Error(), "timed out after 100ms") {
+		t.Errorf("Expected the timeout to be reported but got: %v", err)
+	}
+	if status.Installed {
+		t.Errorf("Expected dependency to be reported as not installed")
+	}
+}
+
+// TestVerifyDependencies tests running verify commands with known output
+func TestVerifyDependencies(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	newDep := func(name, verify string) Dependency {
+		return Dependency{
+			Name: name,
+			Platforms: map[string]PlatformConfig{
+				runtime.GOOS: {
+					Commands: Commands{
+						Verify: []string{"sh", "-c", verify},
+					},
+				},
+			},
+		}
+	}
+
+	manager := &Manager{
+		Config: &DependencyConfig{
+			Dependencies: []Dependency{
+				newDep("good", "echo 'good version 3.1.4'"),
+				newDep("bad", "echo 'segfault'; exit 3"),
+			},
+		},
+		Platform: runtime.GOOS,
+		logger:   &mockLogger{},
+	}
+
+	t.Run("All dependencies", func(t *testing.T) {
+		statuses, err := manager.VerifyDependencies()
+		if err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+
+		if len(statuses) != 2 {
+			t.Fatalf("Expected 2 statuses but got %d", len(statuses))
+		}
+
+		good, bad := statuses[0], statuses[1]
+		if !good.Installed || good.CurrentVersion != "3.1.4" || good.VerifyOutput != "good version 3.1.4" {
+			t.Errorf("Unexpected status for good: %+v", good)
+		}
+		if bad.Installed || bad.Error == nil || bad.VerifyOutput != "segfault" {
+			t.Errorf("Unexpected status for bad: %+v", bad)
+		}
+	})
+
+	t.Run("Named dependency", func(t *testing.T) {
+		statuses, err := manager.VerifyDependencies("bad")
+		if err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+		if len(statuses) != 1 || statuses[0].Name != "bad" {
+			t.Errorf("Expected only bad to be verified but got %+v", statuses)
+		}
+	})
+
+	t.Run("Unknown dependency", func(t *testing.T) {
+		if _, err := manager.VerifyDependencies("missing"); err == nil {
+			t.Errorf("Expected an error but got none")
+		}
+	})
+}
+
+// TestStrictVersionParse tests verify output without a parseable version
+func TestStrictVersionParse(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	testCases := []struct {
+		name           string
+		output         string
+		strict         bool
+		expectError    bool
+		expectedCompat bool
+	}{
+		{name: "Strict with no version", output: "", strict: true, expectError: true, expectedCompat: false},
+		{name: "Strict with garbage output", output: "OK", strict: true, expectError: true, expectedCompat: false},
+		{name: "Strict with version", output: "tool 1.0.0", strict: true, expectError: false, expectedCompat: true},
+		{name: "Lenient with garbage output", output: "OK", strict: false, expectError: true, expectedCompat: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dep := &Dependency{
+				Name:               "tool",
+				Version:            Version{Required: "1.0.0"},
+				StrictVersionParse: tc.strict,
+				Platforms: map[string]PlatformConfig{
+					runtime.GOOS: {
+						Commands: Commands{
+							Verify: []string{"sh", "-c", "printf '" + tc.output + "'"},
+						},
+					},
+				},
+			}
+
+			manager := &Manager{Platform: runtime.GOOS, logger: &mockLogger{}}
+
+			status, err := manager.VerifyDependency(dep)
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			if !status.Installed {
+				t.Errorf("Expected dependency to be reported as installed")
+			}
+			if tc.expectError && status.Error == nil {
+				t.Errorf("Expected an error but got none")
+			}
+			if status.Error != nil && tc.strict && !strings.Contains(status.Error.Error(), "partially installed") {
+				t.Errorf("Expected a partial install error but got: %v", status.Error)
+			}
+			if !tc.expectError && status.Error != nil {
+				t.Errorf("Did not expect an error but got: %v", status.Error)
+			}
+			if status.Compatible != tc.expectedCompat {
+				t.Errorf("Expected compatible %v but got %v", tc.expectedCompat, status.Compatible)
+			}
+		})
+	}
+}
+
+// TestVerifyExpect tests verifying tools by a known success string instead of a version
+func TestVerifyExpect(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	testCases := []struct {
+		name            string
+		output          string
+		expect          string
+		required        string
+		expectError     bool
+		expectInstalled bool
+		expectedVersion string
+		expectedUpdate  UpdateType
+	}{
+		{name: "Success string without version", output: "OK", expect: "OK", expectInstalled: true},
+		{name: "Required version is optional", output: "OK", expect: "OK", required: "1.0.0", expectInstalled: true},
+		{name: "Regex", output: "status: ready", expect: "/status: (ok|ready)/", expectInstalled: true},
+		{name: "Missing success string", output: "FAILED", expect: "OK", expectError: true},
+		{name: "Version still checked when reported", output: "tool 1.2.0 OK", expect: "OK", required: "2.0.0", expectInstalled: true, expectedVersion: "1.2.0", expectedUpdate: MajorUpdate},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dep := &Dependency{
+				Name:        "tool",
+				Version:     Version{Required: tc.required},
+				InstallMode: InstallModeCheckOnly,
+				Platforms: map[string]PlatformConfig{
+					runtime.GOOS: {
+						Commands: Commands{
+							Verify:       []string{"sh", "-c", "printf '" + tc.output + "'"},
+							VerifyExpect: tc.expect,
+						},
+					},
+				},
+			}
+
+			manager := &Manager{
+				Config:   &DependencyConfig{Dependencies: []Dependency{*dep}},
+				Platform: runtime.GOOS,
+				logger:   &mockLogger{},
+			}
+
+			// No required version is needed when the output is matched
+			if errs := manager.validateDependencies(); len(errs) != 0 {
+				t.Errorf("Did not expect validation errors but got: %v", errs)
+			}
+
+			status, err := manager.VerifyDependency(dep)
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("Expected an error but got none")
+				}
+				if !errors.Is(err, ErrVerifyFailed) || !status.Broken {
+					t.Errorf("Expected a broken install but got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			if status.Installed != tc.expectInstalled {
+				t.Errorf("Expected installed %v but got %v", tc.expectInstalled, status.Installed)
+			}
+			if status.CurrentVersion != tc.expectedVersion {
+				t.Errorf("Expected version %q but got %q", tc.expectedVersion, status.CurrentVersion)
+			}
+			if status.RequiredUpdate != tc.expectedUpdate {
+				t.Errorf("Expected update %v but got %v", tc.expectedUpdate, status.RequiredUpdate)
+			}
+			if status.Error != nil || !status.Compatible {
+				t.Errorf("Expected a compatible status without error but got: %v", status.Error)
+			}
+		})
+	}
+}
+
+// TestHealthCheck tests that deep checks run health checks without affecting installed status
+func TestHealthCheck(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	testCases := []struct {
+		name            string
+		verify          string
+		healthCheck     []string
+		deep            bool
+		expectInstalled bool
+		expectChecked   bool
+		expectHealthy   bool
+		expectError     string
+	}{
+		{name: "Passing", verify: "echo 1.0.0", healthCheck: []string{"sh", "-c", "exit 0"}, deep: true, expectInstalled: true, expectChecked: true, expectHealthy: true},
+		{name: "Failing", verify: "echo 1.0.0", healthCheck: []string{"sh", "-c", "echo daemon not running; exit 1"}, deep: true, expectInstalled: true, expectChecked: true, expectError: "daemon not running"},
+		{name: "Timed out", verify: "echo 1.0.0", healthCheck: []string{"sleep", "5"}, deep: true, expectInstalled: true, expectChecked: true, expectError: "timed out"},
+		{name: "Not run without deep", verify: "echo 1.0.0", healthCheck: []string{"sh", "-c", "exit 1"}, expectInstalled: true},
+		{name: "Not run when not installed", verify: "exit 1", healthCheck: []string{"sh", "-c", "exit 0"}, deep: true},
+		{name: "No health check", verify: "echo 1.0.0", deep: true, expectInstalled: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dep := &Dependency{
+				Name:    "tool",
+				Version: Version{Required: "1.0.0"},
+				Platforms: map[string]PlatformConfig{
+					runtime.GOOS: {
+						Commands: Commands{
+							Verify:      []string{"sh", "-c", tc.verify},
+							HealthCheck: tc.healthCheck,
+						},
+					},
+				},
+			}
+
+			manager := &Manager{
+				Config:     &DependencyConfig{Dependencies: []Dependency{*dep}},
+				Platform:   runtime.GOOS,
+				logger:     &mockLogger{},
+				envManager: environment.NewManager(),
+			}
+			WithDeepCheck(tc.deep)(manager)
+			WithHealthCheckTimeout(200 * time.Millisecond)(manager)
+
+			status, _ := manager.VerifyDependency(dep)
+			if status.Installed != tc.expectInstalled {
+				t.Errorf("Expected installed %v but got %v", tc.expectInstalled, status.Installed)
+			}
+			if status.HealthChecked != tc.expectChecked || status.Healthy != tc.expectHealthy {
+				t.Errorf("Expected checked %v and healthy %v but got %v and %v",
+					tc.expectChecked, tc.expectHealthy, status.HealthChecked, status.Healthy)
+			}
+
+			if tc.expectError == "" {
+				if status.HealthError != nil {
+					t.Errorf("Did not expect a health error but got: %v", status.HealthError)
+				}
+				return
+			}
+			if status.HealthError == nil || !strings.Contains(status.HealthError.Error(), tc.expectError) {
+				t.Errorf("Expected a health error containing %q but got: %v", tc.expectError, status.HealthError)
+			}
+			if status.Error != nil || !status.Compatible {
+				t.Errorf("Expected an unhealthy dependency to stay compatible but got: %v", status.Error)
+			}
+		})
+	}
+}
+
+// TestConstraintFirst tests that a satisfied constraint can take precedence over the required version
+func TestConstraintFirst(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	testCases := []struct {
+		name            string
+		installed       string
+		constraint      string
+		constraintFirst bool
+		expectedUpdate  UpdateType
+		expectedCompat  bool
+	}{
+		{name: "Default flags update despite constraint", installed: "1.2.0", constraint: "^1.0.0", constraintFirst: false, expectedUpdate: MinorUpdate, expectedCompat: true},
+		{name: "Satisfied constraint wins", installed: "1.2.0", constraint: "^1.0.0", constraintFirst: true, expectedUpdate: NoUpdate, expectedCompat: true},
+		{name: "Unsatisfied constraint still updates", installed: "0.9.0", constraint: "^1.0.0", constraintFirst: true, expectedUpdate: MajorUpdate, expectedCompat: false},
+		{name: "No constraint uses required", installed: "1.2.0", constraint: "", constraintFirst: true, expectedUpdate: MinorUpdate, expectedCompat: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dep := &Dependency{
+				Name:    "tool",
+				Version: Version{Required: "1.3.0", Constraint: tc.constraint},
+				Platforms: map[string]PlatformConfig{
+					runtime.GOOS: {
+						Commands: Commands{
+							Verify: []string{"sh", "-c", "echo tool " + tc.installed},
+						},
+					},
+				},
+			}
+
+			manager := &Manager{Platform: runtime.GOOS, logger: &mockLogger{}}
+			WithConstraintFirst(tc.constraintFirst)(manager)
+
+			status, err := manager.VerifyDependency(dep)
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			if status.RequiredUpdate != tc.expectedUpdate {
+				t.Errorf("Expected update %v but got %v", tc.expectedUpdate, status.RequiredUpdate)
+			}
+			if status.Compatible != tc.expectedCompat {
+				t.Errorf("Expected compatible %v but got %v", tc.expectedCompat, status.Compatible)
+			}
+			expectInstall := tc.expectedUpdate != NoUpdate || !tc.expectedCompat
+			if needsInstall(status) != expectInstall {
+				t.Errorf("Expected needsInstall %v but got %v", expectInstall, needsInstall(status))
+			}
+		})
+	}
+}
+
+// TestTempDir tests that installs use the configured temp directory and clean it up
+func TestTempDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	testCases := []struct {
+		name        string
+		script      string
+		expectError bool
+	}{
+		{name: "Success", script: "echo {temp_dir}", expectError: false},
+		{name: "Failure", script: "echo {temp_dir}; exit 1", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			parent := filepath.Join(t.TempDir(), "custom", "tmp")
+
+			dep := &Dependency{
+				Name: "test-dep",
+				Platforms: map[string]PlatformConfig{
+					runtime.GOOS: {
+						Commands: Commands{
+							Install: []string{"sh", "-c", tc.script},
+						},
+					},
+				},
+			}
+
+			manager := &Manager{
+				Platform: runtime.GOOS,
+				logger:   &mockLogger{},
+				tempDir:  parent,
+			}
+
+			output, err := manager.installDependency(context.Background(), dep)
+			if tc.expectError && err == nil {
+				t.Errorf("Expected an error but got none")
+			}
+			if !tc.expectError && err != nil {
+				t.Errorf("Did not expect an error but got: %v", err)
+			}
+
+			if filepath.Dir(output) != parent {
+				t.Errorf("Expected install to run in a directory under %s but got %q", parent, output)
+			}
+
+			entries, err := os.ReadDir(parent)
+			if err != nil {
+				t.Fatalf("Expected temp directory parent to be created but got: %v", err)
+			}
+			if len(entries) != 0 {
+				t.Errorf("Expected temp directory to be cleaned up but found %d entries", len(entries))
+			}
+		})
+	}
+}
+
+// TestConcurrentAccess tests using one manager from several goroutines
+// Run with -race to detect unguarded state
+func TestConcurrentAccess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "tool.installed")
+	manager := &Manager{
+		Config: &DependencyConfig{
+			Dependencies: []Dependency{
+				{
+					Name:    "tool",
+					Version: Version{Required: "1.0.0"},
+					Environment: Environment{
+						Path:      []string{filepath.Join(dir, "bin")},
+						Variables: map[string]string{"TOOL_HOME": dir},
+					},
+					Platforms: map[string]PlatformConfig{
+						runtime.GOOS: {
+							Commands: Commands{
+								Install: []string{"touch", marker},
+								Verify:  []string{"sh", "-c", "test -f " + marker + " && echo 1.0.0"},
+							},
+						},
+					},
+				},
+			},
+		},
+		Platform:   runtime.GOOS,
+		logger:     &mockLogger{},
+		envManager: environment.NewManager(),
+	}
+	dep := &manager.Config.Dependencies[0]
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < 10; i++ {
+		wg.Add(4)
+		go func() {
+			defer wg.Done()
+			if _, err := manager.CheckDependency(dep); err != nil && !strings.Contains(err.Error(), "verification failed") {
+				errs <- err
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, _, err := manager.EnsureDependencies(); err != nil {
+				errs <- err
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			manager.GetUpdatedEnvironment()
+		}()
+		go func(i int) {
+			defer wg.Done()
+			if err := manager.ExportDotEnv(filepath.Join(dir, fmt.Sprintf("%d.env", i))); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("Did not expect an error but got: %v", err)
+	}
+
+	status, err := manager.CheckDependency(dep)
+	if err != nil || !status.Installed {
+		t.Errorf("Expected the dependency to be installed but got: %+v, %v", status, err)
+	}
 }
@@ -0,0 +1,179 @@
+package depman
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentConfigVersion is the configuration schema version written and understood by this release
+const CurrentConfigVersion = "1.1"
+
+// configMigration upgrades a configuration document from one schema version to the next
+type configMigration struct {
+	From    string                 // Version the migration applies to
+	To      string                 // Version after the migration
+	Migrate func(*yaml.Node) error // Transforms the top-level mapping node in place
+}
+
+// configMigrations lists the schema migrations in order, each starting where the previous ended
+var configMigrations = []configMigration{
+	// 1.1 only introduced optional fields, so 1.0 configs need no changes
+	{From: "1.0", To: "1.1", Migrate: func(*yaml.Node) error { return nil }},
+}
+
+// MigrateConfigData upgrades YAML configuration data from one schema version to another,
+// applying each migration in between. An empty from uses the version in the data.
+// Formatting and comments are kept where the migrations leave the document untouched.
+func MigrateConfigData(data []byte, from, to string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse dependency file: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("dependency file is not a mapping")
+	}
+	root := doc.Content[0]
+
+	if from == "" {
+		from = mappingValue(root, "version")
+	}
+	if err := migrateNode(root, from, to); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to encode migrated config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode migrated config: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// MigrateConfig upgrades the configuration file from one schema version to another, writing
+// the migrated file in place and reloading it. An empty from uses the version in the file.
+func (m *Manager) MigrateConfig(from, to string) error {
+	path, err := FindDependencyFile(m.ConfigPath)
+	if err != nil {
+		return err
+	}
+	if path == StdinConfigPath {
+		return fmt.Errorf("cannot migrate a configuration read from standard input in place")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read dependency file: %w", err)
+	}
+
+	migrated, err := MigrateConfigData(data, from, to)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, migrated, 0644); err != nil {
+		return fmt.Errorf("failed to write migrated config: %w", err)
+	}
+
+	config, err := LoadDependencyConfig(path)
+	if err != nil {
+		return fmt.Errorf("failed to load migrated config: %w", err)
+	}
+	m.Config = config
+
+	return nil
+}
+
+// migrateNode applies the migrations from one version to another to a top-level mapping node
+func migrateNode(root *yaml.Node, from, to string) error {
+	if sameConfigVersion(from, to) {
+		return nil
+	}
+
+	for _, migration := range configMigrations {
+		if !sameConfigVersion(migration.From, from) {
+			continue
+		}
+
+		if err := migration.Migrate(root); err != nil {
+			return fmt.Errorf("failed to migrate config from %s to %s: %w", migration.From, migration.To, err)
+		}
+		setMappingValue(root, "version", migration.To)
+
+		return migrateNode(root, migration.To, to)
+	}
+
+	return fmt.Errorf("no migration path from config version %s to %s", from, to)
+}
+
+// checkConfigVersion rejects configurations newer than this release understands
+// and reports whether an older configuration needs migrating
+func checkConfigVersion(version string) (bool, error) {
+	if version == "" || sameConfigVersion(version, CurrentConfigVersion) {
+		return false, nil
+	}
+
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false, fmt.Errorf("invalid configuration version '%s': %w", version, err)
+	}
+	if v.GreaterThan(semver.MustParse(CurrentConfigVersion)) {
+		return false, fmt.Errorf("configuration version %s is newer than the supported version %s, upgrade depman",
+			version, CurrentConfigVersion)
+	}
+
+	return true, nil
+}
+
+// sameConfigVersion reports whether two configuration versions are the same, so that
+// 1, 1.0 and 1.0.0 all name the same schema
+func sameConfigVersion(a, b string) bool {
+	if a == b {
+		return true
+	}
+
+	va, err := semver.NewVersion(a)
+	if err != nil {
+		return false
+	}
+	vb, err := semver.NewVersion(b)
+	if err != nil {
+		return false
+	}
+	return va.Equal(vb)
+}
+
+// mappingValue returns the scalar value of key in a mapping node, or "" if it is absent
+func mappingValue(node *yaml.Node, key string) string {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1].Value
+		}
+	}
+	return ""
+}
+
+// setMappingValue sets key in a mapping node to a string scalar, adding it if absent
+func setMappingValue(node *yaml.Node, key, value string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content[i+1].Kind = yaml.ScalarNode
+			node.Content[i+1].Tag = "!!str"
+			node.Content[i+1].Value = value
+			return
+		}
+	}
+
+	node.Content = append([]*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: key},
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: value},
+	}, node.Content...)
+}
@@ -0,0 +1,171 @@
+package depman
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMigrateConfigData(t *testing.T) {
+	// A sample transform renaming a field on each dependency, as a future schema change might
+	renameDesc := configMigration{
+		From: "1.1",
+		To:   "1.2",
+		Migrate: func(root *yaml.Node) error {
+			for i := 0; i+1 < len(root.Content); i += 2 {
+				if root.Content[i].Value != "dependencies" {
+					continue
+				}
+				for _, dep := range root.Content[i+1].Content {
+					for j := 0; j+1 < len(dep.Content); j += 2 {
+						if dep.Content[j].Value == "desc" {
+							dep.Content[j].Value = "description"
+						}
+					}
+				}
+			}
+			return nil
+		},
+	}
+
+	config := `# Keep this comment
+version: "1.0"
+name: "Test App"
+dependencies:
+  - name: "tool"
+    desc: "A tool"
+`
+
+	testCases := []struct {
+		name        string
+		from        string
+		to          string
+		migrations  []configMigration
+		expected    []string
+		expectError bool
+	}{
+		{
+			name:     "No-op 1.0 to 1.1",
+			to:       "1.1",
+			expected: []string{"# Keep this comment", `version: "1.1"`, "desc: \"A tool\""},
+		},
+		{
+			name:       "Sample transform across two migrations",
+			to:         "1.2",
+			migrations: append(append([]configMigration{}, configMigrations...), renameDesc),
+			expected:   []string{`version: "1.2"`, `description: "A tool"`},
+		},
+		{
+			name:       "Explicit from version",
+			from:       "1.1",
+			to:         "1.2",
+			migrations: []configMigration{renameDesc},
+			expected:   []string{`version: "1.2"`, `description: "A tool"`},
+		},
+		{
+			name:        "No migration path",
+			to:          "2.0",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.migrations != nil {
+				original := configMigrations
+				configMigrations = tc.migrations
+				defer func() { configMigrations = original }()
+			}
+
+			migrated, err := MigrateConfigData([]byte(config), tc.from, tc.to)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			for _, expected := range tc.expected {
+				if !strings.Contains(string(migrated), expected) {
+					t.Errorf("Expected migrated config to contain %q but got:\n%s", expected, migrated)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigVersionGating(t *testing.T) {
+	testCases := []struct {
+		name            string
+		version         string
+		expectedVersion string
+		expectError     bool
+	}{
+		{name: "Current version", version: CurrentConfigVersion, expectedVersion: CurrentConfigVersion},
+		{name: "Older version is migrated", version: "1.0", expectedVersion: CurrentConfigVersion},
+		{name: "Major-only older version is migrated", version: "1", expectedVersion: CurrentConfigVersion},
+		{name: "Full older version is migrated", version: "1.0.0", expectedVersion: CurrentConfigVersion},
+		{name: "Full current version", version: CurrentConfigVersion + ".0", expectedVersion: CurrentConfigVersion + ".0"},
+		{name: "Missing version is accepted", version: "", expectedVersion: ""},
+		{name: "Newer version is rejected", version: "9.0", expectError: true},
+		{name: "Invalid version is rejected", version: "latest", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app-dependencies.yml")
+			content := fmt.Sprintf("version: %q\nname: \"Test App\"\ndependencies: []\n", tc.version)
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			config, err := LoadDependencyConfig(path)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+			if config.Version != tc.expectedVersion {
+				t.Errorf("Expected version %q but got %q", tc.expectedVersion, config.Version)
+			}
+		})
+	}
+}
+
+func TestMigrateConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app-dependencies.yml")
+	if err := os.WriteFile(path, []byte("version: \"1.0\"\nname: \"Test App\"\ndependencies: []\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	manager, err := NewManager(path, WithLogger(&mockLogger{}))
+	if err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+
+	if err := manager.MigrateConfig("", CurrentConfigVersion); err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read migrated config: %v", err)
+	}
+	if !strings.Contains(string(data), `version: "`+CurrentConfigVersion+`"`) {
+		t.Errorf("Expected the file to be migrated but got:\n%s", data)
+	}
+	if manager.Config.Name != "Test App" {
+		t.Errorf("Expected the migrated config to be reloaded")
+	}
+}
@@ -0,0 +1,118 @@
+package depman
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// InstallerTypePackage installs a dependency with the platform's package manager instead of
+// downloading it, e.g. apt-get on Debian, brew on macOS or winget on Windows
+const InstallerTypePackage = "package"
+
+// packageManager describes how to install a package with a package manager
+type packageManager struct {
+	install   []string // Install command, followed by the package name
+	needsRoot bool     // Whether the install must run as root (prefixed with sudo otherwise)
+}
+
+// packageManagers are the supported package managers by name
+var packageManagers = map[string]packageManager{
+	"apt":    {install: []string{"apt-get", "install", "-y"}, needsRoot: true},
+	"dnf":    {install: []string{"dnf", "install", "-y"}, needsRoot: true},
+	"yum":    {install: []string{"yum", "install", "-y"}, needsRoot: true},
+	"zypper": {install: []string{"zypper", "--non-interactive", "install"}, needsRoot: true},
+	"pacman": {install: []string{"pacman", "-S", "--noconfirm"}, needsRoot: true},
+	"apk":    {install: []string{"apk", "add"}, needsRoot: true},
+	"brew":   {install: []string{"brew", "install"}},
+	"winget": {install: []string{"winget", "install", "--exact", "--accept-package-agreements", "--accept-source-agreements", "--id"}},
+	"choco":  {install: []string{"choco", "install", "-y"}},
+	"scoop":  {install: []string{"scoop", "install"}},
+}
+
+// platformPackageManagers are the package managers detected on each platform, in order of preference
+var platformPackageManagers = map[string][]string{
+	"linux":   {"apt", "dnf", "yum", "zypper", "pacman", "apk"},
+	"darwin":  {"brew"},
+	"windows": {"winget", "choco", "scoop"},
+}
+
+// packageManagerExecutable returns the executable that identifies a package manager
+func packageManagerExecutable(name string) string {
+	return packageManagers[name].install[0]
+}
+
+// detectPackageManager returns the first package manager of the platform found with lookPath
+func detectPackageManager(platform string, lookPath func(string) (string, error)) (string, error) {
+	candidates := platformPackageManagers[platform]
+	for _, name := range candidates {
+		if _, err := lookPath(packageManagerExecutable(name)); err == nil {
+			return name, nil
+		}
+	}
+
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no package managers are known for platform '%s'", platform)
+	}
+	return "", fmt.Errorf("no package manager found (tried %s)", strings.Join(candidates, ", "))
+}
+
+// packageInstallCommand returns the command installing pkg with a package manager, run with
+// sudo when the package manager needs root and the caller isn't root
+func packageInstallCommand(manager, pkg string, root bool) ([]string, error) {
+	pm, ok := packageManagers[manager]
+	if !ok {
+		return nil, fmt.Errorf("unknown package manager '%s' (expected one of %s)",
+			manager, strings.Join(slices.Sorted(maps.Keys(packageManagers)), ", "))
+	}
+
+	command := append(slices.Clone(pm.install), pkg)
+	if pm.needsRoot && !root {
+		command = append([]string{"sudo"}, command...)
+	}
+	return command, nil
+}
+
+// packageManagerFor returns the package manager to install a dependency with: the one set on its
+// installer, the one set with WithPackageManager, or the first one found on the system
+func (m *Manager) packageManagerFor(installer *Installer) (string, error) {
+	if installer.PackageManager != "" {
+		return installer.PackageManager, nil
+	}
+	if m.packageManager != "" {
+		return m.packageManager, nil
+	}
+	return detectPackageManager(m.Platform, exec.LookPath)
+}
+
+// installPackage installs a dependency of the package installer type with the package manager,
+// skipping the download and install commands
+func (m *Manager) installPackage(ctx context.Context, dep *Dependency, platformConfig *PlatformConfig) (string, error) {
+	// Package managers fetch packages from the network themselves
+	if m.offline {
+		return "", fmt.Errorf("failed to install %s: %w", dep.Name, ErrOffline)
+	}
+
+	manager, err := m.packageManagerFor(&platformConfig.Installer)
+	if err != nil {
+		return "", fmt.Errorf("failed to install %s: %w", dep.Name, err)
+	}
+
+	installCmd, err := packageInstallCommand(manager, platformConfig.Installer.Package, os.Geteuid() == 0)
+	if err != nil {
+		return "", fmt.Errorf("failed to install %s: %w", dep.Name, err)
+	}
+
+	m.notifyStatus(dep.Name, PhaseInstalling, nil)
+	output, err := m.runInstallCommand(ctx, dep, installCmd, platformConfig.RunAs)
+	if err != nil {
+		return output, err
+	}
+
+	m.logger.Infof("Successfully installed %s with %s", dep.Name, manager)
+	return output, nil
+}
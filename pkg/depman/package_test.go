@@ -0,0 +1,165 @@
+package depman
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sobhit-avrl/depman-v1/internal/environment"
+)
+
+func TestPackageInstallCommand(t *testing.T) {
+	testCases := []struct {
+		manager     string
+		root        bool
+		expected    string
+		expectError bool
+	}{
+		{manager: "apt", root: true, expected: "apt-get install -y jq"},
+		{manager: "apt", expected: "sudo apt-get install -y jq"},
+		{manager: "dnf", root: true, expected: "dnf install -y jq"},
+		{manager: "yum", expected: "sudo yum install -y jq"},
+		{manager: "zypper", root: true, expected: "zypper --non-interactive install jq"},
+		{manager: "pacman", root: true, expected: "pacman -S --noconfirm jq"},
+		{manager: "apk", root: true, expected: "apk add jq"},
+		{manager: "brew", expected: "brew install jq"},
+		{manager: "brew", root: true, expected: "brew install jq"},
+		{manager: "winget", expected: "winget install --exact --accept-package-agreements --accept-source-agreements --id jq"},
+		{manager: "choco", expected: "choco install -y jq"},
+		{manager: "scoop", expected: "scoop install jq"},
+		{manager: "emerge", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.manager, func(t *testing.T) {
+			command, err := packageInstallCommand(tc.manager, "jq", tc.root)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			if got := strings.Join(command, " "); got != tc.expected {
+				t.Errorf("Expected command %q but got %q", tc.expected, got)
+			}
+		})
+	}
+
+	// The package manager's own command must not be modified between installs
+	first, _ := packageInstallCommand("brew", "jq", false)
+	second, _ := packageInstallCommand("brew", "yq", false)
+	if first[len(first)-1] != "jq" || second[len(second)-1] != "yq" {
+		t.Errorf("Expected independent commands but got %v and %v", first, second)
+	}
+}
+
+func TestDetectPackageManager(t *testing.T) {
+	testCases := []struct {
+		name        string
+		platform    string
+		available   []string
+		expected    string
+		expectError bool
+	}{
+		{name: "Debian", platform: "linux", available: []string{"apt-get", "dnf"}, expected: "apt"},
+		{name: "Fedora", platform: "linux", available: []string{"dnf", "yum"}, expected: "dnf"},
+		{name: "Alpine", platform: "linux", available: []string{"apk"}, expected: "apk"},
+		{name: "macOS", platform: "darwin", available: []string{"brew"}, expected: "brew"},
+		{name: "Windows without winget", platform: "windows", available: []string{"choco", "scoop"}, expected: "choco"},
+		{name: "None found", platform: "darwin", available: []string{"apt-get"}, expectError: true},
+		{name: "Unknown platform", platform: "plan9", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lookPath := func(name string) (string, error) {
+				if slices.Contains(tc.available, name) {
+					return "/usr/bin/" + name, nil
+				}
+				return "", errors.New("not found")
+			}
+
+			manager, err := detectPackageManager(tc.platform, lookPath)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+			if manager != tc.expected {
+				t.Errorf("Expected package manager %s but got %s", tc.expected, manager)
+			}
+		})
+	}
+}
+
+// TestPackageInstaller tests installing with a package manager instead of downloading
+func TestPackageInstaller(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	// A fake brew on the PATH records what it was asked to install
+	dir := t.TempDir()
+	record := filepath.Join(dir, "installed")
+	script := "#!/bin/sh\necho \"$@\" > " + record + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "brew"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake package manager: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	dep := Dependency{
+		Name:    "jq",
+		Version: Version{Required: "1.7.0"},
+		Platforms: map[string]PlatformConfig{
+			runtime.GOOS: {
+				Installer: Installer{Type: InstallerTypePackage, Package: "jq", URL: "http://127.0.0.1:1/unused"},
+				Commands:  Commands{Verify: []string{"jq", "--version"}},
+			},
+		},
+	}
+	manager := &Manager{
+		Config:     &DependencyConfig{Dependencies: []Dependency{dep}},
+		Platform:   runtime.GOOS,
+		logger:     &mockLogger{},
+		envManager: environment.NewManager(),
+	}
+	WithPackageManager("brew")(manager)
+
+	if errs := manager.validateDependencies(); len(errs) != 0 {
+		t.Fatalf("Did not expect validation errors but got: %v", errs)
+	}
+
+	if _, err := manager.installDependency(context.Background(), &dep); err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+	installed, err := os.ReadFile(record)
+	if err != nil {
+		t.Fatalf("Expected the package manager to run: %v", err)
+	}
+	if got := strings.TrimSpace(string(installed)); got != "install jq" {
+		t.Errorf("Expected brew to be run with %q but got %q", "install jq", got)
+	}
+
+	// A package installer needs a package, and a known package manager
+	dep.Platforms[runtime.GOOS] = PlatformConfig{
+		Installer: Installer{Type: InstallerTypePackage, PackageManager: "emerge"},
+		Commands:  Commands{Verify: []string{"jq", "--version"}},
+	}
+	manager.Config.Dependencies = []Dependency{dep}
+	if errs := manager.validateDependencies(); len(errs) != 2 {
+		t.Errorf("Expected two validation errors but got: %v", errs)
+	}
+}
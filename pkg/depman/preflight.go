@@ -0,0 +1,57 @@
+package depman
+
+import (
+	"fmt"
+
+	"github.com/sobhit-avrl/depman-v1/internal/downloader"
+)
+
+// URLCheck contains the result of checking a dependency's download URL
+type URLCheck struct {
+	URL   string // Download URL that was checked
+	Size  int64  // Advertised size in bytes (-1 if unknown)
+	Error error  // Why the URL is unreachable, nil if it is reachable
+}
+
+// CheckDownloadURLs checks that the download URL of every managed dependency is reachable
+// for the current platform without downloading it. Dependencies without a URL are left out.
+func (m *Manager) CheckDownloadURLs() map[string]*URLCheck {
+	results := make(map[string]*URLCheck)
+
+	for _, dep := range m.Config.Dependencies {
+		if m.isSkipped(&dep) {
+			continue
+		}
+
+		platformConfig, err := m.GetPlatformConfig(&dep)
+		if err != nil || platformConfig.Installer.URL == "" {
+			continue
+		}
+
+		check := &URLCheck{URL: platformConfig.Installer.URL, Size: -1}
+		results[dep.Name] = check
+
+		headers, err := platformConfig.Installer.Auth.Headers()
+		if err != nil {
+			check.Error = fmt.Errorf("invalid auth: %w", err)
+			continue
+		}
+
+		check.Size, check.Error = downloader.Probe(downloader.DownloadOptions{
+			URL:                    platformConfig.Installer.URL,
+			Headers:                headers,
+			UserAgent:              m.userAgent,
+			Client:                 m.httpClient,
+			InsecureSkipVerify:     m.insecureSkipVerify,
+			CACertFile:             m.caCertFile,
+			Offline:                m.offline,
+			AllowCrossHostRedirect: platformConfig.Installer.AllowCrossHostRedirect,
+			MaxRedirects:           platformConfig.Installer.MaxRedirects,
+		})
+		if check.Error != nil {
+			m.logger.Warnf("Download URL for %s is unreachable: %v", dep.Name, check.Error)
+		}
+	}
+
+	return results
+}
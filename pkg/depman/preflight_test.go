@@ -0,0 +1,90 @@
+package depman
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestCheckDownloadURLs tests reachability checks against reachable and missing URLs
+func TestCheckDownloadURLs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tool.tar.gz":
+			w.Header().Set("Content-Length", "1234")
+			if r.Method == http.MethodGet {
+				w.Write(make([]byte, 1234))
+			}
+		case "/no-head.zip":
+			// Some servers reject HEAD but honor ranged GETs
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Range", "bytes 0-0/4096")
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte{0})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	newDep := func(name, path string) Dependency {
+		return Dependency{
+			Name:    name,
+			Version: Version{Required: "1.0.0"},
+			Platforms: map[string]PlatformConfig{
+				"linux": {Installer: Installer{URL: server.URL + path}},
+			},
+		}
+	}
+
+	manager := &Manager{
+		Config: &DependencyConfig{
+			Dependencies: []Dependency{
+				newDep("reachable", "/tool.tar.gz"),
+				newDep("ranged", "/no-head.zip"),
+				newDep("missing", "/missing.tar.gz"),
+				{Name: "no-url", Platforms: map[string]PlatformConfig{"linux": {}}},
+			},
+		},
+		Platform: "linux",
+		logger:   &mockLogger{},
+	}
+
+	results := manager.CheckDownloadURLs()
+
+	testCases := []struct {
+		name         string
+		expectError  bool
+		expectedSize int64
+	}{
+		{name: "reachable", expectError: false, expectedSize: 1234},
+		{name: "ranged", expectError: false, expectedSize: 4096},
+		{name: "missing", expectError: true, expectedSize: -1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			check, ok := results[tc.name]
+			if !ok {
+				t.Fatalf("Expected a result for %s", tc.name)
+			}
+
+			if tc.expectError && check.Error == nil {
+				t.Errorf("Expected an error but got none")
+			}
+			if !tc.expectError && check.Error != nil {
+				t.Errorf("Did not expect an error but got: %v", check.Error)
+			}
+			if check.Size != tc.expectedSize {
+				t.Errorf("Expected size %d but got %d", tc.expectedSize, check.Size)
+			}
+		})
+	}
+
+	if _, ok := results["no-url"]; ok {
+		t.Errorf("Expected dependencies without a URL to be left out")
+	}
+}
@@ -0,0 +1,113 @@
+//go:build !windows
+
+package depman
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// configureProcessGroup runs the command in its own process group so that
+// cancelling it also kills any child processes it spawned
+func configureProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// runAsUser runs the command as the named user, with that user's home directory
+// Switching to another user requires root
+func runAsUser(cmd *exec.Cmd, username string) error {
+	u, err := user.Lookup(username)
+	if err != nil {
+		return fmt.Errorf("failed to look up run_as user: %w", err)
+	}
+
+	credential, err := credentialFor(u)
+	if err != nil {
+		return err
+	}
+	euid := os.Geteuid()
+	if err := checkRunAsPermission(euid, credential, username); err != nil {
+		return err
+	}
+
+	// Already running as the user, so there is nothing to switch
+	if uint32(euid) != credential.Uid {
+		if cmd.SysProcAttr == nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		cmd.SysProcAttr.Credential = credential
+	}
+	if cmd.Env != nil {
+		cmd.Env = append(cmd.Env, "HOME="+u.HomeDir, "USER="+u.Username, "LOGNAME="+u.Username)
+	}
+	return nil
+}
+
+// shareWithUser makes the user the owner of dir and everything in it, so a command run as
+// that user can read downloads and write staged files in a directory created by root
+func shareWithUser(dir, username string) error {
+	u, err := user.Lookup(username)
+	if err != nil {
+		return fmt.Errorf("failed to look up run_as user: %w", err)
+	}
+	credential, err := credentialFor(u)
+	if err != nil {
+		return err
+	}
+	if uint32(os.Geteuid()) == credential.Uid {
+		return nil // Already owned by the user
+	}
+
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := os.Lchown(path, int(credential.Uid), int(credential.Gid)); err != nil {
+			return fmt.Errorf("failed to give %s to %s: %w", path, username, err)
+		}
+		return nil
+	})
+}
+
+// credentialFor returns the uid, gid and supplementary groups of a user
+func credentialFor(u *user.User) (*syscall.Credential, error) {
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("user %s has a non-numeric uid '%s'", u.Username, u.Uid)
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("user %s has a non-numeric gid '%s'", u.Username, u.Gid)
+	}
+
+	credential := &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}
+
+	// Supplementary groups are best effort, the primary group is enough to run the command
+	if groupIDs, err := u.GroupIds(); err == nil {
+		for _, id := range groupIDs {
+			if group, err := strconv.ParseUint(id, 10, 32); err == nil {
+				credential.Groups = append(credential.Groups, uint32(group))
+			}
+		}
+	}
+
+	return credential, nil
+}
+
+// checkRunAsPermission returns an error unless the effective uid may run commands with
+// the credential: root may run as anyone, other users only as themselves
+func checkRunAsPermission(euid int, credential *syscall.Credential, username string) error {
+	if euid != 0 && uint32(euid) != credential.Uid {
+		return fmt.Errorf("running commands as %s requires root", username)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+//go:build !windows
+
+package depman
+
+import (
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+func TestCredentialFor(t *testing.T) {
+	testCases := []struct {
+		name        string
+		user        *user.User
+		expectedUID uint32
+		expectedGID uint32
+		expectError bool
+	}{
+		{name: "Numeric ids", user: &user.User{Username: "builder", Uid: "1001", Gid: "1002"}, expectedUID: 1001, expectedGID: 1002},
+		{name: "Root", user: &user.User{Username: "root", Uid: "0", Gid: "0"}},
+		{name: "Non-numeric uid", user: &user.User{Username: "builder", Uid: "S-1-5-21", Gid: "1002"}, expectError: true},
+		{name: "Non-numeric gid", user: &user.User{Username: "builder", Uid: "1001", Gid: "staff"}, expectError: true},
+		{name: "Uid out of range", user: &user.User{Username: "builder", Uid: "4294967296", Gid: "1002"}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			credential, err := credentialFor(tc.user)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			if credential.Uid != tc.expectedUID || credential.Gid != tc.expectedGID {
+				t.Errorf("Expected uid %d and gid %d but got %d and %d",
+					tc.expectedUID, tc.expectedGID, credential.Uid, credential.Gid)
+			}
+		})
+	}
+
+	t.Run("Current user", func(t *testing.T) {
+		current, err := user.Current()
+		if err != nil {
+			t.Skipf("Current user not available: %v", err)
+		}
+
+		credential, err := credentialFor(current)
+		if err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+		if int(credential.Uid) != os.Getuid() {
+			t.Errorf("Expected uid %d but got %d", os.Getuid(), credential.Uid)
+		}
+		if gid, _ := strconv.Atoi(current.Gid); int(credential.Gid) != gid {
+			t.Errorf("Expected gid %d but got %d", gid, credential.Gid)
+		}
+	})
+}
+
+func TestCheckRunAsPermission(t *testing.T) {
+	other := &syscall.Credential{Uid: 1001, Gid: 1001}
+
+	testCases := []struct {
+		name        string
+		euid        int
+		expectError bool
+	}{
+		{name: "Root may switch", euid: 0},
+		{name: "Same user", euid: 1001},
+		{name: "Other user needs root", euid: 1002, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkRunAsPermission(tc.euid, other, "builder")
+			if tc.expectError && err == nil {
+				t.Errorf("Expected an error but got none")
+			} else if !tc.expectError && err != nil {
+				t.Errorf("Did not expect an error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestRunAsUser(t *testing.T) {
+	current, err := user.Current()
+	if err != nil {
+		t.Skipf("Current user not available: %v", err)
+	}
+
+	// Running as the current user needs no privileges and only adjusts the environment
+	cmd := exec.Command("true")
+	cmd.Env = []string{"HOME=/nowhere"}
+	if err := runAsUser(cmd, current.Username); err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+	if cmd.SysProcAttr != nil && cmd.SysProcAttr.Credential != nil {
+		t.Errorf("Did not expect a credential switch to the current user")
+	}
+	if !slices.Contains(cmd.Env, "HOME="+current.HomeDir) {
+		t.Errorf("Expected HOME of the user in the environment but got %v", cmd.Env)
+	}
+
+	if err := runAsUser(exec.Command("true"), "depman-no-such-user"); err == nil {
+		t.Errorf("Expected an error but got none")
+	}
+}
+
+func TestShareWithUser(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Giving files to another user requires root")
+	}
+	nobody, err := user.Lookup("nobody")
+	if err != nil {
+		t.Skipf("User nobody not available: %v", err)
+	}
+
+	// Created like an install's download directory, private to root
+	dir, err := os.MkdirTemp("", "depman-download-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	download := filepath.Join(dir, "tool.tar.gz")
+	staging := filepath.Join(dir, "staging")
+	if err := os.WriteFile(download, []byte("artifact"), 0600); err != nil {
+		t.Fatalf("Failed to write download: %v", err)
+	}
+	if err := os.Mkdir(staging, 0755); err != nil {
+		t.Fatalf("Failed to create staging directory: %v", err)
+	}
+
+	if err := shareWithUser(dir, "nobody"); err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+
+	for _, path := range []string{dir, download, staging} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Failed to stat %s: %v", path, err)
+		}
+		if uid := strconv.FormatUint(uint64(info.Sys().(*syscall.Stat_t).Uid), 10); uid != nobody.Uid {
+			t.Errorf("Expected %s to be owned by uid %s but got %s", path, nobody.Uid, uid)
+		}
+	}
+
+	// A command run as the user can read the download and write the staging directory
+	cmd := exec.Command("sh", "-c", "cat "+download+" > "+filepath.Join(staging, "tool"))
+	if err := runAsUser(cmd, "nobody"); err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("Expected the user to access the directory but got: %v, output: %s", err, output)
+	}
+}
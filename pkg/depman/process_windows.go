@@ -0,0 +1,39 @@
+//go:build windows
+
+package depman
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// processTreeWaitDelay bounds how long output is waited for once a cancelled command is killed,
+// in case a descendant that escaped the kill keeps its output pipes open
+const processTreeWaitDelay = 5 * time.Second
+
+// configureProcessGroup makes cancelling the command kill its whole process tree, as Windows
+// has no process groups to signal
+// The tree is found through parent process IDs, so descendants whose parent already exited
+// are not killed; the wait for output is bounded so they can't hang the command
+func configureProcessGroup(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		kill := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid))
+		if err := kill.Run(); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = processTreeWaitDelay
+}
+
+// shareWithUser is a no-op on Windows, where run_as is not supported
+func shareWithUser(dir, username string) error {
+	return nil
+}
+
+// runAsUser is not supported on Windows, which has no way to switch users without a password
+func runAsUser(cmd *exec.Cmd, username string) error {
+	return fmt.Errorf("run_as is not supported on Windows")
+}
@@ -0,0 +1,68 @@
+package depman
+
+import (
+	"time"
+)
+
+// EnsureReport summarizes the outcome of an ensure run
+type EnsureReport struct {
+	Installed int                      // Dependencies newly installed
+	Updated   int                      // Dependencies updated from an older or incompatible version
+	Failed    int                      // Dependencies that failed to install or verify
+	Skipped   int                      // Dependencies that were skipped
+	UpToDate  int                      // Dependencies that needed no changes
+	Duration  time.Duration            // Total duration of the run
+	Timings   map[string]time.Duration // Time spent installing each dependency that needed work
+}
+
+// newEnsureReport creates an empty report
+func newEnsureReport() *EnsureReport {
+	return &EnsureReport{
+		Timings: make(map[string]time.Duration),
+	}
+}
+
+// record classifies the outcome of ensuring a single dependency
+// wasInstalled and acted describe the dependency before ensureDependency ran
+func (r *EnsureReport) record(name string, wasInstalled, acted bool, status *DependencyStatus, err error, elapsed time.Duration) {
+	switch {
+	case status.Skipped:
+		r.Skipped++
+		return
+	case !acted:
+		r.UpToDate++
+		return
+	}
+
+	r.Timings[name] = elapsed
+
+	switch {
+	case err != nil || status.Error != nil || !status.Installed:
+		r.Failed++
+	case wasInstalled:
+		r.Updated++
+	default:
+		r.Installed++
+	}
+}
+
+// recordHeldBack records the dependencies among names that failed and were held back for a
+// retry that will not happen, with their last status and error
+// Names never attempted, e.g. those waiting on a failed prerequisite, are left out
+func (r *EnsureReport) recordHeldBack(names []string, attempts map[string]*ensureAttempt, statuses map[string]*DependencyStatus) {
+	for _, name := range names {
+		a := attempts[name]
+		if a == nil || a.lastErr == nil {
+			continue
+		}
+		r.record(name, a.wasInstalled, a.acted, statuses[name], a.lastErr, a.elapsed)
+	}
+}
+
+// needsInstall reports whether a dependency must be installed or updated
+func needsInstall(status *DependencyStatus) bool {
+	if status.Skipped {
+		return false
+	}
+	return !(status.Installed && status.Compatible && status.RequiredUpdate == NoUpdate)
+}
@@ -0,0 +1,126 @@
+package depman
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRunLockName is the standard name of the lock file guarding ensure runs
+const DefaultRunLockName = ".depman-run.lock"
+
+// runLockPollInterval is how often a held run lock is retried while waiting for it
+const runLockPollInterval = 100 * time.Millisecond
+
+// errLockHeld is returned by lockFile when another process holds the lock
+var errLockHeld = errors.New("lock held")
+
+// WithRunLock holds an exclusive lock on the file at path while ensuring dependencies, so
+// concurrent depman processes sharing install directories and state files don't corrupt them
+// A lock held by another process or by a concurrent ensure call on the same manager is waited
+// for up to wait (0 fails immediately) before failing with ErrLocked
+// Only runs using the same path are serialized, so runs sharing install directories must
+// agree on it
+func WithRunLock(path string, wait time.Duration) Option {
+	return func(m *Manager) {
+		m.runLock = path
+		m.runLockWait = wait
+	}
+}
+
+// acquireRunLock takes the run lock, if one is configured, waiting for another process to
+// release it up to the configured wait. The returned function releases the lock
+// Ensure calls on the same manager are serialized first, as the file lock only excludes
+// other processes; both waits share the configured wait
+func (m *Manager) acquireRunLock(ctx context.Context) (func(), error) {
+	if m.runLock == "" {
+		return func() {}, nil
+	}
+
+	deadline := time.Now().Add(m.runLockWait)
+	if err := m.lockRun(ctx, deadline); err != nil {
+		return nil, err
+	}
+	file, err := m.lockRunLockFile(ctx, deadline)
+	if err != nil {
+		m.runMu.Unlock()
+		return nil, err
+	}
+
+	return func() {
+		unlockFile(file)
+		m.runMu.Unlock()
+	}, nil
+}
+
+// lockRun serializes ensure runs of this manager, waiting for a running one to finish until
+// the deadline
+func (m *Manager) lockRun(ctx context.Context, deadline time.Time) error {
+	for !m.runMu.TryLock() {
+		if !time.Now().Before(deadline) {
+			if m.runLockWait > 0 {
+				return fmt.Errorf("%w: another ensure of this manager is still running after waiting %s", ErrLocked, m.runLockWait)
+			}
+			return fmt.Errorf("%w: another ensure of this manager is running", ErrLocked)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for another ensure cancelled: %w", ctx.Err())
+		case <-time.After(min(runLockPollInterval, time.Until(deadline))):
+		}
+	}
+	return nil
+}
+
+// lockRunLockFile locks the run lock file, waiting for another process to release it until
+// the deadline
+func (m *Manager) lockRunLockFile(ctx context.Context, deadline time.Time) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(m.runLock), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory for %s: %w", m.runLock, err)
+	}
+
+	for {
+		file, err := lockFile(m.runLock)
+		if err == nil {
+			// Record the holder so a blocked process can report it
+			_ = file.Truncate(0)
+			_, _ = file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+			return file, nil
+		}
+		if !errors.Is(err, errLockHeld) {
+			return nil, fmt.Errorf("failed to lock %s: %w", m.runLock, err)
+		}
+
+		if !time.Now().Before(deadline) {
+			return nil, m.runLockError()
+		}
+		m.logger.Infof("Waiting for another depman process holding %s", m.runLock)
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for %s cancelled: %w", m.runLock, ctx.Err())
+		case <-time.After(min(runLockPollInterval, time.Until(deadline))):
+		}
+	}
+}
+
+// runLockError describes a run lock held by another process, naming its PID if known
+func (m *Manager) runLockError() error {
+	holder := ""
+	if data, err := os.ReadFile(m.runLock); err == nil {
+		if pid := strings.TrimSpace(string(data)); pid != "" {
+			holder = " (pid " + pid + ")"
+		}
+	}
+
+	if m.runLockWait > 0 {
+		return fmt.Errorf("%w%s: %s is still locked after waiting %s", ErrLocked, holder, m.runLock, m.runLockWait)
+	}
+	return fmt.Errorf("%w%s: %s is locked", ErrLocked, holder, m.runLock)
+}
@@ -0,0 +1,181 @@
+package depman
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sobhit-avrl/depman-v1/internal/environment"
+)
+
+// holdRunLock takes the lock at path as another depman process would, returning its release
+func holdRunLock(t *testing.T, path string) func() {
+	t.Helper()
+
+	holder := &Manager{logger: &mockLogger{}, runLock: path}
+	release, err := holder.acquireRunLock(context.Background())
+	if err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+	return release
+}
+
+// TestRunLock tests that a held run lock fails or delays ensure runs until it is released
+func TestRunLock(t *testing.T) {
+	t.Run("Held lock", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), DefaultRunLockName)
+		release := holdRunLock(t, path)
+		defer release()
+
+		manager := &Manager{Config: &DependencyConfig{}, logger: &mockLogger{}}
+		WithRunLock(path, 0)(manager)
+
+		_, _, err := manager.EnsureDependencies()
+		if !errors.Is(err, ErrLocked) {
+			t.Fatalf("Expected ErrLocked but got: %v", err)
+		}
+		if !strings.Contains(err.Error(), "pid "+strconv.Itoa(os.Getpid())) {
+			t.Errorf("Expected the error to name the holder's PID but got: %v", err)
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), DefaultRunLockName)
+		release := holdRunLock(t, path)
+		defer release()
+
+		manager := &Manager{logger: &mockLogger{}}
+		WithRunLock(path, 250*time.Millisecond)(manager)
+
+		start := time.Now()
+		_, err := manager.acquireRunLock(context.Background())
+		if !errors.Is(err, ErrLocked) {
+			t.Fatalf("Expected ErrLocked but got: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < 250*time.Millisecond {
+			t.Errorf("Expected to wait for the lock but gave up after %s", elapsed)
+		}
+	})
+
+	t.Run("Released while waiting", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("sh command not available on Windows")
+		}
+
+		dir := t.TempDir()
+		path := filepath.Join(dir, DefaultRunLockName)
+		release := holdRunLock(t, path)
+		time.AfterFunc(200*time.Millisecond, release)
+
+		manager := &Manager{
+			Config:     &DependencyConfig{Dependencies: []Dependency{newScriptDependency(dir, "tool", nil, "")}},
+			Platform:   runtime.GOOS,
+			logger:     &mockLogger{},
+			envManager: environment.NewManager(),
+		}
+		WithRunLock(path, 10*time.Second)(manager)
+
+		if _, _, err := manager.EnsureDependenciesParallel(2); err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+
+		// The lock is released once the run completes
+		holdRunLock(t, path)()
+	})
+
+	t.Run("Cancelled while waiting", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), DefaultRunLockName)
+		release := holdRunLock(t, path)
+		defer release()
+
+		manager := &Manager{logger: &mockLogger{}}
+		WithRunLock(path, 10*time.Second)(manager)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		if _, err := manager.acquireRunLock(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected the wait to be cancelled but got: %v", err)
+		}
+	})
+
+	t.Run("Concurrent calls on one manager", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("sh command not available on Windows")
+		}
+
+		dir := t.TempDir()
+		manager := &Manager{
+			Config:     &DependencyConfig{Dependencies: []Dependency{newScriptDependency(dir, "tool", nil, "")}},
+			Platform:   runtime.GOOS,
+			logger:     &mockLogger{},
+			envManager: environment.NewManager(),
+		}
+		WithRunLock(filepath.Join(dir, "locks", DefaultRunLockName), 10*time.Second)(manager)
+
+		// Each call waits for the other instead of failing with ErrLocked
+		var wg sync.WaitGroup
+		errs := make([]error, 4)
+		for i := range errs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _, errs[i] = manager.EnsureDependencies()
+			}()
+		}
+		wg.Wait()
+
+		for _, err := range errs {
+			if err != nil {
+				t.Errorf("Did not expect an error but got: %v", err)
+			}
+		}
+	})
+
+	t.Run("Concurrent call on one manager without waiting", func(t *testing.T) {
+		manager := &Manager{logger: &mockLogger{}}
+		WithRunLock(filepath.Join(t.TempDir(), DefaultRunLockName), 0)(manager)
+
+		release, err := manager.acquireRunLock(context.Background())
+		if err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+		defer release()
+
+		if _, err := manager.acquireRunLock(context.Background()); !errors.Is(err, ErrLocked) {
+			t.Errorf("Expected ErrLocked but got: %v", err)
+		}
+	})
+
+	t.Run("Concurrent call on one manager cancelled", func(t *testing.T) {
+		manager := &Manager{logger: &mockLogger{}}
+		WithRunLock(filepath.Join(t.TempDir(), DefaultRunLockName), 10*time.Second)(manager)
+
+		release, err := manager.acquireRunLock(context.Background())
+		if err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+		defer release()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		if _, err := manager.acquireRunLock(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected the wait to be cancelled but got: %v", err)
+		}
+	})
+
+	t.Run("No lock configured", func(t *testing.T) {
+		manager := &Manager{logger: &mockLogger{}}
+		release, err := manager.acquireRunLock(context.Background())
+		if err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+		release()
+	})
+}
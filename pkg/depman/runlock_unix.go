@@ -0,0 +1,34 @@
+//go:build !windows
+
+package depman
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockFile opens the file at path, creating it if needed, and takes an exclusive lock on it
+// without blocking, returning errLockHeld if another process holds it
+// The lock is released when the file is closed, including when the process exits
+func lockFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, errLockHeld
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+// unlockFile releases a lock taken with lockFile
+func unlockFile(file *os.File) {
+	_ = syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+	file.Close()
+}
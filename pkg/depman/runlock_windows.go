@@ -0,0 +1,37 @@
+//go:build windows
+
+package depman
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// errorSharingViolation is returned when opening a file another process opened exclusively
+const errorSharingViolation syscall.Errno = 32
+
+// lockFile opens the file at path, creating it if needed, without sharing write access, so
+// another process opening it the same way fails, returning errLockHeld in that case
+// The lock is released when the file is closed, including when the process exits
+func lockFile(path string) (*os.File, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+
+	handle, err := syscall.CreateFile(name, syscall.GENERIC_READ|syscall.GENERIC_WRITE,
+		syscall.FILE_SHARE_READ, nil, syscall.OPEN_ALWAYS, syscall.FILE_ATTRIBUTE_NORMAL, 0)
+	if err != nil {
+		if errors.Is(err, errorSharingViolation) {
+			return nil, errLockHeld
+		}
+		return nil, err
+	}
+	return os.NewFile(uintptr(handle), path), nil
+}
+
+// unlockFile releases a lock taken with lockFile
+func unlockFile(file *os.File) {
+	file.Close()
+}
@@ -0,0 +1,85 @@
+package depman
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// ProbeResult describes a tool found (or not found) on the system
+type ProbeResult struct {
+	Name    string // Tool name as given
+	Path    string // Resolved executable path (empty if not found)
+	Version string // Detected version (empty if it could not be determined)
+}
+
+// ProbeTool looks up a tool on PATH and runs "<tool> --version" to detect its version
+// A tool that is not installed is not an error; its Path is simply left empty
+func ProbeTool(name string) (*ProbeResult, error) {
+	result := &ProbeResult{Name: name}
+
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return result, nil
+	}
+	result.Path = path
+
+	// Run the version command with a timeout to avoid hanging
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, path, "--version").CombinedOutput()
+	if err != nil {
+		return result, fmt.Errorf("failed to get version of %s: %w", name, err)
+	}
+
+	// Only keep the version if it is a valid semantic version
+	version := ExtractVersion(strings.TrimSpace(string(output)))
+	if _, err := semver.NewVersion(version); err == nil {
+		result.Version = version
+	}
+
+	return result, nil
+}
+
+// ScaffoldConfig builds a starter configuration for the probed tools on the given platform
+// Detected versions are pre-filled into version.required with a caret constraint; tools without
+// a detected version are kept with no required version. Without an install command, each
+// dependency is check-only, so the configuration is valid until install commands are added
+func ScaffoldConfig(appName, platform string, probes []*ProbeResult) *DependencyConfig {
+	config := &DependencyConfig{
+		Version:      CurrentConfigVersion,
+		Name:         appName,
+		Dependencies: make([]Dependency, 0, len(probes)),
+	}
+
+	for _, probe := range probes {
+		dep := Dependency{
+			Name:        probe.Name,
+			InstallMode: InstallModeCheckOnly,
+			Platforms: map[string]PlatformConfig{
+				platform: {
+					Commands: Commands{
+						Verify: []string{probe.Name, "--version"},
+					},
+				},
+			},
+		}
+
+		if probe.Version != "" {
+			dep.Version = Version{Required: probe.Version, Constraint: "^" + probe.Version}
+		}
+
+		if probe.Path != "" {
+			dep.Description = fmt.Sprintf("Detected at %s", probe.Path)
+		}
+
+		config.Dependencies = append(config.Dependencies, dep)
+	}
+
+	return config
+}
@@ -0,0 +1,106 @@
+package depman
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TestProbeTool tests version detection using fake executables
+func TestProbeTool(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script executables not available on Windows")
+	}
+
+	binDir := t.TempDir()
+	writeTool := func(name, script string) {
+		path := filepath.Join(binDir, name)
+		if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+			t.Fatalf("Failed to create fake executable: %v", err)
+		}
+	}
+
+	writeTool("faketool", `echo "faketool version v2.4.1 (build abc)"`)
+	writeTool("noversion", `echo "no version here"`)
+	writeTool("broken", `exit 1`)
+
+	t.Setenv("PATH", binDir)
+
+	testCases := []struct {
+		name            string
+		tool            string
+		expectFound     bool
+		expectedVersion string
+		expectError     bool
+	}{
+		{name: "Detects version", tool: "faketool", expectFound: true, expectedVersion: "2.4.1"},
+		{name: "No version in output", tool: "noversion", expectFound: true, expectedVersion: ""},
+		{name: "Failing version command", tool: "broken", expectFound: true, expectError: true},
+		{name: "Tool not installed", tool: "missingtool", expectFound: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := ProbeTool(tc.tool)
+
+			// Check error expectation
+			if tc.expectError && err == nil {
+				t.Errorf("Expected an error but got none")
+			}
+			if !tc.expectError && err != nil {
+				t.Errorf("Did not expect an error but got: %v", err)
+			}
+
+			if (result.Path != "") != tc.expectFound {
+				t.Errorf("Expected found=%v but got path '%s'", tc.expectFound, result.Path)
+			}
+
+			if result.Version != tc.expectedVersion {
+				t.Errorf("Expected version '%s' but got '%s'", tc.expectedVersion, result.Version)
+			}
+		})
+	}
+}
+
+// TestScaffoldConfig tests pre-filling versions into a starter configuration
+func TestScaffoldConfig(t *testing.T) {
+	config := ScaffoldConfig("My App", "linux", []*ProbeResult{
+		{Name: "go", Path: "/usr/bin/go", Version: "1.22.0"},
+		{Name: "missing"},
+	})
+
+	if config.Name != "My App" {
+		t.Errorf("Expected app name 'My App' but got '%s'", config.Name)
+	}
+
+	if len(config.Dependencies) != 2 {
+		t.Fatalf("Expected 2 dependencies but got %d", len(config.Dependencies))
+	}
+
+	dep := config.Dependencies[0]
+	if dep.Version.Required != "1.22.0" || dep.Version.Constraint != "^1.22.0" {
+		t.Errorf("Expected version 1.22.0 with constraint ^1.22.0 but got %+v", dep.Version)
+	}
+
+	if verify := dep.Platforms["linux"].Commands.Verify; len(verify) != 2 || verify[0] != "go" {
+		t.Errorf("Expected verify command for go but got %v", verify)
+	}
+
+	for _, dep := range config.Dependencies {
+		if dep.InstallMode != InstallModeCheckOnly {
+			t.Errorf("Expected %s install mode %s but got '%s'", dep.Name, InstallModeCheckOnly, dep.InstallMode)
+		}
+	}
+
+	// A tool without a detected version is kept with no required version
+	if missing := config.Dependencies[1]; missing.Name != "missing" || missing.Version != (Version{}) {
+		t.Errorf("Expected missing tool without a version but got %+v", missing)
+	}
+
+	// The starter configuration passes validation as is
+	manager := &Manager{Config: config, Platform: "linux", logger: &mockLogger{}}
+	if err := manager.validateConfiguration(); err != nil {
+		t.Errorf("Did not expect an error but got: %v", err)
+	}
+}
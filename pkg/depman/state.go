@@ -0,0 +1,137 @@
+package depman
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// DefaultStateFileName is the standard name of the install state file
+const DefaultStateFileName = ".depman-state.json"
+
+// DefaultStateTTL is how long a confirmed dependency skips verification by default
+const DefaultStateTTL = time.Hour
+
+// State records dependencies that were last confirmed installed at the required version
+type State struct {
+	ConfigVersion string                 `json:"config_version"` // Configuration version the entries belong to
+	Dependencies  map[string]*StateEntry `json:"dependencies"`   // Confirmed dependencies by name
+}
+
+// StateEntry records when a dependency was last confirmed
+type StateEntry struct {
+	Version     string    `json:"version"`              // Confirmed installed version
+	Required    string    `json:"required"`             // Required version at the time
+	Constraint  string    `json:"constraint,omitempty"` // Version constraint at the time
+	ConfirmedAt time.Time `json:"confirmed_at"`         // When the version was last verified
+}
+
+// LoadState reads a state file from disk, returning an empty state if it doesn't exist
+func LoadState(path string) (*State, error) {
+	state := &State{Dependencies: make(map[string]*StateEntry)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return &State{Dependencies: make(map[string]*StateEntry)}, fmt.Errorf("failed to parse state file: %w", err)
+	}
+	if state.Dependencies == nil {
+		state.Dependencies = make(map[string]*StateEntry)
+	}
+
+	return state, nil
+}
+
+// Save writes the state file to disk
+func (s *State) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	return nil
+}
+
+// lookup returns the entry for a dependency if it was confirmed within ttl and its
+// version requirements, with the given required version, haven't changed since
+func (s *State) lookup(dep *Dependency, required string, ttl time.Duration, now time.Time) (*StateEntry, bool) {
+	entry, ok := s.Dependencies[dep.Name]
+	if !ok || entry.Required != required || entry.Constraint != dep.Version.Constraint {
+		return nil, false
+	}
+
+	if now.Sub(entry.ConfirmedAt) > ttl {
+		return nil, false
+	}
+
+	return entry, true
+}
+
+// record marks a dependency with the given required version as confirmed at version
+func (s *State) record(dep *Dependency, required, version string, now time.Time) {
+	s.Dependencies[dep.Name] = &StateEntry{
+		Version:     version,
+		Required:    required,
+		Constraint:  dep.Version.Constraint,
+		ConfirmedAt: now,
+	}
+}
+
+// loadState loads the configured state file, or returns nil if none is configured
+// Entries recorded for a different configuration version are discarded
+func (m *Manager) loadState() *State {
+	// A deep check verifies every dependency, so the state can't skip any
+	if m.stateFile == "" || m.deepCheck {
+		return nil
+	}
+
+	state, err := LoadState(m.stateFile)
+	if err != nil {
+		m.logger.Warnf("Ignoring install state: %v", err)
+	}
+
+	if state.ConfigVersion != m.Config.Version {
+		state.ConfigVersion = m.Config.Version
+		state.Dependencies = make(map[string]*StateEntry)
+	}
+
+	return state
+}
+
+// saveState records the dependencies verified during this run and writes the state file
+func (m *Manager) saveState(state *State, statuses map[string]*DependencyStatus) {
+	if state == nil {
+		return
+	}
+
+	now := time.Now()
+	for name, status := range statuses {
+		dep := m.findDependency(name)
+		if dep == nil || status.Skipped || status.Cached {
+			continue
+		}
+
+		if status.Installed && status.Compatible && status.RequiredUpdate == NoUpdate && status.Error == nil {
+			state.record(dep, m.requiredVersion(dep), status.CurrentVersion, now)
+		} else {
+			delete(state.Dependencies, name)
+		}
+	}
+
+	if err := state.Save(m.stateFile); err != nil {
+		m.logger.Warnf("Failed to save install state: %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package depman
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sobhit-avrl/depman-v1/internal/environment"
+)
+
+// TestStateFile tests skipping verification of recently confirmed dependencies
+func TestStateFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	// newManager creates a manager whose verify command logs each run
+	newManager := func(dir string, ttl time.Duration) *Manager {
+		verifyLog := filepath.Join(dir, "verify.log")
+		manager := &Manager{
+			Config: &DependencyConfig{
+				Version: "1.0",
+				Dependencies: []Dependency{
+					{
+						Name:        "tool",
+						Version:     Version{Required: "1.0.0"},
+						InstallMode: InstallModeCheckOnly,
+						Platforms: map[string]PlatformConfig{
+							runtime.GOOS: {
+								Commands: Commands{
+									Verify: []string{"sh", "-c", fmt.Sprintf("echo run >> %s; echo 1.0.0", verifyLog)},
+								},
+							},
+						},
+					},
+				},
+			},
+			Platform:   runtime.GOOS,
+			logger:     &mockLogger{},
+			envManager: environment.NewManager(),
+		}
+		WithStateFile(filepath.Join(dir, DefaultStateFileName))(manager)
+		WithStateTTL(ttl)(manager)
+		return manager
+	}
+
+	verifyRuns := func(t *testing.T, dir string) int {
+		data, err := os.ReadFile(filepath.Join(dir, "verify.log"))
+		if err != nil {
+			t.Fatalf("Failed to read verify log: %v", err)
+		}
+		return strings.Count(string(data), "run")
+	}
+
+	testCases := []struct {
+		name         string
+		ttl          time.Duration
+		change       func(m *Manager)
+		expectedRuns int
+	}{
+		{name: "Hit within TTL", ttl: time.Hour, expectedRuns: 1},
+		{name: "Miss after TTL", ttl: time.Nanosecond, expectedRuns: 2},
+		{name: "Invalidated by config version", ttl: time.Hour, change: func(m *Manager) { m.Config.Version = "2.0" }, expectedRuns: 2},
+		{name: "Invalidated by constraint", ttl: time.Hour, change: func(m *Manager) { m.Config.Dependencies[0].Version.Constraint = ">=1.0.0" }, expectedRuns: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			if _, _, err := newManager(dir, tc.ttl).EnsureDependencies(); err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			manager := newManager(dir, tc.ttl)
+			if tc.change != nil {
+				tc.change(manager)
+			}
+
+			statuses, _, _ := manager.EnsureDependencies()
+			if runs := verifyRuns(t, dir); runs != tc.expectedRuns {
+				t.Errorf("Expected %d verify runs but got %d", tc.expectedRuns, runs)
+			}
+
+			if tc.expectedRuns == 1 && !statuses["tool"].Cached {
+				t.Errorf("Expected the status to come from the state file")
+			}
+		})
+	}
+
+	t.Run("Corrupt state file is ignored", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, DefaultStateFileName), []byte("{not json"), 0644); err != nil {
+			t.Fatalf("Failed to write state file: %v", err)
+		}
+
+		if _, _, err := newManager(dir, time.Hour).EnsureDependencies(); err != nil {
+			t.Fatalf("Did not expect an error but got: %v", err)
+		}
+
+		state, err := LoadState(filepath.Join(dir, DefaultStateFileName))
+		if err != nil {
+			t.Fatalf("Expected the state file to be rewritten but got: %v", err)
+		}
+		if state.Dependencies["tool"] == nil || state.Dependencies["tool"].Version != "1.0.0" {
+			t.Errorf("Expected tool to be recorded but got %+v", state.Dependencies)
+		}
+	})
+}
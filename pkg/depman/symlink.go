@@ -0,0 +1,40 @@
+package depman
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// createSymlinks creates each link pointing at its target, replacing any existing link
+// {install_dir} and environment variables are expanded in both targets and links
+func (m *Manager) createSymlinks(dep *Dependency, symlinks map[string]string, installDir string) error {
+	expand := func(s string) string {
+		return os.ExpandEnv(strings.ReplaceAll(s, "{install_dir}", installDir))
+	}
+
+	// Process in a stable order so failures are reproducible
+	targets := make([]string, 0, len(symlinks))
+	for target := range symlinks {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	for _, target := range targets {
+		link := expand(symlinks[target])
+		target := expand(target)
+
+		if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", link, err)
+		}
+
+		m.logger.Infof("Linking %s -> %s for %s", link, target, dep.Name)
+		if err := replaceLink(target, link); err != nil {
+			return fmt.Errorf("failed to link %s -> %s: %w", link, target, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package depman
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TestSymlinks tests creating links after install and replacing stale ones
+func TestSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require elevation on Windows")
+	}
+
+	testCases := []struct {
+		name  string
+		stale bool
+	}{
+		{name: "Create link", stale: false},
+		{name: "Replace stale link", stale: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("DEPMAN_TEST_BIN", filepath.Join(dir, "bin"))
+
+			installDir := filepath.Join(dir, "tool-1.2.3")
+			link := filepath.Join(dir, "bin", "tool")
+
+			if tc.stale {
+				if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+					t.Fatalf("Failed to create bin directory: %v", err)
+				}
+				if err := os.Symlink(filepath.Join(dir, "tool-1.0.0", "bin", "tool"), link); err != nil {
+					t.Fatalf("Failed to create stale link: %v", err)
+				}
+			}
+
+			dep := &Dependency{
+				Name: "tool",
+				Platforms: map[string]PlatformConfig{
+					runtime.GOOS: {
+						InstallDir: installDir,
+						Commands: Commands{
+							Install: []string{"sh", "-c", "mkdir -p {install_dir}/bin && echo tool > {install_dir}/bin/tool"},
+						},
+						Symlinks: map[string]string{
+							"{install_dir}/bin/tool": "${DEPMAN_TEST_BIN}/tool",
+						},
+					},
+				},
+			}
+
+			manager := &Manager{
+				Platform: runtime.GOOS,
+				logger:   &mockLogger{},
+			}
+
+			if _, err := manager.installDependency(context.Background(), dep); err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			target, err := os.Readlink(link)
+			if err != nil {
+				t.Fatalf("Expected %s to be a symlink: %v", link, err)
+			}
+			if expected := filepath.Join(installDir, "bin", "tool"); target != expected {
+				t.Errorf("Expected link to point at %s but got %s", expected, target)
+			}
+
+			data, err := os.ReadFile(link)
+			if err != nil || string(data) != "tool\n" {
+				t.Errorf("Expected link to resolve to the installed file but got %q, %v", data, err)
+			}
+
+			if _, err := os.Lstat(link + ".depman-tmp"); !os.IsNotExist(err) {
+				t.Errorf("Expected temporary link to be cleaned up")
+			}
+		})
+	}
+}
+
+// TestReplaceLinkKeepsDirectory tests that a real directory at the link path is not replaced
+func TestReplaceLinkKeepsDirectory(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "tool")
+	if err := os.WriteFile(target, []byte("tool\n"), 0755); err != nil {
+		t.Fatalf("Failed to create target: %v", err)
+	}
+
+	link := filepath.Join(dir, "bin")
+	userFile := filepath.Join(link, "notes.txt")
+	if err := os.MkdirAll(link, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(userFile, []byte("keep me"), 0644); err != nil {
+		t.Fatalf("Failed to create user file: %v", err)
+	}
+
+	if err := replaceLink(target, link); err == nil {
+		t.Errorf("Expected an error replacing a directory but got none")
+	}
+	if data, err := os.ReadFile(userFile); err != nil || string(data) != "keep me" {
+		t.Errorf("Expected the directory contents to be kept but got %q, %v", data, err)
+	}
+}
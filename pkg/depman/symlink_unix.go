@@ -0,0 +1,23 @@
+//go:build !windows
+
+package depman
+
+import (
+	"os"
+)
+
+// replaceLink atomically points link at target by renaming a fresh symlink over it
+func replaceLink(target, link string) error {
+	tmp := link + ".depman-tmp"
+	os.Remove(tmp) // Left over from an interrupted run
+
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+//go:build windows
+
+package depman
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// replaceLink points link at target, replacing any existing link
+// Symlinks need developer mode or elevation on Windows, so directories fall back to a
+// junction and files to a copy
+// Only a link, junction or file at link is replaced; a real directory is an error
+func replaceLink(target, link string) error {
+	if err := removeLink(link); err != nil {
+		return err
+	}
+
+	if err := os.Symlink(target, link); err == nil {
+		return nil
+	}
+
+	info, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		if output, err := exec.Command("cmd", "/c", "mklink", "/J", link, target).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to create junction: %w, output: %s", err, output)
+		}
+		return nil
+	}
+
+	return copyFile(target, link, info.Mode())
+}
+
+// removeLink removes a symlink, junction or regular file at link, leaving a real
+// directory in place
+func removeLink(link string) error {
+	info, err := os.Lstat(link)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Junctions are reported as irregular files, or as symlinks by older Go versions
+	if info.IsDir() && info.Mode()&(os.ModeSymlink|os.ModeIrregular) == 0 {
+		return fmt.Errorf("%s is a directory, not a link", link)
+	}
+
+	return os.Remove(link)
+}
@@ -0,0 +1,104 @@
+package depman
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// secretKeyWords are the words marking an environment variable as holding a secret
+var secretKeyWords = []string{"TOKEN", "SECRET", "PASSWORD", "PASSWD", "CREDENTIAL", "AUTH", "KEY"}
+
+// isSecretKey reports whether an environment variable is likely to hold a secret
+func isSecretKey(key string) bool {
+	upper := strings.ToUpper(key)
+	return slices.ContainsFunc(secretKeyWords, func(word string) bool {
+		return strings.Contains(upper, word)
+	})
+}
+
+// MaskEnvironmentDiff returns a copy of diff that is safe to print: the values of variables
+// likely to hold a secret are masked, as are passwords in URL values
+func MaskEnvironmentDiff(diff EnvironmentDiff) EnvironmentDiff {
+	mask := func(changes []EnvironmentVariableChange) []EnvironmentVariableChange {
+		masked := slices.Clone(changes)
+		for i, change := range masked {
+			if isSecretKey(change.Key) {
+				if change.Old != "" {
+					masked[i].Old = "xxxxx"
+				}
+				if change.New != "" {
+					masked[i].New = "xxxxx"
+				}
+				continue
+			}
+			masked[i].Old = maskURL(change.Old, nil)
+			masked[i].New = maskURL(change.New, nil)
+		}
+		return masked
+	}
+
+	diff.Added = mask(diff.Added)
+	diff.Changed = mask(diff.Changed)
+	diff.Removed = mask(diff.Removed)
+	return diff
+}
+
+// traceCommand logs a command about to run for a dependency, if the logger supports tracing:
+// the full argv, the working directory and the environment injected for the dependency, with
+// the values of secret variables masked
+func (m *Manager) traceCommand(dep *Dependency, cmd *exec.Cmd) {
+	tracer, ok := m.logger.(Tracer)
+	if !ok {
+		return
+	}
+
+	dir := cmd.Dir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+
+	// Commands without an explicit environment inherit depman's, without injections
+	env := "inherited"
+	if cmd.Env != nil {
+		env = strings.Join(m.injectedEnv(dep), " ")
+	}
+
+	tracer.Tracef("Running command for %s: %s (dir: %s, env: %s)", dep.Name, formatCommand(cmd.Args), dir, env)
+}
+
+// formatCommand formats a command with each argument quoted, so arguments containing
+// spaces or empty ones are unambiguous
+func formatCommand(args []string) string {
+	return fmt.Sprintf("%q", args)
+}
+
+// injectedEnv returns the environment injected for a dependency's commands as sorted
+// KEY=value entries, with secret values masked and PATH additions as PATH+=entries
+func (m *Manager) injectedEnv(dep *Dependency) []string {
+	depEnv := m.dependencyEnvironment(dep)
+
+	var secrets []string
+	if platformConfig, err := m.GetPlatformConfig(dep); err == nil {
+		secrets = platformConfig.Installer.Auth.secrets()
+	}
+
+	entries := make([]string, 0, len(depEnv.Variables)+1)
+	for key, value := range depEnv.Variables {
+		if isSecretKey(key) {
+			value = "xxxxx"
+		} else {
+			value = maskURL(value, secrets)
+		}
+		entries = append(entries, key+"="+value)
+	}
+	slices.Sort(entries)
+
+	if len(depEnv.Path) > 0 {
+		entries = append(entries, "PATH+="+strings.Join(depEnv.Path, string(filepath.ListSeparator)))
+	}
+	return entries
+}
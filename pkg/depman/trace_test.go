@@ -0,0 +1,118 @@
+package depman
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sobhit-avrl/depman-v1/internal/environment"
+)
+
+// traceLogger is a mockLogger that also records formatted trace messages
+type traceLogger struct {
+	mockLogger
+	mu         sync.Mutex
+	traceLines []string
+}
+
+func (l *traceLogger) Tracef(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.traceLines = append(l.traceLines, fmt.Sprintf(format, args...))
+}
+
+// TestTraceCommands tests that every command run for a dependency is traced with its argv,
+// directory and injected environment, masking secrets
+func TestTraceCommands(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	dir := t.TempDir()
+	dep := Dependency{
+		Name:    "tool",
+		Version: Version{Required: "1.0.0"},
+		Platforms: map[string]PlatformConfig{
+			runtime.GOOS: {
+				Commands: Commands{
+					Install:   []string{"sh", "-c", "echo installed"},
+					Verify:    []string{"sh", "-c", "echo 1.0.0"},
+					Uninstall: []string{"sh", "-c", "echo removed"},
+				},
+			},
+		},
+		Environment: Environment{
+			Path:      []string{dir},
+			Variables: map[string]string{"TOOL_HOME": dir, "TOOL_TOKEN": "s3cret"},
+		},
+	}
+
+	log := &traceLogger{}
+	manager := &Manager{
+		Config:     &DependencyConfig{Dependencies: []Dependency{dep}},
+		Platform:   runtime.GOOS,
+		logger:     log,
+		envManager: environment.NewManager(),
+	}
+
+	if _, err := manager.runInstallCommand(t.Context(), &dep, dep.Platforms[runtime.GOOS].Commands.Install, ""); err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+	if _, err := manager.VerifyDependency(&dep); err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+	if result := manager.cleanDependency(t.Context(), &dep); result.Error != nil {
+		t.Fatalf("Did not expect an error but got: %v", result.Error)
+	}
+
+	if len(log.traceLines) != 3 {
+		t.Fatalf("Expected 3 trace lines but got %d: %v", len(log.traceLines), log.traceLines)
+	}
+
+	injected := fmt.Sprintf("env: TOOL_HOME=%s TOOL_TOKEN=xxxxx PATH+=%s)", dir, dir)
+	expected := []struct {
+		command string
+		env     string
+	}{
+		{command: `["sh" "-c" "echo installed"]`, env: injected},
+		{command: `["sh" "-c" "echo 1.0.0"]`, env: injected},
+		{command: `["sh" "-c" "echo removed"]`, env: injected},
+	}
+	for i, e := range expected {
+		line := log.traceLines[i]
+		if !strings.HasPrefix(line, "Running command for tool: "+e.command+" (dir: ") {
+			t.Errorf("Expected trace line %d to show command %s but got %q", i, e.command, line)
+		}
+		if !strings.HasSuffix(line, e.env) {
+			t.Errorf("Expected trace line %d to end with %q but got %q", i, e.env, line)
+		}
+		if strings.Contains(line, "s3cret") {
+			t.Errorf("Expected trace line %d to mask the token but got %q", i, line)
+		}
+	}
+}
+
+// TestIsSecretKey tests which environment variables are masked in traces
+func TestIsSecretKey(t *testing.T) {
+	testCases := []struct {
+		key      string
+		expected bool
+	}{
+		{key: "GITHUB_TOKEN", expected: true},
+		{key: "db_password", expected: true},
+		{key: "AWS_SECRET_ACCESS_KEY", expected: true},
+		{key: "API_KEY", expected: true},
+		{key: "JAVA_HOME", expected: false},
+		{key: "GOPATH", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.key, func(t *testing.T) {
+			if got := isSecretKey(tc.key); got != tc.expected {
+				t.Errorf("Expected isSecretKey(%q) to be %v but got %v", tc.key, tc.expected, got)
+			}
+		})
+	}
+}
@@ -2,6 +2,11 @@ package depman
 
 import (
 	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
 
 	"github.com/sobhit-avrl/depman-v1/internal/environment"
 	"github.com/sobhit-avrl/depman-v1/internal/logger"
@@ -9,61 +14,250 @@ import (
 
 // Version represents dependency version information with semver support
 type Version struct {
-	Required   string `yaml:"required"`   // Exact version required
-	Constraint string `yaml:"constraint"` // Semver constraint (e.g., "^1.2.3", ">=2.0.0", etc.)
+	Required   string       `yaml:"required"`             // Exact version required
+	Constraint string       `yaml:"constraint,omitempty"` // Semver constraint (e.g., "^1.2.3", ">=2.0.0", etc.)
+	Match      VersionMatch `yaml:"match,omitempty"`      // How to find the version in verify output
+}
+
+// VersionMatch controls how a version is extracted from multiline verify output
+type VersionMatch struct {
+	Line      string `yaml:"line,omitempty"`       // Only match on lines containing this keyword
+	MatchLast bool   `yaml:"match_last,omitempty"` // Prefer the last matching line instead of the first
 }
 
 // Installer contains information about how to install a dependency
 type Installer struct {
-	Type     string `yaml:"type"`     // Installation type (e.g., "msi", "pkg", "binary")
-	URL      string `yaml:"url"`      // URL to download the dependency
-	Checksum string `yaml:"checksum"` // Checksum for verification (format: "algorithm:hash")
+	Type                   string        `yaml:"type,omitempty"`                      // Installation type (e.g., "msi", "pkg", "binary", or "package" for the package manager)
+	URL                    string        `yaml:"url,omitempty"`                       // URL to download the dependency
+	Checksum               string        `yaml:"checksum,omitempty"`                  // Checksum for verification (format: "algorithm:hash", or a bare hash with the algorithm inferred from its length)
+	ChecksumURL            string        `yaml:"checksum_url,omitempty"`              // URL of a sidecar checksum file, used when no checksum is given
+	Auth                   *Auth         `yaml:"auth,omitempty"`                      // Credentials for downloading from a private URL
+	Filename               string        `yaml:"filename,omitempty"`                  // Name to save the download as (defaults to Content-Disposition or URL basename)
+	ExtractFile            string        `yaml:"extract_file,omitempty"`              // Single archive entry to extract into the install directory
+	AllowCrossHostRedirect bool          `yaml:"allow_cross_host_redirect,omitempty"` // Follow redirects to other hosts (e.g. a release page to its CDN)
+	MaxRedirects           int           `yaml:"max_redirects,omitempty"`             // Redirects to follow (0 uses the default of 10, negative disables redirects)
+	Atomic                 bool          `yaml:"atomic,omitempty"`                    // Install into a staging directory swapped in as install_dir on success (archive and binary types); {install_dir} is the staging directory, {final_install_dir} the final one
+	Package                string        `yaml:"package,omitempty"`                   // Package to install with the package manager (package type)
+	PackageManager         string        `yaml:"package_manager,omitempty"`           // Package manager to use (apt, dnf, yum, zypper, pacman, apk, brew, winget, choco or scoop; default detected)
+	Timeout                time.Duration `yaml:"timeout,omitempty"`                   // Download timeout (e.g. "30m"), overriding the manager's
+	MaxRetries             *int          `yaml:"max_retries,omitempty"`               // Extra attempts after a transient download failure, overriding the manager's
+}
+
+// Auth contains credentials for downloading a dependency
+// Credential fields may reference environment variables (e.g. "${TOKEN}")
+type Auth struct {
+	Type     string `yaml:"type"`               // Authentication type ("basic" or "bearer")
+	Username string `yaml:"username,omitempty"` // Username for basic auth
+	Password string `yaml:"password,omitempty"` // Password for basic auth
+	Token    string `yaml:"token,omitempty"`    // Token for bearer auth
 }
 
 // Commands for different operations on a dependency
 type Commands struct {
-	Install   []string `yaml:"install"`   // Command to install the dependency
-	Verify    []string `yaml:"verify"`    // Command to verify the installation (should output version)
-	Uninstall []string `yaml:"uninstall"` // Command to uninstall the dependency
+	Install      []string `yaml:"install,omitempty"`       // Command to install the dependency
+	Verify       []string `yaml:"verify"`                  // Command to verify the installation (should output version)
+	VerifyExpect string   `yaml:"verify_expect,omitempty"` // Output that proves a successful verify (substring, or regex as "/pattern/"); makes the version optional
+	Uninstall    []string `yaml:"uninstall,omitempty"`     // Command to uninstall the dependency
+	HealthCheck  []string `yaml:"health_check,omitempty"`  // Functional smoke test run by deep checks (e.g. docker run hello-world)
 }
 
 // PlatformConfig holds platform-specific configuration
 type PlatformConfig struct {
-	Installer Installer `yaml:"installer"` // Installer information
-	Commands  Commands  `yaml:"commands"`  // Platform-specific commands
+	Installer      Installer         `yaml:"installer,omitempty"`       // Installer information
+	Commands       Commands          `yaml:"commands"`                  // Platform-specific commands
+	InstallDir     string            `yaml:"install_dir,omitempty"`     // Installation directory, available as {install_dir}
+	Symlinks       map[string]string `yaml:"symlinks,omitempty"`        // Links to create after install (target -> link)
+	BinaryPath     string            `yaml:"binary_path,omitempty"`     // Installed binary to hash (defaults to the verify executable)
+	BinaryChecksum string            `yaml:"binary_checksum,omitempty"` // Expected checksum of the installed binary (format: "algorithm:hash")
+	Environment    Environment       `yaml:"environment,omitempty"`     // Platform-specific paths and variables, merged into the dependency's
+	RunAs          string            `yaml:"run_as,omitempty"`          // User to run install and verify commands as (Unix only, requires root)
 }
 
 // Environment variables and paths for a dependency
 type Environment struct {
-	Path      []string          `yaml:"path"`      // Paths to add to PATH
-	Variables map[string]string `yaml:"variables"` // Environment variables to set
+	Path      []string          `yaml:"path,omitempty"`      // Paths to add to PATH
+	Variables map[string]string `yaml:"variables,omitempty"` // Environment variables to set
 }
 
+// EnvironmentDiff describes the PATH entries and variables added, changed or removed by the
+// managed environment
+type EnvironmentDiff = environment.Diff
+
+// EnvironmentVariableChange is a variable added, changed or removed in an EnvironmentDiff
+type EnvironmentVariableChange = environment.VariableChange
+
 // Dependency represents a single dependency with all its properties
 type Dependency struct {
-	Name         string                    `yaml:"name"`         // Unique name of the dependency
-	Description  string                    `yaml:"description"`  // Human-readable description
-	Version      Version                   `yaml:"version"`      // Version requirements
-	Platforms    map[string]PlatformConfig `yaml:"platforms"`    // Platform-specific configurations
-	Environment  Environment               `yaml:"environment"`  // Environment configuration
-	Dependencies []string                  `yaml:"dependencies"` // Dependencies of this dependency
+	Name               string                    `yaml:"name"`                           // Unique name of the dependency
+	Description        string                    `yaml:"description,omitempty"`          // Human-readable description
+	Version            Version                   `yaml:"version"`                        // Version requirements
+	VersionScheme      string                    `yaml:"version_scheme,omitempty"`       // How versions are compared (semver, calver, numeric, lexical; default semver)
+	Platforms          map[string]PlatformConfig `yaml:"platforms"`                      // Platform-specific configurations
+	Commands           Commands                  `yaml:"commands,omitempty"`             // Default commands, overridden per platform
+	Environment        Environment               `yaml:"environment,omitempty"`          // Environment configuration
+	Dependencies       []string                  `yaml:"dependencies,omitempty"`         // Dependencies of this dependency
+	Priority           int                       `yaml:"priority,omitempty"`             // Install order among independent dependencies (lower first)
+	StrictVersionParse bool                      `yaml:"strict_version_parse,omitempty"` // Treat verify output without a parseable version as incompatible
+	Optional           bool                      `yaml:"optional,omitempty"`             // Whether failures should only warn (also skipped on platforms without configuration)
+	Enabled            *bool                     `yaml:"enabled,omitempty"`              // Whether the dependency is managed (omitted means enabled)
+	Tags               []string                  `yaml:"tags,omitempty"`                 // Groups the dependency belongs to (e.g. "build", "test")
+	Provides           []string                  `yaml:"provides,omitempty"`             // Capabilities this dependency satisfies for others (e.g. podman provides docker)
+	InstallMode        string                    `yaml:"install_mode,omitempty"`         // Whether ensure installs the dependency (auto, check-only, manual; default auto)
+	VersionSource      *VersionSource            `yaml:"version_source,omitempty"`       // Endpoint reporting the latest version, which replaces Version.Required
+	Deprecated         string                    `yaml:"deprecated,omitempty"`           // Deprecation message, warned about whenever the dependency is checked
+	When               string                    `yaml:"when,omitempty"`                 // Environment condition for managing the dependency (VAR, !VAR, VAR==value or VAR!=value)
+}
+
+// VersionSource is an endpoint reporting the latest version of a dependency, for dependencies
+// that should always be at the latest version rather than a pinned one
+// Installer and checksum URLs may contain {version}, replaced with the resolved version
+type VersionSource struct {
+	URL      string `yaml:"url"`                 // Endpoint returning the latest version as plain text or JSON
+	Field    string `yaml:"field,omitempty"`     // Dot-separated path of the version in a JSON response (e.g. "tag_name"); empty for plain text
+	URLField string `yaml:"url_field,omitempty"` // Dot-separated path of a download URL template in a JSON response, replacing the installer URL
+	Auth     *Auth  `yaml:"auth,omitempty"`      // Credentials for a private endpoint
+}
+
+// Install modes controlling whether ensure installs a dependency
+const (
+	InstallModeAuto      = "auto"       // Installed and updated by ensure
+	InstallModeCheckOnly = "check-only" // Only verified, e.g. a kernel feature that can't be installed; the version is optional
+	InstallModeManual    = "manual"     // Only verified, the user installs it by hand (e.g. a company VPN)
+)
+
+// InstallsManually reports whether ensure only verifies the dependency and never installs it
+func (d *Dependency) InstallsManually() bool {
+	return d.InstallMode == InstallModeCheckOnly || d.InstallMode == InstallModeManual
+}
+
+// IsEnabled reports whether the dependency is managed, which is the default
+func (d *Dependency) IsEnabled() bool {
+	return d.Enabled == nil || *d.Enabled
+}
+
+// HasTag reports whether the dependency carries the given tag
+func (d *Dependency) HasTag(tag string) bool {
+	for _, t := range d.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Synthetic platform keys used when a dependency has no configuration for the exact platform
+const (
+	PlatformUnix = "unix" // Shared configuration for linux and darwin
+	PlatformAll  = "all"  // Configuration for any platform, as a last resort
+)
+
+// unixPlatforms are the platforms that fall back to the unix key
+var unixPlatforms = map[string]bool{"linux": true, "darwin": true}
+
+// PlatformConfigFor returns the dependency's configuration for a platform, trying the exact
+// platform, then unix (for linux and darwin), then all
+func (d *Dependency) PlatformConfigFor(platform string) (PlatformConfig, bool) {
+	if config, ok := d.Platforms[platform]; ok {
+		return config, true
+	}
+	if unixPlatforms[platform] {
+		if config, ok := d.Platforms[PlatformUnix]; ok {
+			return config, true
+		}
+	}
+	config, ok := d.Platforms[PlatformAll]
+	return config, ok
+}
+
+// provides reports whether the dependency lists the capability in Provides
+func (d *Dependency) provides(name string) bool {
+	for _, p := range d.Provides {
+		if p == name {
+			return true
+		}
+	}
+	return false
 }
 
 // DependencyConfig represents the entire dependency configuration file
 type DependencyConfig struct {
-	Version      string       `yaml:"version"`      // Configuration format version
-	Name         string       `yaml:"name"`         // Application name
-	Description  string       `yaml:"description"`  // Application description
-	Dependencies []Dependency `yaml:"dependencies"` // List of dependencies
+	Version      string       `yaml:"version"`               // Configuration format version
+	Name         string       `yaml:"name"`                  // Application name
+	Description  string       `yaml:"description,omitempty"` // Application description
+	BaseURL      string       `yaml:"base_url,omitempty"`    // Base that relative installer URLs are resolved against
+	Defaults     Defaults     `yaml:"defaults,omitempty"`    // Values merged into dependencies that omit them
+	Dependencies []Dependency `yaml:"dependencies"`          // List of dependencies
+}
+
+// Defaults holds values shared by all dependencies, each used only where a dependency omits it
+type Defaults struct {
+	Commands    Commands                  `yaml:"commands,omitempty"`    // Default commands
+	Environment Environment               `yaml:"environment,omitempty"` // Default paths and variables
+	Platforms   map[string]PlatformConfig `yaml:"platforms,omitempty"`   // Defaults for the platforms each dependency declares
+	Tags        []string                  `yaml:"tags,omitempty"`        // Default tags
 }
 
 // Manager handles dependency management operations
+// A Manager is safe for concurrent use once configured: its shared environment is guarded
+// internally, so checks and ensure runs may be called from several goroutines. Options,
+// Config and Platform must not be changed while calls are in flight, and a custom Logger
+// or callback must itself be safe for concurrent use.
 type Manager struct {
-	Config     *DependencyConfig    // Dependency configuration
-	ConfigPath string               // Path to configuration file
-	Platform   string               // Current platform (windows, linux, darwin)
-	logger     Logger               // Logger for operations
-	envManager *environment.Manager // Environment manager
+	Config               *DependencyConfig         // Dependency configuration
+	ConfigPath           string                    // Path to configuration file, or the glob pattern it was loaded from
+	configFormat         string                    // Format to parse the configuration as (empty detects it from the extension)
+	configChecksum       string                    // Expected checksum of the raw configuration file (empty skips verification)
+	Platform             string                    // Current platform (windows, linux, darwin)
+	Arch                 string                    // Current architecture for URL templates (empty uses runtime.GOARCH)
+	logger               Logger                    // Logger for operations
+	envManager           *environment.Manager      // Environment manager
+	installTimeout       time.Duration             // Maximum duration of an install command (0 means no limit)
+	downloadTimeout      time.Duration             // Timeout of each download (0 uses the downloader default)
+	downloadRetries      int                       // Extra attempts after a transient download failure
+	envMu                sync.Mutex                // Guards envManager, shared by parallel installs and concurrent calls
+	originalEnv          []string                  // Process environment before the managed environment was first applied
+	skip                 map[string]bool           // Names of dependencies to skip
+	only                 map[string]bool           // Names of the only dependencies to manage (all if empty)
+	forceReinstall       bool                      // Reinstall dependencies even if already up to date
+	tags                 []string                  // Only manage dependencies with one of these tags (all if empty)
+	excludeTags          []string                  // Skip dependencies with any of these tags
+	verifyRetries        int                       // Extra verification attempts after install
+	verifyDelay          time.Duration             // Delay between post-install verification attempts
+	verifyCommandRetries int                       // Extra attempts of a failing verify command
+	verifyCommandBackoff time.Duration             // Initial delay between verify command attempts, doubled each retry
+	checksumMu           sync.Mutex                // Guards checksums
+	checksums            map[string]string         // Checksums verified during downloads, by dependency name
+	lock                 *Lockfile                 // Lockfile pinning download checksums (nil if none)
+	userAgent            string                    // User-Agent sent with downloads (empty uses the downloader default)
+	httpClient           *http.Client              // HTTP client for downloads (nil uses the downloader default)
+	insecureSkipVerify   bool                      // Skip TLS certificate verification for downloads
+	requireChecksum      bool                      // Refuse downloads without a checksum
+	keepDownloads        string                    // Directory to retain downloaded artifacts in
+	constraintFirst      bool                      // A satisfied constraint means no update is needed
+	downloadProgress     ProgressFunc              // Receives download progress, if set
+	statusCallback       StatusFunc                // Receives phase changes during ensure, if set
+	auditLog             string                    // Path of the JSONL audit log (empty disables it)
+	auditMu              sync.Mutex                // Serializes audit log writes
+	cleanInstallEnv      bool                      // Run install and verify commands with a minimal environment
+	caCertFile           string                    // PEM file with extra CA certificates for downloads
+	offline              bool                      // Refuse all network access, e.g. for air-gapped checks
+	tempDir              string                    // Parent of per-install temporary directories (empty uses the system temp)
+	runLock              string                    // Path of the lock file held while ensuring (empty disables locking)
+	runMu                sync.Mutex                // Serializes ensure runs of this manager before the run lock is taken
+	runLockWait          time.Duration             // How long to wait for a run lock held by another process
+	latestMu             sync.Mutex                // Guards latest, serializing version source queries
+	latest               map[string]*latestVersion // Versions reported by version sources, by dependency name
+	prefix               string                    // Root of isolated <prefix>/<name>/<version> installs (empty uses install_dir)
+	checkConcurrency     int                       // Maximum simultaneous verify commands (0 means no limit beyond the workers)
+	checkSem             chan struct{}             // Slots for verify commands, nil without a check concurrency
+	downloadSem          chan struct{}             // Slots for downloads, nil without a download concurrency
+	ensureRetries        int                       // Extra attempts for dependencies that failed during an ensure run
+	ensureRetryDelay     time.Duration             // Delay before retrying failed dependencies
+	stateFile            string                    // Path of the install state file (empty disables it)
+	stateTTL             time.Duration             // How long a confirmed dependency skips verification
+	deepCheck            bool                      // Run health checks of installed dependencies when verifying
+	packageManager       string                    // Package manager for package installers that don't set one (empty detects it)
+	healthCheckTimeout   time.Duration             // Maximum duration of a health check
 }
 
 // UpdateType represents the type of update needed
@@ -80,16 +274,53 @@ func (u UpdateType) String() string {
 	return [...]string{"No Update", "Patch Update", "Minor Update", "Major Update"}[u]
 }
 
+// Phase is a step a dependency goes through during ensure
+type Phase int
+
+const (
+	PhaseChecking    Phase = iota // Running the verify command to find the current state
+	PhaseDownloading              // Downloading the installer
+	PhaseInstalling               // Running the install command
+	PhaseVerifying                // Verifying the install worked
+	PhaseVerified                 // Installed and up to date, whether or not it was installed now
+	PhaseSkipped                  // Skipped by name or tag filters
+	PhaseFailed                   // Failed to install or verify
+)
+
+func (p Phase) String() string {
+	return [...]string{"checking", "downloading", "installing", "verifying", "verified", "skipped", "failed"}[p]
+}
+
+// StatusFunc receives a dependency entering a phase, with its latest status
+// The status is nil while downloading and installing, before a new status is known
+type StatusFunc func(name string, phase Phase, status *DependencyStatus)
+
 // DependencyStatus represents the installation status of a dependency
 type DependencyStatus struct {
-	Name           string     // Name of the dependency
-	Installed      bool       // Whether the dependency is installed
-	CurrentVersion string     // Current installed version
-	RequiredUpdate UpdateType // Type of update required
-	Compatible     bool       // Whether the current version is compatible with constraints
-	Error          error      // Any error that occurred during checking
+	Name               string     // Name of the dependency
+	Installed          bool       // Whether the dependency is installed
+	CurrentVersion     string     // Current installed version
+	RequiredUpdate     UpdateType // Type of update required
+	Compatible         bool       // Whether the current version is compatible with constraints
+	Error              error      // Any error that occurred during checking
+	Skipped            bool       // Whether the dependency was skipped
+	Optional           bool       // Whether the dependency is optional
+	InstallOutput      string     // Raw output of the install command, if it was run
+	VerifyOutput       string     // Raw output of the verify command
+	Cached             bool       // Whether the status came from the state file without verifying
+	Broken             bool       // Whether the verify command exists but failed (installed but broken)
+	ManualAction       bool       // Whether the user must install or update it by hand (check-only and manual modes)
+	Deprecated         bool       // Whether the dependency is deprecated
+	DeprecationMessage string     // Why the dependency is deprecated and what replaces it
+	HealthChecked      bool       // Whether a deep check ran the health check
+	Healthy            bool       // Whether the health check passed
+	HealthError        error      // Why the health check failed, if it did
 }
 
+// ProgressFunc receives the progress of a dependency's download: the bytes downloaded so far
+// and the total size (-1 if unknown). A final call with downloaded equal to total marks completion.
+type ProgressFunc func(dependency string, downloaded, total int64)
+
 // Option represents a configuration option for the dependency manager
 type Option func(*Manager)
 
@@ -100,6 +331,338 @@ func WithPlatform(platform string) Option {
 	}
 }
 
+// WithArch sets a specific architecture to expand {arch} in download URLs with
+func WithArch(arch string) Option {
+	return func(m *Manager) {
+		m.Arch = arch
+	}
+}
+
+// WithInstallTimeout sets the maximum duration an install or uninstall command may run
+// A zero duration disables the timeout
+func WithInstallTimeout(d time.Duration) Option {
+	return func(m *Manager) {
+		m.installTimeout = d
+	}
+}
+
+// WithDownloadTimeout sets the timeout of each download, unless its installer sets one
+// A zero duration uses the downloader default
+func WithDownloadTimeout(d time.Duration) Option {
+	return func(m *Manager) {
+		m.downloadTimeout = d
+	}
+}
+
+// WithDownloadRetries retries each download up to count more times after a transient failure
+// (a network error, 5xx or 429 response), unless its installer sets max_retries
+func WithDownloadRetries(count int) Option {
+	return func(m *Manager) {
+		m.downloadRetries = count
+	}
+}
+
+// DefaultHealthCheckTimeout is how long a health check may run unless configured otherwise
+const DefaultHealthCheckTimeout = 2 * time.Minute
+
+// verifyCommandTimeout is how long a verify command may run, a variable so tests can shorten it
+var verifyCommandTimeout = 30 * time.Second
+
+// WithDeepCheck runs the health check of every installed dependency that has one when it is
+// verified, for functional tests beyond the verify command (e.g. running a container)
+// A failing health check marks the dependency unhealthy, not uninstalled
+func WithDeepCheck(enabled bool) Option {
+	return func(m *Manager) {
+		m.deepCheck = enabled
+	}
+}
+
+// WithHealthCheckTimeout sets the maximum duration a health check may run
+// A zero duration uses DefaultHealthCheckTimeout
+func WithHealthCheckTimeout(d time.Duration) Option {
+	return func(m *Manager) {
+		m.healthCheckTimeout = d
+	}
+}
+
+// WithPackageManager sets the package manager used by package installers that don't name one,
+// instead of detecting it
+func WithPackageManager(name string) Option {
+	return func(m *Manager) {
+		m.packageManager = name
+	}
+}
+
+// WithVerifyRetries retries post-install verification up to count more times, waiting
+// delay between attempts, for installers that register binaries asynchronously
+func WithVerifyRetries(count int, delay time.Duration) Option {
+	return func(m *Manager) {
+		m.verifyRetries = count
+		m.verifyDelay = delay
+	}
+}
+
+// WithVerifyCommandRetries retries a failing verify command up to count more times,
+// starting with the given backoff and doubling it after each attempt
+// Unlike WithVerifyRetries this applies to every verification, and timeouts are never retried
+func WithVerifyCommandRetries(count int, backoff time.Duration) Option {
+	return func(m *Manager) {
+		m.verifyCommandRetries = count
+		m.verifyCommandBackoff = backoff
+	}
+}
+
+// WithOverrideExistingEnv sets whether config variables replace values already set
+// in the OS environment (the default). PATH additions are always merged.
+func WithOverrideExistingEnv(override bool) Option {
+	return func(m *Manager) {
+		m.envManager.OverrideExisting = override
+	}
+}
+
+// WithUserAgent sets the User-Agent sent with downloads (e.g. "depman/1.2.3")
+func WithUserAgent(userAgent string) Option {
+	return func(m *Manager) {
+		m.userAgent = userAgent
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for downloads, e.g. one with custom root CAs or mTLS
+func WithHTTPClient(client *http.Client) Option {
+	return func(m *Manager) {
+		m.httpClient = client
+	}
+}
+
+// WithInsecureSkipVerify disables TLS certificate verification for downloads
+// This is dangerous and should only be used with trusted internal mirrors
+func WithInsecureSkipVerify(skip bool) Option {
+	return func(m *Manager) {
+		m.insecureSkipVerify = skip
+	}
+}
+
+// WithRequireChecksum refuses to download dependencies without a checksum in the configuration or
+// the lockfile, or whose checksum is only a size or sha1, as just sha256 and sha512 are trusted
+// A checksum URL alone is not enough, as it is usually served by the same origin as the download
+// When disabled (the default), such downloads are allowed with a warning
+func WithRequireChecksum(require bool) Option {
+	return func(m *Manager) {
+		m.requireChecksum = require
+	}
+}
+
+// WithConstraintFirst treats a dependency whose installed version satisfies its constraint
+// as up to date, even if it differs from the required version
+// Dependencies without a constraint are still compared against the required version
+func WithConstraintFirst(enabled bool) Option {
+	return func(m *Manager) {
+		m.constraintFirst = enabled
+	}
+}
+
+// WithDownloadProgress reports download progress to fn, e.g. to render a progress bar
+// fn must be safe for concurrent use with parallel ensure
+func WithDownloadProgress(fn ProgressFunc) Option {
+	return func(m *Manager) {
+		m.downloadProgress = fn
+	}
+}
+
+// WithStatusCallback reports each dependency entering a phase during ensure to fn, e.g. to
+// show progress in a UI. The checking phase is also reported by CheckDependencies.
+// fn must be safe for concurrent use with parallel ensure
+func WithStatusCallback(fn StatusFunc) Option {
+	return func(m *Manager) {
+		m.statusCallback = fn
+	}
+}
+
+// WithCleanInstallEnv runs install and verify commands with a minimal base environment
+// (PATH, HOME, temp directories and the like) instead of the full parent environment
+// The configured variables and PATH additions are injected either way
+func WithCleanInstallEnv(clean bool) Option {
+	return func(m *Manager) {
+		m.cleanInstallEnv = clean
+	}
+}
+
+// WithKeepDownloads retains downloaded artifacts in dir (under a directory per dependency)
+// instead of deleting them, whether or not the install succeeds
+func WithKeepDownloads(dir string) Option {
+	return func(m *Manager) {
+		m.keepDownloads = dir
+	}
+}
+
+// WithAuditLog appends a JSON line to path for every successful install and uninstall,
+// recording the versions before and after, the artifact checksum and the command
+func WithAuditLog(path string) Option {
+	return func(m *Manager) {
+		m.auditLog = path
+	}
+}
+
+// WithConfigFormat parses the configuration as format ("yaml", "json" or "toml") instead of
+// detecting it from the file extension, e.g. for extensionless paths or standard input
+// It only has an effect when passed to NewManager or NewManagerFromGlob
+func WithConfigFormat(format string) Option {
+	return func(m *Manager) {
+		m.configFormat = format
+	}
+}
+
+// WithConfigChecksum verifies the raw bytes of the configuration file against a checksum in
+// "algorithm:hash" format before parsing it, e.g. for a shared or downloaded configuration
+// It only has an effect when passed to NewManager; a glob of several files can't be verified
+func WithConfigChecksum(checksum string) Option {
+	return func(m *Manager) {
+		m.configChecksum = checksum
+	}
+}
+
+// WithCACertFile trusts the CA certificates in a PEM file for downloads, e.g. for a private mirror
+func WithCACertFile(path string) Option {
+	return func(m *Manager) {
+		m.caCertFile = path
+	}
+}
+
+// WithLockfile verifies downloads against the checksums in a lockfile, so dependencies without
+// a configured checksum still get verified downloads
+// Entries for another platform or version than the required one are ignored, and a configured
+// checksum conflicting with a locked one fails the install
+func WithLockfile(lock *Lockfile) Option {
+	return func(m *Manager) {
+		m.lock = lock
+	}
+}
+
+// WithOffline disables all network access: downloads, checksum URLs, package installers and URL
+// checks fail with ErrOffline without making a request, while local verification still works
+// Version sources aren't queried, so dependencies keep their configured versions
+func WithOffline(offline bool) Option {
+	return func(m *Manager) {
+		m.offline = offline
+	}
+}
+
+// WithPrefix installs each dependency into its own <dir>/<name>/<version> directory, which
+// {install_dir} resolves to in place of the configured install_dir, and adds its bin directory
+// to PATH, so several versions of a dependency can be installed side by side
+func WithPrefix(dir string) Option {
+	return func(m *Manager) {
+		m.prefix = dir
+	}
+}
+
+// WithTempDir sets the directory in which per-install temporary directories are created
+// It is created if needed; the system temp directory is used when unset
+func WithTempDir(path string) Option {
+	return func(m *Manager) {
+		m.tempDir = path
+	}
+}
+
+// WithEnsureRetries retries dependencies that failed to install up to count more times at
+// the end of an ensure run, waiting delay before each retry. Dependencies that succeeded
+// are not reinstalled, and dependents of a failed dependency wait for it to succeed.
+func WithEnsureRetries(count int, delay time.Duration) Option {
+	return func(m *Manager) {
+		m.ensureRetries = count
+		m.ensureRetryDelay = delay
+	}
+}
+
+// WithCheckConcurrency limits how many verify commands run at once across all workers
+// Parallel runs check dependencies up to n at a time before installing, even with fewer
+// workers, so checks can fan out wider than installs; 0 removes the limit
+func WithCheckConcurrency(n int) Option {
+	return func(m *Manager) {
+		m.checkConcurrency = max(n, 0)
+		m.checkSem = nil
+		if n > 0 {
+			m.checkSem = make(chan struct{}, n)
+		}
+	}
+}
+
+// WithDownloadConcurrency limits how many downloads run at once across all workers, so
+// parallel installs don't saturate the bandwidth; 0 removes the limit
+func WithDownloadConcurrency(n int) Option {
+	return func(m *Manager) {
+		m.downloadSem = nil
+		if n > 0 {
+			m.downloadSem = make(chan struct{}, n)
+		}
+	}
+}
+
+// WithStateFile records confirmed dependencies in a state file so that ensure can skip
+// verifying them again within the state TTL (DefaultStateTTL unless set with WithStateTTL)
+func WithStateFile(path string) Option {
+	return func(m *Manager) {
+		m.stateFile = path
+		if m.stateTTL == 0 {
+			m.stateTTL = DefaultStateTTL
+		}
+	}
+}
+
+// WithStateTTL sets how long a dependency confirmed in the state file skips verification
+func WithStateTTL(ttl time.Duration) Option {
+	return func(m *Manager) {
+		m.stateTTL = ttl
+	}
+}
+
+// WithTags limits check and ensure to dependencies carrying at least one of the tags
+func WithTags(tags ...string) Option {
+	return func(m *Manager) {
+		m.tags = append(m.tags, tags...)
+	}
+}
+
+// WithExcludeTags skips dependencies carrying any of the tags, even if selected by WithTags
+func WithExcludeTags(tags ...string) Option {
+	return func(m *Manager) {
+		m.excludeTags = append(m.excludeTags, tags...)
+	}
+}
+
+// WithSkip sets dependencies to skip during check and ensure
+func WithSkip(names ...string) Option {
+	return func(m *Manager) {
+		if m.skip == nil {
+			m.skip = make(map[string]bool)
+		}
+		for _, name := range names {
+			m.skip[name] = true
+		}
+	}
+}
+
+// WithOnly restricts check and ensure to the named dependencies, skipping all others
+// Prerequisites are not added automatically, just as with tag filters
+func WithOnly(names ...string) Option {
+	return func(m *Manager) {
+		if m.only == nil {
+			m.only = make(map[string]bool)
+		}
+		for _, name := range names {
+			m.only[name] = true
+		}
+	}
+}
+
+// WithForceReinstall makes ensure reinstall every selected dependency, even those already
+// installed and up to date (e.g. to repair a corrupted install)
+func WithForceReinstall(force bool) Option {
+	return func(m *Manager) {
+		m.forceReinstall = force
+	}
+}
+
 // WithLogLevel sets the log level for the dependency manager
 func WithLogLevel(level logger.Level) Option {
 	return func(m *Manager) {
@@ -117,17 +680,53 @@ type Logger interface {
 	Errorf(format string, args ...interface{})
 }
 
+// Tracer is implemented by loggers supporting the trace level, below debug, at which every
+// command run for a dependency is logged with its working directory and injected environment
+type Tracer interface {
+	Tracef(format string, args ...interface{})
+}
+
 // defaultLogger is a simple logger that prints to stdout
-type defaultLogger struct{}
+type defaultLogger struct {
+	level logger.Level // Minimum level to print
+	out   io.Writer    // Output writer (defaults to os.Stdout)
+}
+
+var (
+	_ Logger = (*defaultLogger)(nil)
+	_ Tracer = (*defaultLogger)(nil)
+)
+
+// logf prints a message if the level is at or above the minimum level
+func (l *defaultLogger) logf(level logger.Level, format string, args ...interface{}) {
+	if level < l.level {
+		return
+	}
+
+	out := l.out
+	if out == nil {
+		out = os.Stdout
+	}
+
+	fmt.Fprintf(out, "["+level.String()+"] "+format+"\n", args...)
+}
 
 func (l *defaultLogger) Infof(format string, args ...interface{}) {
-	fmt.Printf("[INFO] "+format+"\n", args...)
+	l.logf(logger.LevelInfo, format, args...)
 }
 
 func (l *defaultLogger) Errorf(format string, args ...interface{}) {
-	fmt.Printf("[ERROR] "+format+"\n", args...)
+	l.logf(logger.LevelError, format, args...)
+}
+
+func (l *defaultLogger) Warnf(format string, args ...interface{}) {
+	l.logf(logger.LevelWarn, format, args...)
 }
 
 func (l *defaultLogger) Debugf(format string, args ...interface{}) {
-	// By default, debug logs are disabled
+	l.logf(logger.LevelDebug, format, args...)
+}
+
+func (l *defaultLogger) Tracef(format string, args ...interface{}) {
+	l.logf(logger.LevelTrace, format, args...)
 }
@@ -0,0 +1,41 @@
+package depman
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sobhit-avrl/depman-v1/internal/logger"
+)
+
+// TestDefaultLogger tests that the default logger prints all four levels and respects the minimum level
+func TestDefaultLogger(t *testing.T) {
+	t.Run("All levels", func(t *testing.T) {
+		var buf bytes.Buffer
+		log := &defaultLogger{level: logger.LevelDebug, out: &buf}
+
+		log.Debugf("debug %d", 1)
+		log.Infof("info %d", 2)
+		log.Warnf("warn %d", 3)
+		log.Errorf("error %d", 4)
+
+		expected := "[DEBUG] debug 1\n[INFO] info 2\n[WARN] warn 3\n[ERROR] error 4\n"
+		if buf.String() != expected {
+			t.Errorf("Expected output %q but got %q", expected, buf.String())
+		}
+	})
+
+	t.Run("Respects level", func(t *testing.T) {
+		var buf bytes.Buffer
+		log := &defaultLogger{level: logger.LevelWarn, out: &buf}
+
+		log.Debugf("debug")
+		log.Infof("info")
+		log.Warnf("warn")
+		log.Errorf("error")
+
+		expected := "[WARN] warn\n[ERROR] error\n"
+		if buf.String() != expected {
+			t.Errorf("Expected output %q but got %q", expected, buf.String())
+		}
+	})
+}
@@ -0,0 +1,338 @@
+package depman
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// Version schemes a dependency can declare
+const (
+	SchemeSemver  = "semver"  // Semantic versions (default), e.g. 1.2.3
+	SchemeCalver  = "calver"  // Calendar versions, e.g. 2024.03.1
+	SchemeNumeric = "numeric" // Dot-separated integers of any length, e.g. 118 or 1.2.3.4
+	SchemeLexical = "lexical" // Plain strings compared character by character
+)
+
+// Comparator compares versions of one scheme
+type Comparator interface {
+	// Validate reports whether version is valid for the scheme
+	Validate(version string) error
+
+	// Compare returns -1, 0 or 1 as a is older than, equal to or newer than b
+	Compare(a, b string) (int, error)
+
+	// UpdateType returns the update needed to go from current to required
+	UpdateType(current, required string) (UpdateType, error)
+
+	// Satisfies reports whether version satisfies constraint
+	Satisfies(version, constraint string) (bool, error)
+
+	// ValidateConstraint reports whether constraint is valid for the scheme
+	ValidateConstraint(constraint string) error
+}
+
+// ComparatorFor returns the comparator of a version scheme, semver if scheme is empty
+func ComparatorFor(scheme string) (Comparator, error) {
+	switch strings.ToLower(scheme) {
+	case "", SchemeSemver:
+		return semverComparator{}, nil
+	case SchemeCalver:
+		return componentComparator{scheme: SchemeCalver, validate: validateCalver}, nil
+	case SchemeNumeric:
+		return componentComparator{scheme: SchemeNumeric}, nil
+	case SchemeLexical:
+		return lexicalComparator{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported version scheme '%s' (supported: %s, %s, %s, %s)",
+			scheme, SchemeSemver, SchemeCalver, SchemeNumeric, SchemeLexical)
+	}
+}
+
+// schemePatterns find versions of non-semver schemes in verify output
+// Schemes without patterns use the trimmed output as is
+var schemePatterns = map[string][]*regexp.Regexp{
+	SchemeCalver:  {regexp.MustCompile(`v?(\d{2,4}\.\d{1,2}(?:\.\d+)*)`)}, // Matches: 2024.03, 24.3.1
+	SchemeNumeric: {regexp.MustCompile(`v?(\d+(?:\.\d+)*)`)},              // Matches: 118, 1.2.3.4
+	SchemeLexical: nil,
+}
+
+// versionPatternsFor returns the patterns that find versions of a scheme in verify output
+func versionPatternsFor(scheme string) []*regexp.Regexp {
+	if patterns, ok := schemePatterns[strings.ToLower(scheme)]; ok {
+		return patterns
+	}
+	return versionPatterns
+}
+
+// semverComparator compares semantic versions
+type semverComparator struct{}
+
+func (semverComparator) Validate(version string) error {
+	if _, err := semver.NewVersion(version); err != nil {
+		return fmt.Errorf("invalid semver version '%s': %w", version, err)
+	}
+	return nil
+}
+
+func (semverComparator) Compare(a, b string) (int, error) {
+	va, err := semver.NewVersion(a)
+	if err != nil {
+		return 0, fmt.Errorf("invalid semver version '%s': %w", a, err)
+	}
+	vb, err := semver.NewVersion(b)
+	if err != nil {
+		return 0, fmt.Errorf("invalid semver version '%s': %w", b, err)
+	}
+	return va.Compare(vb), nil
+}
+
+func (semverComparator) UpdateType(current, required string) (UpdateType, error) {
+	return CheckVersionUpdate(current, required)
+}
+
+func (semverComparator) Satisfies(version, constraint string) (bool, error) {
+	return IsVersionCompatible(version, constraint)
+}
+
+func (semverComparator) ValidateConstraint(constraint string) error {
+	_, err := semver.NewConstraint(constraint)
+	return err
+}
+
+// componentComparator compares dot-separated integer versions component by component
+// Missing components count as zero, so 2024.3 equals 2024.3.0
+type componentComparator struct {
+	scheme   string
+	validate func(components []int) error // Extra scheme rules, if any
+}
+
+// parse splits a version into its integer components
+func (c componentComparator) parse(version string) ([]int, error) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if trimmed == "" {
+		return nil, fmt.Errorf("invalid %s version '%s': empty", c.scheme, version)
+	}
+
+	parts := strings.Split(trimmed, ".")
+	components := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid %s version '%s': component '%s' is not a non-negative integer", c.scheme, version, part)
+		}
+		components[i] = n
+	}
+
+	if c.validate != nil {
+		if err := c.validate(components); err != nil {
+			return nil, fmt.Errorf("invalid %s version '%s': %w", c.scheme, version, err)
+		}
+	}
+	return components, nil
+}
+
+func (c componentComparator) Validate(version string) error {
+	_, err := c.parse(version)
+	return err
+}
+
+func (c componentComparator) Compare(a, b string) (int, error) {
+	_, cmp, err := c.compare(a, b)
+	return cmp, err
+}
+
+// compare returns the index of the first differing component along with the comparison
+func (c componentComparator) compare(a, b string) (int, int, error) {
+	ca, err := c.parse(a)
+	if err != nil {
+		return 0, 0, err
+	}
+	cb, err := c.parse(b)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for i := 0; i < max(len(ca), len(cb)); i++ {
+		var x, y int
+		if i < len(ca) {
+			x = ca[i]
+		}
+		if i < len(cb) {
+			y = cb[i]
+		}
+		if x < y {
+			return i, -1, nil
+		}
+		if x > y {
+			return i, 1, nil
+		}
+	}
+	return 0, 0, nil
+}
+
+// UpdateType maps the first component to a major update, the second to a minor update
+// and any later one to a patch update (for calver: year, month, then micro)
+func (c componentComparator) UpdateType(current, required string) (UpdateType, error) {
+	index, cmp, err := c.compare(current, required)
+	if err != nil || cmp >= 0 {
+		return NoUpdate, err
+	}
+
+	switch index {
+	case 0:
+		return MajorUpdate, nil
+	case 1:
+		return MinorUpdate, nil
+	default:
+		return PatchUpdate, nil
+	}
+}
+
+func (c componentComparator) Satisfies(version, constraint string) (bool, error) {
+	return satisfiesConstraint(c, version, constraint)
+}
+
+func (c componentComparator) ValidateConstraint(constraint string) error {
+	return validateConstraint(c, constraint)
+}
+
+// validateCalver checks a calendar version has a year and a valid month
+func validateCalver(components []int) error {
+	if len(components) < 2 {
+		return fmt.Errorf("expected at least a year and a month")
+	}
+	if month := components[1]; month < 1 || month > 12 {
+		return fmt.Errorf("month %d is out of range", month)
+	}
+	return nil
+}
+
+// lexicalComparator compares versions as plain strings
+type lexicalComparator struct{}
+
+func (lexicalComparator) Validate(version string) error {
+	if strings.TrimSpace(version) == "" {
+		return fmt.Errorf("invalid lexical version: empty")
+	}
+	return nil
+}
+
+func (l lexicalComparator) Compare(a, b string) (int, error) {
+	if err := l.Validate(a); err != nil {
+		return 0, err
+	}
+	if err := l.Validate(b); err != nil {
+		return 0, err
+	}
+	return strings.Compare(strings.TrimSpace(a), strings.TrimSpace(b)), nil
+}
+
+// UpdateType reports any older version as a major update, as lexical versions
+// carry no information about the size of a change
+func (l lexicalComparator) UpdateType(current, required string) (UpdateType, error) {
+	cmp, err := l.Compare(current, required)
+	if err != nil || cmp >= 0 {
+		return NoUpdate, err
+	}
+	return MajorUpdate, nil
+}
+
+func (l lexicalComparator) Satisfies(version, constraint string) (bool, error) {
+	return satisfiesConstraint(l, version, constraint)
+}
+
+func (l lexicalComparator) ValidateConstraint(constraint string) error {
+	return validateConstraint(l, constraint)
+}
+
+// constraintOperators are the operators of non-semver constraints, longest first
+var constraintOperators = []string{">=", "<=", "!=", "==", ">", "<", "="}
+
+// constraintTerm is a single comparison in a non-semver constraint
+type constraintTerm struct {
+	op      string
+	version string
+}
+
+// parseConstraint parses a constraint like ">=2024.01, <2025.01 || =2023.12.5"
+// Terms separated by commas must all hold, and any alternative separated by || may match
+func parseConstraint(c Comparator, constraint string) ([][]constraintTerm, error) {
+	var alternatives [][]constraintTerm
+	for _, alternative := range strings.Split(constraint, "||") {
+		var terms []constraintTerm
+		for _, term := range strings.Split(alternative, ",") {
+			term = strings.TrimSpace(term)
+			op := "="
+			for _, candidate := range constraintOperators {
+				if strings.HasPrefix(term, candidate) {
+					op = candidate
+					term = strings.TrimSpace(strings.TrimPrefix(term, candidate))
+					break
+				}
+			}
+			if err := c.Validate(term); err != nil {
+				return nil, fmt.Errorf("invalid constraint '%s': %w", constraint, err)
+			}
+			terms = append(terms, constraintTerm{op: op, version: term})
+		}
+		alternatives = append(alternatives, terms)
+	}
+	return alternatives, nil
+}
+
+// validateConstraint reports whether a non-semver constraint can be parsed
+func validateConstraint(c Comparator, constraint string) error {
+	_, err := parseConstraint(c, constraint)
+	return err
+}
+
+// satisfiesConstraint reports whether version satisfies a non-semver constraint
+func satisfiesConstraint(c Comparator, version, constraint string) (bool, error) {
+	if err := c.Validate(version); err != nil {
+		return false, err
+	}
+	alternatives, err := parseConstraint(c, constraint)
+	if err != nil {
+		return false, err
+	}
+
+	for _, terms := range alternatives {
+		satisfied := true
+		for _, term := range terms {
+			cmp, err := c.Compare(version, term.version)
+			if err != nil {
+				return false, err
+			}
+			if !compareSatisfies(cmp, term.op) {
+				satisfied = false
+				break
+			}
+		}
+		if satisfied {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// compareSatisfies reports whether a comparison result satisfies an operator
+func compareSatisfies(cmp int, op string) bool {
+	switch op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case "!=":
+		return cmp != 0
+	default:
+		return cmp == 0
+	}
+}
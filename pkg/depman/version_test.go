@@ -1,6 +1,7 @@
 package depman
 
 import (
+	"regexp"
 	"testing"
 )
 
@@ -47,6 +48,20 @@ func TestCheckVersionUpdate(t *testing.T) {
 			expectedUpdate:  NoUpdate,
 			expectError:     false,
 		},
+		{
+			name:            "Current major newer with lower minor",
+			currentVersion:  "3.0.0",
+			requiredVersion: "2.5.0",
+			expectedUpdate:  NoUpdate,
+			expectError:     false,
+		},
+		{
+			name:            "Current minor newer with lower patch",
+			currentVersion:  "2.1.0",
+			requiredVersion: "2.0.5",
+			expectedUpdate:  NoUpdate,
+			expectError:     false,
+		},
 		{
 			name:            "Invalid current version",
 			currentVersion:  "not-a-version",
@@ -165,3 +180,181 @@ func TestIsVersionCompatible(t *testing.T) {
 		})
 	}
 }
+
+// TestExtractVersionWith tests version extraction from multiline output
+func TestExtractVersionWith(t *testing.T) {
+	banner := "Example Toolkit 2.0.1 (c) Example Corp\nmytool version 1.4.2\nlibfoo 0.9.0 loaded"
+
+	tests := []struct {
+		name     string
+		output   string
+		match    VersionMatch
+		expected string
+	}{
+		{
+			name:     "Default picks first match",
+			output:   banner,
+			expected: "2.0.1",
+		},
+		{
+			name:     "Anchored to keyword line",
+			output:   banner,
+			match:    VersionMatch{Line: "mytool"},
+			expected: "1.4.2",
+		},
+		{
+			name:     "Last match wins",
+			output:   banner,
+			match:    VersionMatch{MatchLast: true},
+			expected: "0.9.0",
+		},
+		{
+			name:     "Last keyword line wins",
+			output:   "build 1.0.0\nmytool version 1.2.3 (stale)\nmytool version 2.3.4\nplugin 3.0.0",
+			match:    VersionMatch{Line: "mytool", MatchLast: true},
+			expected: "2.3.4",
+		},
+		{
+			name:     "No matching line returns output",
+			output:   "no versions here",
+			match:    VersionMatch{Line: "mytool"},
+			expected: "no versions here",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractVersionWith(tt.output, tt.match); got != tt.expected {
+				t.Errorf("Expected version %s but got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestExtractVersion tests the exported version extraction, with and without additional patterns
+func TestExtractVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		patterns []*regexp.Regexp
+		expected string
+	}{
+		{name: "Plain version", output: "1.2.3", expected: "1.2.3"},
+		{name: "Prefixed version", output: "mytool v2.0.1 (linux/amd64)", expected: "2.0.1"},
+		{name: "No version returns output", output: "unknown", expected: "unknown"},
+		{
+			name:     "Additional pattern wins over built-in ones",
+			output:   "tool 1.0.0 (build 20240301)",
+			patterns: []*regexp.Regexp{regexp.MustCompile(`build (\d+)`)},
+			expected: "20240301",
+		},
+		{
+			name:     "Additional pattern without a group uses the whole match",
+			output:   "release r57",
+			patterns: []*regexp.Regexp{regexp.MustCompile(`r\d+`)},
+			expected: "r57",
+		},
+		{
+			name:     "Falls back to built-in patterns",
+			output:   "tool 1.0.0",
+			patterns: []*regexp.Regexp{regexp.MustCompile(`build (\d+)`)},
+			expected: "1.0.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractVersion(tt.output, tt.patterns...); got != tt.expected {
+				t.Errorf("Expected version %s but got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestVersionSchemes tests comparing versions with non-semver schemes
+func TestVersionSchemes(t *testing.T) {
+	testCases := []struct {
+		name           string
+		scheme         string
+		current        string
+		required       string
+		constraint     string
+		expectedUpdate UpdateType
+		expectedCompat bool
+		expectError    bool
+	}{
+		{name: "Semver major newer with lower minor", scheme: SchemeSemver, current: "3.0.0", required: "2.5.0", expectedUpdate: NoUpdate},
+		{name: "Semver minor newer with lower patch", scheme: SchemeSemver, current: "2.1.0", required: "2.0.5", expectedUpdate: NoUpdate},
+		{name: "Calver equal", scheme: SchemeCalver, current: "2024.03.1", required: "2024.03.1", expectedUpdate: NoUpdate},
+		{name: "Calver missing micro equals zero", scheme: SchemeCalver, current: "2024.03", required: "2024.3.0", expectedUpdate: NoUpdate},
+		{name: "Calver micro update", scheme: SchemeCalver, current: "2024.03.1", required: "2024.03.2", expectedUpdate: PatchUpdate},
+		{name: "Calver month update", scheme: SchemeCalver, current: "2024.03.9", required: "2024.04", expectedUpdate: MinorUpdate},
+		{name: "Calver year update", scheme: SchemeCalver, current: "2023.12.5", required: "2024.01.0", expectedUpdate: MajorUpdate},
+		{name: "Calver newer than required", scheme: SchemeCalver, current: "2024.10", required: "2024.03.1", expectedUpdate: NoUpdate},
+		{name: "Calver month out of range", scheme: SchemeCalver, current: "2024.13", required: "2024.03", expectError: true},
+		{name: "Calver without month", scheme: SchemeCalver, current: "2024", required: "2024.03", expectError: true},
+		{name: "Calver constraint satisfied", scheme: SchemeCalver, current: "2024.03.1", constraint: ">=2024.01, <2025.01", expectedCompat: true},
+		{name: "Calver constraint violated", scheme: SchemeCalver, current: "2025.02", constraint: ">=2024.01, <2025.01", expectedCompat: false},
+		{name: "Numeric update", scheme: SchemeNumeric, current: "117", required: "118", expectedUpdate: MajorUpdate},
+		{name: "Numeric not lexical", scheme: SchemeNumeric, current: "9", required: "10", expectedUpdate: MajorUpdate},
+		{name: "Numeric fourth component", scheme: SchemeNumeric, current: "1.2.3.4", required: "1.2.3.5", expectedUpdate: PatchUpdate},
+		{name: "Numeric newer than required", scheme: SchemeNumeric, current: "120", required: "118", expectedUpdate: NoUpdate},
+		{name: "Numeric invalid", scheme: SchemeNumeric, current: "1.x", required: "2", expectError: true},
+		{name: "Numeric constraint alternatives", scheme: SchemeNumeric, current: "95", constraint: ">=118 || =95", expectedCompat: true},
+		{name: "Numeric invalid constraint", scheme: SchemeNumeric, current: "95", constraint: ">=abc", expectError: true},
+		{name: "Lexical update", scheme: SchemeLexical, current: "bookworm", required: "trixie", expectedUpdate: MajorUpdate},
+		{name: "Lexical not equal constraint", scheme: SchemeLexical, current: "bookworm", constraint: "!=buster", expectedCompat: true},
+		{name: "Unknown scheme", scheme: "roman", current: "IV", required: "V", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			comparator, err := ComparatorFor(tc.scheme)
+			if err == nil && tc.required != "" {
+				var updateType UpdateType
+				updateType, err = comparator.UpdateType(tc.current, tc.required)
+				if err == nil && updateType != tc.expectedUpdate {
+					t.Errorf("Expected %s but got %s", tc.expectedUpdate, updateType)
+				}
+			}
+			if err == nil && tc.constraint != "" {
+				var compatible bool
+				compatible, err = comparator.Satisfies(tc.current, tc.constraint)
+				if err == nil && compatible != tc.expectedCompat {
+					t.Errorf("Expected compatible %v but got %v", tc.expectedCompat, compatible)
+				}
+			}
+
+			if tc.expectError && err == nil {
+				t.Errorf("Expected an error but got none")
+			}
+			if !tc.expectError && err != nil {
+				t.Errorf("Did not expect an error but got: %v", err)
+			}
+		})
+	}
+}
+
+// TestVersionSchemeExtraction tests finding non-semver versions in verify output
+func TestVersionSchemeExtraction(t *testing.T) {
+	testCases := []struct {
+		name     string
+		scheme   string
+		output   string
+		expected string
+	}{
+		{name: "Calver", scheme: SchemeCalver, output: "tool 2024.03 (build 7)", expected: "2024.03"},
+		{name: "Numeric", scheme: SchemeNumeric, output: "Chrome 118", expected: "118"},
+		{name: "Lexical first line", scheme: SchemeLexical, output: "\nbookworm\nextra", expected: "bookworm"},
+		{name: "Semver default", scheme: "", output: "tool v1.2.3", expected: "1.2.3"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			version, found := findVersionWith(tc.output, VersionMatch{}, versionPatternsFor(tc.scheme))
+			if !found || version != tc.expected {
+				t.Errorf("Expected %q but got %q (found %v)", tc.expected, version, found)
+			}
+		})
+	}
+}
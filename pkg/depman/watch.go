@@ -0,0 +1,94 @@
+package depman
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Health states of a dependency as reported by DiffStatuses
+const (
+	HealthOK           = "ok"
+	HealthMissing      = "missing"
+	HealthBroken       = "broken" // Verify command exists but fails
+	HealthIncompatible = "incompatible"
+	HealthOutdated     = "outdated"
+	HealthError        = "error"
+	HealthSkipped      = "skipped"
+	HealthAbsent       = "absent" // Not present in the status set (e.g. removed from the config)
+)
+
+// StatusChange describes a dependency whose health changed between two checks
+type StatusChange struct {
+	Name string            // Name of the dependency
+	From string            // Previous health state
+	To   string            // Current health state
+	Old  *DependencyStatus // Previous status, nil if absent
+	New  *DependencyStatus // Current status, nil if absent
+}
+
+// String returns a human-readable description of the change
+func (c StatusChange) String() string {
+	s := fmt.Sprintf("%s: %s -> %s", c.Name, c.From, c.To)
+	if c.New != nil && c.New.CurrentVersion != "" && (c.Old == nil || c.Old.CurrentVersion != c.New.CurrentVersion) {
+		s += fmt.Sprintf(" (v%s)", c.New.CurrentVersion)
+	}
+	return s
+}
+
+// Degraded reports whether the change is a transition away from a healthy state
+func (c StatusChange) Degraded() bool {
+	return c.From == HealthOK && c.To != HealthOK
+}
+
+// Health classifies a dependency status into one of the Health* states
+func Health(status *DependencyStatus) string {
+	switch {
+	case status == nil:
+		return HealthAbsent
+	case status.Skipped:
+		return HealthSkipped
+	case status.Broken:
+		return HealthBroken
+	case !status.Installed:
+		return HealthMissing
+	case !status.Compatible:
+		return HealthIncompatible
+	case status.RequiredUpdate != NoUpdate:
+		return HealthOutdated
+	case status.Error != nil:
+		return HealthError
+	default:
+		return HealthOK
+	}
+}
+
+// DiffStatuses compares two status sets (as returned by CheckAllDependencies) and
+// returns the dependencies whose health changed, sorted by name. Version changes
+// that keep a dependency healthy are reported as well.
+func DiffStatuses(old, new map[string]*DependencyStatus) []StatusChange {
+	names := make(map[string]bool)
+	for name := range old {
+		names[name] = true
+	}
+	for name := range new {
+		names[name] = true
+	}
+
+	var changes []StatusChange
+	for name := range names {
+		before, after := old[name], new[name]
+		from, to := Health(before), Health(after)
+
+		versionChanged := before != nil && after != nil && before.CurrentVersion != after.CurrentVersion
+		if from == to && !versionChanged {
+			continue
+		}
+
+		changes = append(changes, StatusChange{Name: name, From: from, To: to, Old: before, New: after})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
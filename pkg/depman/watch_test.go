@@ -0,0 +1,81 @@
+package depman
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDiffStatuses(t *testing.T) {
+	ok := &DependencyStatus{Name: "tool", Installed: true, Compatible: true, CurrentVersion: "1.0.0"}
+
+	testCases := []struct {
+		name     string
+		old      map[string]*DependencyStatus
+		new      map[string]*DependencyStatus
+		expected []string
+	}{
+		{
+			name:     "No changes",
+			old:      map[string]*DependencyStatus{"tool": ok},
+			new:      map[string]*DependencyStatus{"tool": ok},
+			expected: nil,
+		},
+		{
+			name:     "Became missing",
+			old:      map[string]*DependencyStatus{"tool": ok},
+			new:      map[string]*DependencyStatus{"tool": {Name: "tool"}},
+			expected: []string{"tool: ok -> missing"},
+		},
+		{
+			name:     "Became incompatible",
+			old:      map[string]*DependencyStatus{"tool": ok},
+			new:      map[string]*DependencyStatus{"tool": {Name: "tool", Installed: true, CurrentVersion: "0.9.0"}},
+			expected: []string{"tool: ok -> incompatible (v0.9.0)"},
+		},
+		{
+			name:     "Became broken",
+			old:      map[string]*DependencyStatus{"tool": ok},
+			new:      map[string]*DependencyStatus{"tool": {Name: "tool", Broken: true}},
+			expected: []string{"tool: ok -> broken"},
+		},
+		{
+			name:     "Recovered",
+			old:      map[string]*DependencyStatus{"tool": {Name: "tool", Error: errors.New("boom")}},
+			new:      map[string]*DependencyStatus{"tool": ok},
+			expected: []string{"tool: missing -> ok (v1.0.0)"},
+		},
+		{
+			name:     "Version changed while healthy",
+			old:      map[string]*DependencyStatus{"tool": ok},
+			new:      map[string]*DependencyStatus{"tool": {Name: "tool", Installed: true, Compatible: true, CurrentVersion: "1.0.1"}},
+			expected: []string{"tool: ok -> ok (v1.0.1)"},
+		},
+		{
+			name:     "Added and removed",
+			old:      map[string]*DependencyStatus{"b": ok},
+			new:      map[string]*DependencyStatus{"a": ok},
+			expected: []string{"a: absent -> ok (v1.0.0)", "b: ok -> absent"},
+		},
+		{
+			name:     "First check",
+			old:      nil,
+			new:      map[string]*DependencyStatus{"tool": {Name: "tool", Skipped: true}},
+			expected: []string{"tool: absent -> skipped"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			changes := DiffStatuses(tc.old, tc.new)
+
+			if len(changes) != len(tc.expected) {
+				t.Fatalf("Expected %d changes but got %d: %v", len(tc.expected), len(changes), changes)
+			}
+			for i, change := range changes {
+				if change.String() != tc.expected[i] {
+					t.Errorf("Expected change %q but got %q", tc.expected[i], change.String())
+				}
+			}
+		})
+	}
+}